@@ -4,6 +4,7 @@ package pubsub
 import (
 	"context"
 	"errors"
+	"sync"
 
 	"github.com/eapache/channels"
 )
@@ -69,7 +70,12 @@ func (s *Subscription) Close() {
 		isSubscribe: false,
 	}
 
-	s.b.cmdCh <- ctx
+	select {
+	case s.b.cmdCh <- ctx:
+	case <-s.b.doneCh:
+		// The broker has been closed, which also closed the subscription's channel.
+		return
+	}
 	if err := <-ctx.errCh; err != nil {
 		panic(err)
 	}
@@ -83,6 +89,10 @@ type Broker struct {
 	lastBroadcasted *broadcastedValue
 
 	onSubscribeHook OnSubscribeHook
+
+	closeLock sync.RWMutex
+	closed    bool
+	doneCh    chan struct{}
 }
 
 // OnSubscribeHook is the on-subscribe callback hook prototype.
@@ -128,8 +138,12 @@ func (b *Broker) SubscribeEx(buffer int64, onSubscribeHook OnSubscribeHook) *Sub
 		isSubscribe:     true,
 	}
 
-	b.cmdCh <- ctx
-	<-ctx.errCh
+	select {
+	case b.cmdCh <- ctx:
+		<-ctx.errCh
+	case <-b.doneCh:
+		ch.Close()
+	}
 
 	return &Subscription{
 		b:  b,
@@ -140,12 +154,35 @@ func (b *Broker) SubscribeEx(buffer int64, onSubscribeHook OnSubscribeHook) *Sub
 // Broadcast queues up a new value to be broadcasted.
 //
 // Note: This makes no special effort to avoid deadlocking if any one
-// of the subscribers' channel is full.
+// of the subscribers' channel is full.  Values broadcasted after the
+// Broker is closed are dropped.
 func (b *Broker) Broadcast(v interface{}) {
+	b.closeLock.RLock()
+	defer b.closeLock.RUnlock()
+
+	if b.closed {
+		return
+	}
 	b.broadcastCh.In() <- v
 }
 
+// Close stops the Broker once all values broadcasted so far have been
+// delivered, closing the channels of all subscriptions.  Subscribing to
+// a closed Broker returns a subscription with a closed channel.
+func (b *Broker) Close() {
+	b.closeLock.Lock()
+	defer b.closeLock.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	b.broadcastCh.Close()
+}
+
 func (b *Broker) worker() {
+	defer close(b.doneCh)
+
 	for {
 		select {
 		case ctx := <-b.cmdCh:
@@ -167,7 +204,13 @@ func (b *Broker) worker() {
 					close(ctx.errCh)
 				}
 			}
-		case v := <-b.broadcastCh.Out():
+		case v, ok := <-b.broadcastCh.Out():
+			if !ok {
+				for ch := range b.subscribers {
+					ch.Close()
+				}
+				return
+			}
 			for ch := range b.subscribers {
 				ch.In() <- v
 			}
@@ -210,5 +253,6 @@ func newBroker() *Broker {
 		subscribers: make(map[channels.Channel]bool),
 		cmdCh:       make(chan *cmdCtx),
 		broadcastCh: channels.NewInfiniteChannel(),
+		doneCh:      make(chan struct{}),
 	}
 }
@@ -19,6 +19,7 @@ func TestPubSub(t *testing.T) {
 	t.Run("PubLastOnSubscribe", testLastOnSubscribe)
 	t.Run("SubscribeEx", testSubscribeEx)
 	t.Run("NewBrokerEx", testNewBrokerEx)
+	t.Run("Close", testClose)
 }
 
 func testBasicInfinity(t *testing.T) {
@@ -154,3 +155,40 @@ func testNewBrokerEx(t *testing.T) {
 		require.Equal(t, sub.ch, callbackCh, "Callback channel != Subscription, inner channel")
 	}
 }
+
+func testClose(t *testing.T) {
+	broker := NewBroker(false)
+
+	sub := broker.Subscribe()
+	typedCh := make(chan int)
+	sub.Unwrap(typedCh)
+
+	// Values broadcasted before the broker is closed should still be delivered.
+	broker.Broadcast(23)
+	broker.Close()
+	select {
+	case v := <-typedCh:
+		require.Equal(t, 23, v, "Broadcast() before Close()")
+	case <-time.After(recvTimeout):
+		t.Fatalf("Failed to receive value broadcasted before Close()")
+	}
+	select {
+	case _, ok := <-typedCh:
+		require.False(t, ok, "subscription channel should be closed")
+	case <-time.After(recvTimeout):
+		t.Fatalf("Failed to observe closed subscription channel")
+	}
+
+	// Using a closed broker should neither block nor panic.
+	require.NotPanics(t, func() { broker.Broadcast(42) }, "Broadcast() after Close()")
+	require.NotPanics(t, func() { broker.Close() }, "repeated Close()")
+	require.NotPanics(t, func() { sub.Close() }, "Subscription.Close() after Close()")
+
+	late := broker.Subscribe()
+	select {
+	case _, ok := <-late.Untyped():
+		require.False(t, ok, "subscription to a closed broker should be closed")
+	case <-time.After(recvTimeout):
+		t.Fatalf("Failed to observe closed late subscription channel")
+	}
+}
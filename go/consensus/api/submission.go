@@ -13,6 +13,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/quantity"
 	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
 	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
 )
 
@@ -209,6 +210,64 @@ func SignAndSubmitTx(ctx context.Context, backend Backend, signer signature.Sign
 	return backend.SubmissionManager().SignAndSubmitTx(ctx, signer, tx)
 }
 
+// SubmitUnfreezeNode is a helper function that signs and submits an unfreeze node transaction to
+// the consensus backend and confirms that the node has been unfrozen.
+//
+// The nonce and the fee are filled in automatically as with SignAndSubmitTx. Once the transaction
+// is included in a block, the emitted node unfrozen event is located and the resulting node status
+// is queried as of the same height.
+func SubmitUnfreezeNode(
+	ctx context.Context,
+	backend Backend,
+	signer signature.Signer,
+	unfreeze *registry.UnfreezeNode,
+) (*registry.UnfreezeNodeResult, error) {
+	status, err := backend.GetStatus(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consensus status: %w", err)
+	}
+	submitHeight := status.LatestHeight
+
+	tx := registry.NewUnfreezeNodeTx(0, nil, unfreeze)
+	if err = SignAndSubmitTx(ctx, backend, signer, tx); err != nil {
+		return nil, err
+	}
+
+	// The transaction has been included in a block after the one that was the latest before
+	// submission, look for the emitted event starting with the most recent block.
+	if status, err = backend.GetStatus(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get consensus status: %w", err)
+	}
+	for height := status.LatestHeight; height > submitHeight; height-- {
+		var evs []*registry.Event
+		if evs, err = backend.Registry().GetEvents(ctx, height); err != nil {
+			return nil, fmt.Errorf("failed to get registry events at height %d: %w", height, err)
+		}
+		for _, ev := range evs {
+			if ev.NodeUnfrozenEvent == nil || !ev.NodeUnfrozenEvent.NodeID.Equal(unfreeze.NodeID) {
+				continue
+			}
+
+			var nodeStatus *registry.NodeStatus
+			nodeStatus, err = backend.Registry().GetNodeStatus(ctx, &registry.IDQuery{
+				Height: height,
+				ID:     unfreeze.NodeID,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get node status at height %d: %w", height, err)
+			}
+
+			return &registry.UnfreezeNodeResult{
+				Height: height,
+				TxHash: ev.TxHash,
+				Status: nodeStatus,
+				Event:  ev.NodeUnfrozenEvent,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("node unfrozen event not found between heights %d and %d", submitHeight+1, status.LatestHeight)
+}
+
 // NoOpSubmissionManager implements a submission manager that doesn't support submitting transactions.
 type NoOpSubmissionManager struct{}
 
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/transaction"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+type testSubmissionBackend struct {
+	Backend
+
+	height   int64
+	txHash   hash.Hash
+	dropped  bool
+	events   map[int64][]*registry.Event
+	statuses map[int64]*registry.NodeStatus
+}
+
+func (b *testSubmissionBackend) GetStatus(ctx context.Context) (*Status, error) {
+	return &Status{LatestHeight: b.height}, nil
+}
+
+func (b *testSubmissionBackend) SubmissionManager() SubmissionManager {
+	return &testSubmissionManager{backend: b}
+}
+
+func (b *testSubmissionBackend) Registry() registry.Backend {
+	return &testRegistryBackend{backend: b}
+}
+
+type testSubmissionManager struct {
+	SubmissionManager
+
+	backend *testSubmissionBackend
+}
+
+func (m *testSubmissionManager) SignAndSubmitTx(ctx context.Context, signer signature.Signer, tx *transaction.Transaction) error {
+	if tx.Method != registry.MethodUnfreezeNode {
+		return transaction.ErrMethodNotSupported
+	}
+	var unfreeze registry.UnfreezeNode
+	if err := cbor.Unmarshal(tx.Body, &unfreeze); err != nil {
+		return err
+	}
+
+	// Include the transaction in the next block, followed by another block without the event.
+	b := m.backend
+	b.height++
+	if b.dropped {
+		b.height++
+		return nil
+	}
+	b.events[b.height] = append(b.events[b.height], &registry.Event{
+		Height:            b.height,
+		TxHash:            b.txHash,
+		NodeUnfrozenEvent: &registry.NodeUnfrozenEvent{NodeID: unfreeze.NodeID},
+	})
+	b.statuses[b.height] = &registry.NodeStatus{}
+	b.height++
+	return nil
+}
+
+type testRegistryBackend struct {
+	registry.Backend
+
+	backend *testSubmissionBackend
+}
+
+func (r *testRegistryBackend) GetEvents(ctx context.Context, height int64) ([]*registry.Event, error) {
+	return r.backend.events[height], nil
+}
+
+func (r *testRegistryBackend) GetNodeStatus(ctx context.Context, query *registry.IDQuery) (*registry.NodeStatus, error) {
+	status, ok := r.backend.statuses[query.Height]
+	if !ok {
+		return nil, registry.ErrNoSuchNode
+	}
+	return status, nil
+}
+
+func TestSubmitUnfreezeNode(t *testing.T) {
+	require := require.New(t)
+
+	signer := memorySigner.NewTestSigner("consensus/api: submission test signer")
+	nodeID := memorySigner.NewTestSigner("consensus/api: submission test node").Public()
+	otherID := memorySigner.NewTestSigner("consensus/api: submission test other node").Public()
+
+	backend := &testSubmissionBackend{
+		height: 10,
+		txHash: hash.NewFromBytes([]byte("unfreeze node")),
+		events: map[int64][]*registry.Event{
+			// An older event for the same node must not be reported.
+			9: {{Height: 9, NodeUnfrozenEvent: &registry.NodeUnfrozenEvent{NodeID: nodeID}}},
+			// An event for another node in the same block must be skipped.
+			11: {{Height: 11, NodeUnfrozenEvent: &registry.NodeUnfrozenEvent{NodeID: otherID}}},
+		},
+		statuses: make(map[int64]*registry.NodeStatus),
+	}
+
+	result, err := SubmitUnfreezeNode(context.Background(), backend, signer, &registry.UnfreezeNode{NodeID: nodeID})
+	require.NoError(err, "SubmitUnfreezeNode")
+	require.EqualValues(11, result.Height, "result should contain the height at which the node was unfrozen")
+	require.Equal(backend.txHash, result.TxHash, "result should contain the transaction hash")
+	require.Same(backend.statuses[11], result.Status, "result should contain the node status at that height")
+	require.NotNil(result.Event, "result should contain the emitted event")
+	require.True(result.Event.NodeID.Equal(nodeID), "event should be for the unfrozen node")
+
+	// Submission should fail if the node unfrozen event cannot be found.
+	backend.dropped = true
+	_, err = SubmitUnfreezeNode(context.Background(), backend, signer, &registry.UnfreezeNode{NodeID: nodeID})
+	require.Error(err, "SubmitUnfreezeNode should fail without a node unfrozen event")
+}
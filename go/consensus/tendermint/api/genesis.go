@@ -17,7 +17,6 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/consensus/tendermint/crypto"
 	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
 	cmdFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
-	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
 	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
 	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
 )
@@ -154,7 +153,7 @@ func convertValidators(d *genesis.Document) ([]tmtypes.GenesisValidator, error)
 	vPerE := make(map[signature.PublicKey]int)
 	for _, v := range d.Registry.Nodes {
 		var openedNode node.Node
-		if err = v.Open(registry.RegisterGenesisNodeSignatureContext, &openedNode); err != nil {
+		if err = v.Open(d.Registry.Parameters.SignatureContexts().RegisterNode, &openedNode); err != nil {
 			return nil, fmt.Errorf("tendermint: failed to verify validator: %w", err)
 		}
 		// TODO: This should cross check that the entity is valid.
@@ -112,6 +112,13 @@ func (app *registryApplication) ExecuteTx(ctx *api.Context, tx *transaction.Tran
 		}
 
 		return app.unfreezeNode(ctx, state, &unfreeze)
+	case registry.MethodMultiSignedUnfreezeNode:
+		var sigUnfreeze registry.MultiSignedUnfreezeNode
+		if err := cbor.Unmarshal(tx.Body, &sigUnfreeze); err != nil {
+			return err
+		}
+
+		return app.multiSignedUnfreezeNode(ctx, state, &sigUnfreeze)
 	case registry.MethodRegisterRuntime:
 		var rt registry.Runtime
 		if err := cbor.Unmarshal(tx.Body, &rt); err != nil {
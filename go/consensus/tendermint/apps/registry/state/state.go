@@ -475,6 +475,17 @@ func (s *ImmutableState) ConsensusParameters(ctx context.Context) (*registry.Con
 	return &params, nil
 }
 
+// TEEFeatures returns the TEE features enabled by the registry consensus parameters.
+//
+// In case no TEE features are enabled, nil is returned.
+func (s *ImmutableState) TEEFeatures(ctx context.Context) (*node.TEEFeatures, error) {
+	params, err := s.ConsensusParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return params.TEEFeatures, nil
+}
+
 // NodeBySubKey looks up a specific node by its consensus, P2P or TLS key.
 func (s *ImmutableState) NodeBySubKey(ctx context.Context, key signature.PublicKey) (*node.Node, error) {
 	rawID, err := s.is.Get(ctx, keyMapKeyFmt.Encode(&key))
@@ -160,3 +160,28 @@ func TestNodeUpdate(t *testing.T) {
 	require.Error(err, "TLS mapping should be gone")
 	require.Equal(registry.ErrNoSuchNode, err, "TLS mapping should be gone")
 }
+
+func TestTEEFeatures(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1580461674, 0)
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock, now)
+	defer ctx.Close()
+
+	s := NewMutableState(ctx.State())
+	err := s.SetConsensusParameters(ctx, &registry.ConsensusParameters{})
+	require.NoError(err, "SetConsensusParameters")
+
+	features, err := s.TEEFeatures(ctx)
+	require.NoError(err, "TEEFeatures")
+	require.Nil(features, "TEE features should not be enabled by default")
+
+	expected := &node.TEEFeatures{SGX: node.TEEFeaturesSGX{PCS: true}}
+	err = s.SetConsensusParameters(ctx, &registry.ConsensusParameters{TEEFeatures: expected})
+	require.NoError(err, "SetConsensusParameters")
+
+	features, err = s.TEEFeatures(ctx)
+	require.NoError(err, "TEEFeatures")
+	require.EqualValues(expected, features, "TEE features should match consensus parameters")
+}
@@ -21,7 +21,15 @@ func (app *registryApplication) registerEntity(
 	state *registryState.MutableState,
 	sigEnt *entity.SignedEntity,
 ) error {
-	ent, err := registry.VerifyRegisterEntityArgs(ctx.Logger(), sigEnt, ctx.IsInitChain(), false)
+	params, err := state.ConsensusParameters(ctx)
+	if err != nil {
+		ctx.Logger().Error("RegisterEntity: failed to fetch consensus parameters",
+			"err", err,
+		)
+		return err
+	}
+
+	ent, err := registry.VerifyRegisterEntityArgs(params, ctx.Logger(), sigEnt, ctx.IsInitChain(), false)
 	if err != nil {
 		return err
 	}
@@ -31,13 +39,6 @@ func (app *registryApplication) registerEntity(
 	}
 
 	// Charge gas for this transaction.
-	params, err := state.ConsensusParameters(ctx)
-	if err != nil {
-		ctx.Logger().Error("RegisterEntity: failed to fetch consensus parameters",
-			"err", err,
-		)
-		return err
-	}
 	if err = ctx.Gas().UseGas(1, registry.GasOpRegisterEntity, params.GasCosts); err != nil {
 		return err
 	}
@@ -559,6 +560,11 @@ func (app *registryApplication) unfreezeNode(
 		return err
 	}
 
+	// When an unfreeze authority is configured, only it can unfreeze nodes.
+	if params.UnfreezeAuthority != nil {
+		return registry.ErrForbidden
+	}
+
 	// Fetch node descriptor.
 	node, err := state.Node(ctx, unfreeze.NodeID)
 	if err != nil {
@@ -584,6 +590,79 @@ func (app *registryApplication) unfreezeNode(
 		return err
 	}
 
+	return app.doUnfreezeNode(ctx, state, node, status)
+}
+
+func (app *registryApplication) multiSignedUnfreezeNode(
+	ctx *api.Context,
+	state *registryState.MutableState,
+	sigUnfreeze *registry.MultiSignedUnfreezeNode,
+) error {
+	if ctx.IsCheckOnly() {
+		return nil
+	}
+
+	// Charge gas for this transaction.
+	params, err := state.ConsensusParameters(ctx)
+	if err != nil {
+		ctx.Logger().Error("MultiSignedUnfreezeNode: failed to fetch consensus parameters",
+			"err", err,
+		)
+		return err
+	}
+	if err = ctx.Gas().UseGas(1, registry.GasOpUnfreezeNode, params.GasCosts); err != nil {
+		return err
+	}
+
+	if params.UnfreezeAuthority == nil {
+		return registry.ErrForbidden
+	}
+
+	// Make sure that the unfreeze request was signed by enough members of the unfreeze authority.
+	unfreeze, err := params.UnfreezeAuthority.Verify(sigUnfreeze, params.SignatureContextVersion)
+	if err != nil {
+		ctx.Logger().Debug("MultiSignedUnfreezeNode: unauthorized unfreeze request",
+			"err", err,
+		)
+		return err
+	}
+
+	// Fetch node descriptor.
+	node, err := state.Node(ctx, unfreeze.NodeID)
+	if err != nil {
+		ctx.Logger().Error("MultiSignedUnfreezeNode: failed to fetch node",
+			"err", err,
+			"node_id", unfreeze.NodeID,
+		)
+		return err
+	}
+
+	// Fetch node status.
+	status, err := state.NodeStatus(ctx, unfreeze.NodeID)
+	if err != nil {
+		ctx.Logger().Error("MultiSignedUnfreezeNode: failed to fetch node status",
+			"err", err,
+			"node_id", unfreeze.NodeID,
+			"entity_id", node.EntityID,
+		)
+		return err
+	}
+
+	// Make sure that the unfreeze request is for the current freeze and has not been used before.
+	if !status.IsFrozen() || status.FreezeEndTime != unfreeze.FreezeEndTime || status.UnfreezeNonce != unfreeze.Nonce {
+		return registry.ErrInvalidArgument
+	}
+	status.UnfreezeNonce++
+
+	return app.doUnfreezeNode(ctx, state, node, status)
+}
+
+func (app *registryApplication) doUnfreezeNode(
+	ctx *api.Context,
+	state *registryState.MutableState,
+	node *node.Node,
+	status *registry.NodeStatus,
+) error {
 	// Ensure if we can actually unfreeze.
 	epoch, err := app.state.GetEpoch(ctx, ctx.BlockHeight()+1)
 	if err != nil {
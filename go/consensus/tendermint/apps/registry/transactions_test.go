@@ -505,3 +505,111 @@ func TestRegisterNode(t *testing.T) {
 		})
 	}
 }
+
+func TestMultiSignedUnfreezeNode(t *testing.T) {
+	require := requirePkg.New(t)
+
+	now := time.Unix(1580461674, 0)
+	cfg := abciAPI.MockApplicationStateConfig{
+		CurrentEpoch: 10,
+	}
+	appState := abciAPI.NewMockApplicationState(&cfg)
+	ctx := appState.NewContext(abciAPI.ContextEndBlock, now)
+	defer ctx.Close()
+
+	var md abciAPI.NoopMessageDispatcher
+	app := registryApplication{appState, &md}
+	state := registryState.NewMutableState(ctx.State())
+
+	members := []signature.Signer{
+		memorySigner.NewTestSigner("consensus/tendermint/apps/registry: unfreeze authority member 1"),
+		memorySigner.NewTestSigner("consensus/tendermint/apps/registry: unfreeze authority member 2"),
+		memorySigner.NewTestSigner("consensus/tendermint/apps/registry: unfreeze authority member 3"),
+	}
+	authority := &registry.UnfreezeAuthority{Threshold: 2}
+	for _, member := range members {
+		authority.Members = append(authority.Members, member.Public())
+	}
+
+	// Set up registry consensus parameters.
+	err := state.SetConsensusParameters(ctx, &registry.ConsensusParameters{
+		MaxNodeExpiration: 5,
+		UnfreezeAuthority: authority,
+	})
+	require.NoError(err, "registry.SetConsensusParameters")
+
+	// Set up a frozen node.
+	entitySigner := memorySigner.NewTestSigner("consensus/tendermint/apps/registry: unfreeze entity signer")
+	nodeSigner := memorySigner.NewTestSigner("consensus/tendermint/apps/registry: unfreeze node signer")
+	n := &node.Node{
+		Versioned:  cbor.NewVersioned(node.LatestNodeDescriptorVersion),
+		ID:         nodeSigner.Public(),
+		EntityID:   entitySigner.Public(),
+		Expiration: 15,
+	}
+	sigNode, err := node.MultiSignNode([]signature.Signer{nodeSigner}, registry.RegisterNodeSignatureContext, n)
+	require.NoError(err, "MultiSignNode")
+	err = state.SetNode(ctx, nil, n, sigNode)
+	require.NoError(err, "SetNode")
+	err = state.SetNodeStatus(ctx, n.ID, &registry.NodeStatus{FreezeEndTime: 5})
+	require.NoError(err, "SetNodeStatus")
+
+	sign := func(unfreeze *registry.UnfreezeNode, signers ...signature.Signer) *registry.MultiSignedUnfreezeNode {
+		var sigs []signature.Signature
+		for _, signer := range signers {
+			sig, serr := registry.SignUnfreezeNode(signer, registry.SignatureContextVersionInitial, unfreeze)
+			require.NoError(serr, "SignUnfreezeNode")
+			sigs = append(sigs, *sig)
+		}
+		return registry.NewMultiSignedUnfreezeNode(unfreeze, sigs)
+	}
+	requireFrozen := func(frozen bool) {
+		status, serr := state.NodeStatus(ctx, n.ID)
+		require.NoError(serr, "NodeStatus")
+		require.Equal(frozen, status.IsFrozen(), "node frozen status should be correct")
+	}
+
+	// Unfreezing by the owning entity alone should be forbidden.
+	txCtx := appState.NewContext(abciAPI.ContextDeliverTx, now)
+	defer txCtx.Close()
+	txCtx.SetTxSigner(entitySigner.Public())
+	err = app.unfreezeNode(txCtx, state, &registry.UnfreezeNode{NodeID: n.ID})
+	require.ErrorIs(err, registry.ErrForbidden, "single-signer unfreeze should be forbidden")
+	requireFrozen(true)
+
+	unfreeze := &registry.UnfreezeNode{NodeID: n.ID, FreezeEndTime: 5}
+
+	// Requests below the threshold should be rejected.
+	err = app.multiSignedUnfreezeNode(txCtx, state, sign(unfreeze, members[0]))
+	require.ErrorIs(err, registry.ErrInsufficientSignatures, "unfreeze below threshold should be rejected")
+	requireFrozen(true)
+
+	// Requests for a different freeze should be rejected.
+	staleUnfreeze := &registry.UnfreezeNode{NodeID: n.ID, FreezeEndTime: 3}
+	err = app.multiSignedUnfreezeNode(txCtx, state, sign(staleUnfreeze, members[0], members[1]))
+	require.ErrorIs(err, registry.ErrInvalidArgument, "unfreeze for a different freeze should be rejected")
+	requireFrozen(true)
+
+	// Requests signed by enough members should be accepted.
+	sigUnfreeze := sign(unfreeze, members[1], members[2])
+	err = app.multiSignedUnfreezeNode(txCtx, state, sigUnfreeze)
+	require.NoError(err, "unfreeze with threshold signatures should succeed")
+	requireFrozen(false)
+
+	// Replaying the request after the node has been frozen again with the same freeze end time
+	// should be rejected.
+	status, err := state.NodeStatus(ctx, n.ID)
+	require.NoError(err, "NodeStatus")
+	require.EqualValues(1, status.UnfreezeNonce, "unfreeze nonce should be incremented")
+	status.FreezeEndTime = 5
+	err = state.SetNodeStatus(ctx, n.ID, status)
+	require.NoError(err, "SetNodeStatus")
+	err = app.multiSignedUnfreezeNode(txCtx, state, sigUnfreeze)
+	require.ErrorIs(err, registry.ErrInvalidArgument, "replayed unfreeze should be rejected")
+	requireFrozen(true)
+
+	// A new request for the current nonce should be accepted.
+	err = app.multiSignedUnfreezeNode(txCtx, state, sign(&registry.UnfreezeNode{NodeID: n.ID, FreezeEndTime: 5, Nonce: 1}, members[0], members[2]))
+	require.NoError(err, "unfreeze with the current nonce should succeed")
+	requireFrozen(false)
+}
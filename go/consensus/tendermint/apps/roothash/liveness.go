@@ -81,6 +81,14 @@ func processLivenessStatistics(ctx *tmapi.Context, epoch beacon.EpochTime, rtSta
 					status.FreezeEndTime = epoch + slashParams.FreezeInterval
 				}
 
+				var regParams *registry.ConsensusParameters
+				if regParams, err = regState.ConsensusParameters(ctx); err != nil {
+					return fmt.Errorf("failed to get registry consensus parameters: %w", err)
+				}
+				if regParams.EnableFreezeReasons {
+					status.FreezeReason = registry.FreezeReasonRuntimeLiveness
+				}
+
 				// Slash if configured.
 				err = onRuntimeLivenessFailure(ctx, nodeID, &slashParams.Amount)
 				if err != nil {
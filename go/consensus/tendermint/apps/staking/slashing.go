@@ -84,6 +84,14 @@ func onEvidenceByzantineConsensus(
 		} else {
 			nodeStatus.FreezeEndTime = epoch + penalty.FreezeInterval
 		}
+
+		var regParams *registry.ConsensusParameters
+		if regParams, err = regState.ConsensusParameters(ctx); err != nil {
+			return err
+		}
+		if regParams.EnableFreezeReasons {
+			nodeStatus.FreezeReason = registry.FreezeReasonConsensusMisbehavior
+		}
 	}
 
 	// Slash validator.
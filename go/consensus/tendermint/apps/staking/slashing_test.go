@@ -87,6 +87,8 @@ func TestOnEvidenceByzantineConsensus(t *testing.T) {
 		},
 	})
 	require.NoError(err, "SetConsensusParameters")
+	err = regState.SetConsensusParameters(ctx, &registry.ConsensusParameters{})
+	require.NoError(err, "SetConsensusParameters")
 
 	// Should not fail if the validator has no stake (which is in any case an
 	// invariant violation as a validator needs to have some stake).
@@ -97,6 +99,7 @@ func TestOnEvidenceByzantineConsensus(t *testing.T) {
 	require.NoError(err, "NodeStatus")
 	require.True(status.IsFrozen(), "node should be frozen after slashing")
 	require.EqualValues(registry.FreezeForever, status.FreezeEndTime, "node should be frozen forever")
+	require.Equal(registry.FreezeReasonNone, status.FreezeReason, "freeze reason should not be recorded unless enabled")
 
 	// Should not fail slashing a frozen node.
 	err = onEvidenceByzantineConsensus(ctx, staking.SlashConsensusEquivocation, validatorAddress, 1, now, 1)
@@ -123,6 +126,9 @@ func TestOnEvidenceByzantineConsensus(t *testing.T) {
 	})
 	require.NoError(err, "SetAccount")
 
+	err = regState.SetConsensusParameters(ctx, &registry.ConsensusParameters{EnableFreezeReasons: true})
+	require.NoError(err, "SetConsensusParameters")
+
 	// Should slash.
 	err = onEvidenceByzantineConsensus(ctx, staking.SlashConsensusEquivocation, validatorAddress, 1, now, 1)
 	require.NoError(err, "slashing should succeed")
@@ -138,6 +144,7 @@ func TestOnEvidenceByzantineConsensus(t *testing.T) {
 	require.NoError(err, "NodeStatus")
 	require.True(status.IsFrozen(), "node should be frozen after slashing")
 	require.EqualValues(registry.FreezeForever, status.FreezeEndTime, "node should be frozen forever")
+	require.Equal(registry.FreezeReasonConsensusMisbehavior, status.FreezeReason, "freeze reason should be recorded")
 
 	// Should not fail in case the slashing penalty is not configured.
 	err = onEvidenceByzantineConsensus(ctx, staking.SlashConsensusLightClientAttack, validatorAddress, 1, now, 1)
@@ -42,7 +42,7 @@ func checkRegistry(ctx *abciAPI.Context, now beacon.EpochTime) error {
 	if err != nil {
 		return fmt.Errorf("SignedEntities: %w", err)
 	}
-	seenEntities, err := registry.SanityCheckEntities(logger, signedEntities)
+	seenEntities, err := registry.SanityCheckEntities(logger, params, signedEntities)
 	if err != nil {
 		return fmt.Errorf("SanityCheckEntities: %w", err)
 	}
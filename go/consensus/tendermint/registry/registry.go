@@ -226,6 +226,14 @@ func (sc *serviceClient) ConsensusParameters(ctx context.Context, height int64)
 	return q.ConsensusParameters(ctx)
 }
 
+func (sc *serviceClient) TEEFeatures(ctx context.Context, height int64) (*node.TEEFeatures, error) {
+	q, err := sc.querier.QueryAt(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return q.TEEFeatures(ctx)
+}
+
 // Implements api.ServiceClient.
 func (sc *serviceClient) ServiceDescriptor() tmapi.ServiceDescriptor {
 	return tmapi.NewStaticServiceDescriptor(api.ModuleName, app.EventType, []tmpubsub.Query{app.QueryApp})
@@ -418,7 +418,7 @@ func populateAddrBookFromGenesis(addrBook p2p.AddrBook, doc *genesis.Document, o
 	var addrs []*p2p.NetAddress
 	for _, v := range doc.Registry.Nodes {
 		var openedNode node.Node
-		if err := v.Open(registry.RegisterGenesisNodeSignatureContext, &openedNode); err != nil {
+		if err := v.Open(doc.Registry.Parameters.SignatureContexts().RegisterNode, &openedNode); err != nil {
 			return fmt.Errorf("tendermint/seed: failed to verify validator: %w", err)
 		}
 		// TODO: This should cross check that the entity is valid.
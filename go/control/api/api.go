@@ -53,6 +53,10 @@ type NodeController interface {
 
 	// GetStatus returns the current status overview of the node.
 	GetStatus(ctx context.Context) (*Status, error)
+
+	// GetUpgradeReadiness returns the node's pending upgrades together with the status of their
+	// migration handlers.
+	GetUpgradeReadiness(ctx context.Context) ([]*upgrade.UpgradeReadiness, error)
 }
 
 // Status is the current status overview.
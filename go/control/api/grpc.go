@@ -29,6 +29,8 @@ var (
 	methodCancelUpgrade = serviceName.NewMethod("CancelUpgrade", nil)
 	// methodGetStatus is the GetStatus method.
 	methodGetStatus = serviceName.NewMethod("GetStatus", nil)
+	// methodGetUpgradeReadiness is the GetUpgradeReadiness method.
+	methodGetUpgradeReadiness = serviceName.NewMethod("GetUpgradeReadiness", nil)
 
 	// serviceDesc is the gRPC service descriptor.
 	serviceDesc = grpc.ServiceDesc{
@@ -67,6 +69,10 @@ var (
 				MethodName: methodGetStatus.ShortName(),
 				Handler:    handlerGetStatus,
 			},
+			{
+				MethodName: methodGetUpgradeReadiness.ShortName(),
+				Handler:    handlerGetUpgradeReadiness,
+			},
 		},
 		Streams: []grpc.StreamDesc{},
 	}
@@ -236,6 +242,25 @@ func handlerGetStatus(
 	return interceptor(ctx, nil, info, handler)
 }
 
+func handlerGetUpgradeReadiness(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	if interceptor == nil {
+		return srv.(NodeController).GetUpgradeReadiness(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetUpgradeReadiness.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NodeController).GetUpgradeReadiness(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
 // RegisterService registers a new node controller service with the given gRPC server.
 func RegisterService(server *grpc.Server, service NodeController) {
 	server.RegisterService(&serviceDesc, service)
@@ -289,6 +314,14 @@ func (c *nodeControllerClient) GetStatus(ctx context.Context) (*Status, error) {
 	return &rsp, nil
 }
 
+func (c *nodeControllerClient) GetUpgradeReadiness(ctx context.Context) ([]*upgradeApi.UpgradeReadiness, error) {
+	var rsp []*upgradeApi.UpgradeReadiness
+	if err := c.conn.Invoke(ctx, methodGetUpgradeReadiness.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
 // NewNodeControllerClient creates a new gRPC node controller client service.
 func NewNodeControllerClient(c *grpc.ClientConn) NodeController {
 	return &nodeControllerClient{c}
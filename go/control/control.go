@@ -135,6 +135,10 @@ func (c *nodeController) GetStatus(ctx context.Context) (*control.Status, error)
 	}, nil
 }
 
+func (c *nodeController) GetUpgradeReadiness(ctx context.Context) ([]*upgrade.UpgradeReadiness, error) {
+	return c.upgrader.GetUpgradeReadiness(ctx)
+}
+
 // New creates a new oasis-node controller.
 func New(node control.ControlledNode, consensus consensus.Backend, upgrader upgrade.Backend) control.NodeController {
 	return &nodeController{
@@ -32,6 +32,10 @@ const (
 	// yes.
 	CfgAssumeYes      = "assume_yes"
 	cfgAssumeYesShort = "y"
+
+	// CfgSignatureContextVersion is the flag used to specify the version of the registry
+	// signature contexts used to sign registrations.
+	CfgSignatureContextVersion = "signature_context_version"
 )
 
 var (
@@ -57,6 +61,9 @@ var (
 
 	// AssumeYesFlag has the assume yes flag.
 	AssumeYesFlag = flag.NewFlagSet("", flag.ContinueOnError)
+
+	// SignatureContextVersionFlag has the registry signature context version flag.
+	SignatureContextVersionFlag = flag.NewFlagSet("", flag.ContinueOnError)
 )
 
 // Verbose returns true iff the verbose flag is set.
@@ -105,6 +112,11 @@ func AssumeYes() bool {
 	return viper.GetBool(CfgAssumeYes)
 }
 
+// SignatureContextVersion returns the set registry signature context version.
+func SignatureContextVersion() uint16 {
+	return uint16(viper.GetUint(CfgSignatureContextVersion))
+}
+
 func init() {
 	VerboseFlags.BoolP(cfgVerbose, "v", false, "verbose output")
 
@@ -127,6 +139,8 @@ func init() {
 
 	AssumeYesFlag.BoolP(CfgAssumeYes, cfgAssumeYesShort, false, "automatically assume yes for all questions")
 
+	SignatureContextVersionFlag.Uint16(CfgSignatureContextVersion, 0, "version of the registry signature contexts used for signing")
+
 	for _, v := range []*flag.FlagSet{
 		VerboseFlags,
 		ForceFlags,
@@ -137,6 +151,7 @@ func init() {
 		DebugDontBlameOasisFlag,
 		DryRunFlag,
 		AssumeYesFlag,
+		SignatureContextVersionFlag,
 	} {
 		_ = viper.BindPFlags(v)
 	}
@@ -65,6 +65,12 @@ var (
 		Run:   doCancelUpgrade,
 	}
 
+	controlUpgradeReadinessCmd = &cobra.Command{
+		Use:   "upgrade-readiness",
+		Short: "check whether the node is able to apply all pending upgrades",
+		Run:   doUpgradeReadiness,
+	}
+
 	controlStatusCmd = &cobra.Command{
 		Use:   "status",
 		Short: "show node status",
@@ -274,6 +280,40 @@ func doCancelUpgrade(cmd *cobra.Command, args []string) {
 	}
 }
 
+func doUpgradeReadiness(cmd *cobra.Command, args []string) {
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	logger.Debug("querying upgrade readiness")
+
+	// Use background context to block until the result comes in.
+	readiness, err := client.GetUpgradeReadiness(context.Background())
+	if err != nil {
+		logger.Error("failed to query upgrade readiness",
+			"err", err,
+		)
+		os.Exit(128)
+	}
+	prettyReadiness, err := cmdCommon.PrettyJSONMarshal(readiness)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of upgrade readiness",
+			"err", err,
+		)
+		os.Exit(128)
+	}
+	fmt.Println(string(prettyReadiness))
+
+	for _, ur := range readiness {
+		if !ur.IsReady() {
+			logger.Error("missing migration handler for pending upgrade",
+				"handler", ur.Upgrade.Descriptor.Handler,
+				"epoch", ur.Upgrade.Descriptor.Epoch,
+			)
+			os.Exit(1)
+		}
+	}
+}
+
 func doStatus(cmd *cobra.Command, args []string) {
 	conn, client := DoConnect(cmd)
 	defer conn.Close()
@@ -310,6 +350,7 @@ func Register(parentCmd *cobra.Command) {
 	controlCmd.AddCommand(controlClearDeregisterCmd)
 	controlCmd.AddCommand(controlUpgradeBinaryCmd)
 	controlCmd.AddCommand(controlCancelUpgradeCmd)
+	controlCmd.AddCommand(controlUpgradeReadinessCmd)
 	controlCmd.AddCommand(controlStatusCmd)
 	controlCmd.AddCommand(controlRuntimeStatsCmd)
 	parentCmd.AddCommand(controlCmd)
@@ -613,20 +613,24 @@ func updateGenesisDoc(oldDoc v4Document) (*genesis.Document, error) {
 	newDoc.Registry.Entities = make([]*entity.SignedEntity, 0)
 	newDoc.Registry.Nodes = make([]*node.MultiSignedNode, 0)
 
+	// Registrations remain signed using the signature contexts of the registry parameters, which
+	// are carried over unchanged.
+	sigCtxs := oldDoc.Registry.Parameters.SignatureContexts()
+
 	// Remove entities with not enough stake.
 	var entities []*entity.Entity
 	var nodes []*node.Node
 	var runtimes []*registry.Runtime
 	for _, sigEntity := range oldDoc.Registry.Entities {
 		var entity entity.Entity
-		if err = sigEntity.Open(registry.RegisterGenesisEntitySignatureContext, &entity); err != nil {
+		if err = sigEntity.Open(sigCtxs.RegisterEntity, &entity); err != nil {
 			return nil, fmt.Errorf("unable to open signed entity: %w", err)
 		}
 		entities = append(entities, &entity)
 	}
 	for _, sigNode := range oldDoc.Registry.Nodes {
 		var node node.Node
-		if err = sigNode.Open(registry.RegisterGenesisNodeSignatureContext, &node); err != nil {
+		if err = sigNode.Open(sigCtxs.RegisterNode, &node); err != nil {
 			return nil, fmt.Errorf("unable to open signed node: %w", err)
 		}
 		nodes = append(nodes, &node)
@@ -649,7 +653,7 @@ func updateGenesisDoc(oldDoc v4Document) (*genesis.Document, error) {
 	entityMap := make(map[signature.PublicKey]*entity.Entity)
 	for _, sigEntity := range oldDoc.Registry.Entities {
 		var entity entity.Entity
-		if err := sigEntity.Open(registry.RegisterEntitySignatureContext, &entity); err != nil {
+		if err := sigEntity.Open(sigCtxs.RegisterEntity, &entity); err != nil {
 			return nil, fmt.Errorf("unable to open signed entity: %w", err)
 		}
 		addr := staking.NewAddress(entity.ID)
@@ -677,7 +681,7 @@ func updateGenesisDoc(oldDoc v4Document) (*genesis.Document, error) {
 NodeLoop:
 	for _, sigNode := range oldDoc.Registry.Nodes {
 		var node node.Node
-		if err := sigNode.Open(registry.RegisterGenesisNodeSignatureContext, &node); err != nil {
+		if err := sigNode.Open(sigCtxs.RegisterNode, &node); err != nil {
 			return nil, fmt.Errorf("unable to open signed node: %w", err)
 		}
 		if ent := removedEntities[node.EntityID]; ent != nil {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -52,6 +53,46 @@ var (
 		Run: doCheckRoots,
 	}
 
+	storageUnquarantineRoundCmd = &cobra.Command{
+		Use:   "unquarantine-round runtime-id (hex) round",
+		Short: "release a storage round quarantined after repeated apply mismatches",
+		Args: func(cmd *cobra.Command, args []string) error {
+			nrFn := cobra.ExactArgs(2)
+			if err := nrFn(cmd, args); err != nil {
+				return err
+			}
+			if err := ValidateRuntimeIDStr(args[0]); err != nil {
+				return fmt.Errorf("malformed runtime id '%v': %w", args[0], err)
+			}
+			if _, err := strconv.ParseUint(args[1], 10, 64); err != nil {
+				return fmt.Errorf("malformed round '%v': %w", args[1], err)
+			}
+
+			return nil
+		},
+		Run: doUnquarantineRound,
+	}
+
+	storageSetSyncCeilingCmd = &cobra.Command{
+		Use:   "set-sync-ceiling runtime-id (hex) round",
+		Short: "raise the round up to which the storage worker syncs (0 removes the ceiling)",
+		Args: func(cmd *cobra.Command, args []string) error {
+			nrFn := cobra.ExactArgs(2)
+			if err := nrFn(cmd, args); err != nil {
+				return err
+			}
+			if err := ValidateRuntimeIDStr(args[0]); err != nil {
+				return fmt.Errorf("malformed runtime id '%v': %w", args[0], err)
+			}
+			if _, err := strconv.ParseUint(args[1], 10, 64); err != nil {
+				return fmt.Errorf("malformed round '%v': %w", args[1], err)
+			}
+
+			return nil
+		},
+		Run: doSetSyncCeiling,
+	}
+
 	logger = logging.GetLogger("cmd/storage")
 )
 
@@ -212,10 +253,56 @@ func doCheckRoots(cmd *cobra.Command, args []string) {
 	}
 }
 
+func doUnquarantineRound(cmd *cobra.Command, args []string) {
+	conn, _ := cmdControl.DoConnect(cmd)
+	storageWorkerClient := storageWorkerAPI.NewStorageWorkerClient(conn)
+	defer conn.Close()
+
+	var id common.Namespace
+	_ = id.UnmarshalHex(args[0])
+	round, _ := strconv.ParseUint(args[1], 10, 64)
+
+	err := storageWorkerClient.UnquarantineRound(context.Background(), &storageWorkerAPI.UnquarantineRoundRequest{
+		RuntimeID: id,
+		Round:     round,
+	})
+	if err != nil {
+		logger.Error("failed to release quarantined round",
+			"err", err,
+			"round", round,
+		)
+		os.Exit(1)
+	}
+}
+
+func doSetSyncCeiling(cmd *cobra.Command, args []string) {
+	conn, _ := cmdControl.DoConnect(cmd)
+	storageWorkerClient := storageWorkerAPI.NewStorageWorkerClient(conn)
+	defer conn.Close()
+
+	var id common.Namespace
+	_ = id.UnmarshalHex(args[0])
+	round, _ := strconv.ParseUint(args[1], 10, 64)
+
+	err := storageWorkerClient.SetSyncCeiling(context.Background(), &storageWorkerAPI.SetSyncCeilingRequest{
+		RuntimeID: id,
+		Round:     round,
+	})
+	if err != nil {
+		logger.Error("failed to set sync ceiling",
+			"err", err,
+			"round", round,
+		)
+		os.Exit(1)
+	}
+}
+
 // Register registers the storage sub-command and all of its children.
 func Register(parentCmd *cobra.Command) {
 	storageCheckRootsCmd.PersistentFlags().AddFlagSet(cmdGrpc.ClientFlags)
 	storageCheckRootsCmd.PersistentFlags().AddFlagSet(cmdFlags.DebugDontBlameOasisFlag)
+	storageUnquarantineRoundCmd.PersistentFlags().AddFlagSet(cmdGrpc.ClientFlags)
+	storageSetSyncCeilingCmd.PersistentFlags().AddFlagSet(cmdGrpc.ClientFlags)
 
 	storageExportCmd.Flags().AddFlagSet(storage.Flags)
 	storageExportCmd.Flags().AddFlagSet(cmdFlags.GenesisFileFlags)
@@ -227,5 +314,7 @@ func Register(parentCmd *cobra.Command) {
 	storageCmd.AddCommand(storageCheckRootsCmd)
 	storageCmd.AddCommand(storageExportCmd)
 	storageCmd.AddCommand(storageBenchmarkCmd)
+	storageCmd.AddCommand(storageUnquarantineRoundCmd)
+	storageCmd.AddCommand(storageSetSyncCeilingCmd)
 	parentCmd.AddCommand(storageCmd)
 }
@@ -143,7 +143,7 @@ func getNodeDesc(rng *rand.Rand, nodeIdentity *identity.Identity, entityID signa
 	return &nodeDesc
 }
 
-func signNode(identity *identity.Identity, nodeDesc *node.Node) (*node.MultiSignedNode, error) {
+func signNode(sigCtx signature.Context, identity *identity.Identity, nodeDesc *node.Node) (*node.MultiSignedNode, error) {
 	return node.MultiSignNode(
 		[]signature.Signer{
 			identity.NodeSigner,
@@ -152,7 +152,7 @@ func signNode(identity *identity.Identity, nodeDesc *node.Node) (*node.MultiSign
 			identity.GetTLSSigner(),
 			identity.VRFSigner,
 		},
-		registry.RegisterNodeSignatureContext,
+		sigCtx,
 		nodeDesc,
 	)
 }
@@ -176,7 +176,17 @@ func (r *registration) Run( // nolint: gocyclo
 	r.BaseWorkload.Init(cnsc, sm, fundingAccount)
 
 	beacon := beacon.NewBeaconClient(conn)
+	registryClient := registry.NewRegistryClient(conn)
 	ctx := context.Background()
+
+	// Sign registrations using the registry's current signature context version.
+	signatureContexts := func() (*registry.SignatureContexts, error) {
+		params, err := registryClient.ConsensusParameters(ctx, consensus.HeightLatest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get registry consensus parameters: %w", err)
+		}
+		return params.SignatureContexts(), nil
+	}
 	var err error
 
 	// Non-existing runtime.
@@ -257,7 +267,11 @@ func (r *registration) Run( // nolint: gocyclo
 		}
 
 		// Register entity.
-		sigEntity, err := entity.SignEntity(entityAccs[i].signer, registry.RegisterEntitySignatureContext, ent)
+		sigCtxs, err := signatureContexts()
+		if err != nil {
+			return err
+		}
+		sigEntity, err := entity.SignEntity(entityAccs[i].signer, sigCtxs.RegisterEntity, ent)
 		if err != nil {
 			return fmt.Errorf("failed to sign entity: %w", err)
 		}
@@ -311,7 +325,11 @@ func (r *registration) Run( // nolint: gocyclo
 		// We should update for at minimum 2 epochs, as the epoch could change between querying it
 		// and actually performing the registration.
 		selectedNode.nodeDesc.Expiration = uint64(epoch) + 2 + uint64(rng.Intn(registryNodeMaxEpochUpdate-1))
-		sigNode, err := signNode(selectedNode.id, selectedNode.nodeDesc)
+		sigCtxs, err := signatureContexts()
+		if err != nil {
+			return err
+		}
+		sigNode, err := signNode(sigCtxs.RegisterNode, selectedNode.id, selectedNode.nodeDesc)
 		if err != nil {
 			return fmt.Errorf("failed to sign node: %w", err)
 		}
@@ -65,6 +65,7 @@ const (
 	cfgRegistryDebugBypassStake              = "registry.debug.bypass_stake" // nolint: gosec
 	cfgRegistryEnableRuntimeGovernanceModels = "registry.enable_runtime_governance_models"
 	CfgRegistryTEEFeaturesSGXPCS             = "registry.tee_features.sgx.pcs"
+	CfgRegistryEnableFreezeReasons           = "registry.enable_freeze_reasons"
 
 	// Scheduler config flags.
 	cfgSchedulerMinValidators          = "scheduler.min_validators"
@@ -338,6 +339,7 @@ func AppendRegistryState(doc *genesis.Document, entities, runtimes, nodes []stri
 			MaxNodeExpiration:             viper.GetUint64(CfgRegistryMaxNodeExpiration),
 			DisableRuntimeRegistration:    viper.GetBool(CfgRegistryDisableRuntimeRegistration),
 			EnableRuntimeGovernanceModels: make(map[registry.RuntimeGovernanceModel]bool),
+			EnableFreezeReasons:           viper.GetBool(CfgRegistryEnableFreezeReasons),
 		},
 		Entities: make([]*entity.SignedEntity, 0, len(entities)),
 		Runtimes: make([]*registry.Runtime, 0, len(runtimes)),
@@ -776,6 +778,7 @@ func init() {
 	initGenesisFlags.Bool(cfgRegistryDebugBypassStake, false, "bypass all stake checks and operations (UNSAFE)")
 	initGenesisFlags.StringSlice(cfgRegistryEnableRuntimeGovernanceModels, []string{"entity"}, "set of enabled runtime governance models")
 	initGenesisFlags.Bool(CfgRegistryTEEFeaturesSGXPCS, true, "enable PCS support for SGX TEEs")
+	initGenesisFlags.Bool(CfgRegistryEnableFreezeReasons, true, "record the reason for which nodes are frozen")
 	_ = initGenesisFlags.MarkHidden(cfgRegistryDebugAllowUnroutableAddresses)
 	_ = initGenesisFlags.MarkHidden(CfgRegistryDebugAllowTestRuntimes)
 	_ = initGenesisFlags.MarkHidden(cfgRegistryDebugBypassStake)
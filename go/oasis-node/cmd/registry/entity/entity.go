@@ -202,7 +202,7 @@ func doUpdate(cmd *cobra.Command, args []string) {
 		}
 
 		var n node.Node
-		if err = signedNode.Open(registry.RegisterGenesisNodeSignatureContext, &n); err != nil {
+		if err = signedNode.Open(genesisSignatureContexts().RegisterNode, &n); err != nil {
 			logger.Error("failed to validate signed node descriptor",
 				"err", err,
 			)
@@ -253,9 +253,15 @@ func doUpdate(cmd *cobra.Command, args []string) {
 	)
 }
 
+// genesisSignatureContexts returns the registry signature contexts used for genesis registrations,
+// at the configured signature context version.
+func genesisSignatureContexts() *registry.SignatureContexts {
+	return registry.NewSignatureContexts(cmdFlags.SignatureContextVersion())
+}
+
 func signAndWriteEntityGenesis(dataDir string, signer signature.Signer, ent *entity.Entity) error {
 	// Sign the entity registration for use in a genesis document.
-	signed, err := entity.SignEntity(signer, registry.RegisterGenesisEntitySignatureContext, ent)
+	signed, err := entity.SignEntity(signer, genesisSignatureContexts().RegisterEntity, ent)
 	if err != nil {
 		logger.Error("failed to sign entity for genesis registration",
 			"err", err,
@@ -298,7 +304,12 @@ func doGenRegister(cmd *cobra.Command, args []string) {
 	}
 	defer signer.Reset()
 
-	signed, err := entity.SignEntity(signer, registry.RegisterEntitySignatureContext, ent)
+	// Sign using the signature context version of the genesis document, unless overridden.
+	sigCtxVersion := genesis.Registry.Parameters.SignatureContextVersion
+	if viper.IsSet(cmdFlags.CfgSignatureContextVersion) {
+		sigCtxVersion = cmdFlags.SignatureContextVersion()
+	}
+	signed, err := entity.SignEntity(signer, registry.NewSignatureContexts(sigCtxVersion).RegisterEntity, ent)
 	if err != nil {
 		logger.Error("failed to sign entity descriptor",
 			"err", err,
@@ -415,6 +426,7 @@ func Register(parentCmd *cobra.Command) {
 	initCmd.Flags().AddFlagSet(initFlags)
 	updateCmd.Flags().AddFlagSet(updateFlags)
 	registerCmd.Flags().AddFlagSet(registerOrDeregisterFlags)
+	registerCmd.Flags().AddFlagSet(cmdFlags.SignatureContextVersionFlag)
 	deregisterCmd.Flags().AddFlagSet(registerOrDeregisterFlags)
 
 	listCmd.Flags().AddFlagSet(cmdFlags.VerboseFlags)
@@ -432,6 +444,7 @@ func init() {
 	initFlags.AddFlagSet(cmdFlags.ForceFlags)
 	initFlags.AddFlagSet(cmdFlags.DebugTestEntityFlags)
 	initFlags.AddFlagSet(cmdFlags.DebugDontBlameOasisFlag)
+	initFlags.AddFlagSet(cmdFlags.SignatureContextVersionFlag)
 	initFlags.AddFlagSet(entityFlags)
 	_ = viper.BindPFlags(initFlags)
 
@@ -440,6 +453,7 @@ func init() {
 	_ = viper.BindPFlags(updateFlags)
 	updateFlags.AddFlagSet(cmdFlags.DebugTestEntityFlags)
 	updateFlags.AddFlagSet(cmdFlags.DebugDontBlameOasisFlag)
+	updateFlags.AddFlagSet(cmdFlags.SignatureContextVersionFlag)
 	updateFlags.AddFlagSet(entityFlags)
 
 	registerOrDeregisterFlags.AddFlagSet(cmdFlags.DebugTestEntityFlags)
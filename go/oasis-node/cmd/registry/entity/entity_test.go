@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	cmdFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func TestSignAndWriteEntityGenesisSignatureContext(t *testing.T) {
+	require := require.New(t)
+	defer viper.Set(cmdFlags.CfgSignatureContextVersion, 0)
+
+	signer := memorySigner.NewTestSigner("oasis-node/cmd/registry/entity: test signer")
+	ent := &entity.Entity{
+		Versioned: cbor.NewVersioned(entity.LatestDescriptorVersion),
+		ID:        signer.Public(),
+	}
+
+	for _, version := range []uint16{registry.SignatureContextVersionInitial, 3} {
+		viper.Set(cmdFlags.CfgSignatureContextVersion, version)
+		dataDir := t.TempDir()
+		require.NoError(signAndWriteEntityGenesis(dataDir, signer, ent), "signAndWriteEntityGenesis")
+
+		raw, err := ioutil.ReadFile(filepath.Join(dataDir, entityGenesisFilename))
+		require.NoError(err, "ReadFile")
+		var signed entity.SignedEntity
+		require.NoError(json.Unmarshal(raw, &signed), "Unmarshal")
+
+		// The registration should verify at the version it was signed at, and only at that version.
+		params := &registry.ConsensusParameters{SignatureContextVersion: version}
+		_, err = registry.VerifyRegisterEntityArgs(params, logger, &signed, true, false)
+		require.NoError(err, "registration should verify at version %d", version)
+
+		params = &registry.ConsensusParameters{SignatureContextVersion: version + 1}
+		_, err = registry.VerifyRegisterEntityArgs(params, logger, &signed, true, false)
+		require.ErrorIs(err, registry.ErrInvalidSignature, "registration should not verify at version %d", version+1)
+	}
+}
@@ -250,7 +250,8 @@ func doInit(cmd *cobra.Command, args []string) { // nolint: gocyclo
 		nodeIdentity.GetTLSSigner(),
 	}
 
-	signed, err := node.MultiSignNode(signers, registry.RegisterGenesisNodeSignatureContext, n)
+	sigCtxs := registry.NewSignatureContexts(cmdFlags.SignatureContextVersion())
+	signed, err := node.MultiSignNode(signers, sigCtxs.RegisterNode, n)
 	if err != nil {
 		logger.Error("failed to sign node genesis registration",
 			"err", err,
@@ -382,6 +383,7 @@ func doIsRegistered(cmd *cobra.Command, args []string) {
 func Register(parentCmd *cobra.Command) {
 	initCmd.Flags().AddFlagSet(flags)
 	initCmd.Flags().AddFlagSet(cmdFlags.DebugTestEntityFlags)
+	initCmd.Flags().AddFlagSet(cmdFlags.SignatureContextVersionFlag)
 	initCmd.Flags().AddFlagSet(cmdSigner.Flags)
 	initCmd.Flags().AddFlagSet(cmdSigner.CLIFlags)
 
@@ -3,6 +3,7 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -52,6 +53,13 @@ var (
 		RunE:  doRenameNs,
 	}
 
+	storageDumpHistoryCmd = &cobra.Command{
+		Use:   "dump-history <runtime...>",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "print the persisted runtime history state as JSON",
+		RunE:  doDumpHistory,
+	}
+
 	logger = logging.GetLogger("cmd/storage")
 
 	pretty = cmdCommon.Isatty(1)
@@ -281,6 +289,30 @@ func doRenameNs(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func doDumpHistory(cmd *cobra.Command, args []string) error {
+	dataDir := cmdCommon.DataDir()
+
+	runtimes, err := parseRuntimes(args)
+	cobra.CheckErr(err)
+
+	for _, rt := range runtimes {
+		runtimeDir := registry.GetRuntimeStateDir(dataDir, rt)
+
+		meta, err := history.DumpMetadata(filepath.Join(runtimeDir, history.DbFilename))
+		if err != nil {
+			logger.Error("error dumping history database", "rt", rt, "err", err)
+			return fmt.Errorf("error dumping history database for runtime %v: %w", rt, err)
+		}
+
+		data, err := json.MarshalIndent(meta, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal history metadata for runtime %v: %w", rt, err)
+		}
+		fmt.Println(string(data))
+	}
+	return nil
+}
+
 // Register registers the client sub-command and all of its children.
 func Register(parentCmd *cobra.Command) {
 	storageMigrateCmd.Flags().AddFlagSet(registry.Flags)
@@ -288,5 +320,6 @@ func Register(parentCmd *cobra.Command) {
 	storageCmd.AddCommand(storageMigrateCmd)
 	storageCmd.AddCommand(storageCheckCmd)
 	storageCmd.AddCommand(storageRenameNsCmd)
+	storageCmd.AddCommand(storageDumpHistoryCmd)
 	parentCmd.AddCommand(storageCmd)
 }
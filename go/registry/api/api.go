@@ -43,6 +43,10 @@ var (
 	// registration.
 	RegisterNodeSignatureContext = signature.NewContext("oasis-core/registry: register node")
 
+	// UnfreezeNodeSignatureContext is the context used for signing node
+	// unfreeze requests by members of the unfreeze authority.
+	UnfreezeNodeSignatureContext = signature.NewContext("oasis-core/registry: unfreeze node")
+
 	// RegisterGenesisNodeSignatureContext is the context used for
 	// node registration in the genesis document.
 	//
@@ -120,6 +124,10 @@ var (
 	// has runtimes.
 	ErrEntityHasRuntimes = errors.New(ModuleName, 19, "registry: entity still has runtimes")
 
+	// ErrInsufficientSignatures is the error returned when a multi-signed request is not signed
+	// by enough authorized signers.
+	ErrInsufficientSignatures = errors.New(ModuleName, 20, "registry: insufficient signatures")
+
 	// MethodRegisterEntity is the method name for entity registrations.
 	MethodRegisterEntity = transaction.NewMethodName(ModuleName, "RegisterEntity", entity.SignedEntity{})
 	// MethodDeregisterEntity is the method name for entity deregistrations.
@@ -128,6 +136,9 @@ var (
 	MethodRegisterNode = transaction.NewMethodName(ModuleName, "RegisterNode", node.MultiSignedNode{})
 	// MethodUnfreezeNode is the method name for unfreezing nodes.
 	MethodUnfreezeNode = transaction.NewMethodName(ModuleName, "UnfreezeNode", UnfreezeNode{})
+	// MethodMultiSignedUnfreezeNode is the method name for unfreezing nodes by the unfreeze
+	// authority.
+	MethodMultiSignedUnfreezeNode = transaction.NewMethodName(ModuleName, "MultiSignedUnfreezeNode", MultiSignedUnfreezeNode{})
 	// MethodRegisterRuntime is the method name for registering runtimes.
 	MethodRegisterRuntime = transaction.NewMethodName(ModuleName, "RegisterRuntime", Runtime{})
 
@@ -137,6 +148,7 @@ var (
 		MethodDeregisterEntity,
 		MethodRegisterNode,
 		MethodUnfreezeNode,
+		MethodMultiSignedUnfreezeNode,
 		MethodRegisterRuntime,
 	}
 
@@ -221,6 +233,10 @@ type Backend interface {
 	// ConsensusParameters returns the registry consensus parameters.
 	ConsensusParameters(ctx context.Context, height int64) (*ConsensusParameters, error)
 
+	// TEEFeatures returns the TEE features enabled by the registry consensus parameters at the
+	// specified block height. In case no TEE features are enabled, nil is returned.
+	TEEFeatures(ctx context.Context, height int64) (*node.TEEFeatures, error)
+
 	// Cleanup cleans up the registry backend.
 	Cleanup()
 }
@@ -281,6 +297,12 @@ func NewUnfreezeNodeTx(nonce uint64, fee *transaction.Fee, unfreeze *UnfreezeNod
 	return transaction.NewTransaction(nonce, fee, MethodUnfreezeNode, unfreeze)
 }
 
+// NewMultiSignedUnfreezeNodeTx creates a new unfreeze node transaction authorized by the unfreeze
+// authority.
+func NewMultiSignedUnfreezeNodeTx(nonce uint64, fee *transaction.Fee, sigUnfreeze *MultiSignedUnfreezeNode) *transaction.Transaction {
+	return transaction.NewTransaction(nonce, fee, MethodMultiSignedUnfreezeNode, sigUnfreeze)
+}
+
 // NewRegisterRuntimeTx creates a new register runtime transaction.
 func NewRegisterRuntimeTx(nonce uint64, fee *transaction.Fee, rt *Runtime) *transaction.Transaction {
 	return transaction.NewTransaction(nonce, fee, MethodRegisterRuntime, rt)
@@ -400,19 +422,21 @@ type RuntimeLookup interface {
 }
 
 // VerifyRegisterEntityArgs verifies arguments for RegisterEntity.
-func VerifyRegisterEntityArgs(logger *logging.Logger, sigEnt *entity.SignedEntity, isGenesis, isSanityCheck bool) (*entity.Entity, error) {
+func VerifyRegisterEntityArgs(
+	params *ConsensusParameters,
+	logger *logging.Logger,
+	sigEnt *entity.SignedEntity,
+	isGenesis bool,
+	isSanityCheck bool,
+) (*entity.Entity, error) {
 	var ent entity.Entity
 	if sigEnt == nil {
 		return nil, ErrInvalidArgument
 	}
 
-	var ctx signature.Context
-	switch isGenesis {
-	case true:
-		ctx = RegisterGenesisEntitySignatureContext
-	case false:
-		ctx = RegisterEntitySignatureContext
-	}
+	// Genesis registrations use the same context as non-genesis registrations to support
+	// migrating existing registrations into a new genesis document.
+	ctx := params.SignatureContexts().RegisterEntity
 
 	if err := sigEnt.Open(ctx, &ent); err != nil {
 		logger.Error("RegisterEntity: invalid signature",
@@ -479,13 +503,9 @@ func VerifyRegisterNodeArgs( // nolint: gocyclo
 		return nil, nil, ErrInvalidArgument
 	}
 
-	var sigCtx signature.Context
-	switch isGenesis {
-	case true:
-		sigCtx = RegisterGenesisNodeSignatureContext
-	case false:
-		sigCtx = RegisterNodeSignatureContext
-	}
+	// Genesis registrations use the same context as non-genesis registrations to support
+	// migrating existing registrations into a new genesis document.
+	sigCtx := params.SignatureContexts().RegisterNode
 
 	if err := sigNode.Open(sigCtx, &n); err != nil {
 		logger.Error("RegisterNode: invalid signature",
@@ -1432,6 +1452,18 @@ type ConsensusParameters struct {
 
 	// TEEFeatures contains the configuration of supported TEE features.
 	TEEFeatures *node.TEEFeatures `json:"tee_features,omitempty"`
+
+	// UnfreezeAuthority is the optional committee authorized to unfreeze frozen nodes. In case it
+	// is configured, nodes can only be unfrozen by requests signed by a threshold of its members.
+	UnfreezeAuthority *UnfreezeAuthority `json:"unfreeze_authority,omitempty"`
+
+	// SignatureContextVersion is the version of the signature contexts used by registry
+	// transactions (see DeriveSignatureContext).
+	SignatureContextVersion uint16 `json:"signature_context_version,omitempty"`
+
+	// EnableFreezeReasons specifies whether the reason for which a node is frozen is recorded in
+	// its status.
+	EnableFreezeReasons bool `json:"enable_freeze_reasons,omitempty"`
 }
 
 const (
@@ -37,6 +37,8 @@ var (
 	methodGetEvents = serviceName.NewMethod("GetEvents", int64(0))
 	// methodConsensusParameters is the ConsensusParameters method.
 	methodConsensusParameters = serviceName.NewMethod("ConsensusParameters", int64(0))
+	// methodTEEFeatures is the TEEFeatures method.
+	methodTEEFeatures = serviceName.NewMethod("TEEFeatures", int64(0))
 
 	// methodWatchEntities is the WatchEntities method.
 	methodWatchEntities = serviceName.NewMethod("WatchEntities", nil)
@@ -96,6 +98,10 @@ var (
 				MethodName: methodConsensusParameters.ShortName(),
 				Handler:    handlerConsensusParameters,
 			},
+			{
+				MethodName: methodTEEFeatures.ShortName(),
+				Handler:    handlerTEEFeatures,
+			},
 		},
 		Streams: []grpc.StreamDesc{
 			{
@@ -375,6 +381,29 @@ func handlerConsensusParameters(
 	return interceptor(ctx, height, info, handler)
 }
 
+func handlerTEEFeatures(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	var height int64
+	if err := dec(&height); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).TEEFeatures(ctx, height)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodTEEFeatures.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Backend).TEEFeatures(ctx, req.(int64))
+	}
+	return interceptor(ctx, height, info, handler)
+}
+
 func handlerWatchEntities(srv interface{}, stream grpc.ServerStream) error {
 	if err := stream.RecvMsg(nil); err != nil {
 		return err
@@ -724,6 +753,14 @@ func (c *registryClient) ConsensusParameters(ctx context.Context, height int64)
 	return &rsp, nil
 }
 
+func (c *registryClient) TEEFeatures(ctx context.Context, height int64) (*node.TEEFeatures, error) {
+	var rsp *node.TEEFeatures
+	if err := c.conn.Invoke(ctx, methodTEEFeatures.FullName(), height, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
 func (c *registryClient) Cleanup() {
 }
 
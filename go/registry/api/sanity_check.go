@@ -35,8 +35,14 @@ func (g *Genesis) SanityCheck(
 		}
 	}
 
+	if ua := g.Parameters.UnfreezeAuthority; ua != nil {
+		if err := ua.ValidateBasic(); err != nil {
+			return fmt.Errorf("registry: sanity check failed: invalid unfreeze authority: %w", err)
+		}
+	}
+
 	// Check entities.
-	seenEntities, err := SanityCheckEntities(logger, g.Entities)
+	seenEntities, err := SanityCheckEntities(logger, &g.Parameters, g.Entities)
 	if err != nil {
 		return err
 	}
@@ -95,10 +101,14 @@ func (g *Genesis) SanityCheck(
 
 // SanityCheckEntities examines the entities table.
 // Returns lookup of entity ID to the entity record for use in other checks.
-func SanityCheckEntities(logger *logging.Logger, entities []*entity.SignedEntity) (map[signature.PublicKey]*entity.Entity, error) {
+func SanityCheckEntities(
+	logger *logging.Logger,
+	params *ConsensusParameters,
+	entities []*entity.SignedEntity,
+) (map[signature.PublicKey]*entity.Entity, error) {
 	seenEntities := make(map[signature.PublicKey]*entity.Entity)
 	for _, signedEnt := range entities {
-		entity, err := VerifyRegisterEntityArgs(logger, signedEnt, true, true)
+		entity, err := VerifyRegisterEntityArgs(params, logger, signedEnt, true, true)
 		if err != nil {
 			return nil, fmt.Errorf("entity sanity check failed: %w", err)
 		}
@@ -175,7 +185,7 @@ func SanityCheckNodes(
 
 		// Open the node to get the referenced entity.
 		var n node.Node
-		if err := signedNode.Open(RegisterGenesisNodeSignatureContext, &n); err != nil {
+		if err := signedNode.Open(params.SignatureContexts().RegisterNode, &n); err != nil {
 			return nil, fmt.Errorf("registry: sanity check failed: unable to open signed node")
 		}
 		if !n.ID.IsValid() {
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// SignatureContextVersionInitial is the initial registry signature context version. Contexts
+// derived at this version are identical to the base contexts.
+const SignatureContextVersionInitial uint16 = 0
+
+type versionedContextKey struct {
+	base    signature.Context
+	version uint16
+}
+
+var (
+	versionedContextsLock sync.Mutex
+	versionedContexts     = make(map[versionedContextKey]signature.Context)
+)
+
+// DeriveSignatureContext derives the signature context for the given base context at the given
+// signature context version.
+//
+// At SignatureContextVersionInitial the base context is returned unchanged so that signatures
+// made before versioning was introduced remain valid. Contexts for later versions are registered
+// on first use.
+func DeriveSignatureContext(base signature.Context, version uint16) signature.Context {
+	if version == SignatureContextVersionInitial {
+		return base
+	}
+
+	versionedContextsLock.Lock()
+	defer versionedContextsLock.Unlock()
+
+	key := versionedContextKey{base: base, version: version}
+	if ctx, ok := versionedContexts[key]; ok {
+		return ctx
+	}
+	ctx := signature.NewContext(fmt.Sprintf("%s v%d", base, version))
+	versionedContexts[key] = ctx
+	return ctx
+}
+
+// SignatureContexts are the signature contexts used by registry transactions at a given signature
+// context version.
+type SignatureContexts struct {
+	// RegisterEntity is the context used for entity registration.
+	RegisterEntity signature.Context
+	// RegisterNode is the context used for node registration.
+	RegisterNode signature.Context
+	// UnfreezeNode is the context used for signing node unfreeze requests.
+	UnfreezeNode signature.Context
+}
+
+// NewSignatureContexts derives all registry transaction signature contexts at the given signature
+// context version.
+func NewSignatureContexts(version uint16) *SignatureContexts {
+	return &SignatureContexts{
+		RegisterEntity: DeriveSignatureContext(RegisterEntitySignatureContext, version),
+		RegisterNode:   DeriveSignatureContext(RegisterNodeSignatureContext, version),
+		UnfreezeNode:   DeriveSignatureContext(UnfreezeNodeSignatureContext, version),
+	}
+}
+
+// SignatureContexts returns the registry transaction signature contexts at the signature context
+// version configured by the consensus parameters.
+func (p *ConsensusParameters) SignatureContexts() *SignatureContexts {
+	return NewSignatureContexts(p.SignatureContextVersion)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+)
+
+func TestDeriveSignatureContext(t *testing.T) {
+	require := require.New(t)
+
+	// The initial version should use the base contexts.
+	initial := NewSignatureContexts(SignatureContextVersionInitial)
+	require.Equal(RegisterEntitySignatureContext, initial.RegisterEntity)
+	require.Equal(RegisterNodeSignatureContext, initial.RegisterNode)
+	require.Equal(UnfreezeNodeSignatureContext, initial.UnfreezeNode)
+	var params ConsensusParameters
+	require.EqualValues(initial, params.SignatureContexts(), "default parameters should use the base contexts")
+
+	// Later versions should derive distinct contexts.
+	v1 := NewSignatureContexts(1)
+	v2 := NewSignatureContexts(2)
+	seen := make(map[signature.Context]bool)
+	for _, ctxs := range []*SignatureContexts{initial, v1, v2} {
+		for _, ctx := range []signature.Context{ctxs.RegisterEntity, ctxs.RegisterNode, ctxs.UnfreezeNode} {
+			require.False(seen[ctx], "derived context %s should be unique", ctx)
+			seen[ctx] = true
+		}
+	}
+
+	// Derivation should be deterministic and safe to repeat.
+	require.EqualValues(v1, NewSignatureContexts(1), "derived contexts should be stable")
+	params.SignatureContextVersion = 2
+	require.EqualValues(v2, params.SignatureContexts(), "parameters should select the configured version")
+
+	// Signatures should not verify across versions.
+	signer := memorySigner.NewTestSigner("registry signature context test")
+	message := []byte("message")
+	sig, err := signature.Sign(signer, v1.RegisterNode, message)
+	require.NoError(err, "Sign")
+	require.True(sig.Verify(v1.RegisterNode, message), "signature should verify with the same version")
+	require.False(sig.Verify(initial.RegisterNode, message), "signature should not verify with the initial version")
+	require.False(sig.Verify(v2.RegisterNode, message), "signature should not verify with a later version")
+}
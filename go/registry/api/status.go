@@ -1,8 +1,12 @@
 package api
 
 import (
+	"fmt"
+
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 )
 
@@ -10,6 +14,41 @@ import (
 // all (practical) time.
 const FreezeForever beacon.EpochTime = 0xffffffffffffffff
 
+// FreezeReason is the reason why a node has been frozen.
+type FreezeReason uint8
+
+const (
+	// FreezeReasonNone means that no freeze reason has been recorded.
+	FreezeReasonNone FreezeReason = 0
+
+	// FreezeReasonLegacy is the reason of nodes that were frozen before freeze reasons were
+	// recorded.
+	FreezeReasonLegacy FreezeReason = 1
+
+	// FreezeReasonRuntimeLiveness is the reason of nodes that failed runtime liveness checks.
+	FreezeReasonRuntimeLiveness FreezeReason = 2
+
+	// FreezeReasonConsensusMisbehavior is the reason of validators that were slashed for
+	// consensus misbehavior.
+	FreezeReasonConsensusMisbehavior FreezeReason = 3
+)
+
+// String returns a string representation of a freeze reason.
+func (r FreezeReason) String() string {
+	switch r {
+	case FreezeReasonNone:
+		return "none"
+	case FreezeReasonLegacy:
+		return "legacy"
+	case FreezeReasonRuntimeLiveness:
+		return "runtime liveness"
+	case FreezeReasonConsensusMisbehavior:
+		return "consensus misbehavior"
+	default:
+		return "[unknown freeze reason]"
+	}
+}
+
 // NodeStatus is live status of a node.
 type NodeStatus struct {
 	// ExpirationProcessed is a flag specifying whether the node expiration
@@ -23,6 +62,11 @@ type NodeStatus struct {
 	// After the specified epoch passes, this flag needs to be explicitly
 	// cleared (set to zero) in order for the node to become unfrozen.
 	FreezeEndTime beacon.EpochTime `json:"freeze_end_time"`
+	// FreezeReason is the reason why the node has been frozen.
+	FreezeReason FreezeReason `json:"freeze_reason,omitempty"`
+	// UnfreezeNonce is the number of unfreeze requests signed by the unfreeze authority that have
+	// been accepted for the node. The next such request must carry it as its nonce.
+	UnfreezeNonce uint64 `json:"unfreeze_nonce,omitempty"`
 	// ElectionEligibleAfter specifies the epoch after which a node is
 	// eligible to be included in non-validator committee elections.
 	//
@@ -39,9 +83,46 @@ func (ns NodeStatus) IsFrozen() bool {
 	return ns.FreezeEndTime > 0
 }
 
+// WillBeFrozenAt returns true if the node will still be frozen at the given epoch, assuming that
+// it is unfrozen as soon as its freeze period ends. Nodes frozen with FreezeForever are frozen at
+// all epochs.
+func (ns NodeStatus) WillBeFrozenAt(epoch beacon.EpochTime) bool {
+	if !ns.IsFrozen() {
+		return false
+	}
+	if ns.FreezeEndTime == FreezeForever {
+		return true
+	}
+	return epoch < ns.FreezeEndTime
+}
+
+// IsEffectivelyFrozen returns true if the node is frozen and its freeze period has not yet ended
+// at the given current epoch. Unlike IsFrozen, nodes whose freeze period has already ended but that
+// have not yet been unfrozen are not considered frozen.
+func (ns NodeStatus) IsEffectivelyFrozen(currentEpoch beacon.EpochTime) bool {
+	return ns.WillBeFrozenAt(currentEpoch)
+}
+
+// IsUnfreezeAmbiguous returns true if the node's eligibility for being unfrozen differs between
+// the two given epochs (e.g., the node's local epoch view and the consensus-derived epoch). In
+// this case any unfreeze decision depends on which epoch view is used.
+func (ns NodeStatus) IsUnfreezeAmbiguous(localEpoch, consensusEpoch beacon.EpochTime) bool {
+	return ns.WillBeFrozenAt(localEpoch) != ns.WillBeFrozenAt(consensusEpoch)
+}
+
+// EpochSkew returns the number of epochs by which the local epoch view lags behind the
+// consensus-derived epoch. A negative skew means that the local epoch view is ahead.
+func EpochSkew(localEpoch, consensusEpoch beacon.EpochTime) int64 {
+	if consensusEpoch >= localEpoch {
+		return int64(consensusEpoch - localEpoch)
+	}
+	return -int64(localEpoch - consensusEpoch)
+}
+
 // Unfreeze makes the node unfrozen.
 func (ns *NodeStatus) Unfreeze() {
 	ns.FreezeEndTime = 0
+	ns.FreezeReason = FreezeReasonNone
 }
 
 // RecordFailure records a liveness failure in the epoch preceding the specified epoch.
@@ -137,4 +218,115 @@ func (f *Fault) IsSuspended(epoch beacon.EpochTime) bool {
 // UnfreezeNode is a request to unfreeze a frozen node.
 type UnfreezeNode struct {
 	NodeID signature.PublicKey `json:"node_id"`
+
+	// FreezeEndTime is the freeze end time of the frozen node. It is only used in requests signed
+	// by the unfreeze authority, where it binds the request to a specific freeze.
+	FreezeEndTime beacon.EpochTime `json:"freeze_end_time,omitempty"`
+	// Nonce is the unfreeze nonce of the frozen node (see NodeStatus.UnfreezeNonce). It is only
+	// used in requests signed by the unfreeze authority, where it makes sure that a request cannot
+	// be replayed to unfreeze the node after it is frozen again with the same freeze end time.
+	Nonce uint64 `json:"nonce,omitempty"`
+}
+
+// UnfreezeNodeResult is the confirmed result of an unfreeze node transaction.
+type UnfreezeNodeResult struct {
+	// Height is the block height at which the node has been unfrozen.
+	Height int64 `json:"height"`
+	// TxHash is the hash of the transaction that unfroze the node.
+	TxHash hash.Hash `json:"tx_hash"`
+	// Status is the node status as of Height.
+	Status *NodeStatus `json:"status"`
+	// Event is the event emitted when the node has been unfrozen.
+	Event *NodeUnfrozenEvent `json:"event"`
+}
+
+// MultiSignedUnfreezeNode is an unfreeze request signed by multiple members of the unfreeze
+// authority.
+type MultiSignedUnfreezeNode struct {
+	signature.MultiSigned
+}
+
+// Open first verifies the blob signatures using the context for the given signature context
+// version and then unmarshals the blob.
+func (s *MultiSignedUnfreezeNode) Open(version uint16, unfreeze *UnfreezeNode) error {
+	return s.MultiSigned.Open(NewSignatureContexts(version).UnfreezeNode, unfreeze)
+}
+
+// SignUnfreezeNode generates a partial signature of the given unfreeze request by a member of the
+// unfreeze authority, using the context for the given signature context version.
+//
+// Partial signatures are exchanged off-chain and aggregated into a single request using
+// NewMultiSignedUnfreezeNode once enough members have signed.
+func SignUnfreezeNode(signer signature.Signer, version uint16, unfreeze *UnfreezeNode) (*signature.Signature, error) {
+	return signature.Sign(signer, NewSignatureContexts(version).UnfreezeNode, cbor.Marshal(unfreeze))
+}
+
+// NewMultiSignedUnfreezeNode aggregates partial signatures generated by SignUnfreezeNode over the
+// given unfreeze request into a multi-signed unfreeze request.
+func NewMultiSignedUnfreezeNode(unfreeze *UnfreezeNode, sigs []signature.Signature) *MultiSignedUnfreezeNode {
+	return &MultiSignedUnfreezeNode{
+		MultiSigned: signature.MultiSigned{
+			Blob:       cbor.Marshal(unfreeze),
+			Signatures: sigs,
+		},
+	}
+}
+
+// UnfreezeAuthority is a committee that is authorized to unfreeze frozen nodes.
+type UnfreezeAuthority struct {
+	// Members are the public keys of the committee members.
+	Members []signature.PublicKey `json:"members"`
+
+	// Threshold is the minimum number of distinct members that need to sign an unfreeze request
+	// for it to be accepted.
+	Threshold uint64 `json:"threshold"`
+}
+
+// ValidateBasic performs basic unfreeze authority validity checks.
+func (ua *UnfreezeAuthority) ValidateBasic() error {
+	if ua.Threshold == 0 {
+		return fmt.Errorf("threshold must be non-zero")
+	}
+	if ua.Threshold > uint64(len(ua.Members)) {
+		return fmt.Errorf("threshold exceeds the number of members")
+	}
+
+	seen := make(map[signature.PublicKey]bool)
+	for _, pk := range ua.Members {
+		if !pk.IsValid() {
+			return fmt.Errorf("invalid member public key: %s", pk)
+		}
+		if seen[pk] {
+			return fmt.Errorf("duplicate member public key: %s", pk)
+		}
+		seen[pk] = true
+	}
+	return nil
+}
+
+// Verify verifies that the multi-signed unfreeze request has been signed only by members of the
+// unfreeze authority (using the context for the given signature context version) and that the
+// number of distinct signing members reaches the threshold. In case verification is successful,
+// the opened unfreeze request is returned.
+func (ua *UnfreezeAuthority) Verify(sigUnfreeze *MultiSignedUnfreezeNode, version uint16) (*UnfreezeNode, error) {
+	var unfreeze UnfreezeNode
+	if err := sigUnfreeze.Open(version, &unfreeze); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	members := make(map[signature.PublicKey]bool)
+	for _, pk := range ua.Members {
+		members[pk] = true
+	}
+	signers := make(map[signature.PublicKey]bool)
+	for _, sig := range sigUnfreeze.Signatures {
+		if !members[sig.PublicKey] {
+			return nil, ErrInvalidSignature
+		}
+		signers[sig.PublicKey] = true
+	}
+	if uint64(len(signers)) < ua.Threshold {
+		return nil, ErrInsufficientSignatures
+	}
+	return &unfreeze, nil
 }
@@ -6,6 +6,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
 )
 
 func TestStatusFaults(t *testing.T) {
@@ -66,3 +69,133 @@ func TestStatusFaults(t *testing.T) {
 	require.False(ns.IsSuspended(testRuntimeID, 26), "should not be suspended in epoch 26")
 	require.Len(ns.Faults, 0, "faults set should be cleared")
 }
+
+func TestStatusWillBeFrozenAt(t *testing.T) {
+	require := require.New(t)
+
+	var ns NodeStatus
+	require.False(ns.WillBeFrozenAt(0), "non-frozen node should not be frozen at epoch 0")
+	require.False(ns.WillBeFrozenAt(10), "non-frozen node should not be frozen at epoch 10")
+
+	ns.FreezeEndTime = 10
+	require.True(ns.WillBeFrozenAt(0), "node should be frozen at epoch 0")
+	require.True(ns.WillBeFrozenAt(9), "node should be frozen at epoch 9")
+	require.False(ns.WillBeFrozenAt(10), "node should not be frozen at freeze end time")
+	require.False(ns.WillBeFrozenAt(11), "node should not be frozen after freeze end time")
+	require.True(ns.IsFrozen(), "WillBeFrozenAt should not mutate the status")
+
+	ns.FreezeEndTime = FreezeForever
+	require.True(ns.WillBeFrozenAt(0), "node frozen forever should be frozen at epoch 0")
+	require.True(ns.WillBeFrozenAt(FreezeForever), "node frozen forever should be frozen at the last epoch")
+
+	ns.Unfreeze()
+	require.False(ns.WillBeFrozenAt(0), "unfrozen node should not be frozen")
+}
+
+func TestStatusIsEffectivelyFrozen(t *testing.T) {
+	require := require.New(t)
+
+	var ns NodeStatus
+	require.False(ns.IsEffectivelyFrozen(10), "non-frozen node should not be effectively frozen")
+
+	ns.FreezeEndTime = 10
+	require.True(ns.IsEffectivelyFrozen(9), "node should be effectively frozen before freeze end time")
+	require.False(ns.IsEffectivelyFrozen(10), "node should not be effectively frozen at freeze end time")
+	require.False(ns.IsEffectivelyFrozen(11), "node should not be effectively frozen after freeze end time")
+	require.True(ns.IsFrozen(), "node should remain frozen until unfrozen")
+
+	ns.FreezeEndTime = FreezeForever
+	require.True(ns.IsEffectivelyFrozen(10), "node frozen forever should be effectively frozen")
+	require.True(ns.IsEffectivelyFrozen(FreezeForever-1), "node frozen forever should be effectively frozen")
+	require.True(ns.IsFrozen(), "node frozen forever should be frozen")
+}
+
+func TestStatusIsUnfreezeAmbiguous(t *testing.T) {
+	require := require.New(t)
+
+	var ns NodeStatus
+	require.False(ns.IsUnfreezeAmbiguous(5, 20), "non-frozen node should never be ambiguous")
+
+	ns.FreezeEndTime = 10
+	require.False(ns.IsUnfreezeAmbiguous(5, 5), "same epochs should never be ambiguous")
+	require.False(ns.IsUnfreezeAmbiguous(5, 9), "both epochs before freeze end should not be ambiguous")
+	require.False(ns.IsUnfreezeAmbiguous(10, 12), "both epochs after freeze end should not be ambiguous")
+	require.True(ns.IsUnfreezeAmbiguous(9, 10), "lagging local epoch view across freeze end should be ambiguous")
+	require.True(ns.IsUnfreezeAmbiguous(11, 8), "leading local epoch view across freeze end should be ambiguous")
+
+	ns.FreezeEndTime = FreezeForever
+	require.False(ns.IsUnfreezeAmbiguous(0, 100), "node frozen forever should never be ambiguous")
+}
+
+func TestEpochSkew(t *testing.T) {
+	require := require.New(t)
+
+	require.EqualValues(0, EpochSkew(10, 10), "equal epochs should have no skew")
+	require.EqualValues(3, EpochSkew(7, 10), "lagging local epoch view should have positive skew")
+	require.EqualValues(-2, EpochSkew(12, 10), "leading local epoch view should have negative skew")
+}
+
+func TestUnfreezeAuthority(t *testing.T) {
+	require := require.New(t)
+
+	signers := []signature.Signer{
+		memorySigner.NewTestSigner("registry/api: unfreeze authority member 1"),
+		memorySigner.NewTestSigner("registry/api: unfreeze authority member 2"),
+		memorySigner.NewTestSigner("registry/api: unfreeze authority member 3"),
+	}
+	outsider := memorySigner.NewTestSigner("registry/api: unfreeze authority outsider")
+	ua := &UnfreezeAuthority{Threshold: 2}
+	for _, signer := range signers {
+		ua.Members = append(ua.Members, signer.Public())
+	}
+	require.NoError(ua.ValidateBasic(), "ValidateBasic")
+
+	for _, invalid := range []*UnfreezeAuthority{
+		{Members: ua.Members, Threshold: 0},
+		{Members: ua.Members, Threshold: 4},
+		{Members: []signature.PublicKey{ua.Members[0], ua.Members[0]}, Threshold: 1},
+	} {
+		require.Error(invalid.ValidateBasic(), "ValidateBasic should fail for invalid authority")
+	}
+
+	unfreeze := &UnfreezeNode{
+		NodeID:        outsider.Public(),
+		FreezeEndTime: 42,
+	}
+	sign := func(signers ...signature.Signer) *MultiSignedUnfreezeNode {
+		var sigs []signature.Signature
+		for _, signer := range signers {
+			sig, err := SignUnfreezeNode(signer, SignatureContextVersionInitial, unfreeze)
+			require.NoError(err, "SignUnfreezeNode")
+			sigs = append(sigs, *sig)
+		}
+		return NewMultiSignedUnfreezeNode(unfreeze, sigs)
+	}
+
+	// Threshold reached.
+	opened, err := ua.Verify(sign(signers[0], signers[2]), SignatureContextVersionInitial)
+	require.NoError(err, "Verify with threshold signatures")
+	require.EqualValues(unfreeze, opened, "opened unfreeze request should be correct")
+	_, err = ua.Verify(sign(signers...), SignatureContextVersionInitial)
+	require.NoError(err, "Verify with all signatures")
+
+	// Below threshold.
+	_, err = ua.Verify(sign(signers[1]), SignatureContextVersionInitial)
+	require.ErrorIs(err, ErrInsufficientSignatures, "Verify below threshold")
+	_, err = ua.Verify(sign(signers[1], signers[1]), SignatureContextVersionInitial)
+	require.ErrorIs(err, ErrInsufficientSignatures, "Verify with duplicate signatures")
+
+	// Non-member signatures.
+	_, err = ua.Verify(sign(signers[0], signers[1], outsider), SignatureContextVersionInitial)
+	require.ErrorIs(err, ErrInvalidSignature, "Verify with non-member signature")
+
+	// Tampered request.
+	sigUnfreeze := sign(signers[0], signers[1])
+	sigUnfreeze.Blob = cbor.Marshal(&UnfreezeNode{NodeID: outsider.Public(), FreezeEndTime: 43})
+	_, err = ua.Verify(sigUnfreeze, SignatureContextVersionInitial)
+	require.ErrorIs(err, ErrInvalidSignature, "Verify with tampered request")
+
+	// Signatures made for a different signature context version.
+	_, err = ua.Verify(sign(signers[0], signers[2]), SignatureContextVersionInitial+1)
+	require.ErrorIs(err, ErrInvalidSignature, "Verify with a different signature context version")
+}
@@ -31,6 +31,10 @@ func main() {
 	chainContext.FromBytes([]byte("registry test vectors"))
 	signature.SetChainContext(chainContext.String())
 
+	// Vectors are generated for the signature contexts of the initial context version.
+	sigCtxVersion := registry.SignatureContextVersionInitial
+	sigCtxs := registry.NewSignatureContexts(sigCtxVersion)
+
 	var vectors []testvectors.TestVector
 
 	// Generate different gas fees.
@@ -55,7 +59,7 @@ func main() {
 						nodeSigner := memorySigner.NewTestSigner(fmt.Sprintf("oasis core registry test vectors: node signer %d", i))
 						ent.Nodes = append(ent.Nodes, nodeSigner.Public())
 					}
-					sigEnt, err := entity.SignEntity(entitySigner, registry.RegisterEntitySignatureContext, &ent)
+					sigEnt, err := entity.SignEntity(entitySigner, sigCtxs.RegisterEntity, &ent)
 					if err != nil {
 						panic(err)
 					}
@@ -75,6 +79,24 @@ func main() {
 				NodeID: nodeSigner.Public(),
 			})
 			vectors = append(vectors, testvectors.MakeTestVector("UnfreezeNode", tx, true))
+
+			// Generate multi-signed unfreeze node transactions.
+			unfreeze := &registry.UnfreezeNode{
+				NodeID:        nodeSigner.Public(),
+				FreezeEndTime: 42,
+				Nonce:         1,
+			}
+			var sigs []signature.Signature
+			for i := 0; i < 2; i++ {
+				memberSigner := memorySigner.NewTestSigner(fmt.Sprintf("oasis-core registry test vectors: unfreeze authority member %d", i))
+				sig, err := registry.SignUnfreezeNode(memberSigner, sigCtxVersion, unfreeze)
+				if err != nil {
+					panic(err)
+				}
+				sigs = append(sigs, *sig)
+			}
+			tx = registry.NewMultiSignedUnfreezeNodeTx(nonce, fee, registry.NewMultiSignedUnfreezeNode(unfreeze, sigs))
+			vectors = append(vectors, testvectors.MakeTestVector("MultiSignedUnfreezeNode", tx, true))
 		}
 	}
 
@@ -230,6 +230,44 @@ func (d *DB) getBlock(round uint64) (*roothash.AnnotatedBlock, error) {
 	return &blk, nil
 }
 
+func (d *DB) getBlocks(startRound, endRound uint64) ([]*roothash.AnnotatedBlock, error) {
+	var blks []*roothash.AnnotatedBlock
+	txErr := d.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{Prefix: blockKeyFmt.Encode()})
+		defer it.Close()
+
+		next := startRound
+		for it.Seek(blockKeyFmt.Encode(startRound)); it.Valid() && next <= endRound; it.Next() {
+			var round uint64
+			if !blockKeyFmt.Decode(it.Item().Key(), &round) {
+				return fmt.Errorf("runtime/history: malformed block key")
+			}
+			if round != next {
+				// Missing block.
+				break
+			}
+
+			var blk roothash.AnnotatedBlock
+			if err := it.Item().Value(func(val []byte) error {
+				return cbor.UnmarshalTrusted(val, &blk)
+			}); err != nil {
+				return err
+			}
+			blks = append(blks, &blk)
+
+			if round == endRound {
+				return nil
+			}
+			next++
+		}
+		return roothash.ErrNotFound
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+	return blks, nil
+}
+
 func (d *DB) getEarliestBlock() (*roothash.AnnotatedBlock, error) {
 	var blk roothash.AnnotatedBlock
 	txErr := d.db.View(func(tx *badger.Txn) error {
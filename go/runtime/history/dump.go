@@ -0,0 +1,126 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dgraph-io/badger/v3"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	cmnBadger "github.com/oasisprotocol/oasis-core/go/common/badger"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+)
+
+// ErrNoMetadata is the error returned when the history database has no metadata section.
+var ErrNoMetadata = errors.New("runtime/history: database has no metadata")
+
+// Metadata is the persisted state of a history database.
+type Metadata struct {
+	// RuntimeID is the runtime ID the database is for.
+	RuntimeID common.Namespace `json:"runtime_id"`
+	// Version is the database schema version.
+	Version uint64 `json:"version"`
+
+	// LastConsensusHeight is the last consensus height.
+	LastConsensusHeight int64 `json:"last_consensus_height"`
+	// LastRound is the last round.
+	LastRound uint64 `json:"last_round"`
+
+	// IORoot is the I/O root of the last round (if its block is available).
+	IORoot *hash.Hash `json:"io_root,omitempty"`
+	// StateRoot is the state root of the last round (if its block is available).
+	StateRoot *hash.Hash `json:"state_root,omitempty"`
+}
+
+// DumpMetadata opens the history database at the given path in read-only mode and returns its
+// persisted metadata together with the roots of the last round.
+//
+// The database is never modified, so this is safe to run against a copy of a live node's database
+// without the node running.
+func DumpMetadata(fn string) (*Metadata, error) {
+	if _, err := os.Stat(fn); err != nil {
+		return nil, fmt.Errorf("runtime/history: failed to open database: %w", err)
+	}
+
+	logger := logging.GetLogger("runtime/history").With("path", fn)
+
+	opts := badger.DefaultOptions(fn)
+	opts = opts.WithLogger(cmnBadger.NewLogAdapter(logger))
+	opts = opts.WithReadOnly(true)
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("runtime/history: failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	var meta *Metadata
+	err = db.View(func(tx *badger.Txn) error {
+		item, err := tx.Get(metadataKeyFmt.Encode())
+		switch err {
+		case nil:
+		case badger.ErrKeyNotFound:
+			return ErrNoMetadata
+		default:
+			return err
+		}
+
+		// Decode the schema version first so that unknown versions are reported as such instead
+		// of as decoding failures.
+		var version struct {
+			Version uint64 `json:"version"`
+		}
+		var raw []byte
+		if raw, err = item.ValueCopy(nil); err != nil {
+			return err
+		}
+		if err = cbor.UnmarshalTrusted(raw, &version); err != nil {
+			return fmt.Errorf("runtime/history: malformed metadata: %w", err)
+		}
+		if version.Version != dbVersion {
+			return fmt.Errorf("runtime/history: unsupported database version (expected: %d got: %d)",
+				dbVersion,
+				version.Version,
+			)
+		}
+
+		var dbMeta dbMetadata
+		if err = cbor.Unmarshal(raw, &dbMeta); err != nil {
+			return fmt.Errorf("runtime/history: malformed metadata: %w", err)
+		}
+		meta = &Metadata{
+			RuntimeID:           dbMeta.RuntimeID,
+			Version:             dbMeta.Version,
+			LastConsensusHeight: dbMeta.LastConsensusHeight,
+			LastRound:           dbMeta.LastRound,
+		}
+
+		item, err = tx.Get(blockKeyFmt.Encode(dbMeta.LastRound))
+		switch err {
+		case nil:
+		case badger.ErrKeyNotFound:
+			// No blocks have been committed yet or the block has been pruned.
+			return nil
+		default:
+			return err
+		}
+
+		var blk roothash.AnnotatedBlock
+		if err = item.Value(func(val []byte) error {
+			return cbor.Unmarshal(val, &blk)
+		}); err != nil {
+			return fmt.Errorf("runtime/history: malformed block: %w", err)
+		}
+		meta.IORoot = &blk.Block.Header.IORoot
+		meta.StateRoot = &blk.Block.Header.StateRoot
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
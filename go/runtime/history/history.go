@@ -24,7 +24,8 @@ const DbFilename = "history.db"
 var (
 	errNopHistory = errors.New("runtime/history: not supported")
 
-	_ History = (*runtimeHistory)(nil)
+	_ History           = (*runtimeHistory)(nil)
+	_ BatchBlockHistory = (*runtimeHistory)(nil)
 )
 
 // Config is runtime history keeper configuration.
@@ -55,6 +56,16 @@ type History interface {
 	Close()
 }
 
+// BatchBlockHistory is implemented by runtime histories that support fetching multiple committed
+// blocks at once.
+type BatchBlockHistory interface {
+	// GetCommittedBlocks returns the committed blocks for all rounds in the (inclusive) range
+	// from startRound to endRound, ordered by round.
+	//
+	// In case any of the blocks is not available, roothash.ErrNotFound is returned.
+	GetCommittedBlocks(ctx context.Context, startRound, endRound uint64) ([]*block.Block, error)
+}
+
 type nopHistory struct {
 	runtimeID common.Namespace
 }
@@ -288,6 +299,24 @@ func (h *runtimeHistory) GetCommittedBlock(ctx context.Context, round uint64) (*
 	return annBlk.Block, nil
 }
 
+func (h *runtimeHistory) GetCommittedBlocks(ctx context.Context, startRound, endRound uint64) ([]*block.Block, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if startRound > endRound {
+		return nil, fmt.Errorf("runtime/history: invalid round range %d-%d", startRound, endRound)
+	}
+	annBlks, err := h.db.getBlocks(startRound, endRound)
+	if err != nil {
+		return nil, err
+	}
+	blks := make([]*block.Block, 0, len(annBlks))
+	for _, annBlk := range annBlks {
+		blks = append(blks, annBlk.Block)
+	}
+	return blks, nil
+}
+
 func (h *runtimeHistory) GetBlock(ctx context.Context, round uint64) (*block.Block, error) {
 	annBlk, err := h.GetAnnotatedBlock(ctx, round)
 	if err != nil {
@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/dgraph-io/badger/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
 	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
 )
@@ -449,3 +452,198 @@ func TestHistoryPruneError(t *testing.T) {
 		require.NoError(err, "GetBlock(%d)", i)
 	}
 }
+
+func TestHistoryPruneUnregisterHandler(t *testing.T) {
+	require := require.New(t)
+
+	var ph1, ph2, ph3 testPruneHandler
+	pruner := newPrunerBase()
+	pruner.RegisterHandler(&ph1)
+	pruner.RegisterHandler(&ph2)
+	pruner.RegisterHandler(&ph3)
+
+	pruner.UnregisterHandler(&ph2)
+	require.Equal([]PruneHandler{&ph1, &ph3}, pruner.handlers, "unregistered handler should be removed")
+
+	// Unregistering a handler that is not registered should do nothing.
+	pruner.UnregisterHandler(&ph2)
+	require.Equal([]PruneHandler{&ph1, &ph3}, pruner.handlers, "other handlers should be kept")
+}
+
+func TestGetCommittedBlocks(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// Create a new random temporary directory under /tmp.
+	dataDir, err := ioutil.TempDir("", "oasis-runtime-history-test_")
+	require.NoError(err, "TempDir")
+	defer os.RemoveAll(dataDir)
+
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("history batch test ns"), 0)
+
+	history, err := New(dataDir, runtimeID, NewDefaultConfig(), false)
+	require.NoError(err, "New")
+	defer history.Close()
+
+	// Create some blocks.
+	for i := 10; i <= 20; i++ {
+		blk := roothash.AnnotatedBlock{
+			Height: int64(i),
+			Block:  block.NewGenesisBlock(runtimeID, 0),
+		}
+		blk.Block.Header.Round = uint64(i)
+
+		err = history.Commit(&blk, nil, false)
+		require.NoError(err, "Commit")
+	}
+
+	batchHistory, ok := history.(BatchBlockHistory)
+	require.True(ok, "runtime history should support batch block fetches")
+
+	blks, err := batchHistory.GetCommittedBlocks(ctx, 12, 17)
+	require.NoError(err, "GetCommittedBlocks")
+	require.Len(blks, 6, "all blocks in the range should be returned")
+	for i, blk := range blks {
+		require.EqualValues(12+i, blk.Header.Round, "blocks should be ordered by round")
+	}
+
+	blks, err = batchHistory.GetCommittedBlocks(ctx, 15, 15)
+	require.NoError(err, "GetCommittedBlocks")
+	require.Len(blks, 1, "single block range should return a single block")
+	require.EqualValues(15, blks[0].Header.Round)
+
+	_, err = batchHistory.GetCommittedBlocks(ctx, 18, 25)
+	require.ErrorIs(err, roothash.ErrNotFound, "GetCommittedBlocks should fail for missing later rounds")
+
+	_, err = batchHistory.GetCommittedBlocks(ctx, 5, 12)
+	require.ErrorIs(err, roothash.ErrNotFound, "GetCommittedBlocks should fail for missing earlier rounds")
+
+	_, err = batchHistory.GetCommittedBlocks(ctx, 17, 12)
+	require.Error(err, "GetCommittedBlocks should fail for an invalid range")
+}
+
+func TestHistoryPruneIsolation(t *testing.T) {
+	require := require.New(t)
+
+	// Create a new random temporary directory under /tmp.
+	dataDir, err := ioutil.TempDir("", "oasis-runtime-history-test_")
+	require.NoError(err, "TempDir")
+	defer os.RemoveAll(dataDir)
+
+	cfg := &Config{
+		Pruner:        NewKeepLastPruner(10),
+		PruneInterval: 50 * time.Millisecond,
+	}
+
+	runtimeID1 := common.NewTestNamespaceFromSeed([]byte("history prune isolation test ns 1"), 0)
+	runtimeID2 := common.NewTestNamespaceFromSeed([]byte("history prune isolation test ns 2"), 0)
+
+	history1, err := New(filepath.Join(dataDir, "1"), runtimeID1, cfg, false)
+	require.NoError(err, "New")
+	defer history1.Close()
+	history2, err := New(filepath.Join(dataDir, "2"), runtimeID2, cfg, false)
+	require.NoError(err, "New")
+	defer history2.Close()
+
+	ph1 := testPruneHandler{
+		doneCh:     make(chan struct{}),
+		waitRounds: 41,
+	}
+	history1.Pruner().RegisterHandler(&ph1)
+	ph2 := testPruneHandler{
+		doneCh:     make(chan struct{}),
+		waitRounds: 1,
+	}
+	history2.Pruner().RegisterHandler(&ph2)
+
+	// Create some blocks for the first runtime only.
+	for i := 0; i <= 50; i++ {
+		blk := roothash.AnnotatedBlock{
+			Height: int64(i),
+			Block:  block.NewGenesisBlock(runtimeID1, 0),
+		}
+		blk.Block.Header.Round = uint64(i)
+
+		err = history1.Commit(&blk, nil, false)
+		require.NoError(err, "Commit")
+	}
+
+	select {
+	case <-ph1.doneCh:
+	case <-time.After(recvTimeout):
+		t.Fatalf("failed to wait for prune to complete")
+	}
+	require.Len(ph1.prunedRounds, 41, "first runtime's rounds should be pruned")
+
+	// Prune events for the first runtime must not be delivered to the second runtime's handler.
+	select {
+	case <-ph2.doneCh:
+		t.Fatalf("prune handler received prune events for another runtime")
+	case <-time.After(5 * cfg.PruneInterval):
+	}
+}
+
+func TestDumpMetadata(t *testing.T) {
+	require := require.New(t)
+
+	dataDir := t.TempDir()
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("history dump test ns"), 0)
+	fn := filepath.Join(dataDir, DbFilename)
+
+	// Missing database.
+	_, err := DumpMetadata(fn)
+	require.Error(err, "DumpMetadata should fail for a missing database")
+
+	history, err := New(dataDir, runtimeID, NewDefaultConfig(), false)
+	require.NoError(err, "New")
+
+	blk := roothash.AnnotatedBlock{
+		Height: 40,
+		Block:  block.NewGenesisBlock(runtimeID, 0),
+	}
+	blk.Block.Header.Round = 10
+	blk.Block.Header.IORoot.FromBytes([]byte("io root"))
+	blk.Block.Header.StateRoot.FromBytes([]byte("state root"))
+	err = history.Commit(&blk, &roothash.RoundResults{}, false)
+	require.NoError(err, "Commit")
+	history.Close()
+
+	meta, err := DumpMetadata(fn)
+	require.NoError(err, "DumpMetadata")
+	require.Equal(runtimeID, meta.RuntimeID)
+	require.EqualValues(dbVersion, meta.Version)
+	require.EqualValues(40, meta.LastConsensusHeight)
+	require.EqualValues(10, meta.LastRound)
+	require.Equal(blk.Block.Header.IORoot, *meta.IORoot)
+	require.Equal(blk.Block.Header.StateRoot, *meta.StateRoot)
+
+	// Dumping must not modify the database.
+	history, err = New(dataDir, runtimeID, NewDefaultConfig(), false)
+	require.NoError(err, "New after DumpMetadata")
+	height, err := history.LastConsensusHeight()
+	require.NoError(err, "LastConsensusHeight")
+	require.EqualValues(40, height, "database should be unmodified")
+	history.Close()
+
+	// Unknown schema versions should be reported.
+	db, err := newDB(fn, runtimeID)
+	require.NoError(err, "newDB")
+	err = db.db.Update(func(tx *badger.Txn) error {
+		return tx.Set(metadataKeyFmt.Encode(), cbor.Marshal(dbMetadata{
+			RuntimeID: runtimeID,
+			Version:   dbVersion + 1,
+		}))
+	})
+	require.NoError(err, "Update")
+	db.close()
+	_, err = DumpMetadata(fn)
+	require.ErrorContains(err, "unsupported database version", "DumpMetadata should fail for unknown versions")
+
+	// Databases without metadata should be reported.
+	emptyFn := filepath.Join(t.TempDir(), DbFilename)
+	emptyDB, err := badger.Open(badger.DefaultOptions(emptyFn).WithLogger(nil))
+	require.NoError(err, "badger.Open")
+	emptyDB.Close()
+	_, err = DumpMetadata(emptyFn)
+	require.ErrorIs(err, ErrNoMetadata, "DumpMetadata should fail without metadata")
+}
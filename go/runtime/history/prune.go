@@ -44,6 +44,9 @@ type Pruner interface {
 
 	// RegisterHandler registers a prune handler.
 	RegisterHandler(handler PruneHandler)
+
+	// UnregisterHandler unregisters a previously registered prune handler.
+	UnregisterHandler(handler PruneHandler)
 }
 
 type prunerBase struct {
@@ -59,6 +62,18 @@ func (p *prunerBase) RegisterHandler(handler PruneHandler) {
 	p.handlers = append(p.handlers, handler)
 }
 
+func (p *prunerBase) UnregisterHandler(handler PruneHandler) {
+	p.Lock()
+	defer p.Unlock()
+
+	for i, ph := range p.handlers {
+		if ph == handler {
+			p.handlers = append(p.handlers[:i], p.handlers[i+1:]...)
+			return
+		}
+	}
+}
+
 func newPrunerBase() prunerBase {
 	return prunerBase{}
 }
@@ -68,6 +83,9 @@ type nonePruner struct{}
 func (p *nonePruner) RegisterHandler(handler PruneHandler) {
 }
 
+func (p *nonePruner) UnregisterHandler(handler PruneHandler) {
+}
+
 func (p *nonePruner) Prune(ctx context.Context, latestRound uint64) error {
 	return nil
 }
@@ -27,11 +27,11 @@ type teeStateECDSA struct {
 
 func (ec *teeStateECDSA) Init(ctx context.Context, sp *sgxProvisioner, runtimeID common.Namespace, version version.Version) ([]byte, error) {
 	// Check whether the consensus layer even supports ECDSA attestations.
-	regParams, err := sp.consensus.Registry().ConsensusParameters(ctx, consensus.HeightLatest)
+	teeFeatures, err := sp.consensus.Registry().TEEFeatures(ctx, consensus.HeightLatest)
 	if err != nil {
-		return nil, fmt.Errorf("unable to determine registry consensus parameters: %w", err)
+		return nil, fmt.Errorf("unable to determine registry TEE features: %w", err)
 	}
-	if regParams.TEEFeatures == nil || !regParams.TEEFeatures.SGX.PCS {
+	if teeFeatures == nil || !teeFeatures.SGX.PCS {
 		return nil, fmt.Errorf("ECDSA not supported by the registry")
 	}
 
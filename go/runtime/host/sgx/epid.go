@@ -44,11 +44,11 @@ func (ep *teeStateEPID) Init(ctx context.Context, sp *sgxProvisioner, runtimeID
 
 func (ep *teeStateEPID) Update(ctx context.Context, sp *sgxProvisioner, conn protocol.Connection, report []byte, nonce string) ([]byte, error) {
 	// Check if new format of attestations is supported in the consensus layer and use it.
-	regParams, err := sp.consensus.Registry().ConsensusParameters(ctx, consensus.HeightLatest)
+	teeFeatures, err := sp.consensus.Registry().TEEFeatures(ctx, consensus.HeightLatest)
 	if err != nil {
-		return nil, fmt.Errorf("unable to determine registry consensus parameters: %w", err)
+		return nil, fmt.Errorf("unable to determine registry TEE features: %w", err)
 	}
-	supportsAttestationV1 := (regParams.TEEFeatures != nil && regParams.TEEFeatures.SGX.PCS)
+	supportsAttestationV1 := (teeFeatures != nil && teeFeatures.SGX.PCS)
 
 	// Update the SigRL (Not cached, knowing if revoked is important).
 	sigRL, err := sp.ias.GetSigRL(ctx, ep.epidGID)
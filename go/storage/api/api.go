@@ -242,6 +242,52 @@ type LocalBackend interface {
 	NodeDB() nodedb.NodeDB
 }
 
+// ApplyBatchBackend is an interface implemented by local storage backends that support applying
+// write logs for multiple consecutive versions of a root at once.
+type ApplyBatchBackend interface {
+	// ApplyBatch applies a sequence of apply requests. All requests must be for the same namespace
+	// and root type and each request must start from the root produced by the previous one.
+	//
+	// Each of the resulting intermediate roots is committed to the local DB so that all versions
+	// can still be finalized individually.
+	ApplyBatch(ctx context.Context, requests []*ApplyRequest) error
+}
+
+// ApplyProgressInterval is the number of write log entries after which the progress of applying a
+// write log is reported by backends implementing ApplyProgressBackend. The progress of applying
+// write logs with fewer entries is not reported.
+const ApplyProgressInterval = 1024
+
+// ApplyProgressFunc is a callback receiving the number of entries of a write log that have been
+// applied so far and the total number of entries of the write log.
+type ApplyProgressFunc func(applied, total uint64)
+
+// ApplyProgressBackend is an interface implemented by local storage backends that support
+// reporting the progress of applying large write logs.
+type ApplyProgressBackend interface {
+	// ApplyWithProgress applies the apply request like Apply, invoking the given progress
+	// callback each time another ApplyProgressInterval write log entries have been applied.
+	ApplyWithProgress(ctx context.Context, request *ApplyRequest, progress ApplyProgressFunc) error
+}
+
+// ConcurrentFinalizeBackend is an interface implemented by local storage backends that may support
+// finalizing multiple versions concurrently.
+type ConcurrentFinalizeBackend interface {
+	// SupportsConcurrentFinalize returns true iff finalizing a version does not depend on the
+	// previous version having been finalized, so that Finalize may be called concurrently for
+	// different versions.
+	SupportsConcurrentFinalize() bool
+}
+
+// EqualRootBackend is an interface implemented by local storage backends that may not need an
+// (empty) write log to be applied for a root that is equal to the root of the previous version.
+type EqualRootBackend interface {
+	// HasEqualRoot returns true iff the given root, whose hash is equal to the given root of the
+	// previous version, is already satisfied by local storage so that it can be finalized as part
+	// of its version without applying an empty write log.
+	HasEqualRoot(prevRoot, root Root) bool
+}
+
 // WrappedLocalBackend is an interface implemented by storage backends that wrap a local storage
 // backend in order to support unwrapping.
 type WrappedLocalBackend interface {
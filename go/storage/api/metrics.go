@@ -106,6 +106,10 @@ type localMetricsWrapper struct {
 func (w *metricsWrapper) Apply(ctx context.Context, request *ApplyRequest) error {
 	start := time.Now()
 	err := w.Backend.(LocalBackend).Apply(ctx, request)
+	return w.recordApply(start, request, err)
+}
+
+func (w *metricsWrapper) recordApply(start time.Time, request *ApplyRequest, err error) error {
 	storageLatency.With(labelApply).Observe(time.Since(start).Seconds())
 
 	var size int
@@ -122,6 +126,60 @@ func (w *metricsWrapper) Apply(ctx context.Context, request *ApplyRequest) error
 	return nil
 }
 
+func (w *localMetricsWrapper) ApplyWithProgress(ctx context.Context, request *ApplyRequest, progress ApplyProgressFunc) error {
+	pb, ok := w.Backend.(ApplyProgressBackend)
+	if !ok {
+		// Backend doesn't support reporting progress, apply without it.
+		return w.Apply(ctx, request)
+	}
+
+	start := time.Now()
+	err := pb.ApplyWithProgress(ctx, request, progress)
+	return w.recordApply(start, request, err)
+}
+
+func (w *localMetricsWrapper) SupportsConcurrentFinalize() bool {
+	cb, ok := w.Backend.(ConcurrentFinalizeBackend)
+	return ok && cb.SupportsConcurrentFinalize()
+}
+
+func (w *localMetricsWrapper) HasEqualRoot(prevRoot, root Root) bool {
+	eb, ok := w.Backend.(EqualRootBackend)
+	return ok && eb.HasEqualRoot(prevRoot, root)
+}
+
+func (w *localMetricsWrapper) ApplyBatch(ctx context.Context, requests []*ApplyRequest) error {
+	bb, ok := w.Backend.(ApplyBatchBackend)
+	if !ok {
+		// Backend doesn't support batching, apply requests one by one.
+		for _, request := range requests {
+			if err := w.Apply(ctx, request); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := time.Now()
+	err := bb.ApplyBatch(ctx, requests)
+	storageLatency.With(labelApply).Observe(time.Since(start).Seconds())
+
+	for _, request := range requests {
+		var size int
+		for _, entry := range request.WriteLog {
+			size += len(entry.Key) + len(entry.Value)
+		}
+		storageValueSize.With(labelApply).Observe(float64(size))
+	}
+	if err != nil {
+		storageFailures.With(labelApply).Inc()
+		return err
+	}
+
+	storageCalls.With(labelApply).Inc()
+	return nil
+}
+
 func (w *localMetricsWrapper) Checkpointer() checkpoint.CreateRestorer {
 	return w.Backend.(LocalBackend).Checkpointer()
 }
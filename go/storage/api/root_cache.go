@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
@@ -27,6 +28,18 @@ func (rc *RootCache) Apply(
 	root Root,
 	expectedNewRoot Root,
 	writeLog WriteLog,
+) (*hash.Hash, error) {
+	return rc.ApplyWithProgress(ctx, root, expectedNewRoot, writeLog, nil)
+}
+
+// ApplyWithProgress is like Apply, but reports the progress of applying large write logs to the
+// given (optional) progress callback.
+func (rc *RootCache) ApplyWithProgress(
+	ctx context.Context,
+	root Root,
+	expectedNewRoot Root,
+	writeLog WriteLog,
+	progress ApplyProgressFunc,
 ) (*hash.Hash, error) {
 	// Sanity check the expected new root.
 	if !expectedNewRoot.Follows(&root) {
@@ -41,7 +54,7 @@ func (rc *RootCache) Apply(
 		tree := mkvs.NewWithRoot(nil, rc.localDB, root)
 		defer tree.Close()
 
-		if err := tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(writeLog)); err != nil {
+		if err := applyWriteLog(ctx, tree, writeLog, progress); err != nil {
 			return nil, err
 		}
 
@@ -58,6 +71,82 @@ func (rc *RootCache) Apply(
 	return &r, nil
 }
 
+// ApplyBatch applies the write logs for a sequence of consecutive roots, reusing the same tree
+// for all of them. Roots which already are in the node database are skipped.
+func (rc *RootCache) ApplyBatch(
+	ctx context.Context,
+	root Root,
+	expectedNewRoots []Root,
+	writeLogs []WriteLog,
+) error {
+	if len(expectedNewRoots) != len(writeLogs) {
+		return fmt.Errorf("storage: mismatched number of roots and write logs")
+	}
+
+	var tree mkvs.Tree
+	defer func() {
+		if tree != nil {
+			tree.Close()
+		}
+	}()
+
+	for i, expectedNewRoot := range expectedNewRoots {
+		// Sanity check the expected new root.
+		if !expectedNewRoot.Follows(&root) {
+			return ErrRootMustFollowOld
+		}
+
+		if rc.localDB.HasRoot(expectedNewRoot) {
+			// Root already exists, the tree needs to be recreated at the new root.
+			if tree != nil {
+				tree.Close()
+				tree = nil
+			}
+			root = expectedNewRoot
+			continue
+		}
+
+		if tree == nil {
+			tree = mkvs.NewWithRoot(nil, rc.localDB, root)
+		}
+		if err := tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(writeLogs[i])); err != nil {
+			return err
+		}
+
+		_, err := tree.CommitKnown(ctx, expectedNewRoot)
+		switch err {
+		case nil:
+		case mkvs.ErrKnownRootMismatch:
+			return ErrExpectedRootMismatch
+		default:
+			return err
+		}
+		root = expectedNewRoot
+	}
+	return nil
+}
+
+// applyWriteLog applies the write log to the given tree, in chunks of ApplyProgressInterval entries
+// in case the progress needs to be reported.
+func applyWriteLog(ctx context.Context, tree mkvs.Tree, writeLog WriteLog, progress ApplyProgressFunc) error {
+	if progress == nil || len(writeLog) <= ApplyProgressInterval {
+		return tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(writeLog))
+	}
+
+	total := uint64(len(writeLog))
+	for start := 0; start < len(writeLog); start += ApplyProgressInterval {
+		end := start + ApplyProgressInterval
+		if end > len(writeLog) {
+			end = len(writeLog)
+		}
+		if err := tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(writeLog[start:end])); err != nil {
+			return err
+		}
+		progress(uint64(end), total)
+	}
+	return nil
+}
+
 func (rc *RootCache) HasRoot(root Root) bool {
 	return rc.localDB.HasRoot(root)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
 	nodedb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
 	badgerNodedb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
+	inmemNodedb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/inmem"
 )
 
 const (
@@ -21,6 +22,13 @@ const (
 	// DBFileBadgerDB is the default BadgerDB backing store filename.
 	DBFileBadgerDB = "mkvs_storage.badger.db"
 
+	// BackendNameInMemory is the name of the in-memory database backend. All state is lost when
+	// the backend is closed so it should only be used for tests and ephemeral nodes.
+	BackendNameInMemory = "inmem"
+
+	// DBFileInMemory is the default in-memory backend directory used for storing checkpoints.
+	DBFileInMemory = "mkvs_storage.inmem"
+
 	checkpointDir = "checkpoints"
 )
 
@@ -30,6 +38,8 @@ func DefaultFileName(backend string) string {
 	switch backend {
 	case BackendNameBadgerDB:
 		return DBFileBadgerDB
+	case BackendNameInMemory:
+		return DBFileInMemory
 	default:
 		panic("storage/database: can't get default filename for unknown backend")
 	}
@@ -56,6 +66,8 @@ func New(cfg *api.Config) (api.LocalBackend, error) {
 	switch cfg.Backend {
 	case BackendNameBadgerDB:
 		ndb, err = badgerNodedb.New(ndbCfg)
+	case BackendNameInMemory:
+		ndb, err = inmemNodedb.New(ndbCfg)
 	default:
 		err = errors.New("storage/database: unsupported backend")
 	}
@@ -146,6 +158,11 @@ func (ba *databaseBackend) GetCheckpointChunk(ctx context.Context, chunk *checkp
 
 // Implements api.LocalBackend.
 func (ba *databaseBackend) Apply(ctx context.Context, request *api.ApplyRequest) error {
+	return ba.ApplyWithProgress(ctx, request, nil)
+}
+
+// Implements api.ApplyProgressBackend.
+func (ba *databaseBackend) ApplyWithProgress(ctx context.Context, request *api.ApplyRequest, progress api.ApplyProgressFunc) error {
 	if ba.readOnly {
 		return fmt.Errorf("storage/database: failed to Apply: %w", api.ErrReadOnly)
 	}
@@ -162,11 +179,12 @@ func (ba *databaseBackend) Apply(ctx context.Context, request *api.ApplyRequest)
 		Type:      request.RootType,
 		Hash:      request.DstRoot,
 	}
-	_, err := ba.rootCache.Apply(
+	_, err := ba.rootCache.ApplyWithProgress(
 		ctx,
 		oldRoot,
 		expectedNewRoot,
 		request.WriteLog,
+		progress,
 	)
 	if err != nil {
 		return fmt.Errorf("storage/database: failed to Apply: %w", err)
@@ -174,6 +192,49 @@ func (ba *databaseBackend) Apply(ctx context.Context, request *api.ApplyRequest)
 	return nil
 }
 
+// Implements api.ApplyBatchBackend.
+func (ba *databaseBackend) ApplyBatch(ctx context.Context, requests []*api.ApplyRequest) error {
+	if ba.readOnly {
+		return fmt.Errorf("storage/database: failed to ApplyBatch: %w", api.ErrReadOnly)
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+
+	first := requests[0]
+	oldRoot := api.Root{
+		Namespace: first.Namespace,
+		Version:   first.SrcRound,
+		Type:      first.RootType,
+		Hash:      first.SrcRoot,
+	}
+	expectedNewRoots := make([]api.Root, 0, len(requests))
+	writeLogs := make([]api.WriteLog, 0, len(requests))
+	for i, request := range requests {
+		if request.Namespace != first.Namespace || request.RootType != first.RootType {
+			return fmt.Errorf("storage/database: failed to ApplyBatch: request %d is for a different root", i)
+		}
+		if i > 0 {
+			prev := requests[i-1]
+			if request.SrcRound != prev.DstRound || !request.SrcRoot.Equal(&prev.DstRoot) {
+				return fmt.Errorf("storage/database: failed to ApplyBatch: request %d does not follow the previous one", i)
+			}
+		}
+		expectedNewRoots = append(expectedNewRoots, api.Root{
+			Namespace: request.Namespace,
+			Version:   request.DstRound,
+			Type:      request.RootType,
+			Hash:      request.DstRoot,
+		})
+		writeLogs = append(writeLogs, request.WriteLog)
+	}
+
+	if err := ba.rootCache.ApplyBatch(ctx, oldRoot, expectedNewRoots, writeLogs); err != nil {
+		return fmt.Errorf("storage/database: failed to ApplyBatch: %w", err)
+	}
+	return nil
+}
+
 // Implements api.LocalBackend.
 func (ba *databaseBackend) Checkpointer() checkpoint.CreateRestorer {
 	return ba.checkpointer
@@ -17,6 +17,7 @@ import (
 func TestStorageDatabase(t *testing.T) {
 	for _, v := range []string{
 		BackendNameBadgerDB,
+		BackendNameInMemory,
 	} {
 		t.Run(v, func(t *testing.T) {
 			doTestImpl(t, v)
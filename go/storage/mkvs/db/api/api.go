@@ -53,6 +53,9 @@ var (
 	// ErrUpgradeInProgress indicates that a database upgrade was started by the upgrader tool and the
 	// database is therefore unusable. Run the upgrade tool to finish upgrading.
 	ErrUpgradeInProgress = errors.New(ModuleName, 15, "mkvs: database upgrade in progress")
+	// ErrNodeCorrupted indicates that a node stored in the database is corrupted (e.g., its hash
+	// does not match the hash under which it is referenced).
+	ErrNodeCorrupted = errors.New(ModuleName, 16, "mkvs: node is corrupted")
 )
 
 // Config is the node database backend configuration.
@@ -141,6 +144,35 @@ type NodeDB interface {
 	Close()
 }
 
+// FinalizeResult is the result of finalizing a version.
+type FinalizeResult struct {
+	// Nodes is the number of nodes added in the finalized version that are retained as they are
+	// part of finalized roots.
+	Nodes uint64
+	// RemovedNodes is the number of nodes added in the finalized version that were removed as they
+	// were only part of discarded roots.
+	RemovedNodes uint64
+	// DiscardedRoots is the number of non-finalized roots that were discarded.
+	DiscardedRoots uint64
+}
+
+// FinalizeResultNodeDB is a node database which can report the result of finalizing a version.
+type FinalizeResultNodeDB interface {
+	// FinalizeWithResult finalizes the version comprising the passed list of finalized roots like
+	// Finalize does and returns the result of finalization.
+	FinalizeWithResult(ctx context.Context, roots []node.Root) (*FinalizeResult, error)
+}
+
+// FinalizeWithResult finalizes the version comprising the passed list of finalized roots and
+// returns the result of finalization. In case the node database cannot report results, the
+// returned result is nil.
+func FinalizeWithResult(ctx context.Context, ndb NodeDB, roots []node.Root) (*FinalizeResult, error) {
+	if rdb, ok := ndb.(FinalizeResultNodeDB); ok {
+		return rdb.FinalizeWithResult(ctx, roots)
+	}
+	return nil, ndb.Finalize(ctx, roots)
+}
+
 // Subtree is a NodeDB-specific subtree implementation.
 type Subtree interface {
 	// PutNode persists a node in the NodeDB.
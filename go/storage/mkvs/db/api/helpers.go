@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
@@ -163,3 +164,138 @@ func doVisit(ctx context.Context, ndb NodeDB, root node.Root, visitor NodeVisito
 
 	return nil
 }
+
+// CorruptNodeError is the error returned by Verify when a corrupted node is encountered.
+type CorruptNodeError struct {
+	// Path is the path in the tree leading to the corrupted node.
+	Path node.Key
+	// PathBitLength is the length of the path in bits.
+	PathBitLength node.Depth
+	// Hash is the hash under which the corrupted node is referenced.
+	Hash hash.Hash
+	// Err is the reason why the node is considered corrupted.
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *CorruptNodeError) Error() string {
+	return fmt.Sprintf("%s (path: %x, bit length: %d, hash: %s): %s",
+		ErrNodeCorrupted, e.Path, e.PathBitLength, e.Hash, e.Err,
+	)
+}
+
+// Is returns true iff the target is ErrNodeCorrupted.
+func (e *CorruptNodeError) Is(target error) bool {
+	return target == ErrNodeCorrupted
+}
+
+// Unwrap returns the reason why the node is considered corrupted.
+func (e *CorruptNodeError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyProgressFunc is a function that is called by Verify after each verified node with the
+// total number of nodes verified so far.
+type VerifyProgressFunc func(nodes uint64)
+
+// Verify traverses the tree with the given root and checks that every node can be loaded from the
+// node database and that its hash matches the hash under which it is referenced.
+//
+// It returns the number of verified nodes. In case a corrupted node is found, the traversal stops
+// and a *CorruptNodeError is returned for the first such node.
+func Verify(ctx context.Context, ndb NodeDB, root node.Root, progress VerifyProgressFunc) (uint64, error) {
+	if !ndb.HasRoot(root) {
+		return 0, ErrRootNotFound
+	}
+	if root.Hash.IsEmpty() {
+		return 0, nil
+	}
+
+	v := &verifier{
+		ndb:      ndb,
+		root:     root,
+		progress: progress,
+	}
+	ptr := &node.Pointer{
+		Clean: true,
+		Hash:  root.Hash,
+	}
+	err := v.doVerify(ctx, ptr, 0, node.Key{}, 0)
+	return v.nodes, err
+}
+
+type verifier struct {
+	ndb      NodeDB
+	root     node.Root
+	progress VerifyProgressFunc
+
+	nodes uint64
+}
+
+func (v *verifier) doVerify(
+	ctx context.Context,
+	ptr *node.Pointer,
+	bitDepth node.Depth,
+	path node.Key,
+	pathBitLength node.Depth,
+) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	corrupted := func(err error) error {
+		return &CorruptNodeError{
+			Path:          path,
+			PathBitLength: pathBitLength,
+			Hash:          ptr.Hash,
+			Err:           err,
+		}
+	}
+
+	nd := ptr.Node
+	if nd == nil {
+		var err error
+		nd, err = v.ndb.GetNode(v.root, ptr)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Nodes that are missing or fail to deserialize are corrupted as well.
+			return corrupted(err)
+		}
+	}
+	if h := nd.GetHash(); !h.Equal(&ptr.Hash) {
+		return corrupted(fmt.Errorf("hash mismatch (computed: %s)", h))
+	}
+
+	v.nodes++
+	if v.progress != nil {
+		v.progress(v.nodes)
+	}
+
+	n, ok := nd.(*node.InternalNode)
+	if !ok {
+		return nil
+	}
+
+	bitLength := bitDepth + n.LabelBitLength
+	newPath := path.Merge(bitDepth, n.Label, n.LabelBitLength)
+	if n.LeafNode != nil {
+		if err := v.doVerify(ctx, n.LeafNode, bitLength, newPath, bitLength); err != nil {
+			return err
+		}
+	}
+	if n.Left != nil {
+		if err := v.doVerify(ctx, n.Left, bitLength, newPath.AppendBit(bitLength, false), bitLength+1); err != nil {
+			return err
+		}
+	}
+	if n.Right != nil {
+		if err := v.doVerify(ctx, n.Right, bitLength, newPath.AppendBit(bitLength, true), bitLength+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -528,13 +528,20 @@ func (d *badgerNodeDB) HasRoot(root node.Root) bool {
 	return exists
 }
 
-func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error { // nolint: gocyclo
+// Implements api.NodeDB.
+func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
+	_, err := d.FinalizeWithResult(ctx, roots)
+	return err
+}
+
+// Implements api.FinalizeResultNodeDB.
+func (d *badgerNodeDB) FinalizeWithResult(ctx context.Context, roots []node.Root) (*api.FinalizeResult, error) { // nolint: gocyclo
 	if d.readOnly {
-		return api.ErrReadOnly
+		return nil, api.ErrReadOnly
 	}
 
 	if len(roots) == 0 {
-		return fmt.Errorf("mkvs/badger: need at least one root to finalize")
+		return nil, fmt.Errorf("mkvs/badger: need at least one root to finalize")
 	}
 	version := roots[0].Version
 
@@ -542,7 +549,7 @@ func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
 	defer d.metaUpdateLock.Unlock()
 
 	if d.multipartVersion != multipartVersionNone && d.multipartVersion != version {
-		return api.ErrInvalidMultipartVersion
+		return nil, api.ErrInvalidMultipartVersion
 	}
 
 	// Version batch collects removals at the version timestamp.
@@ -555,11 +562,11 @@ func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
 	// Make sure that the previous version has been finalized (if we are not restoring).
 	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
 	if d.multipartVersion == multipartVersionNone && version > 0 && exists && lastFinalizedVersion < (version-1) {
-		return api.ErrNotFinalized
+		return nil, api.ErrNotFinalized
 	}
 	// Make sure that this version has not yet been finalized.
 	if exists && version <= lastFinalizedVersion {
-		return api.ErrAlreadyFinalized
+		return nil, api.ErrAlreadyFinalized
 	}
 
 	// Determine the set of finalized roots. Finalization is transitive, so if
@@ -567,7 +574,7 @@ func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
 	finalizedRoots := make(map[typedHash]bool)
 	for _, root := range roots {
 		if root.Version != version {
-			return fmt.Errorf("mkvs/badger: roots to finalize don't have matching versions")
+			return nil, fmt.Errorf("mkvs/badger: roots to finalize don't have matching versions")
 		}
 		finalizedRoots[typedHashFromRoot(root)] = true
 	}
@@ -575,7 +582,7 @@ func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
 	var rootsChanged bool
 	rootsMeta, err := loadRootsMetadata(tx, version)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for updated := true; updated; {
@@ -599,13 +606,14 @@ func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
 	for iroot := range finalizedRoots {
 		h := iroot.Hash()
 		if _, ok := rootsMeta.Roots[iroot]; !ok && !h.IsEmpty() {
-			return api.ErrRootNotFound
+			return nil, api.ErrRootNotFound
 		}
 	}
 
 	// Go through all roots and prune them based on whether they are finalized or not.
 	maybeLoneNodes := make(map[hash.Hash]bool)
 	notLoneNodes := make(map[hash.Hash]bool)
+	var result api.FinalizeResult
 
 	for rootHash := range rootsMeta.Roots {
 		// TODO: Consider colocating updated nodes with the root metadata.
@@ -647,6 +655,7 @@ func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
 
 			delete(rootsMeta.Roots, rootHash)
 			rootsChanged = true
+			result.DiscardedRoots++
 
 			// Remove write logs for the non-finalized root.
 			if !d.discardWriteLogs {
@@ -662,14 +671,14 @@ func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
 					}
 					return nil
 				}(); err != nil {
-					return err
+					return nil, err
 				}
 			}
 		}
 
 		// Set of updated nodes no longer needed after finalization.
 		if err = tx.Delete(rootUpdatedNodesKey); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
@@ -681,38 +690,40 @@ func (d *badgerNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
 
 		key := nodeKeyFmt.Encode(&h)
 		if err := versionBatch.Delete(key); err != nil {
-			return err
+			return nil, err
 		}
+		result.RemovedNodes++
 	}
+	result.Nodes = uint64(len(notLoneNodes))
 
 	// Commit batch.
 	if err := versionBatch.Flush(); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Save roots metadata if changed.
 	if rootsChanged {
 		if err := rootsMeta.save(tx); err != nil {
-			return fmt.Errorf("mkvs/badger: failed to save roots metadata: %w", err)
+			return nil, fmt.Errorf("mkvs/badger: failed to save roots metadata: %w", err)
 		}
 	}
 
 	// Update last finalized version.
 	if err := d.meta.setLastFinalizedVersion(tx, version); err != nil {
-		return fmt.Errorf("mkvs/badger: failed to set last finalized version: %w", err)
+		return nil, fmt.Errorf("mkvs/badger: failed to set last finalized version: %w", err)
 	}
 
 	if err := tx.CommitAt(tsMetadata, nil); err != nil {
-		return fmt.Errorf("mkvs/badger: failed to commit metadata: %w", err)
+		return nil, fmt.Errorf("mkvs/badger: failed to commit metadata: %w", err)
 	}
 
 	// Clean multipart metadata if there is any.
 	if d.multipartVersion != multipartVersionNone {
 		if err := d.cleanMultipartLocked(false); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	return nil
+	return &result, nil
 }
 
 func (d *badgerNodeDB) Prune(ctx context.Context, version uint64) error {
@@ -380,3 +380,106 @@ func TestFinalizeBasic(t *testing.T) {
 	err = ndb.Finalize(ctx, []node.Root{root2})
 	require.Errorf(err, "mkvs: root not found", "Finalize({root2-broken})")
 }
+
+func TestFinalizeWithResult(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	countNodes := func(root node.Root) (count uint64) {
+		err = api.Visit(ctx, ndb, root, func(ctx context.Context, n node.Node) bool {
+			count++
+			return true
+		})
+		require.NoError(err, "Visit()")
+		return
+	}
+
+	// All nodes of the first root are new.
+	root1 := fillDB(ctx, require, testValues, nil, 1, 2, ndb)
+	result, err := api.FinalizeWithResult(ctx, ndb, []node.Root{root1})
+	require.NoError(err, "FinalizeWithResult({root1})")
+	require.Equal(&api.FinalizeResult{Nodes: countNodes(root1)}, result, "finalize result of root1")
+
+	// Nodes only referenced by the discarded root should be removed.
+	root2a := fillDB(ctx, require, [][]byte{[]byte("first")}, &root1, 2, 3, ndb)
+	root2b := fillDB(ctx, require, [][]byte{[]byte("second")}, &root1, 2, 3, ndb)
+	result, err = api.FinalizeWithResult(ctx, ndb, []node.Root{root2a})
+	require.NoError(err, "FinalizeWithResult({root2a})")
+	require.NotNil(result, "finalize result of root2a")
+	require.EqualValues(1, result.DiscardedRoots, "discarded roots")
+	require.NotZero(result.Nodes, "finalized nodes")
+	require.NotZero(result.RemovedNodes, "removed nodes")
+	require.False(ndb.HasRoot(root2b), "HasRoot(root2b) after finalization")
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+
+	root := fillDB(ctx, require, testValues, nil, 1, 2, ndb)
+	err = ndb.Finalize(ctx, []node.Root{root})
+	require.NoError(err, "Finalize({root})")
+
+	var (
+		leaves   []*node.LeafNode
+		progress uint64
+	)
+	err = api.Visit(ctx, ndb, root, func(ctx context.Context, n node.Node) bool {
+		if leaf, ok := n.(*node.LeafNode); ok {
+			leaves = append(leaves, leaf)
+		}
+		return true
+	})
+	require.NoError(err, "Visit()")
+	require.Len(leaves, len(testValues), "all leaves should be visited")
+
+	nodes, err := api.Verify(ctx, ndb, root, func(n uint64) { progress = n })
+	require.NoError(err, "Verify() on an intact tree")
+	require.NotZero(nodes, "nodes should be verified")
+	require.Equal(nodes, progress, "progress should be reported for all nodes")
+
+	// Unknown roots cannot be verified.
+	bogusRoot := root
+	bogusRoot.Hash[3]++
+	_, err = api.Verify(ctx, ndb, bogusRoot, nil)
+	require.ErrorIs(err, api.ErrRootNotFound, "Verify() on an unknown root")
+
+	// Replace one of the leaves with a different node stored under the same hash.
+	leaf := leaves[0]
+	h := leaf.GetHash()
+	corrupted := node.LeafNode{Key: leaf.Key, Value: []byte("corrupted")}
+	data, err := corrupted.MarshalBinary()
+	require.NoError(err, "MarshalBinary()")
+	batch := badgerdb.db.NewWriteBatchAt(versionToTs(root.Version))
+	require.NoError(batch.Set(nodeKeyFmt.Encode(&h), data), "Set()")
+	require.NoError(batch.Flush(), "Flush()")
+
+	_, err = api.Verify(ctx, ndb, root, nil)
+	require.ErrorIs(err, api.ErrNodeCorrupted, "Verify() on a corrupted tree")
+	var corruptErr *api.CorruptNodeError
+	require.ErrorAs(err, &corruptErr)
+	require.Equal(h, corruptErr.Hash, "the corrupted node should be reported")
+	require.Equal(
+		corruptErr.PathBitLength,
+		leaf.Key.CommonPrefixLen(leaf.Key.BitLength(), corruptErr.Path, corruptErr.PathBitLength),
+		"the reported path should be a prefix of the corrupted leaf key",
+	)
+
+	// Remove the node altogether.
+	batch = badgerdb.db.NewWriteBatchAt(versionToTs(root.Version))
+	require.NoError(batch.Delete(nodeKeyFmt.Encode(&h)), "Delete()")
+	require.NoError(batch.Flush(), "Flush()")
+
+	_, err = api.Verify(ctx, ndb, root, nil)
+	require.ErrorIs(err, api.ErrNodeCorrupted, "Verify() on a tree with a missing node")
+	require.ErrorIs(err, api.ErrNodeNotFound, "Verify() on a tree with a missing node")
+}
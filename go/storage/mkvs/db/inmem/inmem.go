@@ -0,0 +1,805 @@
+// Package inmem provides a memory-only node database backend.
+//
+// The backend is mostly useful for tests and ephemeral nodes as all data is lost once the
+// database is closed. It implements the same semantics as the persistent backends.
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+const multipartVersionNone uint64 = 0
+
+// rootKey is a version-independent root identifier.
+type rootKey struct {
+	typ  node.RootType
+	hash hash.Hash
+}
+
+func rootKeyFromRoot(root node.Root) rootKey {
+	return rootKey{typ: root.Type, hash: root.Hash}
+}
+
+func (k rootKey) toRoot(ns common.Namespace, version uint64) node.Root {
+	return node.Root{
+		Namespace: ns,
+		Version:   version,
+		Type:      k.typ,
+		Hash:      k.hash,
+	}
+}
+
+type updatedNode struct {
+	removed bool
+	hash    hash.Hash
+}
+
+// rootInfo is the per-root metadata within a version.
+type rootInfo struct {
+	// derivedRoots are the roots derived from this root.
+	derivedRoots []rootKey
+	// updatedNodes are the nodes updated when committing this root. They are only needed until
+	// the version is finalized.
+	updatedNodes []updatedNode
+}
+
+type memNode struct {
+	data []byte
+	// firstVersion is the first version in which the node has been written.
+	firstVersion uint64
+	// lastVersion is the last version in which the node has been written.
+	lastVersion uint64
+}
+
+type writeLogEntry struct {
+	startRoot rootKey
+	log       api.HashedDBWriteLog
+}
+
+// New creates a new in-memory node database.
+func New(cfg *api.Config) (api.NodeDB, error) {
+	return &memNodeDB{
+		logger:           logging.GetLogger("mkvs/db/inmem"),
+		namespace:        cfg.Namespace,
+		readOnly:         cfg.ReadOnly,
+		discardWriteLogs: cfg.DiscardWriteLogs,
+		nodes:            make(map[hash.Hash]*memNode),
+		roots:            make(map[uint64]map[rootKey]*rootInfo),
+		rootVersions:     make(map[rootKey]map[uint64]struct{}),
+		writeLogs:        make(map[uint64]map[rootKey][]writeLogEntry),
+	}, nil
+}
+
+type memNodeDB struct { // nolint: maligned
+	logger *logging.Logger
+
+	namespace common.Namespace
+
+	readOnly         bool
+	discardWriteLogs bool
+
+	// lock must be held whenever any of the fields below are accessed.
+	lock sync.RWMutex
+
+	nodes map[hash.Hash]*memNode
+	// roots are the roots for each version.
+	roots map[uint64]map[rootKey]*rootInfo
+	// rootVersions are the versions in which each root has been committed.
+	rootVersions map[rootKey]map[uint64]struct{}
+	// writeLogs are the write logs for each version, indexed by their end root.
+	writeLogs map[uint64]map[rootKey][]writeLogEntry
+
+	earliestVersion      uint64
+	lastFinalizedVersion *uint64
+
+	multipartVersion uint64
+	multipartNodes   map[hash.Hash]struct{}
+	multipartRoots   map[rootKey]struct{}
+}
+
+func (d *memNodeDB) sanityCheckNamespace(ns common.Namespace) error {
+	if !ns.Equal(&d.namespace) {
+		return api.ErrBadNamespace
+	}
+	return nil
+}
+
+// Assumes lock is held when called.
+func (d *memNodeDB) getLastFinalizedVersionLocked() (uint64, bool) {
+	if d.lastFinalizedVersion == nil {
+		return 0, false
+	}
+	return *d.lastFinalizedVersion, true
+}
+
+// Assumes lock is held when called.
+func (d *memNodeDB) addRootLocked(version uint64, key rootKey) *rootInfo {
+	versionRoots := d.roots[version]
+	if versionRoots == nil {
+		versionRoots = make(map[rootKey]*rootInfo)
+		d.roots[version] = versionRoots
+	}
+	info := &rootInfo{}
+	versionRoots[key] = info
+
+	versions := d.rootVersions[key]
+	if versions == nil {
+		versions = make(map[uint64]struct{})
+		d.rootVersions[key] = versions
+	}
+	versions[version] = struct{}{}
+
+	return info
+}
+
+// Assumes lock is held when called.
+func (d *memNodeDB) removeRootLocked(version uint64, key rootKey) {
+	delete(d.roots[version], key)
+	if len(d.roots[version]) == 0 {
+		delete(d.roots, version)
+	}
+
+	delete(d.rootVersions[key], version)
+	if len(d.rootVersions[key]) == 0 {
+		delete(d.rootVersions, key)
+	}
+}
+
+// Assumes lock is held when called.
+func (d *memNodeDB) checkRootLocked(root node.Root) error {
+	// The root node is present if the root has been committed at the same or an earlier version.
+	for version := range d.rootVersions[rootKeyFromRoot(root)] {
+		if version <= root.Version && version >= d.earliestVersion {
+			return nil
+		}
+	}
+	return api.ErrRootNotFound
+}
+
+// Assumes lock is held when called.
+func (d *memNodeDB) getNodeLocked(root node.Root, ptr *node.Pointer) (node.Node, error) {
+	if ptr == nil || !ptr.IsClean() {
+		panic("mkvs/inmem: attempted to get invalid pointer from node database")
+	}
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return nil, err
+	}
+	// If the version is earlier than the earliest version, we don't have the node (it was pruned).
+	if root.Version < d.earliestVersion {
+		return nil, api.ErrNodeNotFound
+	}
+
+	// Check if the root actually exists.
+	if err := d.checkRootLocked(root); err != nil {
+		return nil, err
+	}
+
+	mn, ok := d.nodes[ptr.Hash]
+	if !ok {
+		return nil, api.ErrNodeNotFound
+	}
+
+	n, err := node.UnmarshalBinary(mn.data)
+	if err != nil {
+		d.logger.Error("failed to unmarshal node",
+			"err", err,
+		)
+		return nil, fmt.Errorf("mkvs/inmem: failed to unmarshal node: %w", err)
+	}
+	return n, nil
+}
+
+// Assumes lock is held when called.
+func (d *memNodeDB) cleanMultipartLocked(removeNodes bool) {
+	if d.multipartVersion == multipartVersionNone {
+		// No multipart in progress, but it's not an error to call in a situation like this.
+		return
+	}
+
+	if removeNodes {
+		if len(d.multipartNodes) > 0 || len(d.multipartRoots) > 0 {
+			d.logger.Info("removing some nodes from a multipart restore")
+		}
+		for h := range d.multipartNodes {
+			delete(d.nodes, h)
+		}
+		for key := range d.multipartRoots {
+			d.removeRootLocked(d.multipartVersion, key)
+		}
+	}
+
+	d.multipartVersion = multipartVersionNone
+	d.multipartNodes = nil
+	d.multipartRoots = nil
+}
+
+func (d *memNodeDB) GetNode(root node.Root, ptr *node.Pointer) (node.Node, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.getNodeLocked(root, ptr)
+}
+
+func (d *memNodeDB) GetWriteLog(ctx context.Context, startRoot, endRoot node.Root) (writelog.Iterator, error) {
+	if d.discardWriteLogs {
+		return nil, api.ErrWriteLogNotFound
+	}
+	if !endRoot.Follows(&startRoot) {
+		return nil, api.ErrRootMustFollowOld
+	}
+	if err := d.sanityCheckNamespace(startRoot.Namespace); err != nil {
+		return nil, err
+	}
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	// If the version is earlier than the earliest version, we don't have the roots.
+	if endRoot.Version < d.earliestVersion {
+		return nil, api.ErrWriteLogNotFound
+	}
+
+	// Check if the root actually exists.
+	if err := d.checkRootLocked(endRoot); err != nil {
+		return nil, err
+	}
+
+	// Start at the end root and search towards the start root. Same as for the persistent
+	// backends, we refuse to traverse more than two hops.
+	const maxAllowedHops = 2
+
+	type wlItem struct {
+		depth       uint8
+		endRootHash rootKey
+		logs        []api.HashedDBWriteLog
+		logRoots    []rootKey
+	}
+	queue := []*wlItem{{depth: 0, endRootHash: rootKeyFromRoot(endRoot)}}
+	startRootHash := rootKeyFromRoot(startRoot)
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		curItem := queue[0]
+		queue = queue[1:]
+
+		for _, entry := range d.writeLogs[endRoot.Version][curItem.endRootHash] {
+			nextItem := wlItem{
+				depth:       curItem.depth + 1,
+				endRootHash: entry.startRoot,
+				logs:        append(append([]api.HashedDBWriteLog{}, curItem.logs...), entry.log),
+				logRoots:    append(append([]rootKey{}, curItem.logRoots...), curItem.endRootHash),
+			}
+			if nextItem.endRootHash == startRootHash {
+				// Path has been found, stream write logs.
+				var index int
+				return api.ReviveHashedDBWriteLogs(ctx,
+					func() (node.Root, api.HashedDBWriteLog, error) {
+						if index >= len(nextItem.logs) {
+							return node.Root{}, nil, nil
+						}
+
+						root := nextItem.logRoots[index].toRoot(endRoot.Namespace, endRoot.Version)
+						log := nextItem.logs[index]
+						index++
+						return root, log, nil
+					},
+					func(root node.Root, h hash.Hash) (*node.LeafNode, error) {
+						leaf, err := d.GetNode(root, &node.Pointer{Hash: h, Clean: true})
+						if err != nil {
+							return nil, err
+						}
+						return leaf.(*node.LeafNode), nil
+					},
+					func() {},
+				)
+			}
+
+			if nextItem.depth < maxAllowedHops {
+				queue = append(queue, &nextItem)
+			}
+		}
+	}
+
+	return nil, api.ErrWriteLogNotFound
+}
+
+func (d *memNodeDB) GetLatestVersion() (uint64, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.getLastFinalizedVersionLocked()
+}
+
+func (d *memNodeDB) GetEarliestVersion() uint64 {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.earliestVersion
+}
+
+func (d *memNodeDB) GetRootsForVersion(ctx context.Context, version uint64) (roots []node.Root, err error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	// If the version is earlier than the earliest version, we don't have the roots.
+	if version < d.earliestVersion {
+		return nil, nil
+	}
+
+	for key := range d.roots[version] {
+		roots = append(roots, key.toRoot(d.namespace, version))
+	}
+	return
+}
+
+func (d *memNodeDB) HasRoot(root node.Root) bool {
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return false
+	}
+
+	// An empty root is always implicitly present.
+	if root.Hash.IsEmpty() {
+		return true
+	}
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	// If the version is earlier than the earliest version, we don't have the root.
+	if root.Version < d.earliestVersion {
+		return false
+	}
+
+	_, exists := d.roots[root.Version][rootKeyFromRoot(root)]
+	return exists
+}
+
+// Implements api.NodeDB.
+func (d *memNodeDB) Finalize(ctx context.Context, roots []node.Root) error {
+	_, err := d.FinalizeWithResult(ctx, roots)
+	return err
+}
+
+// Implements api.FinalizeResultNodeDB.
+func (d *memNodeDB) FinalizeWithResult(ctx context.Context, roots []node.Root) (*api.FinalizeResult, error) { // nolint: gocyclo
+	if d.readOnly {
+		return nil, api.ErrReadOnly
+	}
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("mkvs/inmem: need at least one root to finalize")
+	}
+	version := roots[0].Version
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.multipartVersion != multipartVersionNone && d.multipartVersion != version {
+		return nil, api.ErrInvalidMultipartVersion
+	}
+
+	// Make sure that the previous version has been finalized (if we are not restoring).
+	lastFinalizedVersion, exists := d.getLastFinalizedVersionLocked()
+	if d.multipartVersion == multipartVersionNone && version > 0 && exists && lastFinalizedVersion < (version-1) {
+		return nil, api.ErrNotFinalized
+	}
+	// Make sure that this version has not yet been finalized.
+	if exists && version <= lastFinalizedVersion {
+		return nil, api.ErrAlreadyFinalized
+	}
+
+	// Determine the set of finalized roots. Finalization is transitive, so if
+	// a parent root is finalized the child should be considered finalized too.
+	finalizedRoots := make(map[rootKey]bool)
+	for _, root := range roots {
+		if root.Version != version {
+			return nil, fmt.Errorf("mkvs/inmem: roots to finalize don't have matching versions")
+		}
+		finalizedRoots[rootKeyFromRoot(root)] = true
+	}
+
+	versionRoots := d.roots[version]
+	for updated := true; updated; {
+		updated = false
+
+		for key, info := range versionRoots {
+			for _, nextRoot := range info.derivedRoots {
+				if !finalizedRoots[key] && finalizedRoots[nextRoot] {
+					finalizedRoots[key] = true
+					updated = true
+				}
+			}
+		}
+	}
+
+	// Sanity check the input roots list.
+	for key := range finalizedRoots {
+		if _, ok := versionRoots[key]; !ok && !key.hash.IsEmpty() {
+			return nil, api.ErrRootNotFound
+		}
+	}
+
+	// Go through all roots and prune them based on whether they are finalized or not.
+	maybeLoneNodes := make(map[hash.Hash]bool)
+	notLoneNodes := make(map[hash.Hash]bool)
+	var result api.FinalizeResult
+
+	for key, info := range versionRoots {
+		if finalizedRoots[key] {
+			// Make sure not to remove any nodes shared with finalized roots.
+			for _, n := range info.updatedNodes {
+				if n.removed {
+					maybeLoneNodes[n.hash] = true
+				} else {
+					notLoneNodes[n.hash] = true
+				}
+			}
+		} else {
+			// Remove any non-finalized roots.
+			for _, n := range info.updatedNodes {
+				if !n.removed {
+					maybeLoneNodes[n.hash] = true
+				}
+			}
+
+			d.removeRootLocked(version, key)
+			result.DiscardedRoots++
+
+			// Remove write logs for the non-finalized root.
+			delete(d.writeLogs[version], key)
+		}
+
+		// Set of updated nodes no longer needed after finalization.
+		info.updatedNodes = nil
+	}
+
+	// Clean any lone nodes. Only nodes that have been written exclusively in this version can be
+	// removed as otherwise they may still be referenced by other roots.
+	for h := range maybeLoneNodes {
+		if notLoneNodes[h] {
+			continue
+		}
+		if mn, ok := d.nodes[h]; ok && mn.firstVersion == version && mn.lastVersion == version {
+			delete(d.nodes, h)
+			result.RemovedNodes++
+		}
+	}
+	result.Nodes = uint64(len(notLoneNodes))
+
+	// Update last finalized version.
+	if d.lastFinalizedVersion == nil {
+		d.earliestVersion = version
+	}
+	d.lastFinalizedVersion = &version
+
+	// Clean multipart metadata if there is any.
+	d.cleanMultipartLocked(false)
+
+	return &result, nil
+}
+
+func (d *memNodeDB) Prune(ctx context.Context, version uint64) error {
+	if d.readOnly {
+		return api.ErrReadOnly
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.multipartVersion != multipartVersionNone {
+		return api.ErrMultipartInProgress
+	}
+
+	// Make sure that the version that we try to prune has been finalized.
+	lastFinalizedVersion, exists := d.getLastFinalizedVersionLocked()
+	if !exists || lastFinalizedVersion < version {
+		return api.ErrNotFinalized
+	}
+	// Make sure that the version that we are trying to prune is the earliest version.
+	if version != d.earliestVersion {
+		return api.ErrNotEarliest
+	}
+
+	// Remove all roots in version.
+	pruneNodes := make(map[hash.Hash]bool)
+	for key, info := range d.roots[version] {
+		if len(info.derivedRoots) > 0 {
+			// Not a lone root.
+			continue
+		}
+
+		// Traverse the root and prune all nodes that were last written in this version.
+		err := api.Visit(ctx, &lockedNodeDB{d}, key.toRoot(d.namespace, version), func(ctx context.Context, n node.Node) bool {
+			h := n.GetHash()
+			if mn, ok := d.nodes[h]; ok && mn.lastVersion == version {
+				pruneNodes[h] = true
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for h := range pruneNodes {
+		delete(d.nodes, h)
+	}
+	for key := range d.roots[version] {
+		d.removeRootLocked(version, key)
+	}
+
+	// Prune all write logs in version.
+	delete(d.writeLogs, version)
+
+	// Update metadata.
+	d.earliestVersion = version + 1
+
+	return nil
+}
+
+func (d *memNodeDB) StartMultipartInsert(version uint64) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if version == multipartVersionNone {
+		return api.ErrInvalidMultipartVersion
+	}
+
+	if d.multipartVersion != multipartVersionNone {
+		if d.multipartVersion != version {
+			return api.ErrMultipartInProgress
+		}
+		// Multipart already initialized at the same version, so this was
+		// probably called e.g. as part of a further checkpoint restore.
+		return nil
+	}
+
+	d.multipartVersion = version
+	d.multipartNodes = make(map[hash.Hash]struct{})
+	d.multipartRoots = make(map[rootKey]struct{})
+
+	return nil
+}
+
+func (d *memNodeDB) AbortMultipartInsert() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.cleanMultipartLocked(true)
+	return nil
+}
+
+func (d *memNodeDB) NewBatch(oldRoot node.Root, version uint64, chunk bool) (api.Batch, error) {
+	if d.readOnly {
+		return nil, api.ErrReadOnly
+	}
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	if d.multipartVersion != multipartVersionNone && d.multipartVersion != version {
+		return nil, api.ErrInvalidMultipartVersion
+	}
+	if chunk != (d.multipartVersion != multipartVersionNone) {
+		return nil, api.ErrMultipartInProgress
+	}
+
+	return &memBatch{
+		db:      d,
+		oldRoot: oldRoot,
+		chunk:   chunk,
+		nodes:   make(map[hash.Hash][]byte),
+	}, nil
+}
+
+func (d *memNodeDB) Size() (int64, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	var size int64
+	for _, mn := range d.nodes {
+		size += int64(len(mn.data))
+	}
+	return size, nil
+}
+
+func (d *memNodeDB) Sync() error {
+	return nil
+}
+
+func (d *memNodeDB) Close() {
+}
+
+// lockedNodeDB is a node database wrapper that can be used to traverse the tree while the
+// database lock is already held.
+type lockedNodeDB struct {
+	*memNodeDB
+}
+
+func (d *lockedNodeDB) GetNode(root node.Root, ptr *node.Pointer) (node.Node, error) {
+	return d.getNodeLocked(root, ptr)
+}
+
+type memBatch struct {
+	api.BaseBatch
+
+	db *memNodeDB
+
+	oldRoot node.Root
+	chunk   bool
+
+	nodes        map[hash.Hash][]byte
+	writeLog     writelog.WriteLog
+	annotations  writelog.Annotations
+	updatedNodes []updatedNode
+}
+
+func (ba *memBatch) MaybeStartSubtree(subtree api.Subtree, depth node.Depth, subtreeRoot *node.Pointer) api.Subtree {
+	if subtree == nil {
+		return &memSubtree{batch: ba}
+	}
+	return subtree
+}
+
+func (ba *memBatch) PutWriteLog(writeLog writelog.WriteLog, annotations writelog.Annotations) error {
+	if ba.chunk {
+		return fmt.Errorf("mkvs/inmem: cannot put write log in chunk mode")
+	}
+	if ba.db.discardWriteLogs {
+		return nil
+	}
+
+	ba.writeLog = writeLog
+	ba.annotations = annotations
+	return nil
+}
+
+func (ba *memBatch) RemoveNodes(nodes []node.Node) error {
+	if ba.chunk {
+		return fmt.Errorf("mkvs/inmem: cannot remove nodes in chunk mode")
+	}
+
+	for _, n := range nodes {
+		ba.updatedNodes = append(ba.updatedNodes, updatedNode{
+			removed: true,
+			hash:    n.GetHash(),
+		})
+	}
+	return nil
+}
+
+func (ba *memBatch) Commit(root node.Root) error {
+	ba.db.lock.Lock()
+	defer ba.db.lock.Unlock()
+
+	if ba.db.multipartVersion != multipartVersionNone && ba.db.multipartVersion != root.Version {
+		return api.ErrInvalidMultipartVersion
+	}
+
+	if err := ba.db.sanityCheckNamespace(root.Namespace); err != nil {
+		return err
+	}
+	if !root.Follows(&ba.oldRoot) {
+		return api.ErrRootMustFollowOld
+	}
+
+	// Make sure that the version that we try to commit into has not yet been finalized.
+	lastFinalizedVersion, exists := ba.db.getLastFinalizedVersionLocked()
+	if exists && lastFinalizedVersion >= root.Version {
+		return api.ErrAlreadyFinalized
+	}
+
+	key := rootKeyFromRoot(root)
+	info, rootExists := ba.db.roots[root.Version][key]
+	if rootExists && !ba.chunk {
+		// Root already exists, no need to do anything since if the hash matches, everything will
+		// be identical and we would just be duplicating work.
+		ba.Reset()
+		return ba.BaseBatch.Commit(root)
+	}
+
+	if !ba.chunk {
+		// Update the root link for the old root.
+		if !ba.oldRoot.Hash.IsEmpty() {
+			if ba.oldRoot.Version < ba.db.earliestVersion && ba.oldRoot.Version != root.Version {
+				return api.ErrPreviousVersionMismatch
+			}
+
+			oldInfo, ok := ba.db.roots[ba.oldRoot.Version][rootKeyFromRoot(ba.oldRoot)]
+			if !ok {
+				return api.ErrRootNotFound
+			}
+			oldInfo.derivedRoots = append(oldInfo.derivedRoots, key)
+		}
+	}
+
+	if !rootExists {
+		// Create root with no derived roots.
+		info = ba.db.addRootLocked(root.Version, key)
+		if ba.db.multipartVersion != multipartVersionNone {
+			ba.db.multipartRoots[key] = struct{}{}
+		}
+	}
+
+	if !ba.chunk {
+		// Store updated nodes (only needed until the version is finalized).
+		info.updatedNodes = ba.updatedNodes
+
+		// Store write log.
+		if ba.writeLog != nil && ba.annotations != nil {
+			versionLogs := ba.db.writeLogs[root.Version]
+			if versionLogs == nil {
+				versionLogs = make(map[rootKey][]writeLogEntry)
+				ba.db.writeLogs[root.Version] = versionLogs
+			}
+			versionLogs[key] = append(versionLogs[key], writeLogEntry{
+				startRoot: rootKeyFromRoot(ba.oldRoot),
+				log:       api.MakeHashedDBWriteLog(ba.writeLog, ba.annotations),
+			})
+		}
+	}
+
+	// Flush node updates.
+	for h, data := range ba.nodes {
+		mn, ok := ba.db.nodes[h]
+		if !ok {
+			mn = &memNode{
+				data:         data,
+				firstVersion: root.Version,
+			}
+			ba.db.nodes[h] = mn
+
+			if ba.db.multipartVersion != multipartVersionNone {
+				ba.db.multipartNodes[h] = struct{}{}
+			}
+		}
+		if root.Version > mn.lastVersion {
+			mn.lastVersion = root.Version
+		}
+	}
+
+	ba.Reset()
+
+	return ba.BaseBatch.Commit(root)
+}
+
+func (ba *memBatch) Reset() {
+	ba.nodes = make(map[hash.Hash][]byte)
+	ba.writeLog = nil
+	ba.annotations = nil
+	ba.updatedNodes = nil
+}
+
+type memSubtree struct {
+	batch *memBatch
+}
+
+func (s *memSubtree) PutNode(depth node.Depth, ptr *node.Pointer) error {
+	data, err := ptr.Node.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	h := ptr.Node.GetHash()
+	s.batch.updatedNodes = append(s.batch.updatedNodes, updatedNode{hash: h})
+	s.batch.nodes[h] = data
+	return nil
+}
+
+func (s *memSubtree) VisitCleanNode(depth node.Depth, ptr *node.Pointer) error {
+	return nil
+}
+
+func (s *memSubtree) Commit() error {
+	return nil
+}
@@ -0,0 +1,215 @@
+package inmem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+var testNs = common.NewTestNamespaceFromSeed([]byte("inmem node db test ns"), 0)
+
+func commitTree(ctx context.Context, require *require.Assertions, ndb api.NodeDB, prevRoot node.Root, version uint64, key, value []byte) node.Root {
+	tree := mkvs.NewWithRoot(nil, ndb, prevRoot)
+	defer tree.Close()
+
+	err := tree.Insert(ctx, key, value)
+	require.NoError(err, "Insert()")
+	_, rootHash, err := tree.Commit(ctx, testNs, version)
+	require.NoError(err, "Commit()")
+
+	return node.Root{
+		Namespace: testNs,
+		Version:   version,
+		Type:      node.RootTypeState,
+		Hash:      rootHash,
+	}
+}
+
+func TestVersionChecks(t *testing.T) {
+	require := require.New(t)
+	ndb, err := New(&api.Config{Namespace: testNs})
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	err = ndb.StartMultipartInsert(0)
+	require.Error(err, "StartMultipartInsert(0)")
+
+	err = ndb.StartMultipartInsert(42)
+	require.NoError(err, "StartMultipartInsert(42)")
+	err = ndb.StartMultipartInsert(44)
+	require.Error(err, "StartMultipartInsert(44)")
+
+	root := node.Root{}
+	_, err = ndb.NewBatch(root, 0, false) // Normal chunks not allowed during multipart.
+	require.Error(err, "NewBatch(.., 0, false)")
+	_, err = ndb.NewBatch(root, 13, true)
+	require.Error(err, "NewBatch(.., 13, true)")
+	batch, err := ndb.NewBatch(root, 42, true)
+	require.NoError(err, "NewBatch(.., 42, true)")
+	defer batch.Reset()
+
+	err = batch.Commit(root)
+	require.Error(err, "Commit(Root{0})")
+}
+
+func TestReadOnlyBatch(t *testing.T) {
+	require := require.New(t)
+	ndb, err := New(&api.Config{Namespace: testNs, ReadOnly: true})
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	_, err = ndb.NewBatch(node.Root{}, 13, false)
+	require.ErrorIs(err, api.ErrReadOnly, "NewBatch()")
+	err = ndb.Finalize(context.Background(), []node.Root{{Namespace: testNs}})
+	require.ErrorIs(err, api.ErrReadOnly, "Finalize()")
+	err = ndb.Prune(context.Background(), 0)
+	require.ErrorIs(err, api.ErrReadOnly, "Prune()")
+}
+
+func TestFinalizeSemantics(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+	ndb, err := New(&api.Config{Namespace: testNs})
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	emptyRoot := node.Root{Namespace: testNs, Version: 1, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+
+	root1 := commitTree(ctx, require, ndb, emptyRoot, 1, []byte("foo"), []byte("bar"))
+	require.True(ndb.HasRoot(root1), "HasRoot(root1)")
+	_, exists := ndb.GetLatestVersion()
+	require.False(exists, "no version should be finalized yet")
+
+	err = ndb.Finalize(ctx, []node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	latest, exists := ndb.GetLatestVersion()
+	require.True(exists, "latest version should exist")
+	require.EqualValues(1, latest, "latest version should be correct")
+	require.EqualValues(1, ndb.GetEarliestVersion(), "earliest version should be the first finalized one")
+
+	// Finalizing the same version again must fail.
+	err = ndb.Finalize(ctx, []node.Root{root1})
+	require.ErrorIs(err, api.ErrAlreadyFinalized, "Finalize({root1}) again")
+
+	// Committing new roots into an already finalized version must fail.
+	tree := mkvs.NewWithRoot(nil, ndb, root1)
+	defer tree.Close()
+	err = tree.Insert(ctx, []byte("moo"), []byte("goo"))
+	require.NoError(err, "Insert()")
+	_, _, err = tree.Commit(ctx, testNs, 1)
+	require.ErrorIs(err, api.ErrAlreadyFinalized, "Commit() into finalized version")
+
+	// Skipping versions must fail.
+	emptyRoot3 := emptyRoot
+	emptyRoot3.Version = 3
+	root3 := commitTree(ctx, require, ndb, emptyRoot3, 3, []byte("moo"), []byte("goo"))
+	err = ndb.Finalize(ctx, []node.Root{root3})
+	require.ErrorIs(err, api.ErrNotFinalized, "Finalize({root3}) with version 2 not finalized")
+
+	// Non-finalized roots should be discarded.
+	root2a := commitTree(ctx, require, ndb, root1, 2, []byte("moo"), []byte("goo"))
+	root2b := commitTree(ctx, require, ndb, root1, 2, []byte("moo"), []byte("boo"))
+	err = ndb.Finalize(ctx, []node.Root{root2a})
+	require.NoError(err, "Finalize({root2a})")
+	require.True(ndb.HasRoot(root2a), "HasRoot(root2a)")
+	require.False(ndb.HasRoot(root2b), "HasRoot(root2b) after finalization")
+
+	// Pruning should only be possible for the earliest version.
+	err = ndb.Prune(ctx, 2)
+	require.ErrorIs(err, api.ErrNotEarliest, "Prune(2)")
+	err = ndb.Prune(ctx, 1)
+	require.NoError(err, "Prune(1)")
+	require.False(ndb.HasRoot(root1), "HasRoot(root1) after pruning")
+	require.EqualValues(2, ndb.GetEarliestVersion(), "earliest version should be updated")
+
+	// Data should still be available in later versions.
+	tree = mkvs.NewWithRoot(nil, ndb, root2a)
+	defer tree.Close()
+	value, err := tree.Get(ctx, []byte("foo"))
+	require.NoError(err, "Get(foo)")
+	require.EqualValues([]byte("bar"), value)
+}
+
+func TestFinalizeWithResult(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+	ndb, err := New(&api.Config{Namespace: testNs})
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	emptyRoot := node.Root{Namespace: testNs, Version: 1, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+
+	// A tree with a single key consists of a single leaf node.
+	root1 := commitTree(ctx, require, ndb, emptyRoot, 1, []byte("foo"), []byte("bar"))
+	result, err := api.FinalizeWithResult(ctx, ndb, []node.Root{root1})
+	require.NoError(err, "FinalizeWithResult({root1})")
+	require.Equal(&api.FinalizeResult{Nodes: 1}, result, "finalize result of root1")
+
+	// Adding a key adds a new leaf and an internal node to each root, and the nodes of the
+	// non-finalized root should be removed.
+	root2a := commitTree(ctx, require, ndb, root1, 2, []byte("moo"), []byte("goo"))
+	root2b := commitTree(ctx, require, ndb, root1, 2, []byte("moo"), []byte("boo"))
+	result, err = api.FinalizeWithResult(ctx, ndb, []node.Root{root2a})
+	require.NoError(err, "FinalizeWithResult({root2a})")
+	require.Equal(&api.FinalizeResult{Nodes: 2, RemovedNodes: 2, DiscardedRoots: 1}, result, "finalize result of root2a")
+	require.False(ndb.HasRoot(root2b), "HasRoot(root2b) after finalization")
+
+	// Failed finalization should not report a result.
+	result, err = api.FinalizeWithResult(ctx, ndb, []node.Root{root2a})
+	require.ErrorIs(err, api.ErrAlreadyFinalized, "FinalizeWithResult({root2a}) again")
+	require.Nil(result, "failed finalization should not report a result")
+
+	// Node databases that don't report results should still be finalized.
+	nop, err := api.NewNopNodeDB()
+	require.NoError(err, "NewNopNodeDB()")
+	result, err = api.FinalizeWithResult(ctx, nop, []node.Root{root2a})
+	require.NoError(err, "FinalizeWithResult() on a node database without results")
+	require.Nil(result, "node databases without results should not report a result")
+}
+
+func TestMultipartAbort(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+	ndb, err := New(&api.Config{Namespace: testNs})
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	emptyRoot := node.Root{Namespace: testNs, Version: 1, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+	root := commitTree(ctx, require, ndb, emptyRoot, 1, []byte("foo"), []byte("bar"))
+
+	// Restore the same tree into a fresh database in chunk mode and then abort.
+	restored, err := New(&api.Config{Namespace: testNs})
+	require.NoError(err, "New()")
+	defer restored.Close()
+
+	err = restored.StartMultipartInsert(root.Version)
+	require.NoError(err, "StartMultipartInsert()")
+
+	batch, err := restored.NewBatch(emptyRoot, root.Version, true)
+	require.NoError(err, "NewBatch()")
+	err = api.Visit(ctx, ndb, root, func(ctx context.Context, n node.Node) bool {
+		subtree := batch.MaybeStartSubtree(nil, 0, nil)
+		require.NoError(subtree.PutNode(0, &node.Pointer{Clean: true, Hash: n.GetHash(), Node: n}))
+		return true
+	})
+	require.NoError(err, "Visit()")
+	err = batch.Commit(root)
+	require.NoError(err, "Commit()")
+	require.True(restored.HasRoot(root), "HasRoot() during restore")
+
+	err = restored.AbortMultipartInsert()
+	require.NoError(err, "AbortMultipartInsert()")
+	require.False(restored.HasRoot(root), "HasRoot() after abort")
+	size, err := restored.Size()
+	require.NoError(err, "Size()")
+	require.EqualValues(0, size, "all restored nodes should be removed")
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	db "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
 	badgerDb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
+	inmemDb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/inmem"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
 	mkvsTests "github.com/oasisprotocol/oasis-core/go/storage/mkvs/tests"
@@ -2291,6 +2292,34 @@ func TestBadgerBackend(t *testing.T) {
 	}, nil)
 }
 
+func TestInMemoryBackend(t *testing.T) {
+	testBackend(t, func(t *testing.T) (NodeDBFactory, func()) {
+		// Create an in-memory Node DB factory. As the database is not persisted, reopening it
+		// returns the same instance in order to simulate persistence.
+		var (
+			ndb   db.NodeDB
+			ndbNs common.Namespace
+		)
+		factory := func(ns common.Namespace) (db.NodeDB, error) {
+			if ndb != nil {
+				if !ns.Equal(&ndbNs) {
+					return nil, fmt.Errorf("incompatible namespace (expected: %s got: %s)", ndbNs, ns)
+				}
+				return ndb, nil
+			}
+
+			var err error
+			if ndb, err = inmemDb.New(&db.Config{Namespace: ns}); err != nil {
+				return nil, err
+			}
+			ndbNs = ns
+			return ndb, nil
+		}
+
+		return factory, func() {}
+	}, nil)
+}
+
 func BenchmarkInsertCommitBatch1(b *testing.B) {
 	benchmarkInsertBatch(b, 1, true)
 }
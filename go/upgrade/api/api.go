@@ -223,6 +223,21 @@ func (pu *PendingUpgrade) PushStage(stage UpgradeStage) {
 	pu.LastCompletedStage = stage
 }
 
+// UpgradeReadiness describes whether the node is able to apply a pending upgrade.
+type UpgradeReadiness struct {
+	// Upgrade is the pending upgrade.
+	Upgrade *PendingUpgrade `json:"upgrade"`
+
+	// HandlerRegistered is true iff the migration handler named by the upgrade descriptor is
+	// registered in the running binary.
+	HandlerRegistered bool `json:"handler_registered"`
+}
+
+// IsReady checks whether the node will be able to apply the pending upgrade.
+func (ur UpgradeReadiness) IsReady() bool {
+	return ur.Upgrade.IsCompleted() || ur.HandlerRegistered
+}
+
 // Backend defines the interface for upgrade managers.
 type Backend interface {
 	// SubmitDescriptor submits the serialized descriptor to the upgrade manager
@@ -243,6 +258,11 @@ type Backend interface {
 	// In case no such upgrade exists, this returns ErrUpgradeNotFound.
 	GetUpgrade(context.Context, *Descriptor) (*PendingUpgrade, error)
 
+	// GetUpgradeReadiness returns the pending upgrades together with the status of their
+	// migration handlers, so that a node unable to apply an upgrade can be detected before
+	// the upgrade epoch is reached.
+	GetUpgradeReadiness(context.Context) ([]*UpgradeReadiness, error)
+
 	// StartupUpgrade performs the startup portion of the upgrade.
 	// It is idempotent with respect to the current upgrade descriptor.
 	StartupUpgrade() error
@@ -31,6 +31,10 @@ func (u *dummyUpgradeManager) GetUpgrade(ctx context.Context, descriptor *api.De
 	return nil, api.ErrUpgradeNotFound
 }
 
+func (u *dummyUpgradeManager) GetUpgradeReadiness(ctx context.Context) ([]*api.UpgradeReadiness, error) {
+	return nil, nil
+}
+
 func (u *dummyUpgradeManager) StartupUpgrade() error {
 	return nil
 }
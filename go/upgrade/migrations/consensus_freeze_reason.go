@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"fmt"
+
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/api"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/apps/registry/state"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+const (
+	// ConsensusFreezeReasonHandler is the name of the upgrade that enables recording freeze
+	// reasons and sets the freeze reason of nodes that were frozen before they were recorded.
+	ConsensusFreezeReasonHandler = "consensus-freeze-reason"
+)
+
+var _ Handler = (*freezeReasonHandler)(nil)
+
+type freezeReasonHandler struct{}
+
+func (th *freezeReasonHandler) StartupUpgrade(ctx *Context) error {
+	return nil
+}
+
+func (th *freezeReasonHandler) ConsensusUpgrade(ctx *Context, privateCtx interface{}) error {
+	abciCtx := privateCtx.(*abciAPI.Context)
+	switch abciCtx.Mode() {
+	case abciAPI.ContextBeginBlock:
+		// Nothing to do during begin block.
+	case abciAPI.ContextEndBlock:
+		// Enable freeze reasons and set the freeze reason of already frozen nodes during EndBlock.
+		state := registryState.NewMutableState(abciCtx.State())
+
+		params, err := state.ConsensusParameters(abciCtx)
+		if err != nil {
+			return fmt.Errorf("unable to load registry consensus parameters: %w", err)
+		}
+
+		params.EnableFreezeReasons = true
+
+		if err = state.SetConsensusParameters(abciCtx, params); err != nil {
+			return fmt.Errorf("failed to update registry consensus parameters: %w", err)
+		}
+
+		nodes, err := state.Nodes(abciCtx)
+		if err != nil {
+			return fmt.Errorf("unable to load registered nodes: %w", err)
+		}
+
+		for _, n := range nodes {
+			status, err := state.NodeStatus(abciCtx, n.ID)
+			if err != nil {
+				return fmt.Errorf("unable to load status of node %s: %w", n.ID, err)
+			}
+			if !status.IsFrozen() || status.FreezeReason != registry.FreezeReasonNone {
+				continue
+			}
+
+			status.FreezeReason = registry.FreezeReasonLegacy
+			if err = state.SetNodeStatus(abciCtx, n.ID, status); err != nil {
+				return fmt.Errorf("failed to update status of node %s: %w", n.ID, err)
+			}
+		}
+	default:
+		return fmt.Errorf("upgrade handler called in unexpected context: %s", abciCtx.Mode())
+	}
+	return nil
+}
+
+func init() {
+	Register(ConsensusFreezeReasonHandler, &freezeReasonHandler{})
+}
@@ -0,0 +1,89 @@
+package migrations
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/api"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/apps/registry/state"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func TestFreezeReasonHandler(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1580461674, 0)
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock, now)
+	defer ctx.Close()
+
+	state := registryState.NewMutableState(ctx.State())
+
+	ent, entitySigner, _ := entity.TestEntity()
+	sigEntity, err := entity.SignEntity(entitySigner, registry.RegisterEntitySignatureContext, ent)
+	require.NoError(err, "SignEntity")
+	err = state.SetEntity(ctx, ent, sigEntity)
+	require.NoError(err, "SetEntity")
+
+	statuses := []*registry.NodeStatus{
+		// Frozen before freeze reasons were recorded.
+		{FreezeEndTime: 10},
+		{FreezeEndTime: registry.FreezeForever},
+		// Frozen with a recorded reason.
+		{FreezeEndTime: 10, FreezeReason: registry.FreezeReasonRuntimeLiveness},
+		// Not frozen.
+		{},
+	}
+	var nodeIDs []signature.PublicKey
+	for i, status := range statuses {
+		nodeSigner := memorySigner.NewTestSigner(fmt.Sprintf("freeze reason test node signer %d", i))
+		consensusSigner := memorySigner.NewTestSigner(fmt.Sprintf("freeze reason test consensus signer %d", i))
+		nod := &node.Node{
+			Versioned: cbor.NewVersioned(node.LatestNodeDescriptorVersion),
+			ID:        nodeSigner.Public(),
+			EntityID:  ent.ID,
+			Consensus: node.ConsensusInfo{ID: consensusSigner.Public()},
+		}
+		sigNode, err := node.MultiSignNode([]signature.Signer{nodeSigner}, registry.RegisterNodeSignatureContext, nod)
+		require.NoError(err, "MultiSignNode")
+		err = state.SetNode(ctx, nil, nod, sigNode)
+		require.NoError(err, "SetNode")
+		err = state.SetNodeStatus(ctx, nod.ID, status)
+		require.NoError(err, "SetNodeStatus")
+		nodeIDs = append(nodeIDs, nod.ID)
+	}
+
+	err = state.SetConsensusParameters(ctx, &registry.ConsensusParameters{
+		MaxNodeExpiration: 5,
+	})
+	require.NoError(err, "SetConsensusParameters")
+
+	handler := &freezeReasonHandler{}
+	err = handler.ConsensusUpgrade(&Context{}, ctx)
+	require.NoError(err, "ConsensusUpgrade")
+
+	// Recording freeze reasons should be enabled, leaving other consensus parameters unchanged.
+	params, err := state.ConsensusParameters(ctx)
+	require.NoError(err, "ConsensusParameters")
+	require.True(params.EnableFreezeReasons, "freeze reasons should be enabled")
+	require.EqualValues(5, params.MaxNodeExpiration, "MaxNodeExpiration should be unchanged")
+
+	for i, expected := range []*registry.NodeStatus{
+		{FreezeEndTime: 10, FreezeReason: registry.FreezeReasonLegacy},
+		{FreezeEndTime: registry.FreezeForever, FreezeReason: registry.FreezeReasonLegacy},
+		{FreezeEndTime: 10, FreezeReason: registry.FreezeReasonRuntimeLiveness},
+		{},
+	} {
+		status, err := state.NodeStatus(ctx, nodeIDs[i])
+		require.NoError(err, "NodeStatus")
+		require.EqualValues(expected, status, "node status %d should be migrated correctly", i)
+	}
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	abciAPI "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/api"
+	registryState "github.com/oasisprotocol/oasis-core/go/consensus/tendermint/apps/registry/state"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func TestTEEPCSHandler(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1580461674, 0)
+	appState := abciAPI.NewMockApplicationState(&abciAPI.MockApplicationStateConfig{})
+	ctx := appState.NewContext(abciAPI.ContextEndBlock, now)
+	defer ctx.Close()
+
+	state := registryState.NewMutableState(ctx.State())
+	err := state.SetConsensusParameters(ctx, &registry.ConsensusParameters{
+		MaxNodeExpiration: 5,
+	})
+	require.NoError(err, "SetConsensusParameters")
+
+	handler := &teePcsHandler{}
+	err = handler.ConsensusUpgrade(&Context{}, ctx)
+	require.NoError(err, "ConsensusUpgrade")
+
+	// The upgrade should enable PCS and leave other consensus parameters unchanged.
+	params, err := state.ConsensusParameters(ctx)
+	require.NoError(err, "ConsensusParameters")
+	require.EqualValues(&node.TEEFeatures{SGX: node.TEEFeaturesSGX{PCS: true}}, params.TEEFeatures, "PCS should be enabled")
+	require.EqualValues(5, params.MaxNodeExpiration, "MaxNodeExpiration should be unchanged")
+}
@@ -3,6 +3,7 @@ package migrations
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
@@ -77,3 +78,26 @@ func GetHandler(name upgradeApi.HandlerName) (Handler, error) {
 
 	return h.(Handler), nil
 }
+
+// HandlerInfo describes a registered migration handler.
+type HandlerInfo struct {
+	// Name is the name of the upgrade the handler is registered for.
+	Name upgradeApi.HandlerName `json:"name"`
+}
+
+// RegisteredHandlers returns descriptions of all registered migration handlers, ordered by name.
+//
+// This can be used to confirm that a binary contains the handler for a scheduled upgrade.
+func RegisteredHandlers() []HandlerInfo {
+	var handlers []HandlerInfo
+	registeredHandlers.Range(func(key, _ interface{}) bool {
+		handlers = append(handlers, HandlerInfo{
+			Name: key.(upgradeApi.HandlerName),
+		})
+		return true
+	})
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].Name < handlers[j].Name
+	})
+	return handlers
+}
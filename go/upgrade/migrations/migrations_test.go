@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	upgradeApi "github.com/oasisprotocol/oasis-core/go/upgrade/api"
+)
+
+func TestRegisteredHandlers(t *testing.T) {
+	require := require.New(t)
+
+	handlers := RegisteredHandlers()
+	names := make([]upgradeApi.HandlerName, 0, len(handlers))
+	for _, h := range handlers {
+		names = append(names, h.Name)
+	}
+	require.True(sort.SliceIsSorted(names, func(i, j int) bool {
+		return names[i] < names[j]
+	}), "handlers should be ordered by name")
+
+	for _, name := range []upgradeApi.HandlerName{
+		EmptyHandler,
+		DummyUpgradeHandler,
+		ConsensusMaxAllowances16Handler,
+		ConsensusFreezeReasonHandler,
+		ConsensusTEEPCSHandler,
+	} {
+		require.Contains(names, name, "known handler should be registered")
+
+		_, err := GetHandler(name)
+		require.NoError(err, "GetHandler(%s)", name)
+	}
+}
@@ -141,6 +141,22 @@ func (u *upgradeManager) GetUpgrade(ctx context.Context, descriptor *api.Descrip
 	return nil, api.ErrUpgradeNotFound
 }
 
+// Implements api.Backend.
+func (u *upgradeManager) GetUpgradeReadiness(ctx context.Context) ([]*api.UpgradeReadiness, error) {
+	u.Lock()
+	defer u.Unlock()
+
+	readiness := make([]*api.UpgradeReadiness, 0, len(u.pending))
+	for _, pu := range u.pending {
+		_, err := migrations.GetHandler(pu.Descriptor.Handler)
+		readiness = append(readiness, &api.UpgradeReadiness{
+			Upgrade:           pu,
+			HandlerRegistered: err == nil,
+		})
+	}
+	return readiness, nil
+}
+
 func (u *upgradeManager) checkStatus() error {
 	u.Lock()
 	defer u.Unlock()
@@ -0,0 +1,59 @@
+package upgrade
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/persistent"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	"github.com/oasisprotocol/oasis-core/go/upgrade/api"
+	"github.com/oasisprotocol/oasis-core/go/upgrade/migrations"
+)
+
+func TestUpgradeReadiness(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	dataDir := t.TempDir()
+	store, err := persistent.NewCommonStore(dataDir)
+	require.NoError(err, "NewCommonStore")
+	defer store.Close()
+
+	upgrader, err := New(store, dataDir, true)
+	require.NoError(err, "New")
+	defer upgrader.Close()
+
+	readiness, err := upgrader.GetUpgradeReadiness(ctx)
+	require.NoError(err, "GetUpgradeReadiness")
+	require.Empty(readiness, "there should be no pending upgrades")
+
+	registered := &api.Descriptor{
+		Versioned: cbor.NewVersioned(api.LatestDescriptorVersion),
+		Handler:   migrations.EmptyHandler,
+		Target:    version.Versions,
+		Epoch:     42,
+	}
+	missing := &api.Descriptor{
+		Versioned: cbor.NewVersioned(api.LatestDescriptorVersion),
+		Handler:   "__missing-handler",
+		Target:    version.Versions,
+		Epoch:     43,
+	}
+	for _, desc := range []*api.Descriptor{registered, missing} {
+		err = upgrader.SubmitDescriptor(ctx, desc)
+		require.NoError(err, "SubmitDescriptor")
+	}
+
+	readiness, err = upgrader.GetUpgradeReadiness(ctx)
+	require.NoError(err, "GetUpgradeReadiness")
+	require.Len(readiness, 2, "all pending upgrades should be listed")
+	require.True(readiness[0].Upgrade.Descriptor.Equals(registered))
+	require.True(readiness[0].HandlerRegistered, "registered handler should be detected")
+	require.True(readiness[0].IsReady(), "upgrade with a registered handler should be ready")
+	require.True(readiness[1].Upgrade.Descriptor.Equals(missing))
+	require.False(readiness[1].HandlerRegistered, "missing handler should be detected")
+	require.False(readiness[1].IsReady(), "upgrade with a missing handler should not be ready")
+}
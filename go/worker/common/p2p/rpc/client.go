@@ -2,7 +2,9 @@ package rpc
 
 import (
 	"context"
+	goErrors "errors"
 	"fmt"
+	"net"
 	"reflect"
 	"time"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/libp2p/go-libp2p/core"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
@@ -41,6 +44,9 @@ type PeerFeedback interface {
 	//
 	// The peer will be ignored during peer selection.
 	RecordBadPeer()
+
+	// PeerID returns the identifier of the peer that the feedback is for.
+	PeerID() core.PeerID
 }
 
 type peerFeedback struct {
@@ -61,6 +67,10 @@ func (pf *peerFeedback) RecordBadPeer() {
 	pf.mgr.RecordBadPeer(pf.peerID)
 }
 
+func (pf *peerFeedback) PeerID() core.PeerID {
+	return pf.peerID
+}
+
 type nopPeerFeedback struct{}
 
 func (pf *nopPeerFeedback) RecordSuccess() {
@@ -72,6 +82,10 @@ func (pf *nopPeerFeedback) RecordFailure() {
 func (pf *nopPeerFeedback) RecordBadPeer() {
 }
 
+func (pf *nopPeerFeedback) PeerID() core.PeerID {
+	return ""
+}
+
 // NewNopPeerFeedback creates a no-op peer feedback instance.
 func NewNopPeerFeedback() PeerFeedback {
 	return &nopPeerFeedback{}
@@ -81,6 +95,11 @@ func NewNopPeerFeedback() PeerFeedback {
 type ClientOptions struct {
 	stickyPeers bool
 	peerFilter  PeerFilter
+
+	streamPoolSize        int
+	streamPoolIdleTimeout time.Duration
+
+	readLimiter ReadLimiter
 }
 
 // ClientOption is a client option setter.
@@ -111,6 +130,19 @@ func WithPeerFilter(filter PeerFilter) ClientOption {
 	}
 }
 
+// WithStreamPool configures stream pooling.
+//
+// When enabled (size is non-zero), streams are kept open after a call completes and are reused for
+// subsequent calls to the same peer. At most size idle streams are kept per peer and idle streams
+// are closed once they have not been used for idleTimeout (zero means no timeout). Note that
+// idleTimeout should be lower than the server's StreamIdleTimeout.
+func WithStreamPool(size int, idleTimeout time.Duration) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.streamPoolSize = size
+		opts.streamPoolIdleTimeout = idleTimeout
+	}
+}
+
 // ValidationFunc is a call response validation function.
 type ValidationFunc func(pf PeerFeedback) error
 
@@ -120,6 +152,7 @@ type CallOptions struct {
 	maxRetries    uint64
 	validationFn  ValidationFunc
 	limitPeers    map[core.PeerID]struct{}
+	peerSelectFn  PeerSelectFunc
 }
 
 // CallOption is a per-call option setter.
@@ -162,6 +195,22 @@ func WithLimitPeers(peers []PeerFeedback) CallOption {
 	}
 }
 
+// PeerSelectFunc is a peer selection function.
+//
+// It is passed the candidate peers for the call, ordered by past experience with the peers (best
+// first), and returns the peer that should be tried first.
+type PeerSelectFunc func(candidates []core.PeerID) core.PeerID
+
+// WithPeerSelectFn configures the peer selection function to use for the call.
+//
+// The selected peer is tried first, followed by the remaining candidate peers in case the call to
+// the selected peer fails. Selecting a peer that is not a candidate has no effect.
+func WithPeerSelectFn(fn PeerSelectFunc) CallOption {
+	return func(opts *CallOptions) {
+		opts.peerSelectFn = fn
+	}
+}
+
 // AggregateFunc returns a result aggregation function.
 //
 // The function is passed the response and PeerFeedback instance. If the function returns true, the
@@ -222,7 +271,11 @@ type client struct {
 	protocolID protocol.ID
 	runtimeID  common.Namespace
 
-	opts *ClientOptions
+	opts       *ClientOptions
+	streamPool *streamPool
+	// connStreams observes the number of streams multiplexed over the connection of each newly
+	// opened stream.
+	connStreams prometheus.Observer
 
 	logger *logging.Logger
 }
@@ -275,8 +328,13 @@ func (c *client) Call(
 
 	var pf PeerFeedback
 	tryPeers := func() error {
+		peers := c.getFilteredBestPeers(co.limitPeers)
+		if co.peerSelectFn != nil && len(peers) > 0 {
+			peers = prioritizePeer(peers, co.peerSelectFn(peers))
+		}
+
 		// Iterate through the prioritized list of peers and attempt to execute the request.
-		for _, peer := range c.getFilteredBestPeers(co.limitPeers) {
+		for _, peer := range peers {
 			c.logger.Debug("trying peer",
 				"method", method,
 				"peer_id", peer,
@@ -320,6 +378,20 @@ func (c *client) Call(
 	return pf, err
 }
 
+// prioritizePeer moves the given peer to the front of the list of peers, in case it is present.
+func prioritizePeer(peers []core.PeerID, peer core.PeerID) []core.PeerID {
+	for i, p := range peers {
+		if p != peer {
+			continue
+		}
+		prioritized := make([]core.PeerID, 0, len(peers))
+		prioritized = append(prioritized, peer)
+		prioritized = append(prioritized, peers[:i]...)
+		return append(prioritized, peers[i+1:]...)
+	}
+	return peers
+}
+
 func (c *client) CallMulti(
 	ctx context.Context,
 	method string,
@@ -460,6 +532,69 @@ func (c *client) sendRequestAndDecodeResponse(
 	rsp interface{},
 	maxPeerResponseTime time.Duration,
 ) error {
+	rawRsp, err := c.exchange(ctx, peerID, request, maxPeerResponseTime)
+	if err != nil {
+		return err
+	}
+
+	// Decode response.
+	if rawRsp.Error != nil {
+		return errors.FromCode(rawRsp.Error.Module, rawRsp.Error.Code, rawRsp.Error.Message)
+	}
+
+	if rsp != nil {
+		return cbor.Unmarshal(rawRsp.Ok, rsp)
+	}
+	return nil
+}
+
+// exchange sends the request to the given peer and reads the raw response, reusing a pooled
+// stream when the stream pool is enabled.
+func (c *client) exchange(
+	ctx context.Context,
+	peerID core.PeerID,
+	request *Request,
+	maxPeerResponseTime time.Duration,
+) (*Response, error) {
+	if c.streamPool == nil {
+		ps, err := c.openStream(ctx, peerID)
+		if err != nil {
+			return nil, err
+		}
+		defer ps.stream.Close()
+
+		return c.roundTrip(ctx, ps, peerID, request, maxPeerResponseTime)
+	}
+
+	if ps := c.streamPool.get(peerID); ps != nil {
+		rawRsp, err := c.roundTrip(ctx, ps, peerID, request, maxPeerResponseTime)
+		if err == nil {
+			c.streamPool.put(peerID, ps)
+			return rawRsp, nil
+		}
+		c.streamPool.discard(peerID, ps, !isTimeout(err))
+		if isTimeout(err) {
+			return nil, err
+		}
+		// The peer may have closed the idle stream, retry using a new stream.
+	}
+
+	ps, err := c.openStream(ctx, peerID)
+	if err != nil {
+		return nil, err
+	}
+	c.streamPool.opened()
+
+	rawRsp, err := c.roundTrip(ctx, ps, peerID, request, maxPeerResponseTime)
+	if err != nil {
+		c.streamPool.discard(peerID, ps, false)
+		return nil, err
+	}
+	c.streamPool.put(peerID, ps)
+	return rawRsp, nil
+}
+
+func (c *client) openStream(ctx context.Context, peerID core.PeerID) (*pooledStream, error) {
 	// Attempt to open stream to the given peer.
 	stream, err := c.host.NewStream(
 		network.WithNoDial(ctx, "should already have connection"),
@@ -467,45 +602,73 @@ func (c *client) sendRequestAndDecodeResponse(
 		c.protocolID,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to open stream: %w", err)
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	// Streams to the same peer are multiplexed over its existing connection, which has its stream
+	// muxer negotiated when the connection is established.
+	if c.connStreams != nil {
+		c.connStreams.Observe(float64(len(stream.Conn().GetStreams())))
 	}
-	defer stream.Close()
 
-	codec := cbor.NewMessageCodec(stream, codecModuleName)
+	ps := &pooledStream{
+		stream: stream,
+	}
+	if c.opts.readLimiter == nil {
+		ps.codec = cbor.NewMessageCodec(stream, codecModuleName)
+		return ps, nil
+	}
+
+	ps.throttled = &throttledStream{
+		Stream:  stream,
+		limiter: c.opts.readLimiter,
+	}
+	ps.codec = cbor.NewMessageCodec(ps.throttled, codecModuleName)
+	return ps, nil
+}
 
+func (c *client) roundTrip(
+	ctx context.Context,
+	ps *pooledStream,
+	peerID core.PeerID,
+	request *Request,
+	maxPeerResponseTime time.Duration,
+) (*Response, error) {
 	// Send request.
-	_ = stream.SetWriteDeadline(time.Now().Add(RequestWriteDeadline))
-	if err = codec.Write(request); err != nil {
+	_ = ps.stream.SetWriteDeadline(time.Now().Add(RequestWriteDeadline))
+	if err := ps.codec.Write(request); err != nil {
 		c.logger.Debug("failed to send request",
 			"err", err,
 			"peer_id", peerID,
 		)
-		return fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	_ = stream.SetWriteDeadline(time.Time{})
+	_ = ps.stream.SetWriteDeadline(time.Time{})
 
 	// Read response.
 	// TODO: Add required minimum speed.
 	var rawRsp Response
-	_ = stream.SetReadDeadline(time.Now().Add(maxPeerResponseTime))
-	if err = codec.Read(&rawRsp); err != nil {
+	deadline := time.Now().Add(maxPeerResponseTime)
+	if ps.throttled != nil {
+		ps.throttled.startRead(ctx, deadline)
+	} else {
+		_ = ps.stream.SetReadDeadline(deadline)
+	}
+	if err := ps.codec.Read(&rawRsp); err != nil {
 		c.logger.Debug("failed to read response",
 			"err", err,
 			"peer_id", peerID,
 		)
-		return fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
-	_ = stream.SetWriteDeadline(time.Time{})
+	_ = ps.stream.SetReadDeadline(time.Time{})
 
-	// Decode response.
-	if rawRsp.Error != nil {
-		return errors.FromCode(rawRsp.Error.Module, rawRsp.Error.Code, rawRsp.Error.Message)
-	}
+	return &rawRsp, nil
+}
 
-	if rsp != nil {
-		return cbor.Unmarshal(rawRsp.Ok, rsp)
-	}
-	return nil
+// isTimeout returns true iff the given error is caused by a deadline being exceeded.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return goErrors.As(err, &netErr) && netErr.Timeout()
 }
 
 // NewClient creates a new RPC client for the given protocol.
@@ -521,15 +684,21 @@ func NewClient(p2p P2P, runtimeID common.Namespace, protocolID string, version v
 		// No P2P service, use the no-op client.
 		return &nopClient{&nopPeerManager{}}
 	}
-	return &client{
+	initMetrics()
+	c := &client{
 		PeerManager: NewPeerManager(p2p, pid, co.stickyPeers),
 		host:        p2p.GetHost(),
 		protocolID:  pid,
 		runtimeID:   runtimeID,
 		opts:        &co,
+		connStreams: connectionStreams.WithLabelValues(protocolID),
 		logger: logging.GetLogger("worker/common/p2p/rpc/client").With(
 			"protocol", protocolID,
 			"runtime_id", runtimeID,
 		),
 	}
+	if co.streamPoolSize > 0 {
+		c.streamPool = newStreamPool(protocolID, co.streamPoolSize, co.streamPoolIdleTimeout)
+	}
+	return c
 }
@@ -0,0 +1,190 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+)
+
+// testBlockingService is a service which only responds once released.
+type testBlockingService struct {
+	inFlight  int64
+	releaseCh chan struct{}
+}
+
+func (s *testBlockingService) HandleRequest(ctx context.Context, method string, body cbor.RawMessage) (interface{}, error) {
+	atomic.AddInt64(&s.inFlight, 1)
+	<-s.releaseCh
+	return method, nil
+}
+
+func TestConcurrentCallsShareConnection(t *testing.T) {
+	const numCalls = 5
+
+	for _, tc := range []struct {
+		name          string
+		pool          *streamPool
+		singleRequest bool
+	}{
+		{"NoPool", nil, false},
+		{"Pool", newStreamPool("test", numCalls, time.Minute), false},
+		{"SingleRequestServer", newStreamPool("test", numCalls, time.Minute), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			mn, err := mocknet.FullMeshConnected(2)
+			require.NoError(err, "FullMeshConnected")
+			t.Cleanup(func() { _ = mn.Close() })
+			hosts := mn.Hosts()
+
+			service := &testBlockingService{releaseCh: make(chan struct{})}
+			runtimeID := common.NewTestNamespaceFromSeed([]byte("p2p rpc client test ns"), 0)
+			srv := NewServer(runtimeID, "test", version.Version{Major: 1}, service).(*server)
+			hosts[1].SetStreamHandler(srv.Protocol(), func(stream network.Stream) {
+				if !tc.singleRequest {
+					srv.HandleStream(stream)
+					return
+				}
+
+				// Simulate a server that only handles a single request per stream.
+				defer stream.Close()
+				codec := cbor.NewMessageCodec(stream, codecModuleName)
+				srv.handleRequest(srv.logger, stream, codec, RequestReadDeadline)
+			})
+
+			c := &client{
+				host:        hosts[0],
+				protocolID:  srv.Protocol(),
+				runtimeID:   runtimeID,
+				opts:        &ClientOptions{},
+				streamPool:  tc.pool,
+				connStreams: connectionStreams.WithLabelValues("test"),
+				logger:      logging.GetLogger("worker/common/p2p/rpc/client/test"),
+			}
+			remote := hosts[1].ID()
+
+			// Issue concurrent calls which are all in flight at the same time.
+			var wg sync.WaitGroup
+			errCh := make(chan error, numCalls)
+			for i := 0; i < numCalls; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errCh <- c.sendRequestAndDecodeResponse(ctx, remote, &Request{Method: "Block"}, nil, 10*time.Second)
+				}()
+			}
+			require.Eventually(func() bool {
+				return atomic.LoadInt64(&service.inFlight) == numCalls
+			}, 10*time.Second, 10*time.Millisecond, "all calls should be in flight")
+
+			// All calls should be multiplexed over a single connection to the peer.
+			conns := hosts[0].Network().ConnsToPeer(remote)
+			require.Len(conns, 1, "calls should not open additional connections")
+			require.Len(conns[0].GetStreams(), numCalls, "each call should use its own stream")
+
+			close(service.releaseCh)
+			wg.Wait()
+			close(errCh)
+			for err := range errCh {
+				require.NoError(err, "sendRequestAndDecodeResponse")
+			}
+		})
+	}
+}
+
+// testNamedService is a service which responds with its name.
+type testNamedService struct {
+	name string
+}
+
+func (s *testNamedService) HandleRequest(ctx context.Context, method string, body cbor.RawMessage) (interface{}, error) {
+	return s.name, nil
+}
+
+// testStaticPeerManager is a peer manager which always returns the same peers.
+type testStaticPeerManager struct {
+	nopPeerManager
+
+	peers []core.PeerID
+}
+
+func (mgr *testStaticPeerManager) GetBestPeers() []core.PeerID {
+	return append([]core.PeerID{}, mgr.peers...)
+}
+
+func TestCallPeerSelection(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	mn, err := mocknet.FullMeshConnected(3)
+	require.NoError(err, "FullMeshConnected")
+	t.Cleanup(func() { _ = mn.Close() })
+	hosts := mn.Hosts()
+
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("p2p rpc client test ns"), 0)
+	var protocolID protocol.ID
+	for i, name := range []string{"first", "second"} {
+		srv := NewServer(runtimeID, "test", version.Version{Major: 1}, &testNamedService{name: name})
+		protocolID = srv.Protocol()
+		hosts[i+1].SetStreamHandler(protocolID, srv.HandleStream)
+	}
+	first, second := hosts[1].ID(), hosts[2].ID()
+
+	c := &client{
+		PeerManager: &testStaticPeerManager{peers: []core.PeerID{first, second}},
+		host:        hosts[0],
+		protocolID:  protocolID,
+		runtimeID:   runtimeID,
+		opts:        &ClientOptions{},
+		logger:      logging.GetLogger("worker/common/p2p/rpc/client/test"),
+	}
+	call := func(opts ...CallOption) (string, core.PeerID) {
+		var rsp string
+		pf, err := c.Call(ctx, "Name", nil, &rsp, 10*time.Second, opts...)
+		require.NoError(err, "Call")
+		return rsp, pf.PeerID()
+	}
+
+	// By default, peers are tried in order.
+	name, peer := call()
+	require.Equal("first", name, "best peer should be called by default")
+	require.Equal(first, peer)
+
+	// The selected peer should be tried first.
+	var candidates []core.PeerID
+	name, peer = call(WithPeerSelectFn(func(peers []core.PeerID) core.PeerID {
+		candidates = peers
+		return second
+	}))
+	require.Equal("second", name, "selected peer should be called")
+	require.Equal(second, peer)
+	require.Equal([]core.PeerID{first, second}, candidates, "all peers should be candidates")
+
+	// Selecting an unknown peer should have no effect.
+	name, _ = call(WithPeerSelectFn(func(peers []core.PeerID) core.PeerID {
+		return hosts[0].ID()
+	}))
+	require.Equal("first", name, "best peer should be called when an unknown peer is selected")
+
+	// Remaining peers should be tried in case the selected peer fails.
+	hosts[2].RemoveStreamHandler(protocolID)
+	name, _ = call(WithPeerSelectFn(func(peers []core.PeerID) core.PeerID {
+		return second
+	}))
+	require.Equal("first", name, "remaining peers should be tried")
+}
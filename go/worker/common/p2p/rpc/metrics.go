@@ -0,0 +1,39 @@
+package rpc
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	pooledStreams = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_p2p_rpc_pooled_streams",
+			Help: "Number of RPC client streams managed by stream pools.",
+		},
+		[]string{"protocol", "state"},
+	)
+
+	connectionStreams = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "oasis_p2p_rpc_streams_per_connection",
+			Help:    "Number of streams multiplexed over the connection of a newly opened RPC client stream.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"protocol"},
+	)
+
+	rpcCollectors = []prometheus.Collector{
+		pooledStreams,
+		connectionStreams,
+	}
+
+	metricsOnce sync.Once
+)
+
+func initMetrics() {
+	metricsOnce.Do(func() {
+		prometheus.MustRegister(rpcCollectors...)
+	})
+}
@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+// ReadLimiter limits the rate at which responses are read from peers.
+type ReadLimiter interface {
+	// WaitN blocks until n bytes that have been read may be consumed.
+	WaitN(ctx context.Context, n int) error
+}
+
+// WithReadLimiter configures a limiter for the rate at which responses are read.
+//
+// Reading a response is throttled by the limiter without causing the call to time out as the
+// response read deadline is extended by the time spent waiting for the limiter.
+func WithReadLimiter(limiter ReadLimiter) ClientOption {
+	return func(opts *ClientOptions) {
+		opts.readLimiter = limiter
+	}
+}
+
+// throttledStream is a stream whose reads are throttled by a read limiter.
+type throttledStream struct {
+	network.Stream
+
+	limiter ReadLimiter
+
+	ctx      context.Context
+	deadline time.Time
+}
+
+// startRead prepares the stream for reading a response with the given deadline.
+func (s *throttledStream) startRead(ctx context.Context, deadline time.Time) {
+	s.ctx = ctx
+	s.deadline = deadline
+	_ = s.Stream.SetReadDeadline(deadline)
+}
+
+// Read implements io.Reader.
+func (s *throttledStream) Read(p []byte) (int, error) {
+	n, err := s.Stream.Read(p)
+	if n == 0 {
+		return n, err
+	}
+
+	start := time.Now()
+	if werr := s.limiter.WaitN(s.ctx, n); werr != nil && err == nil {
+		err = werr
+	}
+	if !s.deadline.IsZero() {
+		// Make sure that throttling does not cause the response to time out.
+		s.deadline = s.deadline.Add(time.Since(start))
+		_ = s.Stream.SetReadDeadline(s.deadline)
+	}
+	return n, err
+}
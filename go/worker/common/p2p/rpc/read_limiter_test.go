@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+// slowReadLimiter is a read limiter which delays each read.
+type slowReadLimiter struct {
+	delay time.Duration
+	bytes int
+}
+
+func (l *slowReadLimiter) WaitN(ctx context.Context, n int) error {
+	l.bytes += n
+	time.Sleep(l.delay)
+	return nil
+}
+
+// deadlineStream is a stream which reads from a buffer and records the read deadline.
+type deadlineStream struct {
+	network.Stream
+
+	buf      *bytes.Buffer
+	deadline time.Time
+}
+
+func (s *deadlineStream) Read(p []byte) (int, error) {
+	return s.buf.Read(p)
+}
+
+func (s *deadlineStream) SetReadDeadline(t time.Time) error {
+	s.deadline = t
+	return nil
+}
+
+func TestReadLimiter(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	for _, pool := range []*streamPool{nil, newStreamPool("test", 1, time.Minute)} {
+		limiter := &slowReadLimiter{}
+		c, _, _ := setupStreamPoolTest(t, pool, false)
+		c.opts.readLimiter = limiter
+		remote := c.host.Network().Peers()[0]
+
+		for i := 0; i < 2; i++ {
+			var rsp string
+			err := c.sendRequestAndDecodeResponse(ctx, remote, &Request{
+				Method: "Echo",
+				Body:   cbor.Marshal("hello"),
+			}, &rsp, time.Second)
+			require.NoError(err, "sendRequestAndDecodeResponse")
+			require.Equal("Echo hello", rsp, "response should be correct")
+		}
+		require.NotZero(limiter.bytes, "responses should be read through the limiter")
+	}
+}
+
+func TestThrottledStreamDeadline(t *testing.T) {
+	require := require.New(t)
+
+	stream := &deadlineStream{buf: bytes.NewBufferString("hello")}
+	limiter := &slowReadLimiter{delay: 50 * time.Millisecond}
+	throttled := &throttledStream{Stream: stream, limiter: limiter}
+
+	deadline := time.Now().Add(10 * time.Millisecond)
+	throttled.startRead(context.Background(), deadline)
+	require.Equal(deadline, stream.deadline, "read deadline should be set")
+
+	// Time spent waiting for the limiter should not count towards the read deadline.
+	n, err := throttled.Read(make([]byte, 16))
+	require.NoError(err, "Read")
+	require.Equal(5, n, "all bytes should be read")
+	require.Equal(5, limiter.bytes, "read bytes should be passed to the limiter")
+	require.GreaterOrEqual(stream.deadline.Sub(deadline), limiter.delay, "read deadline should be extended")
+}
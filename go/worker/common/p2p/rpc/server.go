@@ -2,6 +2,7 @@ package rpc
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/libp2p/go-libp2p/core/network"
@@ -18,6 +19,10 @@ const (
 	RequestReadDeadline   = 5 * time.Second
 	RequestHandleTimeout  = 60 * time.Second
 	ResponseWriteDeadline = 60 * time.Second
+
+	// StreamIdleTimeout is the maximum amount of time that a stream which has already been used
+	// for a request is kept open while waiting for the next request.
+	StreamIdleTimeout = 60 * time.Second
 )
 
 // Service is an RPC service implementation.
@@ -54,14 +59,35 @@ func (s *server) HandleStream(stream network.Stream) {
 	logger := s.logger.With("peer_id", stream.Conn().RemotePeer())
 	codec := cbor.NewMessageCodec(stream, codecModuleName)
 
+	// Clients may reuse the stream for subsequent requests, so keep serving requests until the
+	// client closes the stream or it has been idle for too long.
+	readDeadline := RequestReadDeadline
+	for {
+		if !s.handleRequest(logger, stream, codec, readDeadline) {
+			return
+		}
+		readDeadline = StreamIdleTimeout
+	}
+}
+
+// handleRequest reads and handles a single request from the given stream. It returns true iff the
+// stream can be used for further requests.
+func (s *server) handleRequest(
+	logger *logging.Logger,
+	stream network.Stream,
+	codec *cbor.MessageCodec,
+	readDeadline time.Duration,
+) bool {
 	// Read request.
 	var request Request
-	_ = stream.SetReadDeadline(time.Now().Add(RequestReadDeadline))
+	_ = stream.SetReadDeadline(time.Now().Add(readDeadline))
 	if err := codec.Read(&request); err != nil {
-		logger.Debug("failed to read request",
-			"err", err,
-		)
-		return
+		if err != io.EOF {
+			logger.Debug("failed to read request",
+				"err", err,
+			)
+		}
+		return false
 	}
 	_ = stream.SetReadDeadline(time.Time{})
 
@@ -100,9 +126,10 @@ func (s *server) HandleStream(stream network.Stream) {
 		logger.Debug("failed to write response",
 			"err", err,
 		)
-		return
+		return false
 	}
 	_ = stream.SetWriteDeadline(time.Time{})
+	return true
 }
 
 // NewServer creates a new RPC server for the given protocol.
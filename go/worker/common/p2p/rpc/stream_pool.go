@@ -0,0 +1,136 @@
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+const (
+	streamStateActive = "active"
+	streamStateIdle   = "idle"
+)
+
+// pooledStream is a stream together with its message codec.
+type pooledStream struct {
+	stream network.Stream
+	codec  *cbor.MessageCodec
+	// throttled is the throttled wrapper of the stream in case reads are rate limited.
+	throttled *throttledStream
+
+	// reused is true iff the stream has already been used for a previous request.
+	reused    bool
+	idleSince time.Time
+}
+
+// streamPool is a bounded per-peer pool of idle streams that can be reused for subsequent
+// requests to the same peer, avoiding the need to open and negotiate a new stream for each call.
+type streamPool struct {
+	sync.Mutex
+
+	maxIdle     int
+	idleTimeout time.Duration
+
+	idle map[core.PeerID][]*pooledStream
+	// noReuse is the set of peers that do not support serving multiple requests over the same
+	// stream (e.g., peers running older versions).
+	noReuse map[core.PeerID]struct{}
+
+	activeGauge prometheus.Gauge
+	idleGauge   prometheus.Gauge
+}
+
+func newStreamPool(protocol string, maxIdle int, idleTimeout time.Duration) *streamPool {
+	initMetrics()
+
+	return &streamPool{
+		maxIdle:     maxIdle,
+		idleTimeout: idleTimeout,
+		idle:        make(map[core.PeerID][]*pooledStream),
+		noReuse:     make(map[core.PeerID]struct{}),
+		activeGauge: pooledStreams.WithLabelValues(protocol, streamStateActive),
+		idleGauge:   pooledStreams.WithLabelValues(protocol, streamStateIdle),
+	}
+}
+
+// get returns an idle stream to the given peer, if any. Expired idle streams are closed.
+func (p *streamPool) get(peerID core.PeerID) *pooledStream {
+	p.Lock()
+	defer p.Unlock()
+
+	streams := p.idle[peerID]
+	for len(streams) > 0 {
+		// Prefer the most recently used stream as it is the least likely to have expired.
+		ps := streams[len(streams)-1]
+		streams = streams[:len(streams)-1]
+		p.idleGauge.Dec()
+
+		if p.idleTimeout > 0 && time.Since(ps.idleSince) > p.idleTimeout {
+			_ = ps.stream.Reset()
+			continue
+		}
+
+		p.setIdle(peerID, streams)
+		p.activeGauge.Inc()
+		return ps
+	}
+	p.setIdle(peerID, nil)
+	return nil
+}
+
+// opened records that a new stream has been opened.
+func (p *streamPool) opened() {
+	p.activeGauge.Inc()
+}
+
+// put returns a stream, which has been used successfully, to the pool.
+func (p *streamPool) put(peerID core.PeerID, ps *pooledStream) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.activeGauge.Dec()
+	if _, ok := p.noReuse[peerID]; ok || len(p.idle[peerID]) >= p.maxIdle {
+		_ = ps.stream.Close()
+		return
+	}
+
+	ps.reused = true
+	ps.idleSince = time.Now()
+	p.idle[peerID] = append(p.idle[peerID], ps)
+	p.idleGauge.Inc()
+}
+
+// discard closes a stream that failed to be used.
+//
+// In case the stream has already been used before and reuse failed, the peer is assumed to not
+// support serving multiple requests over the same stream and streams to it are no longer pooled.
+func (p *streamPool) discard(peerID core.PeerID, ps *pooledStream, reuseFailed bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.activeGauge.Dec()
+	_ = ps.stream.Reset()
+	if !ps.reused || !reuseFailed {
+		return
+	}
+
+	p.noReuse[peerID] = struct{}{}
+	for _, idle := range p.idle[peerID] {
+		_ = idle.stream.Reset()
+		p.idleGauge.Dec()
+	}
+	delete(p.idle, peerID)
+}
+
+func (p *streamPool) setIdle(peerID core.PeerID, streams []*pooledStream) {
+	if len(streams) == 0 {
+		delete(p.idle, peerID)
+		return
+	}
+	p.idle[peerID] = streams
+}
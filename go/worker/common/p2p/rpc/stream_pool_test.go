@@ -0,0 +1,119 @@
+package rpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+)
+
+type testEchoService struct{}
+
+func (s *testEchoService) HandleRequest(ctx context.Context, method string, body cbor.RawMessage) (interface{}, error) {
+	var rq string
+	if err := cbor.Unmarshal(body, &rq); err != nil {
+		return nil, err
+	}
+	return method + " " + rq, nil
+}
+
+// setupStreamPoolTest creates a client and a server host and returns a client using the given
+// stream pool together with the number of streams opened by the client.
+func setupStreamPoolTest(t *testing.T, pool *streamPool, singleRequest bool) (*client, *server, *int64) {
+	require := require.New(t)
+
+	mn, err := mocknet.FullMeshConnected(2)
+	require.NoError(err, "FullMeshConnected")
+	t.Cleanup(func() { _ = mn.Close() })
+	hosts := mn.Hosts()
+
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("p2p rpc stream pool test ns"), 0)
+	srv := NewServer(runtimeID, "test", version.Version{Major: 1}, &testEchoService{}).(*server)
+
+	var streams int64
+	hosts[1].SetStreamHandler(srv.Protocol(), func(stream network.Stream) {
+		atomic.AddInt64(&streams, 1)
+		if !singleRequest {
+			srv.HandleStream(stream)
+			return
+		}
+
+		// Simulate a server that only handles a single request per stream.
+		defer stream.Close()
+		codec := cbor.NewMessageCodec(stream, codecModuleName)
+		srv.handleRequest(srv.logger, stream, codec, RequestReadDeadline)
+	})
+
+	c := &client{
+		host:       hosts[0],
+		protocolID: srv.Protocol(),
+		runtimeID:  runtimeID,
+		opts:       &ClientOptions{},
+		streamPool: pool,
+		logger:     logging.GetLogger("worker/common/p2p/rpc/client/test"),
+	}
+	return c, srv, &streams
+}
+
+func TestStreamPool(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name            string
+		pool            *streamPool
+		singleRequest   bool
+		expectedStreams int64
+	}{
+		{"NoPool", nil, false, 5},
+		{"Pool", newStreamPool("test", 1, time.Minute), false, 1},
+		{"SingleRequestServer", newStreamPool("test", 1, time.Minute), true, 5},
+	} {
+		c, _, streams := setupStreamPoolTest(t, tc.pool, tc.singleRequest)
+		remote := c.host.Network().Peers()[0]
+		for i := 0; i < 5; i++ {
+			var rsp string
+			err := c.sendRequestAndDecodeResponse(ctx, remote, &Request{
+				Method: "Echo",
+				Body:   cbor.Marshal("hello"),
+			}, &rsp, time.Second)
+			require.NoError(err, "%s: sendRequestAndDecodeResponse", tc.name)
+			require.Equal("Echo hello", rsp, "%s: response should be correct", tc.name)
+		}
+		require.EqualValues(tc.expectedStreams, atomic.LoadInt64(streams), "%s: number of opened streams", tc.name)
+
+		if tc.singleRequest {
+			_, noReuse := tc.pool.noReuse[remote]
+			require.True(noReuse, "%s: streams to peers not supporting reuse should not be pooled", tc.name)
+		}
+	}
+}
+
+func TestStreamPoolIdleTimeout(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	pool := newStreamPool("test", 1, time.Millisecond)
+	c, _, streams := setupStreamPoolTest(t, pool, false)
+	remote := c.host.Network().Peers()[0]
+
+	for i := 0; i < 2; i++ {
+		err := c.sendRequestAndDecodeResponse(ctx, remote, &Request{
+			Method: "Echo",
+			Body:   cbor.Marshal("hello"),
+		}, nil, time.Second)
+		require.NoError(err, "sendRequestAndDecodeResponse")
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.EqualValues(2, atomic.LoadInt64(streams), "expired idle streams should not be reused")
+	require.Nil(pool.get(remote), "expired idle streams should be closed")
+}
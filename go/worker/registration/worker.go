@@ -54,8 +54,15 @@ const (
 	// CfgRegistrationRotateCerts sets the number of epochs that a node's TLS
 	// certificate should be valid for.
 	CfgRegistrationRotateCerts = "worker.registration.rotate_certs"
+	// CfgRegistrationEpochSkewWarningThreshold sets the number of epochs by which the epoch used
+	// for (re-)registering a frozen node may differ from the consensus epoch before a warning is
+	// logged.
+	CfgRegistrationEpochSkewWarningThreshold = "worker.registration.epoch_skew_warning_threshold"
 
 	periodicMetricsInterval = 60 * time.Second
+
+	// epochSkewWarningInterval is the minimum interval between epoch skew warnings.
+	epochSkewWarningInterval = 10 * time.Minute
 )
 
 var (
@@ -91,6 +98,12 @@ var (
 		},
 		[]string{"runtime"},
 	)
+	workerNodeEpochSkew = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_node_epoch_skew",
+			Help: "Number of epochs by which the node's epoch view lags behind the consensus epoch.",
+		},
+	)
 	workerNodeRuntimeSuspended = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "oasis_worker_node_status_runtime_suspended",
@@ -104,6 +117,7 @@ var (
 		workerNodeStatusFrozen,
 		workerNodeRegistrationEligible,
 		workerNodeStatusFaults,
+		workerNodeEpochSkew,
 		workerNodeRuntimeSuspended,
 	}
 
@@ -223,6 +237,11 @@ type Worker struct { // nolint: maligned
 	registerCh    chan struct{}
 
 	status control.RegistrationStatus
+
+	// epochView is the latest epoch observed by the registration loop.
+	epochView beacon.EpochTime
+	// lastEpochSkewWarning is the time of the last epoch skew warning.
+	lastEpochSkewWarning time.Time
 }
 
 // DebugForceAllowUnroutableAddresses allows unroutable addresses.
@@ -392,6 +411,9 @@ func (w *Worker) registrationLoop() { // nolint: gocyclo
 		tlsRotationPending = true
 		first              = true
 	)
+	w.Lock()
+	w.epochView = epoch
+	w.Unlock()
 Loop:
 	for {
 		select {
@@ -412,6 +434,9 @@ Loop:
 			)
 		case epoch = <-ch:
 			// Epoch updated, check if we can submit a registration.
+			w.Lock()
+			w.epochView = epoch
+			w.Unlock()
 
 			// Check if we need to rotate the node's TLS certificate.
 			if !w.identity.DoNotRotateTLS && !tlsRotationPending {
@@ -664,6 +689,14 @@ func (w *Worker) metricsWorker() {
 			continue
 		}
 
+		// Epoch skew metric.
+		w.RLock()
+		epochView := w.epochView
+		w.RUnlock()
+		if epochView != beacon.EpochInvalid {
+			workerNodeEpochSkew.Set(float64(registry.EpochSkew(epochView, epoch)))
+		}
+
 		// Frozen metric.
 		switch nodeStatus.IsFrozen() {
 		case true:
@@ -971,6 +1004,46 @@ func (w *Worker) gatherTLSAddresses(sentryTLSAddrs []node.TLSAddress) ([]node.TL
 	return validatedAddrs, nil
 }
 
+// checkEpochSkew warns in case a frozen node is being (re-)registered using an epoch that differs
+// significantly from the consensus epoch, as decisions based on the freeze end time may then be
+// wrong. Warnings are rate-limited.
+func (w *Worker) checkEpochSkew(epoch beacon.EpochTime) {
+	threshold := viper.GetUint64(CfgRegistrationEpochSkewWarningThreshold)
+	if threshold == 0 || time.Since(w.lastEpochSkewWarning) < epochSkewWarningInterval {
+		return
+	}
+
+	consensusEpoch, err := w.beacon.GetEpoch(w.ctx, consensus.HeightLatest)
+	if err != nil {
+		w.logger.Debug("unable to query consensus epoch for epoch skew check", "err", err)
+		return
+	}
+	skew := registry.EpochSkew(epoch, consensusEpoch)
+	if skew < 0 {
+		skew = -skew
+	}
+	if uint64(skew) < threshold {
+		return
+	}
+
+	nodeStatus, err := w.registry.GetNodeStatus(w.ctx, &registry.IDQuery{
+		ID:     w.identity.NodeSigner.Public(),
+		Height: consensus.HeightLatest,
+	})
+	if err != nil || !nodeStatus.IsFrozen() {
+		return
+	}
+
+	w.lastEpochSkewWarning = time.Now()
+	w.logger.Warn("registering frozen node under significant epoch skew",
+		"epoch", epoch,
+		"consensus_epoch", consensusEpoch,
+		"skew", registry.EpochSkew(epoch, consensusEpoch),
+		"freeze_end_time", nodeStatus.FreezeEndTime,
+		"unfreeze_ambiguous", nodeStatus.IsUnfreezeAmbiguous(epoch, consensusEpoch),
+	)
+}
+
 func (w *Worker) registerNode(epoch beacon.EpochTime, hook RegisterNodeHook) error {
 	identityPublic := w.identity.NodeSigner.Public()
 	w.logger.Info("performing node (re-)registration",
@@ -978,6 +1051,8 @@ func (w *Worker) registerNode(epoch beacon.EpochTime, hook RegisterNodeHook) err
 		"node_id", identityPublic.String(),
 	)
 
+	w.checkEpochSkew(epoch)
+
 	var nextPubKey signature.PublicKey
 	if s := w.identity.GetNextTLSSigner(); s != nil {
 		nextPubKey = s.Public()
@@ -1060,7 +1135,16 @@ func (w *Worker) registerNode(epoch beacon.EpochTime, hook RegisterNodeHook) err
 		nodeSigners = append([]signature.Signer{w.identity.NodeSigner}, nodeSigners...)
 	}
 
-	sigNode, err := node.MultiSignNode(nodeSigners, registry.RegisterNodeSignatureContext, &nodeDesc)
+	// Sign the descriptor using the registry's current signature context version.
+	registryParams, err := w.registry.ConsensusParameters(w.ctx, consensus.HeightLatest)
+	if err != nil {
+		w.logger.Error("failed to register node: unable to get registry consensus parameters",
+			"err", err,
+		)
+		return err
+	}
+
+	sigNode, err := node.MultiSignNode(nodeSigners, registryParams.SignatureContexts().RegisterNode, &nodeDesc)
 	if err != nil {
 		w.logger.Error("failed to register node: unable to sign node descriptor",
 			"err", err,
@@ -1334,6 +1418,7 @@ func init() {
 	Flags.String(CfgRegistrationEntity, "", "entity to use as the node owner in registrations")
 	Flags.Bool(CfgRegistrationForceRegister, false, "(DEPRECATED) override a previously saved deregistration request")
 	Flags.Uint64(CfgRegistrationRotateCerts, 0, "rotate node TLS certificates every N epochs (0 to disable)")
+	Flags.Uint64(CfgRegistrationEpochSkewWarningThreshold, 2, "warn when a frozen node registers with an epoch skew of at least N epochs (0 to disable)")
 
 	_ = viper.BindPFlags(Flags)
 }
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/errors"
@@ -17,6 +18,11 @@ var (
 	// ErrCantPauseCheckpointer is the error returned when trying to pause the checkpointer without
 	// setting the debug flag.
 	ErrCantPauseCheckpointer = errors.New(ModuleName, 2, "worker/storage: pausing checkpointer only available in debug mode")
+	// ErrRoundNotQuarantined is the error returned when trying to release a round that is not
+	// quarantined.
+	ErrRoundNotQuarantined = errors.New(ModuleName, 3, "worker/storage: round not quarantined")
+	// ErrSyncCeilingLowered is the error returned when trying to lower the sync ceiling.
+	ErrSyncCeilingLowered = errors.New(ModuleName, 4, "worker/storage: sync ceiling can only be raised")
 )
 
 // StorageWorker is the storage worker control API interface.
@@ -26,6 +32,18 @@ type StorageWorker interface {
 
 	// PauseCheckpointer pauses or unpauses the storage worker's checkpointer.
 	PauseCheckpointer(ctx context.Context, request *PauseCheckpointerRequest) error
+
+	// GetSyncedRuntimes retrieves the last synced round for all runtimes synced by the storage
+	// worker.
+	GetSyncedRuntimes(ctx context.Context) ([]*SyncedRuntime, error)
+
+	// UnquarantineRound releases a round that was quarantined after its diffs repeatedly failed
+	// to apply, so that syncing it is retried.
+	UnquarantineRound(ctx context.Context, request *UnquarantineRoundRequest) error
+
+	// SetSyncCeiling raises the round up to which the storage worker syncs a runtime, resuming
+	// sync in case the worker was idling at the previous ceiling.
+	SetSyncCeiling(ctx context.Context, request *SetSyncCeilingRequest) error
 }
 
 // GetLastSyncedRoundRequest is a GetLastSyncedRound request.
@@ -46,8 +64,52 @@ type PauseCheckpointerRequest struct {
 	Pause     bool             `json:"pause"`
 }
 
+// UnquarantineRoundRequest is an UnquarantineRound request.
+type UnquarantineRoundRequest struct {
+	RuntimeID common.Namespace `json:"runtime_id"`
+	Round     uint64           `json:"round"`
+}
+
+// SetSyncCeilingRequest is a SetSyncCeiling request.
+type SetSyncCeilingRequest struct {
+	RuntimeID common.Namespace `json:"runtime_id"`
+	// Round is the new sync ceiling. Zero removes the ceiling.
+	Round uint64 `json:"round"`
+}
+
+// SyncedRuntime is the sync status of a single runtime synced by the storage worker.
+type SyncedRuntime struct {
+	// RuntimeID is the identifier of the runtime.
+	RuntimeID common.Namespace `json:"runtime_id"`
+	// LastSyncedRound is the last synced round.
+	LastSyncedRound uint64 `json:"last_synced_round"`
+}
+
+// RoundWriteLogStats are the statistics of the write logs applied for a single finalized round.
+type RoundWriteLogStats struct {
+	// Round is the round number.
+	Round uint64 `json:"round"`
+	// Entries is the number of write log entries applied for the round.
+	Entries uint64 `json:"entries"`
+	// Bytes is the total size of the keys and values of the applied write log entries.
+	Bytes uint64 `json:"bytes"`
+	// FinalizedNodes is the number of nodes committed when finalizing the round, in case the
+	// storage backend reports it.
+	FinalizedNodes uint64 `json:"finalized_nodes,omitempty"`
+	// FinalizeDuration is the time it took to finalize the round.
+	FinalizeDuration time.Duration `json:"finalize_duration,omitempty"`
+}
+
+// SyncStats are the storage sync statistics of recently finalized rounds.
+type SyncStats struct {
+	// Rounds are the write log statistics of recently finalized rounds, ordered by round.
+	Rounds []RoundWriteLogStats `json:"rounds"`
+}
+
 // Status is the storage worker status.
 type Status struct {
 	// LastFinalizedRound is the last synced and finalized round.
 	LastFinalizedRound uint64 `json:"last_finalized_round"`
+	// SyncCeiling is the round up to which rounds are synced, if any.
+	SyncCeiling uint64 `json:"sync_ceiling,omitempty"`
 }
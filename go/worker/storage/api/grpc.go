@@ -16,6 +16,12 @@ var (
 	methodGetLastSyncedRound = serviceName.NewMethod("GetLastSyncedRound", &GetLastSyncedRoundRequest{})
 	// methodPauseCheckpointer is the PauseCheckpointer method.
 	methodPauseCheckpointer = serviceName.NewMethod("PauseCheckpointer", &PauseCheckpointerRequest{})
+	// methodGetSyncedRuntimes is the GetSyncedRuntimes method.
+	methodGetSyncedRuntimes = serviceName.NewMethod("GetSyncedRuntimes", nil)
+	// methodUnquarantineRound is the UnquarantineRound method.
+	methodUnquarantineRound = serviceName.NewMethod("UnquarantineRound", &UnquarantineRoundRequest{})
+	// methodSetSyncCeiling is the SetSyncCeiling method.
+	methodSetSyncCeiling = serviceName.NewMethod("SetSyncCeiling", &SetSyncCeilingRequest{})
 
 	// serviceDesc is the gRPC service descriptor.
 	serviceDesc = grpc.ServiceDesc{
@@ -30,6 +36,18 @@ var (
 				MethodName: methodPauseCheckpointer.ShortName(),
 				Handler:    handlerPauseCheckpointer,
 			},
+			{
+				MethodName: methodGetSyncedRuntimes.ShortName(),
+				Handler:    handlerGetSyncedRuntimes,
+			},
+			{
+				MethodName: methodUnquarantineRound.ShortName(),
+				Handler:    handlerUnquarantineRound,
+			},
+			{
+				MethodName: methodSetSyncCeiling.ShortName(),
+				Handler:    handlerSetSyncCeiling,
+			},
 		},
 		Streams: []grpc.StreamDesc{},
 	}
@@ -81,6 +99,71 @@ func handlerPauseCheckpointer(
 	return interceptor(ctx, rq, info, handler)
 }
 
+func handlerGetSyncedRuntimes(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	if interceptor == nil {
+		return srv.(StorageWorker).GetSyncedRuntimes(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetSyncedRuntimes.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageWorker).GetSyncedRuntimes(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerUnquarantineRound(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	rq := new(UnquarantineRoundRequest)
+	if err := dec(rq); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(StorageWorker).UnquarantineRound(ctx, rq)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodUnquarantineRound.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, srv.(StorageWorker).UnquarantineRound(ctx, req.(*UnquarantineRoundRequest))
+	}
+	return interceptor(ctx, rq, info, handler)
+}
+
+func handlerSetSyncCeiling(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	rq := new(SetSyncCeilingRequest)
+	if err := dec(rq); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(StorageWorker).SetSyncCeiling(ctx, rq)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodSetSyncCeiling.FullName(),
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, srv.(StorageWorker).SetSyncCeiling(ctx, req.(*SetSyncCeilingRequest))
+	}
+	return interceptor(ctx, rq, info, handler)
+}
+
 // RegisterService registers a new storage worker service with the given gRPC server.
 func RegisterService(server *grpc.Server, service StorageWorker) {
 	server.RegisterService(&serviceDesc, service)
@@ -102,6 +185,22 @@ func (c *storageWorkerClient) PauseCheckpointer(ctx context.Context, req *PauseC
 	return c.conn.Invoke(ctx, methodPauseCheckpointer.FullName(), req, nil)
 }
 
+func (c *storageWorkerClient) GetSyncedRuntimes(ctx context.Context) ([]*SyncedRuntime, error) {
+	var rsp []*SyncedRuntime
+	if err := c.conn.Invoke(ctx, methodGetSyncedRuntimes.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func (c *storageWorkerClient) UnquarantineRound(ctx context.Context, req *UnquarantineRoundRequest) error {
+	return c.conn.Invoke(ctx, methodUnquarantineRound.FullName(), req, nil)
+}
+
+func (c *storageWorkerClient) SetSyncCeiling(ctx context.Context, req *SetSyncCeilingRequest) error {
+	return c.conn.Invoke(ctx, methodSetSyncCeiling.FullName(), req, nil)
+}
+
 // NewStorageWorkerClient creates a new gRPC transaction scheduler
 // client service.
 func NewStorageWorkerClient(c *grpc.ClientConn) StorageWorker {
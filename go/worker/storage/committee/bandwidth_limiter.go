@@ -0,0 +1,87 @@
+package committee
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
+)
+
+// BandwidthLimiter is a byte rate limiter which bounds the aggregate bandwidth used for fetching
+// diffs. A single limiter can be shared among the storage nodes of multiple runtimes.
+type BandwidthLimiter struct {
+	sync.Mutex
+
+	rate  float64
+	burst float64
+
+	tokens float64
+	last   time.Time
+}
+
+// NewBandwidthLimiter creates a new bandwidth limiter allowing the given number of bytes per
+// second on average.
+func NewBandwidthLimiter(bytesPerSecond uint64) *BandwidthLimiter {
+	return &BandwidthLimiter{
+		rate: float64(bytesPerSecond),
+		// Allow bursts of up to a second worth of data.
+		burst:  float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// WaitN blocks until n bytes that have already been read may be consumed.
+//
+// The bytes are accounted for immediately so that concurrent callers are served in order, each
+// waiting until the bytes of all previous callers have been paid for.
+func (l *BandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	l.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	tokens := l.tokens
+	l.Unlock()
+
+	if tokens >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-tokens / l.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// diffReadLimiter is a per-runtime read limiter for diff responses which accounts the read bytes
+// and waits on the shared bandwidth limiter.
+type diffReadLimiter struct {
+	limiter *BandwidthLimiter
+	labels  prometheus.Labels
+}
+
+// Implements rpc.ReadLimiter.
+func (l *diffReadLimiter) WaitN(ctx context.Context, n int) error {
+	storageWorkerDiffBytesRead.With(l.labels).Add(float64(n))
+	return l.limiter.WaitN(ctx, n)
+}
+
+func newDiffReadLimiter(limiter *BandwidthLimiter, runtimeID common.Namespace) rpc.ReadLimiter {
+	return &diffReadLimiter{
+		limiter: limiter,
+		labels:  prometheus.Labels{"runtime": runtimeID.String()},
+	}
+}
@@ -0,0 +1,58 @@
+package committee
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	const (
+		rate      = 100_000
+		fetchers  = 4
+		chunkSize = 1_000
+		chunks    = 50
+	)
+	limiter := NewBandwidthLimiter(rate)
+
+	// Concurrent fetches share the limiter, so the aggregate rate should be capped. The first
+	// second worth of data is allowed as a burst.
+	start := time.Now()
+	var wg sync.WaitGroup
+	errCh := make(chan error, fetchers)
+	for i := 0; i < fetchers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < chunks; j++ {
+				if err := limiter.WaitN(ctx, chunkSize); err != nil {
+					errCh <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(errCh)
+	for err := range errCh {
+		require.NoError(err, "WaitN")
+	}
+
+	total := fetchers * chunks * chunkSize
+	expected := time.Duration(float64(total-rate) / rate * float64(time.Second))
+	require.GreaterOrEqual(elapsed, expected-50*time.Millisecond, "aggregate rate should be capped")
+	require.Less(elapsed, expected+time.Second, "throttled fetches should complete")
+
+	// Waiting should be aborted once the context is canceled.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err := limiter.WaitN(cancelCtx, 10*rate)
+	require.ErrorIs(err, context.Canceled, "WaitN should fail on a canceled context")
+}
@@ -0,0 +1,66 @@
+package committee
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+)
+
+// blockBacklog tracks the times at which blocks were enqueued to the block channel of the worker,
+// so that the age of the oldest block that has not been consumed yet can be determined without
+// inspecting the channel buffer.
+type blockBacklog struct {
+	sync.Mutex
+
+	enqueuedAt []time.Time
+}
+
+// enqueueBlock enqueues the given block to the block channel of the worker.
+func (n *Node) enqueueBlock(blk *block.Block) {
+	n.blockBacklog.Lock()
+	defer n.blockBacklog.Unlock()
+
+	n.blockBacklog.enqueuedAt = append(n.blockBacklog.enqueuedAt, time.Now())
+	n.blockCh.In() <- blk
+}
+
+// sampleBlockBacklog returns the number of blocks buffered in the block channel of the worker and
+// the age of the oldest one, and exports both as metrics.
+func (n *Node) sampleBlockBacklog() (int, time.Duration) {
+	n.blockBacklog.Lock()
+	defer n.blockBacklog.Unlock()
+
+	// Blocks are consumed in order, so the enqueue times of consumed blocks are the oldest ones.
+	depth := n.blockCh.Len()
+	if excess := len(n.blockBacklog.enqueuedAt) - depth; excess > 0 {
+		n.blockBacklog.enqueuedAt = append([]time.Time{}, n.blockBacklog.enqueuedAt[excess:]...)
+	}
+	var age time.Duration
+	if depth > 0 && len(n.blockBacklog.enqueuedAt) > 0 {
+		age = time.Since(n.blockBacklog.enqueuedAt[0])
+	}
+
+	labels := n.getMetricLabels()
+	storageWorkerBufferedBlocks.With(labels).Set(float64(depth))
+	storageWorkerOldestBufferedBlockAge.With(labels).Set(age.Seconds())
+	return depth, age
+}
+
+// blockBacklogSampler periodically samples the backlog of blocks buffered in the block channel.
+func (n *Node) blockBacklogSampler() {
+	ticker := time.NewTicker(n.cfg.BlockBacklogSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.quitCh:
+			return
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n.sampleBlockBacklog()
+	}
+}
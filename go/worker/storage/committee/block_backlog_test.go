@@ -0,0 +1,52 @@
+package committee
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+)
+
+func TestSampleBlockBacklog(t *testing.T) {
+	require := require.New(t)
+
+	n := newTestNode(t)
+	n.blockCh = channels.NewInfiniteChannel()
+	labels := n.getMetricLabels()
+
+	depth, age := n.sampleBlockBacklog()
+	require.Zero(depth, "no blocks should be buffered initially")
+	require.Zero(age, "there should be no oldest buffered block initially")
+
+	blk := block.NewGenesisBlock(testNs, 0)
+	for i := 0; i < 3; i++ {
+		n.HandleNewBlockLocked(blk)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	depth, age = n.sampleBlockBacklog()
+	require.Equal(3, depth, "enqueued blocks should be buffered")
+	require.GreaterOrEqual(age, 10*time.Millisecond, "age of the oldest buffered block")
+	require.EqualValues(3, testutil.ToFloat64(storageWorkerBufferedBlocks.With(labels)))
+	require.EqualValues(age.Seconds(), testutil.ToFloat64(storageWorkerOldestBufferedBlockAge.With(labels)))
+
+	// Consumed blocks should no longer be part of the backlog.
+	<-n.blockCh.Out()
+	n.HandleNewBlockLocked(blk)
+	for i := 0; i < 2; i++ {
+		<-n.blockCh.Out()
+	}
+	depth, age = n.sampleBlockBacklog()
+	require.Equal(1, depth, "only the block that was not consumed should be buffered")
+	require.Less(age, 10*time.Millisecond, "age of the block enqueued last")
+
+	<-n.blockCh.Out()
+	depth, age = n.sampleBlockBacklog()
+	require.Zero(depth, "no blocks should be buffered once all are consumed")
+	require.Zero(age, "there should be no oldest buffered block once all are consumed")
+	require.Zero(testutil.ToFloat64(storageWorkerBufferedBlocks.With(labels)))
+}
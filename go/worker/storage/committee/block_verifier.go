@@ -0,0 +1,106 @@
+package committee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	roothashApi "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/history"
+)
+
+// blockVerifier verifies that incoming blocks form a valid chain before their roots are used to
+// drive sync.
+//
+// Each block must match the block committed for the same round in the runtime history (if it is
+// already there) and must extend the chain of previously verified blocks through its previous
+// block hash. The first block is verified against its parent in the runtime history, if any.
+type blockVerifier struct {
+	history history.History
+
+	// last is the header of the last verified block.
+	last *block.Header
+}
+
+func newBlockVerifier(history history.History) *blockVerifier {
+	return &blockVerifier{
+		history: history,
+	}
+}
+
+// verify verifies the given block, fetching any blocks between the last verified block and the
+// given block from the runtime history.
+func (v *blockVerifier) verify(ctx context.Context, blk *block.Block) error {
+	committed, err := v.history.GetCommittedBlock(ctx, blk.Header.Round)
+	switch {
+	case err == nil:
+		if !committed.Header.MostlyEqual(&blk.Header) {
+			return fmt.Errorf("block does not match committed block for round %d", blk.Header.Round)
+		}
+	case errors.Is(err, roothashApi.ErrNotFound):
+	default:
+		return fmt.Errorf("failed to get committed block for round %d: %w", blk.Header.Round, err)
+	}
+
+	parent := v.last
+	if parent == nil {
+		if blk.Header.Round == 0 {
+			v.last = &blk.Header
+			return nil
+		}
+		var parentBlk *block.Block
+		parentBlk, err = v.history.GetCommittedBlock(ctx, blk.Header.Round-1)
+		switch {
+		case err == nil:
+			parent = &parentBlk.Header
+		case errors.Is(err, roothashApi.ErrNotFound):
+			// Nothing to verify against, e.g., as the runtime history has been pruned.
+			v.last = &blk.Header
+			return nil
+		default:
+			return fmt.Errorf("failed to get committed block for round %d: %w", blk.Header.Round-1, err)
+		}
+	}
+
+	switch {
+	case blk.Header.Round < parent.Round:
+		return fmt.Errorf("block for round %d precedes last verified round %d", blk.Header.Round, parent.Round)
+	case blk.Header.Round == parent.Round:
+		if !parent.MostlyEqual(&blk.Header) {
+			return fmt.Errorf("block conflicts with verified block for round %d", blk.Header.Round)
+		}
+		return nil
+	}
+
+	// Verify the chain of intermediate blocks that were not delivered.
+	for round := parent.Round + 1; round < blk.Header.Round; round++ {
+		var intermediate *block.Block
+		if intermediate, err = v.history.GetCommittedBlock(ctx, round); err != nil {
+			return fmt.Errorf("failed to get committed block for round %d: %w", round, err)
+		}
+		if err = verifyBlockParent(parent, &intermediate.Header); err != nil {
+			return err
+		}
+		parent = &intermediate.Header
+	}
+	if err = verifyBlockParent(parent, &blk.Header); err != nil {
+		return err
+	}
+
+	v.last = &blk.Header
+	return nil
+}
+
+// verifyBlockParent verifies that the given child header directly follows the given parent header.
+func verifyBlockParent(parent, child *block.Header) error {
+	if !child.Namespace.Equal(&parent.Namespace) || child.Round != parent.Round+1 {
+		return fmt.Errorf("block for round %d does not follow block for round %d", child.Round, parent.Round)
+	}
+	if parentHash := parent.EncodedHash(); !child.PreviousHash.Equal(&parentHash) {
+		return fmt.Errorf("block for round %d has unexpected previous hash (expected: %s got: %s)",
+			child.Round, parentHash, child.PreviousHash,
+		)
+	}
+	return nil
+}
@@ -10,8 +10,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/libp2p/go-libp2p/core"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
+	p2p "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
 	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
 )
 
@@ -40,6 +45,11 @@ type CheckpointSyncConfig struct {
 
 	// ChunkFetcherCount specifies the number of parallel checkpoint chunk fetchers.
 	ChunkFetcherCount uint
+
+	// TrustedProviders are the node IDs of the only peers from which checkpoints are restored. In
+	// case it is empty, checkpoints are restored from any peer. Diffs are always fetched from any
+	// peer.
+	TrustedProviders []signature.PublicKey
 }
 
 // Validate performs configuration checks.
@@ -47,9 +57,30 @@ func (cfg *CheckpointSyncConfig) Validate() error {
 	if !cfg.Disabled && cfg.ChunkFetcherCount == 0 {
 		return fmt.Errorf("number of checkpoint chunk fetchers must be greater than zero")
 	}
+	for _, pk := range cfg.TrustedProviders {
+		if _, err := p2p.PublicKeyToPeerID(pk); err != nil {
+			return fmt.Errorf("malformed trusted checkpoint provider %s: %w", pk, err)
+		}
+	}
 	return nil
 }
 
+// trustedPeers returns the set of peers from which checkpoints are restored, or nil in case
+// checkpoints are restored from any peer.
+func (cfg *CheckpointSyncConfig) trustedPeers() map[core.PeerID]bool {
+	if len(cfg.TrustedProviders) == 0 {
+		return nil
+	}
+	peers := make(map[core.PeerID]bool, len(cfg.TrustedProviders))
+	for _, pk := range cfg.TrustedProviders {
+		// Malformed public keys are rejected during validation.
+		if peerID, err := p2p.PublicKeyToPeerID(pk); err == nil {
+			peers[peerID] = true
+		}
+	}
+	return peers
+}
+
 type chunk struct {
 	*checkpoint.ChunkMetadata
 
@@ -265,6 +296,15 @@ func (n *Node) handleCheckpoint(check *storageSync.Checkpoint, maxParallelReques
 			next = nil
 
 		case <-doneCh:
+			// The worker that restored the last chunk exits right after signalling completion,
+			// so make sure that the signal is not missed.
+			select {
+			case returned := <-chunkReturnCh:
+				if returned == nil {
+					return checkpointStatusDone, nil
+				}
+			default:
+			}
 			// No usable workers left, move on to the next checkpoint.
 			return checkpointStatusNext, fmt.Errorf("no usable workers")
 		}
@@ -289,6 +329,27 @@ func (n *Node) getCheckpointList() ([]*storageSync.Checkpoint, error) {
 		return nil, err
 	}
 
+	// Only consider checkpoints advertised by trusted providers and make sure that their chunks
+	// are only fetched from those providers.
+	if trusted := n.checkpointSyncCfg.trustedPeers(); trusted != nil {
+		filtered := list[:0]
+		for _, cp := range list {
+			var peers []rpc.PeerFeedback
+			for _, pf := range cp.Peers {
+				if trusted[pf.PeerID()] {
+					peers = append(peers, pf)
+				}
+			}
+			if len(peers) == 0 {
+				n.logger.Info("checkpoint from untrusted providers skipped", "root", cp.Root)
+				continue
+			}
+			cp.Peers = peers
+			filtered = append(filtered, cp)
+		}
+		list = filtered
+	}
+
 	// Sort checkpoints by version, descending.
 	sort.Slice(list, func(i, j int) bool {
 		// Descending!
@@ -331,12 +392,32 @@ func (n *Node) checkCheckpointUsable(cp *storageSync.Checkpoint, remainingMask o
 	return false
 }
 
+// exceedsCheckpointRestoreLag returns true in case the given last finalized round is behind the
+// given latest round by more than the configured checkpoint restore lag threshold, so that the
+// state should be restored from a checkpoint instead of syncing all missed rounds. The round at
+// which the last restore was attempted is treated as the last finalized round in case it is later.
+func (n *Node) exceedsCheckpointRestoreLag(lastFinalized, lastAttempt, latestRound uint64) bool {
+	threshold := n.cfg.CheckpointRestoreLagThreshold
+	if threshold == 0 || n.checkpointSyncCfg.Disabled || n.storageSync == nil {
+		return false
+	}
+	base := lastFinalized
+	if lastAttempt > base || base == n.undefinedRound {
+		base = lastAttempt
+	}
+	return latestRound > base && latestRound-base > threshold
+}
+
 func (n *Node) syncCheckpoints(genesisRound uint64) (*blockSummary, error) {
 	// Store roots and round info for checkpoints that finished syncing.
 	// Round and namespace info will get overwritten as rounds are skipped
 	// for errors, driven by remainingRoots.
 	var syncState blockSummary
 
+	if n.storageSync == nil {
+		return nil, fmt.Errorf("can't sync checkpoints with the remote storage client disabled")
+	}
+
 	// Fetch checkpoints from peers.
 	cps, err := n.getCheckpointList()
 	if err != nil {
@@ -0,0 +1,82 @@
+package committee
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
+	p2p "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+type testCheckpointsClient struct {
+	storageSync.Client
+
+	checkpoints []*storageSync.Checkpoint
+}
+
+func (c *testCheckpointsClient) GetCheckpoints(ctx context.Context, request *storageSync.GetCheckpointsRequest) ([]*storageSync.Checkpoint, error) {
+	return c.checkpoints, nil
+}
+
+func TestCheckpointListTrustedProviders(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		pks     []signature.PublicKey
+		peerFbs []rpc.PeerFeedback
+	)
+	for _, seed := range []string{"trusted provider", "untrusted provider"} {
+		pk := memorySigner.NewTestSigner(seed).Public()
+		peerID, err := p2p.PublicKeyToPeerID(pk)
+		require.NoError(err, "PublicKeyToPeerID")
+		pks = append(pks, pk)
+		peerFbs = append(peerFbs, &testPeerFeedback{peerID})
+	}
+	trusted, untrusted := peerFbs[0], peerFbs[1]
+
+	newCheckpoint := func(version uint64, peers ...rpc.PeerFeedback) *storageSync.Checkpoint {
+		return &storageSync.Checkpoint{
+			Metadata: &checkpoint.Metadata{
+				Root: storageApi.Root{Namespace: testNs, Version: version, Type: storageApi.RootTypeState},
+			},
+			Peers: peers,
+		}
+	}
+	newList := func() []*storageSync.Checkpoint {
+		return []*storageSync.Checkpoint{
+			newCheckpoint(1, untrusted),
+			newCheckpoint(2, trusted, untrusted),
+			newCheckpoint(3, trusted),
+		}
+	}
+
+	n := newTestNode(t)
+	n.storageSync = &testCheckpointsClient{checkpoints: newList()}
+
+	// Without trusted providers, checkpoints from all peers should be used.
+	n.checkpointSyncCfg = &CheckpointSyncConfig{ChunkFetcherCount: 1}
+	require.NoError(n.checkpointSyncCfg.Validate(), "Validate")
+	list, err := n.getCheckpointList()
+	require.NoError(err, "getCheckpointList")
+	require.Len(list, 3, "all checkpoints should be used")
+
+	// With trusted providers, checkpoints only advertised by other peers should be ignored and
+	// chunks should only be fetched from trusted providers.
+	n.storageSync = &testCheckpointsClient{checkpoints: newList()}
+	n.checkpointSyncCfg = &CheckpointSyncConfig{ChunkFetcherCount: 1, TrustedProviders: pks[:1]}
+	require.NoError(n.checkpointSyncCfg.Validate(), "Validate")
+	list, err = n.getCheckpointList()
+	require.NoError(err, "getCheckpointList")
+	require.Len(list, 2, "checkpoint from an untrusted provider should be ignored")
+	for i, version := range []uint64{3, 2} {
+		require.EqualValues(version, list[i].Root.Version, "checkpoints should be sorted by version")
+		require.Equal([]rpc.PeerFeedback{trusted}, list[i].Peers, "only trusted providers should be used")
+	}
+}
@@ -0,0 +1,42 @@
+package committee
+
+import (
+	"github.com/libp2p/go-libp2p/core"
+
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+	p2p "github.com/oasisprotocol/oasis-core/go/worker/common/p2p/api"
+)
+
+// committeeMembership determines whether remote peers are members of the runtime's committee.
+type committeeMembership interface {
+	// IsCommitteePeer returns true iff the given peer is a member of the runtime's committee in
+	// the current epoch.
+	IsCommitteePeer(peerID core.PeerID) bool
+}
+
+// epochCommitteeMembership is a committee membership check based on the current epoch snapshot.
+type epochCommitteeMembership struct {
+	group *committee.Group
+}
+
+func (m *epochCommitteeMembership) IsCommitteePeer(peerID core.PeerID) bool {
+	epoch := m.group.GetEpochSnapshot()
+	if !epoch.IsValid() {
+		return false
+	}
+	ci := epoch.GetExecutorCommittee()
+	if ci == nil {
+		return false
+	}
+
+	for pk := range ci.Peers {
+		id, err := p2p.PublicKeyToPeerID(pk)
+		if err != nil {
+			continue
+		}
+		if id == peerID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package committee
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+// Config is the storage committee node configuration.
+type Config struct {
+	// DebugValidateRootChaining enables runtime validation of the assumptions about how storage
+	// roots are chained between rounds (state roots are chained, I/O roots are not). Any violation
+	// is logged as an error before the corresponding write log is applied.
+	DebugValidateRootChaining bool
+
+	// BlockWatchdogInterval is the interval after which, in case no blocks have been received
+	// from the block subscription while the chain is advancing, the worker falls back to polling
+	// for the latest block until the subscription recovers. Zero disables the watchdog.
+	BlockWatchdogInterval time.Duration
+
+	// LoopWatchdogInterval is the interval after which the worker main loop is considered stalled
+	// in case it made no progress while work is pending. It should be well above the maximum
+	// retry interval, as the main loop may legitimately wait that long for diffs to be fetched.
+	// Zero disables the watchdog.
+	LoopWatchdogInterval time.Duration
+
+	// RetryWarningThreshold is the duration after which an in-flight round whose diffs keep failing
+	// to be fetched or applied is reported with a warning. Zero disables the warning.
+	RetryWarningThreshold time.Duration
+
+	// ConsistencyCheckInterval is the interval at which the roots of a recently finalized round
+	// are compared against the roots reported by remote peers. Zero disables the check.
+	ConsistencyCheckInterval time.Duration
+
+	// ScrubInterval is the interval at which the next finalized round of local storage is verified
+	// by the background scrubber, limiting the impact of scrubbing on sync. Zero disables the
+	// scrubber.
+	ScrubInterval time.Duration
+
+	// ScrubRecheckInterval is the minimum interval between the starts of consecutive scrubber
+	// passes over all finalized rounds, so that recently verified rounds are not verified again.
+	ScrubRecheckInterval time.Duration
+
+	// BlockBacklogSampleInterval is the interval at which the number of received blocks waiting
+	// to be processed and the age of the oldest one are sampled and exported as metrics. Zero
+	// disables sampling.
+	BlockBacklogSampleInterval time.Duration
+
+	// WriteLogCacheSize is the maximum size (in bytes) of the cache of recently fetched write logs
+	// which is consulted before fetching a diff from remote peers. Zero disables the cache.
+	WriteLogCacheSize uint64
+
+	// ApplyBatchSize is the maximum number of consecutive rounds whose already fetched state write
+	// logs are applied together in a single batch, in case the local storage backend supports
+	// batched applies. Zero or one disables batching. Batched rounds are still finalized one by one.
+	ApplyBatchSize uint64
+
+	// DiffChannelSize is the size of the buffer of the channel through which fetched diffs are
+	// passed to the main worker loop. Zero means that the channel is unbuffered and fetchers block
+	// until the main loop receives their result.
+	//
+	// Note that as long as the main loop (which applies the diffs) is the bottleneck, buffering
+	// does not measurably improve catch-up throughput (see BenchmarkDiffChannelSize), it only
+	// frees up fetchers earlier.
+	DiffChannelSize uint
+
+	// FinalizeChannelSize is the size of the buffer of the channel through which finalization
+	// results are passed to the main worker loop. Zero means that the channel is unbuffered.
+	FinalizeChannelSize uint
+
+	// RequireCommitteePeers enables rejecting diffs that are served by peers which are not members
+	// of the runtime's committee in the current epoch.
+	RequireCommitteePeers bool
+
+	// QuarantineThreshold is the number of distinct peers whose diffs for a round need to fail
+	// root verification before the round is quarantined and no longer retried until released via
+	// Unquarantine. Zero disables quarantining.
+	QuarantineThreshold uint64
+
+	// SyncCeiling is the round up to which rounds are synced and finalized. Blocks for later rounds
+	// are not synced until the ceiling is raised via SetSyncCeiling. Zero means that there is no
+	// ceiling.
+	SyncCeiling uint64
+
+	// DiffStreamPoolSize is the maximum number of idle streams per peer that are kept open and
+	// reused for subsequent diff fetches from the same peer. Zero disables stream pooling.
+	DiffStreamPoolSize uint
+	// DiffStreamIdleTimeout is the amount of time after which pooled idle streams are closed. Zero
+	// means that idle streams are never closed due to inactivity.
+	DiffStreamIdleTimeout time.Duration
+
+	// BandwidthLimiter is an optional limiter of the aggregate bandwidth used for reading diffs
+	// fetched from peers, which may be shared among runtimes. Throttled fetches take longer, but do
+	// not fail. In case it is nil, bandwidth is not limited.
+	BandwidthLimiter *BandwidthLimiter
+
+	// DiffPeerSelector is an optional strategy for selecting the peer from which each diff is
+	// requested first. In case it is nil, the best scored peer is used.
+	DiffPeerSelector storageSync.PeerSelector
+
+	// CompactDiffs enables requesting diffs from peers in the delta-encoded write log format. Peers
+	// that do not support it are transparently queried again using the plain format.
+	CompactDiffs bool
+
+	// SkipEqualRootApply enables skipping the apply of the empty write log for roots that are
+	// equal to the root of the previous round (e.g., during epoch transitions) in case the local
+	// storage backend confirms that the root is already satisfied.
+	SkipEqualRootApply bool
+
+	// VerifyBlocks enables verification of incoming blocks before their roots are used to drive
+	// sync. Each block must match the block committed for its round in the runtime history and
+	// must extend the chain of previously verified blocks, otherwise it is rejected. This adds
+	// some cost per block as blocks that were not delivered also need to be fetched from history.
+	VerifyBlocks bool
+
+	// SyncStatsWindowSize is the number of most recently finalized rounds for which write log
+	// statistics are kept and reported via SyncStats. Zero disables keeping statistics.
+	SyncStatsWindowSize uint64
+
+	// FinalizePolicy is an optional policy deciding when fully applied rounds are finalized. In case
+	// it is nil, each round is finalized as soon as it has been fully applied.
+	FinalizePolicy FinalizePolicy
+
+	// MaxFinalizeRetries is the number of times a failed finalization of a round is retried, with
+	// exponential backoff, before the failure is reported as fatal. Zero disables retries.
+	MaxFinalizeRetries uint64
+	// FinalizeRetryInterval is the initial interval between finalization retries. Zero means that
+	// the default backoff interval is used.
+	FinalizeRetryInterval time.Duration
+	// FinalizeGracePeriod is the time that finalizations which are in progress when the node is
+	// stopped are given to complete before they are canceled, which avoids leaving a partially
+	// written round in local storage. Zero means that they are canceled immediately.
+	FinalizeGracePeriod time.Duration
+
+	// MaxGenesisBlockRetries is the number of times a failure to retrieve the genesis block at
+	// startup is retried, with exponential backoff, before the worker gives up. Zero disables
+	// retries.
+	MaxGenesisBlockRetries uint64
+	// GenesisBlockRetryInterval is the initial interval between genesis block retrieval retries.
+	// Zero means that the default backoff interval is used.
+	GenesisBlockRetryInterval time.Duration
+
+	// Tunables are the initial storage sync tunables, which can later be changed via Reconfigure.
+	// In case it is nil, the default tunables are used.
+	Tunables *Tunables
+	// RuntimeTunables are optional per-runtime initial storage sync tunables, keyed by runtime ID.
+	// They take precedence over Tunables for the given runtimes, which allows tuning runtimes with
+	// very different state sizes and churn independently.
+	RuntimeTunables map[common.Namespace]Tunables
+
+	// PruneOrdering defines how pruning of finalized rounds is ordered with respect to
+	// finalization of later rounds that is in progress at the same time. By default, pruning
+	// waits for in-progress finalizations to complete.
+	PruneOrdering PruneOrdering
+
+	// FetchOrdering defines the order in which the diff fetches of the rounds being synced are
+	// submitted. By default, the fetches of all roots of a round are submitted before those of
+	// the next round.
+	FetchOrdering FetchOrdering
+
+	// MaxConcurrentFinalizes is the maximum number of batches of consecutive fully applied rounds
+	// that are finalized concurrently, in case the local storage backend supports concurrent
+	// finalization. Zero or one means that finalization is serialized.
+	MaxConcurrentFinalizes uint
+
+	// MaxConcurrentWrites is the maximum number of write operations on local storage, i.e.
+	// applies of write logs and finalizations of rounds, that are performed concurrently. Limiting
+	// it avoids applies and finalizations contending for disk IO, at the expense of catch-up
+	// throughput as applies need to wait for in-progress finalizations and vice versa (see the
+	// oasis_worker_storage_write_slot_wait_seconds metric). Zero means unlimited.
+	MaxConcurrentWrites uint
+
+	// Tracer is an optional tracer used to create tracing spans around diff fetches, applies and
+	// finalizations. In case it is nil, no spans are created.
+	Tracer Tracer
+
+	// WriteLogInterceptor is an optional hook invoked with each fetched write log before it is
+	// applied, which may transform or reject it. In case it is nil, write logs are applied as
+	// fetched.
+	WriteLogInterceptor WriteLogInterceptor
+
+	// EventSink is an optional sink which receives sync lifecycle events (rounds being fetched,
+	// applied and finalized and sync errors). In case it is nil, no events are emitted.
+	EventSink EventSink
+
+	// TransitionLogSize is the number of the most recent worker state transitions (blocks being
+	// received, diffs being fetched, applied and retried and rounds being finalized and pruned)
+	// that are kept in memory, see GetTransitionLog. The log is dumped in case of a fatal error.
+	// Zero disables the log.
+	TransitionLogSize uint
+
+	// PersistedSummaries is the maximum number of summaries of seen blocks that have not yet been
+	// finalized which are persisted to PersistedSummariesPath, so that they do not need to be
+	// queried from the runtime history again when catching up after a restart. Zero disables
+	// persisting block summaries.
+	PersistedSummaries uint64
+	// PersistedSummariesPath is the path of the file in which block summaries are persisted.
+	PersistedSummariesPath string
+
+	// RecoverCorruptState enables recovering from an imported synced state that can't be decoded
+	// (see ImportSyncState). Instead of failing the import, the corrupted file is moved aside for
+	// inspection and the sync position is derived from local storage as if nothing was imported.
+	RecoverCorruptState bool
+
+	// MaxBufferedDiffBytes is the soft limit on the total size (in bytes) of the write logs of
+	// fetched diffs waiting to be applied. Once it is reached, only diffs of the round that is
+	// applied next are fetched until applying drains the buffer. Zero means no limit.
+	MaxBufferedDiffBytes uint64
+
+	// PrioritizedTipRounds is the number of the most recent rounds whose diffs are fetched before
+	// those of older rounds in case the node is further behind than the maximum number of rounds
+	// in flight. Zero means that rounds are always fetched oldest-first.
+	//
+	// Rounds are still applied and finalized strictly in order, so prioritizing near-tip rounds
+	// only means that their diffs are already available once the older rounds have been synced.
+	// This comes at the cost of the reserved rounds no longer being available for syncing the
+	// backlog, which slows down catching up. At least one in-flight round is always left for the
+	// backlog.
+	PrioritizedTipRounds uint64
+
+	// PrioritizeStateFetches enables fetching diffs of state roots ahead of diffs of IO roots in
+	// case the fetch pool is saturated. State roots are chained, so their diffs are on the
+	// critical path of applying and finalizing rounds in order, while IO diffs are not.
+	PrioritizeStateFetches bool
+
+	// CheckpointRestoreLagThreshold is the number of rounds by which the last finalized round may
+	// fall behind the latest round while the worker is running before incremental sync is
+	// abandoned in favor of restoring the state from a checkpoint, after which incremental sync
+	// resumes from the checkpoint round. Blocks received during the restore are not lost. Zero
+	// disables restoring from checkpoints after startup.
+	CheckpointRestoreLagThreshold uint64
+
+	// CatchUpAndExit enables the one-shot mode, in which the worker syncs up to the latest round
+	// at the time syncing starts (or up to the sync ceiling, if lower) and then exits instead of
+	// continuing to follow the chain.
+	CatchUpAndExit bool
+
+	// ObserverMode enables running the worker without local storage. In observer mode the worker
+	// only tracks the sync position from the headers of new blocks and never fetches, applies or
+	// finalizes any state, nor does it serve storage to peers. Nodes that do not have a local
+	// storage backend can only be created in observer mode.
+	ObserverMode bool
+
+	// BlockSource is an optional alternate source of runtime blocks (e.g., a local archive) used
+	// instead of the consensus roothash service. In case it is nil, the consensus roothash service
+	// is used.
+	BlockSource BlockSource
+
+	// StorageSyncClient is an optional storage sync client used to fetch diffs and checkpoints
+	// from peers, e.g., one shared by consecutive nodes of the same runtime. In case it is nil, a
+	// new client is created using NewStorageSyncClient.
+	StorageSyncClient storageSync.Client
+
+	// DisableRemoteClient disables the storage sync client, so that the worker never fetches diffs
+	// or checkpoints from remote nodes and only replays state that is available locally (e.g.,
+	// cached or previously applied diffs). Rounds whose roots are not available locally are
+	// reported as fatal sync errors. Storage is still served to peers.
+	DisableRemoteClient bool
+}
+
+// Validate performs configuration checks.
+func (cfg *Config) Validate() error {
+	if cfg.BlockWatchdogInterval < 0 {
+		return fmt.Errorf("block watchdog interval must not be negative")
+	}
+	if cfg.LoopWatchdogInterval < 0 {
+		return fmt.Errorf("loop watchdog interval must not be negative")
+	}
+	if cfg.RetryWarningThreshold < 0 {
+		return fmt.Errorf("retry warning threshold must not be negative")
+	}
+	if cfg.ConsistencyCheckInterval < 0 {
+		return fmt.Errorf("consistency check interval must not be negative")
+	}
+	if cfg.ScrubInterval < 0 {
+		return fmt.Errorf("scrub interval must not be negative")
+	}
+	if cfg.ScrubRecheckInterval < 0 {
+		return fmt.Errorf("scrub recheck interval must not be negative")
+	}
+	if cfg.BlockBacklogSampleInterval < 0 {
+		return fmt.Errorf("block backlog sample interval must not be negative")
+	}
+	if cfg.FinalizeRetryInterval < 0 {
+		return fmt.Errorf("finalize retry interval must not be negative")
+	}
+	if cfg.FinalizeGracePeriod < 0 {
+		return fmt.Errorf("finalize grace period must not be negative")
+	}
+	if cfg.GenesisBlockRetryInterval < 0 {
+		return fmt.Errorf("genesis block retry interval must not be negative")
+	}
+	if cfg.DiffStreamIdleTimeout < 0 {
+		return fmt.Errorf("diff stream idle timeout must not be negative")
+	}
+	if cfg.DisableRemoteClient && cfg.StorageSyncClient != nil {
+		return fmt.Errorf("storage sync client must not be configured when the remote client is disabled")
+	}
+	if cfg.Tunables != nil {
+		if err := cfg.Tunables.Validate(); err != nil {
+			return fmt.Errorf("bad tunables: %w", err)
+		}
+	}
+	for runtimeID, tunables := range cfg.RuntimeTunables {
+		if err := tunables.Validate(); err != nil {
+			return fmt.Errorf("bad tunables for runtime %s: %w", runtimeID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package committee
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+// compareRoots checks whether the roots reported by a peer are consistent with the given local
+// roots. The checked flag is false in case the peer does not have any roots for the version (e.g.,
+// because it has not yet synced it or has already pruned it) and so cannot be compared.
+func compareRoots(localRoots, peerRoots []storageApi.Root) (checked, consistent bool) {
+	if len(peerRoots) == 0 {
+		return false, false
+	}
+
+	for _, localRoot := range localRoots {
+		// Empty roots are never reported as they are implicitly present.
+		if localRoot.Hash.IsEmpty() {
+			continue
+		}
+
+		var found bool
+		for _, peerRoot := range peerRoots {
+			if peerRoot.Type == localRoot.Type && peerRoot.Hash.Equal(&localRoot.Hash) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return true, false
+		}
+	}
+	return true, true
+}
+
+// consistencyChecker periodically compares the roots of a recently finalized round against the
+// roots reported by remote peers.
+func (n *Node) consistencyChecker() {
+	// Wait for the common node to be initialized.
+	select {
+	case <-n.commonNode.Initialized():
+	case <-n.ctx.Done():
+		return
+	}
+
+	ticker := time.NewTicker(n.cfg.ConsistencyCheckInterval)
+	defer ticker.Stop()
+
+	// To give remote peers enough time to finalize the same round, the round checked in each
+	// iteration is the one that was last synced during the previous iteration.
+	var candidate *blockSummary
+	for {
+		select {
+		case <-n.quitCh:
+			return
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if candidate != nil {
+			n.checkConsistency(candidate)
+		}
+
+		n.syncedLock.RLock()
+		if n.syncedState.Round != n.undefinedRound && len(n.syncedState.Roots) > 0 {
+			summary := n.syncedState
+			candidate = &summary
+		}
+		n.syncedLock.RUnlock()
+	}
+}
+
+func (n *Node) checkConsistency(summary *blockSummary) {
+	// Skip rounds that have already been pruned locally.
+	if summary.Round < n.localStorage.NodeDB().GetEarliestVersion() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(n.ctx, n.cfg.ConsistencyCheckInterval)
+	defer cancel()
+
+	peerRoots, err := n.storageSync.GetRoots(ctx, &storageSync.GetRootsRequest{
+		Version: summary.Round,
+	})
+	if err != nil {
+		n.logger.Warn("failed to fetch roots from peers for consistency check",
+			"err", err,
+			"round", summary.Round,
+		)
+		return
+	}
+
+	var (
+		numChecked int
+		divergent  []core.PeerID
+	)
+	for _, pr := range peerRoots {
+		checked, consistent := compareRoots(summary.Roots, pr.Roots)
+		if !checked {
+			continue
+		}
+		numChecked++
+		if !consistent {
+			divergent = append(divergent, pr.Peer.PeerID())
+		}
+	}
+
+	switch {
+	case numChecked == 0:
+		n.logger.Debug("no peers available for consistency check",
+			"round", summary.Round,
+		)
+	case len(divergent) > 0:
+		n.logger.Error("local roots diverge from roots reported by peers",
+			"round", summary.Round,
+			"roots", summary.Roots,
+			"num_checked", numChecked,
+			"divergent_peers", divergent,
+		)
+
+		syncErr := newSyncError(ErrRootDivergence, summary.Round, storageApi.RootTypeInvalid, nil)
+		syncErr.Peers = divergent
+		n.reportSyncError(syncErr)
+	default:
+		n.logger.Debug("consistency check passed",
+			"round", summary.Round,
+			"num_checked", numChecked,
+		)
+	}
+}
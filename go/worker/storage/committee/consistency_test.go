@@ -0,0 +1,123 @@
+package committee
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+type testPeerFeedback struct {
+	peerID core.PeerID
+}
+
+func (pf *testPeerFeedback) RecordSuccess()      {}
+func (pf *testPeerFeedback) RecordFailure()      {}
+func (pf *testPeerFeedback) RecordBadPeer()      {}
+func (pf *testPeerFeedback) PeerID() core.PeerID { return pf.peerID }
+
+type testRootsClient struct {
+	storageSync.Client
+
+	roots map[core.PeerID][]storageApi.Root
+}
+
+func (c *testRootsClient) GetRoots(ctx context.Context, request *storageSync.GetRootsRequest) ([]*storageSync.PeerRoots, error) {
+	var rsp []*storageSync.PeerRoots
+	for peerID, roots := range c.roots {
+		rsp = append(rsp, &storageSync.PeerRoots{
+			Roots: roots,
+			Peer:  &testPeerFeedback{peerID},
+		})
+	}
+	return rsp, nil
+}
+
+var _ rpc.PeerFeedback = (*testPeerFeedback)(nil)
+
+func TestCompareRoots(t *testing.T) {
+	require := require.New(t)
+
+	var ioRoot, stateRoot, otherRoot storageApi.Root
+	ioRoot.Type = storageApi.RootTypeIO
+	ioRoot.Hash.FromBytes([]byte("io root"))
+	stateRoot.Type = storageApi.RootTypeState
+	stateRoot.Hash.FromBytes([]byte("state root"))
+	otherRoot.Type = storageApi.RootTypeState
+	otherRoot.Hash.FromBytes([]byte("other state root"))
+	emptyIORoot := storageApi.Root{Type: storageApi.RootTypeIO}
+	emptyIORoot.Hash.Empty()
+	// Same hash as the state root, but a different type.
+	wrongTypeRoot := stateRoot
+	wrongTypeRoot.Type = storageApi.RootTypeIO
+
+	for _, tc := range []struct {
+		name       string
+		local      []storageApi.Root
+		peer       []storageApi.Root
+		checked    bool
+		consistent bool
+	}{
+		{"NoPeerRoots", []storageApi.Root{ioRoot, stateRoot}, nil, false, false},
+		{"Equal", []storageApi.Root{ioRoot, stateRoot}, []storageApi.Root{stateRoot, ioRoot}, true, true},
+		{"PeerSuperset", []storageApi.Root{ioRoot, stateRoot}, []storageApi.Root{otherRoot, stateRoot, ioRoot}, true, true},
+		{"EmptyLocalRoot", []storageApi.Root{emptyIORoot, stateRoot}, []storageApi.Root{stateRoot}, true, true},
+		{"Divergent", []storageApi.Root{ioRoot, stateRoot}, []storageApi.Root{ioRoot, otherRoot}, true, false},
+		{"WrongType", []storageApi.Root{stateRoot}, []storageApi.Root{wrongTypeRoot}, true, false},
+	} {
+		checked, consistent := compareRoots(tc.local, tc.peer)
+		require.Equal(tc.checked, checked, "checked (%s)", tc.name)
+		require.Equal(tc.consistent, consistent, "consistent (%s)", tc.name)
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.cfg = &Config{ConsistencyCheckInterval: time.Second}
+	n.syncErrNotifier = pubsub.NewBroker(false)
+
+	s := newTestState(t, n)
+	summary := s.advance()
+
+	divergentRoot := s.root
+	divergentRoot.Hash.FromBytes([]byte("divergent"))
+	client := &testRootsClient{
+		roots: map[core.PeerID][]storageApi.Root{
+			"good":      {s.root},
+			"behind":    nil,
+			"divergent": {divergentRoot},
+		},
+	}
+	n.storageSync = client
+
+	errCh, sub := n.WatchSyncErrors()
+	defer sub.Close()
+
+	n.checkConsistency(summary)
+	select {
+	case syncErr := <-errCh:
+		require.True(errors.Is(syncErr, ErrRootDivergence), "sync error should be a root divergence")
+		require.EqualValues(summary.Round, syncErr.Round)
+		require.Equal([]core.PeerID{"divergent"}, syncErr.Peers, "only divergent peers should be reported")
+	case <-time.After(time.Second):
+		require.Fail("root divergence should be reported")
+	}
+
+	// No errors should be reported when all peers are consistent.
+	delete(client.roots, "divergent")
+	n.checkConsistency(summary)
+	select {
+	case syncErr := <-errCh:
+		require.Fail("unexpected sync error", "err", syncErr)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
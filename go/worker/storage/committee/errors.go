@@ -0,0 +1,116 @@
+package committee
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+var (
+	// ErrDiffFetchFailed is the error returned when a diff could not be fetched from remote nodes.
+	ErrDiffFetchFailed = errors.New("storage: failed to fetch diff")
+	// ErrApplyMismatch is the error returned when applying a fetched write log does not result in
+	// the expected root.
+	ErrApplyMismatch = errors.New("storage: applied write log does not match expected root")
+	// ErrApplyNamespaceMismatch is the error returned when a fetched write log would be applied to
+	// roots that do not belong to the runtime that is being synced.
+	ErrApplyNamespaceMismatch = errors.New("storage: write log roots do not belong to runtime")
+	// ErrApplyFailed is the error returned when a fetched write log could not be applied.
+	ErrApplyFailed = errors.New("storage: failed to apply write log")
+	// ErrFinalizeFailed is the error returned when a fully synced round could not be finalized.
+	ErrFinalizeFailed = errors.New("storage: failed to finalize round")
+	// ErrBacklogTooLarge is the error returned when the number of rounds in flight reached the
+	// limit and further rounds will only be fetched once earlier ones are applied.
+	ErrBacklogTooLarge = errors.New("storage: too many rounds in flight")
+	// ErrRootDivergence is the error returned when the consistency check detects that some remote
+	// nodes have different roots for a finalized round than the local node.
+	ErrRootDivergence = errors.New("storage: roots diverge from remote nodes")
+	// ErrRoundQuarantined is the error returned when a round has been quarantined after its
+	// diffs repeatedly failed to apply and syncing will not proceed without operator
+	// intervention.
+	ErrRoundQuarantined = errors.New("storage: round quarantined after repeated apply mismatches")
+	// ErrNamespaceMismatch is the error returned when a block does not belong to the runtime that
+	// is being synced (e.g., due to a misconfiguration).
+	ErrNamespaceMismatch = errors.New("storage: block namespace does not match runtime")
+	// ErrLocalStateCorrupted is the error returned when the background scrubber finds that the
+	// local state of a finalized round is missing or corrupted.
+	ErrLocalStateCorrupted = errors.New("storage: local state is corrupted")
+	// ErrBlockVerificationFailed is the error returned when an incoming block could not be verified
+	// and is not used for syncing.
+	ErrBlockVerificationFailed = errors.New("storage: block verification failed")
+	// ErrRoundNotFinalized is the error returned when the state of a round is requested that has
+	// not been finalized in local storage (e.g., as it has not been synced yet or was pruned).
+	ErrRoundNotFinalized = errors.New("storage: round not finalized locally")
+	// ErrDiffUnavailable is the error returned when a diff is not available locally and can't be
+	// fetched as the remote storage client is disabled.
+	ErrDiffUnavailable = errors.New("storage: diff not available locally")
+)
+
+// SyncError is an error encountered by the storage worker while syncing a round.
+//
+// Use errors.Is with one of the Err* sentinel errors to determine the kind of failure.
+type SyncError struct {
+	// Kind is the sentinel error describing the kind of failure.
+	Kind error
+	// Round is the round that was being synced.
+	Round uint64
+	// RootType is the type of the root that was being synced (if applicable).
+	RootType storageApi.RootType
+	// Cause is the underlying error (if any).
+	Cause error
+	// Peers are the remote peers involved in the failure (if applicable).
+	Peers []core.PeerID
+}
+
+// Error returns a string representation of the sync error.
+func (e *SyncError) Error() string {
+	msg := fmt.Sprintf("%s (round: %d", e.Kind, e.Round)
+	if e.RootType != storageApi.RootTypeInvalid {
+		msg += fmt.Sprintf(", root type: %s", e.RootType)
+	}
+	if len(e.Peers) > 0 {
+		msg += fmt.Sprintf(", peers: %v", e.Peers)
+	}
+	msg += ")"
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+// Is returns true if the target error is the kind of this sync error.
+func (e *SyncError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Unwrap returns the underlying error.
+func (e *SyncError) Unwrap() error {
+	return e.Cause
+}
+
+// IsFatal returns true if the sync error is unlikely to be resolved by retrying and sync cannot
+// proceed without intervention.
+func (e *SyncError) IsFatal() bool {
+	return errors.Is(e.Kind, ErrFinalizeFailed) || errors.Is(e.Kind, ErrRoundQuarantined) || errors.Is(e.Kind, ErrDiffUnavailable)
+}
+
+func newSyncError(kind error, round uint64, rootType storageApi.RootType, cause error) *SyncError {
+	return &SyncError{
+		Kind:     kind,
+		Round:    round,
+		RootType: rootType,
+		Cause:    cause,
+	}
+}
+
+// IsFatalSyncError returns true if the given error is a fatal storage sync error.
+func IsFatalSyncError(err error) bool {
+	var syncErr *SyncError
+	if !errors.As(err, &syncErr) {
+		return false
+	}
+	return syncErr.IsFatal()
+}
@@ -0,0 +1,58 @@
+package committee
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestSyncErrorClassification(t *testing.T) {
+	require := require.New(t)
+
+	cause := errors.New("peer went away")
+	for _, tc := range []struct {
+		kind  error
+		fatal bool
+	}{
+		{ErrDiffFetchFailed, false},
+		{ErrApplyMismatch, false},
+		{ErrApplyFailed, false},
+		{ErrFinalizeFailed, true},
+		{ErrBacklogTooLarge, false},
+		{ErrRootDivergence, false},
+		{ErrRoundQuarantined, true},
+		{ErrDiffUnavailable, true},
+	} {
+		syncErr := newSyncError(tc.kind, 42, storageApi.RootTypeState, cause)
+		require.True(errors.Is(syncErr, tc.kind), "sync error should match its kind")
+		require.True(errors.Is(syncErr, cause), "sync error should match its cause")
+		require.Equal(tc.fatal, syncErr.IsFatal(), "fatality of %s", tc.kind)
+
+		// Classification should also work through wrapping.
+		wrapped := fmt.Errorf("supervisor: %w", syncErr)
+		require.True(errors.Is(wrapped, tc.kind), "wrapped sync error should match its kind")
+		require.Equal(tc.fatal, IsFatalSyncError(wrapped), "fatality of wrapped %s", tc.kind)
+
+		var asSyncErr *SyncError
+		require.True(errors.As(wrapped, &asSyncErr), "wrapped sync error should be extractable")
+		require.EqualValues(42, asSyncErr.Round)
+		require.Equal(storageApi.RootTypeState, asSyncErr.RootType)
+
+		for _, other := range []error{ErrDiffFetchFailed, ErrApplyMismatch, ErrApplyFailed, ErrFinalizeFailed, ErrBacklogTooLarge, ErrRootDivergence, ErrRoundQuarantined, ErrDiffUnavailable} {
+			if other == tc.kind {
+				continue
+			}
+			require.False(errors.Is(syncErr, other), "sync error of kind %s should not match %s", tc.kind, other)
+		}
+	}
+
+	require.False(IsFatalSyncError(cause), "non-sync errors should not be fatal sync errors")
+	require.Equal(
+		"storage: failed to finalize round (round: 10): peer went away",
+		newSyncError(ErrFinalizeFailed, 10, storageApi.RootTypeInvalid, cause).Error(),
+	)
+}
@@ -0,0 +1,116 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// eventSinkBufferSize is the number of events that are buffered for the event sink. In case the
+// buffer is full (i.e. the sink is too slow), further events are dropped.
+const eventSinkBufferSize = 1024
+
+// EventKind is the kind of a storage sync lifecycle event.
+type EventKind uint8
+
+const (
+	// EventRoundFetched is emitted after the diff for one of the roots of a round has been fetched.
+	EventRoundFetched EventKind = iota + 1
+	// EventRoundApplied is emitted after all roots of a round have been applied to local storage.
+	EventRoundApplied
+	// EventRoundFinalized is emitted after a round has been finalized.
+	EventRoundFinalized
+	// EventSyncError is emitted for each error encountered while syncing a round.
+	EventSyncError
+)
+
+// String returns a string representation of the event kind.
+func (k EventKind) String() string {
+	switch k {
+	case EventRoundFetched:
+		return "round_fetched"
+	case EventRoundApplied:
+		return "round_applied"
+	case EventRoundFinalized:
+		return "round_finalized"
+	case EventSyncError:
+		return "sync_error"
+	default:
+		return fmt.Sprintf("[unknown: %d]", uint8(k))
+	}
+}
+
+// Event is a storage sync lifecycle event.
+type Event struct {
+	// Kind is the kind of the event.
+	Kind EventKind
+	// Round is the round the event refers to.
+	Round uint64
+	// RootType is the type of the root the event refers to, in case the event refers to a single
+	// root of the round (otherwise it is RootTypeInvalid).
+	RootType storageApi.RootType
+	// Err is the sync error in case of EventSyncError events.
+	Err *SyncError
+}
+
+// EventSink receives storage sync lifecycle events (e.g., to forward them to an external event
+// bus).
+//
+// Events are delivered in order from a single goroutine that is separate from the sync loop, so
+// a slow sink does not stall syncing. In case the sink falls too far behind, events are dropped.
+type EventSink interface {
+	// HandleEvent handles the given event.
+	HandleEvent(ev *Event)
+}
+
+// eventDispatcher buffers events and delivers them to the event sink.
+type eventDispatcher struct {
+	sink    EventSink
+	eventCh chan *Event
+
+	dropped prometheus.Counter
+}
+
+func newEventDispatcher(sink EventSink, labels prometheus.Labels) *eventDispatcher {
+	return &eventDispatcher{
+		sink:    sink,
+		eventCh: make(chan *Event, eventSinkBufferSize),
+		dropped: storageWorkerDroppedEvents.With(labels),
+	}
+}
+
+// run delivers buffered events to the sink until the given context is canceled.
+func (d *eventDispatcher) run(ctx context.Context) {
+	for {
+		select {
+		case ev := <-d.eventCh:
+			d.sink.HandleEvent(ev)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// emit queues the given event for delivery without blocking.
+func (d *eventDispatcher) emit(ev *Event) {
+	select {
+	case d.eventCh <- ev:
+	default:
+		d.dropped.Inc()
+	}
+}
+
+// emitEvent emits the given event in case an event sink is configured.
+func (n *Node) emitEvent(kind EventKind, round uint64, rootType storageApi.RootType) {
+	if n.events == nil {
+		return
+	}
+	n.events.emit(&Event{
+		Kind:     kind,
+		Round:    round,
+		RootType: rootType,
+	})
+}
@@ -0,0 +1,94 @@
+package committee
+
+import (
+	"fmt"
+	"strings"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// FetchOrdering defines the order in which the diff fetches of the rounds being synced are
+// submitted to the fetch queue. It only affects how evenly the roots of different types progress
+// while catching up, rounds are always applied and finalized in order.
+type FetchOrdering uint8
+
+const (
+	// FetchOldestFirst submits the fetches of all roots of a round before those of the next
+	// scheduled round (which is the next later round unless near-tip rounds are prioritized).
+	// This is the default.
+	FetchOldestFirst FetchOrdering = iota
+	// FetchRoundRobin alternates between the root types when submitting fetches, each type in
+	// round order, so that the fetches of one type do not queue up behind those of the other
+	// (e.g., when the fetches of one type are being retried).
+	FetchRoundRobin
+)
+
+const (
+	// FetchOrderingOldestFirst is the name of the FetchOldestFirst fetch ordering.
+	FetchOrderingOldestFirst = "oldest_first"
+	// FetchOrderingRoundRobin is the name of the FetchRoundRobin fetch ordering.
+	FetchOrderingRoundRobin = "round_robin"
+)
+
+// String returns a string representation of the fetch ordering.
+func (o FetchOrdering) String() string {
+	switch o {
+	case FetchOldestFirst:
+		return FetchOrderingOldestFirst
+	case FetchRoundRobin:
+		return FetchOrderingRoundRobin
+	default:
+		return fmt.Sprintf("[unknown: %d]", uint8(o))
+	}
+}
+
+// NewFetchOrdering returns the fetch ordering with the given name.
+func NewFetchOrdering(name string) (FetchOrdering, error) {
+	switch strings.ToLower(name) {
+	case FetchOrderingOldestFirst:
+		return FetchOldestFirst, nil
+	case FetchOrderingRoundRobin:
+		return FetchRoundRobin, nil
+	default:
+		return 0, fmt.Errorf("unsupported fetch ordering: '%s'", name)
+	}
+}
+
+// scheduledFetch is a diff fetch that has been scheduled but not yet submitted to the fetch queue.
+type scheduledFetch struct {
+	round    uint64
+	rootType storageApi.RootType
+	priority FetchPriority
+	job      func()
+}
+
+// orderFetches returns the given scheduled fetches in the order in which they should be submitted.
+// The fetches are given in the order in which their rounds were scheduled.
+func orderFetches(fetches []scheduledFetch, ordering FetchOrdering) []scheduledFetch {
+	if ordering != FetchRoundRobin || len(fetches) == 0 {
+		return fetches
+	}
+
+	// Split the fetches by root type, in order of first appearance of each type.
+	var (
+		types  []storageApi.RootType
+		byType = make(map[storageApi.RootType][]scheduledFetch)
+	)
+	for _, fetch := range fetches {
+		if _, ok := byType[fetch.rootType]; !ok {
+			types = append(types, fetch.rootType)
+		}
+		byType[fetch.rootType] = append(byType[fetch.rootType], fetch)
+	}
+
+	ordered := make([]scheduledFetch, 0, len(fetches))
+	for len(ordered) < len(fetches) {
+		for _, rootType := range types {
+			if queue := byType[rootType]; len(queue) > 0 {
+				ordered = append(ordered, queue[0])
+				byType[rootType] = queue[1:]
+			}
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,66 @@
+package committee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestNewFetchOrdering(t *testing.T) {
+	require := require.New(t)
+
+	for _, ordering := range []FetchOrdering{FetchOldestFirst, FetchRoundRobin} {
+		parsed, err := NewFetchOrdering(ordering.String())
+		require.NoError(err, "NewFetchOrdering(%s)", ordering)
+		require.Equal(ordering, parsed)
+	}
+	_, err := NewFetchOrdering("newest_first")
+	require.Error(err, "unsupported fetch ordering should fail")
+}
+
+func TestOrderFetches(t *testing.T) {
+	require := require.New(t)
+
+	type fetchKey struct {
+		round    uint64
+		rootType storageApi.RootType
+	}
+	const (
+		io    = storageApi.RootTypeIO
+		state = storageApi.RootTypeState
+	)
+	// State diffs of rounds 1-3 are being retried, while rounds 4 and 5 are new.
+	scheduled := []fetchKey{
+		{1, state}, {2, state}, {3, state},
+		{4, io}, {4, state},
+		{5, io}, {5, state},
+	}
+	fetches := make([]scheduledFetch, 0, len(scheduled))
+	for _, key := range scheduled {
+		fetches = append(fetches, scheduledFetch{round: key.round, rootType: key.rootType})
+	}
+
+	for _, tc := range []struct {
+		ordering FetchOrdering
+		expected []fetchKey
+	}{
+		{FetchOldestFirst, scheduled},
+		{FetchRoundRobin, []fetchKey{
+			{1, state}, {4, io},
+			{2, state}, {5, io},
+			{3, state},
+			{4, state},
+			{5, state},
+		}},
+	} {
+		var submitted []fetchKey
+		for _, fetch := range orderFetches(fetches, tc.ordering) {
+			submitted = append(submitted, fetchKey{fetch.round, fetch.rootType})
+		}
+		require.Equal(tc.expected, submitted, "submission order for %s", tc.ordering)
+	}
+
+	require.Empty(orderFetches(nil, FetchRoundRobin), "no fetches")
+}
@@ -0,0 +1,249 @@
+package committee
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/workerpool"
+)
+
+const (
+	// DefaultFetchWeight is the fetch weight used for runtimes without a configured weight.
+	DefaultFetchWeight = uint64(1)
+
+	// fetchStrideScale is the scale used when computing per-runtime strides. It should be large
+	// enough so that strides for all reasonable weights are distinct.
+	fetchStrideScale = uint64(1 << 32)
+)
+
+// FetchPriority is a priority hint for jobs submitted to the fetch queue.
+type FetchPriority uint8
+
+const (
+	// FetchPriorityNormal is the priority of regular jobs.
+	FetchPriorityNormal FetchPriority = iota
+	// FetchPriorityHigh is the priority of jobs that are dispatched ahead of the runtime's pending
+	// regular jobs in case of contention.
+	FetchPriorityHigh
+)
+
+type fetchQueueRuntime struct {
+	weight       uint64
+	pass         uint64
+	jobs         []func()
+	priorityJobs []func()
+}
+
+func (r *fetchQueueRuntime) pending() int {
+	return len(r.jobs) + len(r.priorityJobs)
+}
+
+func (r *fetchQueueRuntime) popJob() func() {
+	queue := &r.jobs
+	if len(r.priorityJobs) > 0 {
+		queue = &r.priorityJobs
+	}
+	job := (*queue)[0]
+	(*queue)[0] = nil
+	*queue = (*queue)[1:]
+	return job
+}
+
+func (r *fetchQueueRuntime) stride() uint64 {
+	return fetchStrideScale / r.weight
+}
+
+// FetchQueue is a weighted fair queue in front of a worker pool that is shared between multiple
+// runtimes. At most capacity jobs are submitted to the pool at any given time and, in case of
+// contention, the next job is selected using stride scheduling so that each runtime receives a
+// share of the pool proportional to its weight.
+type FetchQueue struct {
+	lock sync.Mutex
+
+	pool     *workerpool.Pool
+	capacity uint
+	inFlight uint
+
+	// globalPass is the pass value of the last dispatched job. It is used to make sure that
+	// runtimes that were idle for a while don't accumulate credit.
+	globalPass uint64
+	runtimes   map[common.Namespace]*fetchQueueRuntime
+
+	// unstarted are the jobs that have been submitted to the pool, but not yet started by it.
+	// They are run directly once the pool quits, as the pool drops them when stopped.
+	unstarted map[uint64]func()
+	nextJobID uint64
+}
+
+// SetWeight configures the fetch weight for the given runtime.
+func (q *FetchQueue) SetWeight(runtimeID common.Namespace, weight uint64) error {
+	if weight == 0 {
+		return fmt.Errorf("fetch weight for runtime %s must be greater than zero", runtimeID)
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.getRuntimeLocked(runtimeID).weight = weight
+	return nil
+}
+
+// Submit queues a job for the given runtime. The job will be submitted to the underlying worker
+// pool once there is capacity available and it is the runtime's turn.
+func (q *FetchQueue) Submit(runtimeID common.Namespace, job func()) {
+	q.SubmitWithPriority(runtimeID, FetchPriorityNormal, job)
+}
+
+// SubmitWithPriority queues a job for the given runtime with the given priority hint. When it is
+// the runtime's turn, its pending high priority jobs are submitted to the underlying worker pool
+// before any of its pending regular jobs. The priority does not affect sharing of the pool among
+// runtimes.
+func (q *FetchQueue) SubmitWithPriority(runtimeID common.Namespace, priority FetchPriority, job func()) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	rt := q.getRuntimeLocked(runtimeID)
+	if rt.pending() == 0 && rt.pass < q.globalPass {
+		// Runtime was idle, make sure it doesn't get to use the accumulated credit.
+		rt.pass = q.globalPass
+	}
+	switch priority {
+	case FetchPriorityHigh:
+		rt.priorityJobs = append(rt.priorityJobs, job)
+	default:
+		rt.jobs = append(rt.jobs, job)
+	}
+
+	q.dispatchLocked()
+}
+
+func (q *FetchQueue) getRuntimeLocked(runtimeID common.Namespace) *fetchQueueRuntime {
+	rt, ok := q.runtimes[runtimeID]
+	if !ok {
+		rt = &fetchQueueRuntime{
+			weight: DefaultFetchWeight,
+			pass:   q.globalPass,
+		}
+		q.runtimes[runtimeID] = rt
+	}
+	return rt
+}
+
+func (q *FetchQueue) dispatchLocked() {
+	for q.inFlight < q.capacity {
+		// Select the runtime with pending jobs and the lowest pass value.
+		var next *fetchQueueRuntime
+		for _, rt := range q.runtimes {
+			if rt.pending() == 0 {
+				continue
+			}
+			if next == nil || rt.pass < next.pass {
+				next = rt
+			}
+		}
+		if next == nil {
+			return
+		}
+
+		job := next.popJob()
+		q.globalPass = next.pass
+		next.pass += next.stride()
+
+		id := q.nextJobID
+		q.nextJobID++
+		q.unstarted[id] = job
+		q.inFlight++
+		if q.pool.Submit(func() {
+			if !q.startJob(id) {
+				return
+			}
+			defer q.jobDone()
+			job()
+		}) == nil {
+			// Pool has been stopped, so neither this job nor any of the queued jobs will be
+			// executed by it. Run them directly instead so that anything waiting for them to
+			// complete is released.
+			delete(q.unstarted, id)
+			q.inFlight--
+			runJobs(append([]func(){job}, q.drainLocked()...))
+			return
+		}
+	}
+}
+
+// startJob marks the given submitted job as started and returns true in case it should be run by
+// the pool, i.e. it has not already been taken over after the pool quit.
+func (q *FetchQueue) startJob(id uint64) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if _, ok := q.unstarted[id]; !ok {
+		return false
+	}
+	delete(q.unstarted, id)
+	return true
+}
+
+// drainLocked removes and returns all pending jobs of all runtimes.
+func (q *FetchQueue) drainLocked() []func() {
+	var jobs []func()
+	for _, rt := range q.runtimes {
+		for rt.pending() > 0 {
+			jobs = append(jobs, rt.popJob())
+		}
+	}
+	return jobs
+}
+
+// watchPool runs the jobs that the pool dropped when it was stopped, together with any queued
+// jobs, once the pool quits.
+func (q *FetchQueue) watchPool() {
+	<-q.pool.Quit()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	jobs := q.drainLocked()
+	for id, job := range q.unstarted {
+		jobs = append(jobs, job)
+		delete(q.unstarted, id)
+	}
+	runJobs(jobs)
+}
+
+func (q *FetchQueue) jobDone() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.inFlight--
+	q.dispatchLocked()
+}
+
+// NewFetchQueue creates a new weighted fair queue in front of the given worker pool, allowing at
+// most capacity concurrently submitted jobs. The capacity should match the pool size.
+func NewFetchQueue(pool *workerpool.Pool, capacity uint) *FetchQueue {
+	if capacity == 0 {
+		capacity = 1
+	}
+	q := &FetchQueue{
+		pool:      pool,
+		capacity:  capacity,
+		runtimes:  make(map[common.Namespace]*fetchQueueRuntime),
+		unstarted: make(map[uint64]func()),
+	}
+	go q.watchPool()
+	return q
+}
+
+// runJobs runs the given jobs in the background, outside of the pool.
+func runJobs(jobs []func()) {
+	if len(jobs) == 0 {
+		return
+	}
+	go func() {
+		for _, job := range jobs {
+			job()
+		}
+	}()
+}
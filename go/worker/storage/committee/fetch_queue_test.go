@@ -0,0 +1,216 @@
+package committee
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/workerpool"
+)
+
+func TestFetchQueueWeights(t *testing.T) {
+	require := require.New(t)
+
+	pool := workerpool.New("storage_fetch_test")
+	defer pool.Stop()
+	queue := NewFetchQueue(pool, 1)
+
+	rtA := common.NewTestNamespaceFromSeed([]byte("fetch queue test runtime A"), 0)
+	rtB := common.NewTestNamespaceFromSeed([]byte("fetch queue test runtime B"), 0)
+	rtC := common.NewTestNamespaceFromSeed([]byte("fetch queue test runtime C"), 0)
+	require.NoError(queue.SetWeight(rtA, 3))
+	require.NoError(queue.SetWeight(rtB, 1))
+	require.Error(queue.SetWeight(rtC, 0), "zero weight should be rejected")
+
+	// Block the pool so that all subsequently submitted jobs are queued.
+	gateCh := make(chan struct{})
+	queue.Submit(rtC, func() {
+		<-gateCh
+	})
+
+	const numJobs = 100
+	var (
+		lock  sync.Mutex
+		order []common.Namespace
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < numJobs; i++ {
+		for _, id := range []common.Namespace{rtA, rtB} {
+			id := id
+			wg.Add(1)
+			queue.Submit(id, func() {
+				defer wg.Done()
+				lock.Lock()
+				order = append(order, id)
+				lock.Unlock()
+			})
+		}
+	}
+	close(gateCh)
+	wg.Wait()
+
+	// While both runtimes have pending jobs, allocation should follow the configured weights.
+	var countA int
+	for _, id := range order[:numJobs] {
+		if id.Equal(&rtA) {
+			countA++
+		}
+	}
+	require.InDelta(75, countA, 2, "runtime A should get approximately 3/4 of the fetches under contention")
+	require.Len(order, 2*numJobs, "all jobs should be executed")
+}
+
+func TestFetchQueueIdleCredit(t *testing.T) {
+	require := require.New(t)
+
+	pool := workerpool.New("storage_fetch_test")
+	defer pool.Stop()
+	queue := NewFetchQueue(pool, 1)
+
+	rtA := common.NewTestNamespaceFromSeed([]byte("fetch queue test runtime A"), 0)
+	rtB := common.NewTestNamespaceFromSeed([]byte("fetch queue test runtime B"), 0)
+
+	// Runtime A runs alone for a while.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		queue.Submit(rtA, wg.Done)
+	}
+	wg.Wait()
+
+	// Once runtime B becomes active, it should not be able to monopolize the pool.
+	gateCh := make(chan struct{})
+	queue.Submit(rtA, func() {
+		<-gateCh
+	})
+	var (
+		lock  sync.Mutex
+		order []common.Namespace
+	)
+	for i := 0; i < 10; i++ {
+		for _, id := range []common.Namespace{rtA, rtB} {
+			id := id
+			wg.Add(1)
+			queue.Submit(id, func() {
+				defer wg.Done()
+				lock.Lock()
+				order = append(order, id)
+				lock.Unlock()
+			})
+		}
+	}
+	close(gateCh)
+	wg.Wait()
+
+	var countB int
+	for _, id := range order[:10] {
+		if id.Equal(&rtB) {
+			countB++
+		}
+	}
+	require.InDelta(5, countB, 1, "previously idle runtime should not accumulate credit")
+}
+
+func TestFetchQueuePriority(t *testing.T) {
+	require := require.New(t)
+
+	pool := workerpool.New("storage_fetch_test")
+	defer pool.Stop()
+	queue := NewFetchQueue(pool, 1)
+
+	rt := common.NewTestNamespaceFromSeed([]byte("fetch queue test runtime A"), 0)
+
+	// Block the pool so that all subsequently submitted jobs are queued.
+	gateCh := make(chan struct{})
+	queue.Submit(rt, func() {
+		<-gateCh
+	})
+
+	const numRounds = 10
+	var (
+		lock  sync.Mutex
+		order []FetchPriority
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < numRounds; i++ {
+		// Submit in the same order as the worker does for each round, IO first.
+		for _, priority := range []FetchPriority{FetchPriorityNormal, FetchPriorityHigh} {
+			priority := priority
+			wg.Add(1)
+			queue.SubmitWithPriority(rt, priority, func() {
+				defer wg.Done()
+				lock.Lock()
+				order = append(order, priority)
+				lock.Unlock()
+			})
+		}
+	}
+	close(gateCh)
+	wg.Wait()
+
+	require.Len(order, 2*numRounds, "all jobs should be executed")
+	for i, priority := range order {
+		expected := FetchPriorityNormal
+		if i < numRounds {
+			expected = FetchPriorityHigh
+		}
+		require.Equal(expected, priority, "high priority jobs should run ahead of regular jobs (job %d)", i)
+	}
+}
+
+func TestFetchQueueStoppedPool(t *testing.T) {
+	require := require.New(t)
+
+	pool := workerpool.New("storage_fetch_test")
+	queue := NewFetchQueue(pool, 1)
+	rt := common.NewTestNamespaceFromSeed([]byte("fetch queue test runtime"), 0)
+
+	// Block the pool so that all subsequently submitted jobs are queued.
+	gateCh := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	queue.Submit(rt, func() {
+		defer wg.Done()
+		<-gateCh
+	})
+
+	const numJobs = 10
+	var (
+		lock sync.Mutex
+		ran  int
+	)
+	job := func() {
+		defer wg.Done()
+		lock.Lock()
+		ran++
+		lock.Unlock()
+	}
+	for i := 0; i < numJobs; i++ {
+		wg.Add(1)
+		queue.SubmitWithPriority(rt, FetchPriority(i%2), job)
+	}
+
+	// Jobs queued when the pool is stopped and jobs submitted to the stopped pool should still
+	// be executed.
+	pool.Stop()
+	close(gateCh)
+	for i := 0; i < numJobs; i++ {
+		wg.Add(1)
+		queue.Submit(rt, job)
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-doneCh:
+	case <-time.After(workerTestTimeout):
+		t.Fatalf("jobs not executed after the pool has been stopped")
+	}
+	require.Equal(2*numJobs, ran, "all jobs should be executed")
+}
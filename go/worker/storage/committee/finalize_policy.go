@@ -0,0 +1,86 @@
+package committee
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// FinalizePolicyImmediate is the name of the immediate finalize policy.
+	FinalizePolicyImmediate = "immediate"
+	// FinalizePolicyDeferred is the name of the deferred finalize policy.
+	FinalizePolicyDeferred = "deferred"
+)
+
+// FinalizePolicy decides when fully applied rounds are finalized.
+//
+// Rounds are always applied as soon as possible, so their roots can be queried even before they
+// are finalized. Finalization is still serialized by round, the policy only decides how many
+// consecutive rounds are finalized together.
+type FinalizePolicy interface {
+	// ShouldFinalize returns true iff the consecutive fully applied but not yet finalized rounds
+	// from firstRound to lastRound (inclusive) should be finalized now.
+	ShouldFinalize(firstRound, lastRound uint64) bool
+}
+
+type immediateFinalizePolicy struct{}
+
+func (immediateFinalizePolicy) ShouldFinalize(firstRound, lastRound uint64) bool {
+	return true
+}
+
+// NewImmediateFinalizePolicy creates a finalize policy which finalizes each round as soon as it has
+// been fully applied.
+func NewImmediateFinalizePolicy() FinalizePolicy {
+	return immediateFinalizePolicy{}
+}
+
+type deferredFinalizePolicy struct {
+	interval uint64
+}
+
+func (p *deferredFinalizePolicy) ShouldFinalize(firstRound, lastRound uint64) bool {
+	if lastRound < firstRound {
+		return false
+	}
+	// Finalize once enough rounds are pending or a boundary round has been reached.
+	if lastRound-firstRound+1 >= p.interval {
+		return true
+	}
+	if firstRound == 0 {
+		// Round zero (e.g., a genesis round of zero) is a boundary round for any interval.
+		return true
+	}
+	return lastRound/p.interval != (firstRound-1)/p.interval
+}
+
+// NewDeferredFinalizePolicy creates a finalize policy which only finalizes rounds in batches, once
+// a round that is a multiple of the given interval (e.g., a checkpoint boundary) has been applied.
+//
+// Note that this means the last finalized round may lag behind the last applied round by up to
+// interval rounds.
+func NewDeferredFinalizePolicy(interval uint64) (FinalizePolicy, error) {
+	if interval == 0 {
+		return nil, fmt.Errorf("deferred finalize policy interval must be positive")
+	}
+	return &deferredFinalizePolicy{interval: interval}, nil
+}
+
+// NewFinalizePolicy creates a new finalize policy by name.
+func NewFinalizePolicy(name string, interval uint64) (FinalizePolicy, error) {
+	switch strings.ToLower(name) {
+	case FinalizePolicyImmediate:
+		return NewImmediateFinalizePolicy(), nil
+	case FinalizePolicyDeferred:
+		return NewDeferredFinalizePolicy(interval)
+	default:
+		return nil, fmt.Errorf("unsupported finalize policy: '%s'", name)
+	}
+}
+
+func (n *Node) shouldFinalize(firstRound, lastRound uint64) bool {
+	if n.cfg.FinalizePolicy == nil {
+		return true
+	}
+	return n.cfg.FinalizePolicy.ShouldFinalize(firstRound, lastRound)
+}
@@ -0,0 +1,107 @@
+package committee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestFinalizePolicy(t *testing.T) {
+	require := require.New(t)
+
+	immediate, err := NewFinalizePolicy(FinalizePolicyImmediate, 0)
+	require.NoError(err, "NewFinalizePolicy(immediate)")
+	require.True(immediate.ShouldFinalize(1, 1), "immediate policy should always finalize")
+	require.True(immediate.ShouldFinalize(5, 7), "immediate policy should always finalize")
+
+	_, err = NewFinalizePolicy(FinalizePolicyDeferred, 0)
+	require.Error(err, "deferred policy requires a positive interval")
+	_, err = NewFinalizePolicy("bogus", 0)
+	require.Error(err, "unknown policies should be rejected")
+
+	deferred, err := NewFinalizePolicy(FinalizePolicyDeferred, 4)
+	require.NoError(err, "NewFinalizePolicy(deferred)")
+	for _, tc := range []struct {
+		firstRound uint64
+		lastRound  uint64
+		expected   bool
+	}{
+		{1, 1, false},
+		{1, 3, false},
+		{1, 4, true},
+		{5, 6, false},
+		{3, 5, true},
+		{5, 8, true},
+		{6, 9, true},
+		{9, 11, false},
+		// Round zero is a boundary round.
+		{0, 0, true},
+		{0, 2, true},
+	} {
+		require.Equal(tc.expected, deferred.ShouldFinalize(tc.firstRound, tc.lastRound), "ShouldFinalize(%d, %d)", tc.firstRound, tc.lastRound)
+	}
+}
+
+func TestFinalizeBatch(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	s := newTestState(t, n)
+	s.advance()
+
+	// Applied but not yet finalized rounds should be queryable.
+	var batch []*blockSummary
+	for i := 0; i < 3; i++ {
+		summary := s.apply()
+		require.True(n.localStorage.NodeDB().HasRoot(summary.Roots[0]), "applied root should exist before finalization")
+		batch = append(batch, summary)
+	}
+	latest, _ := n.localStorage.NodeDB().GetLatestVersion()
+	require.EqualValues(1, latest, "applied rounds should not be finalized")
+
+	n.finalize(n.ctx, batch...)
+	result := <-n.finalizeCh
+	require.NoError(result.err, "finalize()")
+	require.Equal(batch[2], result.summary, "result should be for the last round of the batch")
+	latest, _ = n.localStorage.NodeDB().GetLatestVersion()
+	require.EqualValues(4, latest, "all rounds in the batch should be finalized")
+
+	// A failing round should stop the batch and be reported.
+	next := s.apply()
+	bogusRoot := next.Roots[0]
+	bogusRoot.Version++
+	bogusRoot.Hash[0]++
+	bogus := &blockSummary{
+		Namespace: testNs,
+		Round:     bogusRoot.Version,
+		Roots:     []storageApi.Root{bogusRoot},
+	}
+	n.finalize(n.ctx, next, bogus)
+	result = <-n.finalizeCh
+	require.Error(result.err, "finalize() with missing roots")
+	require.Equal(bogus, result.summary, "result should be for the failing round")
+}
+
+func TestFinalizeRecovery(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.diffCh = make(chan *fetchedDiff, 1)
+	s := newTestState(t, n)
+	prev := s.advance()
+
+	// Simulate a crash after a round has been applied but before it has been finalized.
+	applied := s.apply()
+
+	// After restarting, the applied round should not be fetched again, only finalized.
+	n.fetchDiff(n.ctx, applied.Round, prev.Roots[0], applied.Roots[0])
+	result := <-n.diffCh
+	require.NoError(result.err, "fetchDiff()")
+	require.False(result.fetched, "applied round should not be fetched again")
+
+	n.finalize(n.ctx, applied)
+	finalized := <-n.finalizeCh
+	require.NoError(finalized.err, "finalize()")
+	latest, _ := n.localStorage.NodeDB().GetLatestVersion()
+	require.EqualValues(applied.Round, latest, "applied round should be finalized")
+}
@@ -0,0 +1,91 @@
+package committee
+
+import (
+	"sort"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// finalizeTracker keeps track of batches of consecutive rounds that are being finalized
+// concurrently and releases their results in round order.
+type finalizeTracker struct {
+	// inFlight is the number of batches that are currently being finalized.
+	inFlight int
+
+	nextRound uint64
+	completed map[uint64]finalizeResult
+}
+
+func newFinalizeTracker(lastFinalizedRound uint64) *finalizeTracker {
+	return &finalizeTracker{
+		nextRound: lastFinalizedRound + 1,
+		completed: make(map[uint64]finalizeResult),
+	}
+}
+
+// complete records the result of finalizing a batch and returns the results of all batches that
+// directly follow the last released batch, in round order.
+//
+// Failed results are returned immediately (following any released batches). In case some rounds
+// of a failed batch were finalized before the failure, they are released like a batch on its own,
+// so that only rounds that were actually finalized are ever released. The remaining rounds need
+// to be finalized again as a new batch.
+func (t *finalizeTracker) complete(result finalizeResult) []finalizeResult {
+	t.inFlight--
+	if result.err != nil && result.lastFinalized == nil {
+		return []finalizeResult{result}
+	}
+
+	var failed []finalizeResult
+	if result.err != nil {
+		failed = append(failed, result)
+		result = finalizeResult{
+			firstRound: result.firstRound,
+			summary:    result.lastFinalized,
+			stats:      result.stats,
+		}
+	}
+
+	t.completed[result.firstRound] = result
+	var ready []finalizeResult
+	for {
+		next, ok := t.completed[t.nextRound]
+		if !ok {
+			break
+		}
+		delete(t.completed, t.nextRound)
+		ready = append(ready, next)
+		t.nextRound = next.summary.Round + 1
+	}
+	return append(ready, failed...)
+}
+
+// requeueFinalizeBatch inserts the given batch of rounds that failed to finalize into the given
+// list of batches, keeping it ordered by round.
+func requeueFinalizeBatch(batches [][]*blockSummary, batch []*blockSummary) [][]*blockSummary {
+	if len(batch) == 0 {
+		return batches
+	}
+	idx := sort.Search(len(batches), func(i int) bool {
+		return batches[i][0].Round > batch[0].Round
+	})
+	batches = append(batches, nil)
+	copy(batches[idx+1:], batches[idx:])
+	batches[idx] = batch
+	return batches
+}
+
+// finalizeConcurrency returns the maximum number of batches of rounds that may be finalized
+// concurrently.
+func (n *Node) finalizeConcurrency() int {
+	if n.cfg.MaxConcurrentFinalizes <= 1 {
+		return 1
+	}
+	if cb, ok := n.localStorage.(storageApi.ConcurrentFinalizeBackend); !ok || !cb.SupportsConcurrentFinalize() {
+		n.logger.Warn("local storage backend does not support concurrent finalization, finalizing serially",
+			"max_concurrent_finalizes", n.cfg.MaxConcurrentFinalizes,
+		)
+		return 1
+	}
+	return int(n.cfg.MaxConcurrentFinalizes)
+}
@@ -0,0 +1,186 @@
+package committee
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// testConcurrentFinalizeBackend is a local storage backend that declares support for concurrent
+// finalization and blocks finalizing each version until it is released by the test.
+type testConcurrentFinalizeBackend struct {
+	storageApi.LocalBackend
+
+	nodeDB *testConcurrentFinalizeNodeDB
+}
+
+func (b *testConcurrentFinalizeBackend) SupportsConcurrentFinalize() bool {
+	return true
+}
+
+func (b *testConcurrentFinalizeBackend) NodeDB() mkvsDB.NodeDB {
+	return b.nodeDB
+}
+
+type testConcurrentFinalizeNodeDB struct {
+	mkvsDB.NodeDB
+
+	sync.Mutex
+	release   map[uint64]chan struct{}
+	finalized []uint64
+}
+
+func (d *testConcurrentFinalizeNodeDB) releaseCh(version uint64) chan struct{} {
+	d.Lock()
+	defer d.Unlock()
+
+	ch, ok := d.release[version]
+	if !ok {
+		ch = make(chan struct{})
+		d.release[version] = ch
+	}
+	return ch
+}
+
+func (d *testConcurrentFinalizeNodeDB) Finalize(ctx context.Context, roots []storageApi.Root) error {
+	<-d.releaseCh(roots[0].Version)
+
+	d.Lock()
+	defer d.Unlock()
+	d.finalized = append(d.finalized, roots[0].Version)
+	return nil
+}
+
+func testFinalizeSummary(round uint64) *blockSummary {
+	return &blockSummary{
+		Namespace: testNs,
+		Round:     round,
+		Roots:     []storageApi.Root{{Namespace: testNs, Version: round, Type: storageApi.RootTypeState}},
+	}
+}
+
+func TestFinalizeTracker(t *testing.T) {
+	require := require.New(t)
+
+	tracker := newFinalizeTracker(10)
+	success := func(first, last uint64) finalizeResult {
+		return finalizeResult{firstRound: first, summary: testFinalizeSummary(last)}
+	}
+
+	tracker.inFlight = 3
+	require.Empty(tracker.complete(success(14, 14)), "batch not following the last finalized round should be held back")
+	require.Empty(tracker.complete(success(12, 13)), "batch not following the last finalized round should be held back")
+	ready := tracker.complete(success(11, 11))
+	require.Len(ready, 3, "all contiguous batches should be released")
+	for i, round := range []uint64{11, 13, 14} {
+		require.EqualValues(round, ready[i].summary.Round, "batches should be released in round order")
+	}
+	require.Zero(tracker.inFlight)
+
+	tracker.inFlight = 2
+	require.Empty(tracker.complete(success(16, 16)))
+	failed := finalizeResult{firstRound: 15, summary: testFinalizeSummary(15), err: errors.New("failed")}
+	ready = tracker.complete(failed)
+	require.Equal([]finalizeResult{failed}, ready, "failures should be released immediately")
+
+	// Rounds of a failed batch that were finalized before the failure should still be released in
+	// order, but not the following ones.
+	tracker = newFinalizeTracker(20)
+	tracker.inFlight = 2
+	partial := finalizeResult{
+		firstRound:    23,
+		summary:       testFinalizeSummary(25),
+		err:           errors.New("failed"),
+		lastFinalized: testFinalizeSummary(24),
+		unfinalized:   []*blockSummary{testFinalizeSummary(25), testFinalizeSummary(26)},
+	}
+	ready = tracker.complete(partial)
+	require.Equal([]finalizeResult{partial}, ready, "failures should be released immediately")
+	ready = tracker.complete(success(21, 22))
+	require.Len(ready, 2, "finalized rounds of the failed batch should be released")
+	require.EqualValues(22, ready[0].summary.Round, "batches should be released in round order")
+	require.EqualValues(24, ready[1].summary.Round, "only finalized rounds should be released")
+	require.NoError(ready[1].err, "finalized rounds should be released as a successful batch")
+	require.EqualValues(25, tracker.nextRound, "failed round should be finalized next")
+
+	// With a genesis round of zero, nothing has been finalized before round zero.
+	tracker = newFinalizeTracker(defaultUndefinedRound)
+	tracker.inFlight = 2
+	require.Empty(tracker.complete(success(1, 2)), "batch not following genesis should be held back")
+	ready = tracker.complete(success(0, 0))
+	require.Len(ready, 2, "batches following genesis round zero should be released")
+	require.EqualValues(0, ready[0].summary.Round, "batches should be released in round order")
+}
+
+func TestRequeueFinalizeBatch(t *testing.T) {
+	require := require.New(t)
+
+	batch := func(rounds ...uint64) []*blockSummary {
+		var summaries []*blockSummary
+		for _, round := range rounds {
+			summaries = append(summaries, testFinalizeSummary(round))
+		}
+		return summaries
+	}
+
+	var batches [][]*blockSummary
+	batches = requeueFinalizeBatch(batches, batch(7, 8))
+	batches = requeueFinalizeBatch(batches, batch(3, 4))
+	batches = requeueFinalizeBatch(batches, nil)
+	batches = requeueFinalizeBatch(batches, batch(5))
+	require.Equal([][]*blockSummary{batch(3, 4), batch(5), batch(7, 8)}, batches, "batches should be ordered by round")
+}
+
+func TestFinalizeConcurrency(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+
+	require.Equal(1, n.finalizeConcurrency(), "finalization should be serialized by default")
+	n.cfg.MaxConcurrentFinalizes = 4
+	require.Equal(1, n.finalizeConcurrency(), "finalization should be serialized for unsupported backends")
+
+	nodeDB := &testConcurrentFinalizeNodeDB{
+		NodeDB:  n.localStorage.NodeDB(),
+		release: make(map[uint64]chan struct{}),
+	}
+	n.localStorage = &testConcurrentFinalizeBackend{
+		LocalBackend: n.localStorage,
+		nodeDB:       nodeDB,
+	}
+	require.Equal(4, n.finalizeConcurrency(), "finalization should be concurrent for supported backends")
+
+	// Finalize batches concurrently and complete them in reverse order.
+	tracker := newFinalizeTracker(0)
+	batches := [][]*blockSummary{
+		{testFinalizeSummary(1)},
+		{testFinalizeSummary(2), testFinalizeSummary(3)},
+		{testFinalizeSummary(4)},
+	}
+	for _, batch := range batches {
+		tracker.inFlight++
+		go n.finalize(n.ctx, batch...)
+	}
+
+	var released []uint64
+	for _, versions := range [][]uint64{{4}, {2, 3}, {1}} {
+		for _, v := range versions {
+			close(nodeDB.releaseCh(v))
+		}
+		for _, finalized := range tracker.complete(<-n.finalizeCh) {
+			require.NoError(finalized.err, "finalize()")
+			released = append(released, finalized.summary.Round)
+		}
+		if versions[0] != 1 {
+			require.Empty(released, "no batch should be released before the first round is finalized")
+		}
+	}
+	require.Equal([]uint64{1, 3, 4}, released, "batches should be released in round order")
+	require.ElementsMatch([]uint64{1, 2, 3, 4}, nodeDB.finalized, "all rounds should be finalized")
+	require.Zero(tracker.inFlight)
+}
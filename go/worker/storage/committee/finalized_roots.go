@@ -0,0 +1,67 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// RoundRoots are the root hashes of a finalized round.
+type RoundRoots struct {
+	// Round is the finalized round.
+	Round uint64 `json:"round"`
+	// IORoot is the I/O root hash of the round.
+	IORoot hash.Hash `json:"io_root"`
+	// StateRoot is the state root hash of the round.
+	StateRoot hash.Hash `json:"state_root"`
+}
+
+// FinalizedRoots returns the root hashes of all rounds in the given (inclusive) range, in order.
+//
+// All of the rounds must be finalized in local storage, otherwise an error matching
+// ErrRoundNotFinalized is returned.
+func (n *Node) FinalizedRoots(ctx context.Context, fromRound, toRound uint64) ([]RoundRoots, error) {
+	if n.localStorage == nil {
+		return nil, ErrNonLocalBackend
+	}
+	if fromRound > toRound {
+		return nil, fmt.Errorf("invalid round range %d-%d", fromRound, toRound)
+	}
+
+	n.syncedLock.RLock()
+	lastFinalized := n.syncedState.Round
+	n.syncedLock.RUnlock()
+
+	if lastFinalized == defaultUndefinedRound || lastFinalized == n.undefinedRound || toRound > lastFinalized {
+		return nil, fmt.Errorf("%w: round %d", ErrRoundNotFinalized, toRound)
+	}
+	ndb := n.localStorage.NodeDB()
+	if earliest := ndb.GetEarliestVersion(); fromRound < earliest {
+		return nil, fmt.Errorf("%w: round %d (earliest: %d)", ErrRoundNotFinalized, fromRound, earliest)
+	}
+
+	roots := make([]RoundRoots, 0, toRound-fromRound+1)
+	for round := fromRound; round <= toRound; round++ {
+		summary, err := n.committedSummary(ctx, round)
+		if err != nil {
+			return nil, err
+		}
+
+		rr := RoundRoots{Round: round}
+		for _, root := range summary.Roots {
+			if !ndb.HasRoot(root) {
+				return nil, fmt.Errorf("%w: round %d (missing %s)", ErrRoundNotFinalized, round, root.Type)
+			}
+			switch root.Type {
+			case storageApi.RootTypeIO:
+				rr.IORoot = root.Hash
+			case storageApi.RootTypeState:
+				rr.StateRoot = root.Hash
+			}
+		}
+		roots = append(roots, rr)
+	}
+	return roots, nil
+}
@@ -0,0 +1,76 @@
+package committee
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+)
+
+// loopWatchdog detects a stalled worker main loop, e.g. due to a call that blocks forever.
+//
+// The main loop is expected to beat on each iteration. In case it does not beat for longer than
+// the watchdog interval while there is work pending, the stall is reported.
+type loopWatchdog struct {
+	logger   *logging.Logger
+	interval time.Duration
+	stalls   prometheus.Counter
+
+	// lastBeat is the time of the last beat in Unix nanoseconds.
+	lastBeat int64
+	// pending is non-zero when there was work pending at the last beat.
+	pending uint32
+}
+
+func newLoopWatchdog(logger *logging.Logger, interval time.Duration, labels prometheus.Labels) *loopWatchdog {
+	return &loopWatchdog{
+		logger:   logger,
+		interval: interval,
+		stalls:   storageWorkerMainLoopStalls.With(labels),
+		lastBeat: time.Now().UnixNano(),
+	}
+}
+
+// beat records progress of the main loop and whether it has work pending.
+func (w *loopWatchdog) beat(pending bool) {
+	var p uint32
+	if pending {
+		p = 1
+	}
+	atomic.StoreUint32(&w.pending, p)
+	atomic.StoreInt64(&w.lastBeat, time.Now().UnixNano())
+}
+
+// run checks for main loop stalls until the given context is canceled. Each stall is reported
+// once, until the main loop makes progress again.
+func (w *loopWatchdog) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval / 2)
+	defer ticker.Stop()
+
+	var reportedBeat int64
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		lastBeat := atomic.LoadInt64(&w.lastBeat)
+		if atomic.LoadUint32(&w.pending) == 0 || lastBeat == reportedBeat {
+			continue
+		}
+		stalledFor := time.Since(time.Unix(0, lastBeat))
+		if stalledFor < w.interval {
+			continue
+		}
+
+		w.logger.Error("storage worker main loop stalled while work is pending",
+			"stalled_for", stalledFor,
+		)
+		w.stalls.Inc()
+		reportedBeat = lastBeat
+	}
+}
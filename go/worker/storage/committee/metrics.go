@@ -39,11 +39,230 @@ var (
 		[]string{"runtime"},
 	)
 
+	storageWorkerBlockPollingFallbacks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_block_polling_fallbacks",
+			Help: "Number of times the worker fell back to polling for blocks due to a stalled block subscription.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerWriteLogCacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_write_log_cache_hits",
+			Help: "Number of diff fetches served from the write log cache.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerWriteLogCacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_write_log_cache_misses",
+			Help: "Number of diff fetches not served from the write log cache.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerDiffBytesRead = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_diff_bytes_read",
+			Help: "Number of bytes of diff responses read from peers while bandwidth is limited (use rate() for throughput).",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerRootFilterHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_root_filter_hits",
+			Help: "Number of root lookups answered by the root filter without querying storage.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerRootFilterMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_root_filter_misses",
+			Help: "Number of root lookups that needed to query storage.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerRootFilterFalsePositives = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_root_filter_false_positives",
+			Help: "Number of root lookups where the root filter reported a root missing from storage.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerRoundWriteLogEntries = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "oasis_worker_storage_round_write_log_entries",
+			Help:    "Number of write log entries applied per finalized round.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 12),
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerRoundWriteLogBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "oasis_worker_storage_round_write_log_bytes",
+			Help:    "Total size of write log entries applied per finalized round (bytes).",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 12),
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerRoundFinalizedNodes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "oasis_worker_storage_round_finalized_nodes",
+			Help:    "Number of nodes committed per finalized round.",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 12),
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerRoundFinalizeLatency = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name: "oasis_worker_storage_round_finalize_latency",
+			Help: "Storage round finalize latency (seconds).",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerDroppedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_dropped_events",
+			Help: "Number of sync lifecycle events dropped because the event sink fell behind.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerApplyNamespaceMismatches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_apply_namespace_mismatches",
+			Help: "Number of write logs rejected as their roots do not belong to the runtime.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerBufferedDiffBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_buffered_diff_bytes",
+			Help: "Total size of the write logs of fetched diffs waiting to be applied (bytes).",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerGrowthPerRound = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_growth_per_round_bytes",
+			Help: "Average size of the data added to local storage per recently finalized round (bytes).",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerMainLoopStalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_main_loop_stalls",
+			Help: "Number of times the worker main loop made no progress for longer than the watchdog interval while work was pending.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerLastScrubbedRound = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_last_scrubbed_round",
+			Help: "Last finalized round verified by the local storage scrubber.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerScrubCorruptions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_scrub_corruptions",
+			Help: "Number of finalized rounds found missing or corrupted by the local storage scrubber.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerWriteSlotWait = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_write_slot_wait_seconds",
+			Help: "Total time local storage writes waited for other writes to complete due to the concurrent write limit (seconds).",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerOldestRetryingRound = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_oldest_retrying_round",
+			Help: "Oldest in-flight round whose diffs keep failing to be fetched or applied (zero if none).",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerOldestRetryingRoundAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_oldest_retrying_round_age_seconds",
+			Help: "Time since diffs of the oldest retrying in-flight round first failed (seconds, zero if none).",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerBufferedBlocks = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_buffered_blocks",
+			Help: "Number of received blocks waiting to be processed by the worker.",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerOldestBufferedBlockAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_oldest_buffered_block_age_seconds",
+			Help: "Time since the oldest block waiting to be processed by the worker was received (seconds, zero if none).",
+		},
+		[]string{"runtime"},
+	)
+
+	storageWorkerRedundantFinalizes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_storage_redundant_finalizes",
+			Help: "Number of attempts to finalize a round that was already finalized.",
+		},
+		[]string{"runtime"},
+	)
+
 	storageWorkerCollectors = []prometheus.Collector{
 		storageWorkerLastFullRound,
 		storageWorkerLastSyncedRound,
 		storageWorkerLastPendingRound,
 		storageWorkerRoundSyncLatency,
+		storageWorkerBlockPollingFallbacks,
+		storageWorkerWriteLogCacheHits,
+		storageWorkerWriteLogCacheMisses,
+		storageWorkerDiffBytesRead,
+		storageWorkerRootFilterHits,
+		storageWorkerRootFilterMisses,
+		storageWorkerRootFilterFalsePositives,
+		storageWorkerRoundWriteLogEntries,
+		storageWorkerRoundWriteLogBytes,
+		storageWorkerRoundFinalizedNodes,
+		storageWorkerRoundFinalizeLatency,
+		storageWorkerDroppedEvents,
+		storageWorkerApplyNamespaceMismatches,
+		storageWorkerBufferedDiffBytes,
+		storageWorkerGrowthPerRound,
+		storageWorkerMainLoopStalls,
+		storageWorkerRedundantFinalizes,
+		storageWorkerLastScrubbedRound,
+		storageWorkerScrubCorruptions,
+		storageWorkerWriteSlotWait,
+		storageWorkerOldestRetryingRound,
+		storageWorkerOldestRetryingRoundAge,
+		storageWorkerBufferedBlocks,
+		storageWorkerOldestBufferedBlockAge,
 	}
 
 	prometheusOnce sync.Once
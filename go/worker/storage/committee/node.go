@@ -11,15 +11,18 @@ import (
 
 	"github.com/eapache/channels"
 
+	cmnBackoff "github.com/oasisprotocol/oasis-core/go/common/backoff"
+	"github.com/oasisprotocol/oasis-core/go/common/cache/lru"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
-	"github.com/oasisprotocol/oasis-core/go/common/workerpool"
 	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
 	commonFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
 	registryApi "github.com/oasisprotocol/oasis-core/go/registry/api"
 	roothashApi "github.com/oasisprotocol/oasis-core/go/roothash/api"
 	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/history"
 	"github.com/oasisprotocol/oasis-core/go/runtime/host"
 	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
@@ -38,12 +41,19 @@ var (
 
 	// ErrNonLocalBackend is the error returned when the storage backend doesn't implement the LocalBackend interface.
 	ErrNonLocalBackend = errors.New("storage: storage backend doesn't support local storage")
+
+	errNoDiffResponse   = errors.New("storage: GetDiff returned no response")
+	errNonCommitteePeer = errors.New("storage: diff served by a peer that is not a committee member")
 )
 
 const (
 	// RoundLatest is a magic value for the latest round.
 	RoundLatest = math.MaxUint64
 
+	// defaultUndefinedRound is the last synced round before anything has been synced.
+	//
+	// Note that it is equal to the undefined round of runtimes with a genesis round of zero (the
+	// round before genesis wraps around), so both mean that nothing has been synced yet.
 	defaultUndefinedRound = ^uint64(0)
 
 	checkpointSyncRetryDelay = 10 * time.Second
@@ -56,9 +66,13 @@ const (
 	// it to stop advertising availability.
 	minimumRoundDelayForUnavailability = uint64(15)
 
-	// maxInFlightRounds is the maximum number of rounds that should be fetched before waiting
-	// for them to be applied.
+	// maxInFlightRounds is the default and maximum number of rounds that should be fetched
+	// before waiting for them to be applied.
 	maxInFlightRounds = 100
+
+	// maxBlockSummaryBatchSize is the maximum number of blocks that are fetched from the runtime
+	// history at once when populating block summaries for missing rounds.
+	maxBlockSummaryBatchSize = uint64(128)
 )
 
 type roundItem interface {
@@ -87,6 +101,15 @@ func (q *outOfOrderRoundQueue) Pop() interface{} {
 	return x
 }
 
+// BlockSource is a read-only source of runtime blocks used by the storage worker.
+type BlockSource interface {
+	// GetGenesisBlock returns the genesis block.
+	GetGenesisBlock(ctx context.Context, request *roothashApi.RuntimeRequest) (*block.Block, error)
+
+	// GetLatestBlock returns the latest block.
+	GetLatestBlock(ctx context.Context, request *roothashApi.RuntimeRequest) (*block.Block, error)
+}
+
 // fetchedDiff has all the context needed for a single GetDiff operation.
 type fetchedDiff struct {
 	fetched  bool
@@ -102,9 +125,42 @@ func (d *fetchedDiff) GetRound() uint64 {
 	return d.round
 }
 
+// appliedWriteLogKey identifies a write log that has been applied to a specific root.
+type appliedWriteLogKey struct {
+	prevRoot storageApi.Root
+	thisRoot storageApi.Root
+	writeLog hash.Hash
+}
+
+// appliedWriteLogs keeps track of write logs that have been successfully applied, indexed by
+// round, so that identical write logs fetched redundantly (e.g., during retries) are not applied
+// more than once.
+type appliedWriteLogs map[uint64]map[appliedWriteLogKey]struct{}
+
+func (a appliedWriteLogs) contains(round uint64, key appliedWriteLogKey) bool {
+	_, ok := a[round][key]
+	return ok
+}
+
+func (a appliedWriteLogs) add(round uint64, key appliedWriteLogKey) {
+	keys, ok := a[round]
+	if !ok {
+		keys = make(map[appliedWriteLogKey]struct{})
+		a[round] = keys
+	}
+	keys[key] = struct{}{}
+}
+
 type finalizeResult struct {
-	summary *blockSummary
-	err     error
+	firstRound uint64
+	summary    *blockSummary
+	stats      map[uint64]finalizeStats
+	err        error
+
+	// lastFinalized is the last round of the batch that was successfully finalized (if any).
+	lastFinalized *blockSummary
+	// unfinalized are the rounds of the batch that were not finalized due to the failure.
+	unfinalized []*blockSummary
 }
 
 // Node watches blocks for storage changes.
@@ -121,41 +177,91 @@ type Node struct { // nolint: maligned
 
 	storageSync storageSync.Client
 
+	blockSource BlockSource
+
+	membership committeeMembership
+
+	writeLogCache *lru.Cache
+	syncStats     *syncStatsWindow
+	quarantine    *roundQuarantine
+
+	syncCeiling   uint64
+	syncCeilingCh chan struct{}
+
+	tunablesLock sync.RWMutex
+	tunables     Tunables
+
+	writeLimiter *storageWriteLimiter
+
+	// undefinedRound is the round before the genesis round. In case the genesis round is zero,
+	// this wraps around to defaultUndefinedRound and rounds following it (e.g., for starting
+	// syncing) wrap around to zero, which is relied upon throughout the worker.
 	undefinedRound uint64
 
-	fetchPool *workerpool.Pool
+	fetchQueue *FetchQueue
 
 	workerCommonCfg workerCommon.Config
+	cfg             *Config
 
 	checkpointer         checkpoint.Checkpointer
 	checkpointSyncCfg    *CheckpointSyncConfig
 	checkpointSyncForced bool
 
-	syncedLock  sync.RWMutex
-	syncedState blockSummary
+	growth growthEstimator
+
+	scrubLock     sync.Mutex
+	scrubProgress ScrubProgress
+
+	syncedLock        sync.RWMutex
+	syncedState       blockSummary
+	lastSyncErr       *SyncError
+	lastSyncErrAt     time.Time
+	pruneLock         sync.RWMutex
+	importedSyncState *blockSummary
+	syncStarted       bool
 
-	blockCh    *channels.InfiniteChannel
-	diffCh     chan *fetchedDiff
-	finalizeCh chan finalizeResult
+	syncErrNotifier       *pubsub.Broker
+	roundProgressNotifier *pubsub.Broker
+	events                *eventDispatcher
+	transitions           *transitionLog
+
+	pruneHandler *pruneHandler
+
+	blockCh      *channels.InfiniteChannel
+	blockBacklog blockBacklog
+	diffCh       chan *fetchedDiff
+	finalizeCh   chan finalizeResult
+
+	// queueSnapshotCh receives requests for snapshots of the internal queues of the worker loop.
+	// It is only set in tests, so no snapshots are served in production.
+	queueSnapshotCh chan chan *queueSnapshot
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
 
+	// finalizeCtx is the context of finalizations, which is only canceled once the finalize
+	// grace period after stopping the node has elapsed.
+	finalizeCtx       context.Context
+	finalizeCtxCancel context.CancelFunc
+
 	quitCh       chan struct{}
 	workerQuitCh chan struct{}
 
-	initCh chan struct{}
+	initCh     chan struct{}
+	initOnce   sync.Once
+	caughtUpCh chan struct{}
 }
 
 func NewNode(
 	commonNode *committee.Node,
-	fetchPool *workerpool.Pool,
+	fetchQueue *FetchQueue,
 	roleProvider registration.RoleProvider,
 	rpcRoleProvider registration.RoleProvider,
 	workerCommonCfg workerCommon.Config,
 	localStorage storageApi.LocalBackend,
 	checkpointerCfg *checkpoint.CheckpointerConfig,
 	checkpointSyncCfg *CheckpointSyncConfig,
+	cfg *Config,
 ) (*Node, error) {
 	initMetrics()
 
@@ -168,20 +274,47 @@ func NewNode(
 		logger: logging.GetLogger("worker/storage/committee").With("runtime_id", commonNode.Runtime.ID()),
 
 		workerCommonCfg: workerCommonCfg,
+		cfg:             cfg,
 
 		localStorage: localStorage,
 
-		fetchPool: fetchPool,
+		fetchQueue: fetchQueue,
+
+		blockSource: cfg.BlockSource,
+
+		syncCeiling:   cfg.SyncCeiling,
+		syncCeilingCh: make(chan struct{}, 1),
+
+		tunables: cfg.effectiveTunables(commonNode.Runtime.ID()),
 
 		checkpointSyncCfg: checkpointSyncCfg,
 
+		syncErrNotifier:       pubsub.NewBroker(false),
+		roundProgressNotifier: pubsub.NewBroker(false),
+
 		blockCh:    channels.NewInfiniteChannel(),
-		diffCh:     make(chan *fetchedDiff),
-		finalizeCh: make(chan finalizeResult),
+		diffCh:     make(chan *fetchedDiff, cfg.DiffChannelSize),
+		finalizeCh: make(chan finalizeResult, cfg.FinalizeChannelSize),
 
 		quitCh:       make(chan struct{}),
 		workerQuitCh: make(chan struct{}),
 		initCh:       make(chan struct{}),
+		caughtUpCh:   make(chan struct{}),
+	}
+	if n.blockSource == nil {
+		n.blockSource = commonNode.Consensus.RootHash()
+	}
+	if cfg.RequireCommitteePeers {
+		n.membership = &epochCommitteeMembership{group: commonNode.Group}
+	}
+	if cfg.EventSink != nil {
+		n.events = newEventDispatcher(cfg.EventSink, n.getMetricLabels())
+	}
+	if cfg.TransitionLogSize > 0 {
+		n.transitions = newTransitionLog(cfg.TransitionLogSize)
+	}
+	if cfg.MaxConcurrentWrites > 0 {
+		n.writeLimiter = newStorageWriteLimiter(cfg.MaxConcurrentWrites, n.getMetricLabels())
 	}
 
 	// Validate checkpoint sync configuration.
@@ -189,10 +322,31 @@ func NewNode(
 		return nil, fmt.Errorf("bad checkpoint sync configuration: %w", err)
 	}
 
+	// Validate storage sync configuration.
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("bad storage sync configuration: %w", err)
+	}
+
+	if cfg.ObserverMode {
+		n.ctx, n.ctxCancel = context.WithCancel(context.Background())
+		n.initObserver()
+		return n, nil
+	}
+	if localStorage == nil {
+		return nil, ErrNonLocalBackend
+	}
+
+	// Make sure that local storage accepts writes, as otherwise syncing would only fail once the
+	// first round needs to be applied.
+	if err := checkLocalStorageWritable(localStorage); err != nil {
+		return nil, err
+	}
+
 	// Initialize sync state.
 	n.syncedState.Round = defaultUndefinedRound
 
 	n.ctx, n.ctxCancel = context.WithCancel(context.Background())
+	n.finalizeCtx, n.finalizeCtxCancel = context.WithCancel(context.Background())
 
 	// Create a new checkpointer if enabled.
 	if checkpointerCfg != nil {
@@ -207,7 +361,7 @@ func NewNode(
 					return nil, fmt.Errorf("failed to retrieve runtime descriptor: %w", rerr)
 				}
 
-				blk, rerr := commonNode.Consensus.RootHash().GetGenesisBlock(ctx, &roothashApi.RuntimeRequest{
+				blk, rerr := n.blockSource.GetGenesisBlock(ctx, &roothashApi.RuntimeRequest{
 					RuntimeID: rt.ID,
 					Height:    consensus.HeightLatest,
 				})
@@ -243,15 +397,28 @@ func NewNode(
 		}
 	}
 
+	// Create the write log cache if configured.
+	writeLogCache, err := newWriteLogCache(cfg.WriteLogCacheSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create write log cache: %w", err)
+	}
+	n.writeLogCache = writeLogCache
+	n.syncStats = newSyncStatsWindow(cfg.SyncStatsWindowSize)
+	n.quarantine = newRoundQuarantine(cfg.QuarantineThreshold)
+
 	// Register prune handler.
-	commonNode.Runtime.History().Pruner().RegisterHandler(&pruneHandler{
+	n.pruneHandler = &pruneHandler{
 		logger: n.logger,
 		node:   n,
-	})
+	}
+	commonNode.Runtime.History().Pruner().RegisterHandler(n.pruneHandler)
 
 	// Register storage sync service.
 	commonNode.P2P.RegisterProtocolServer(storageSync.NewServer(commonNode.Runtime.ID(), localStorage))
-	n.storageSync = storageSync.NewClient(commonNode.P2P, commonNode.Runtime.ID())
+	n.storageSync = cfg.StorageSyncClient
+	if n.storageSync == nil && !cfg.DisableRemoteClient {
+		n.storageSync = NewStorageSyncClient(commonNode, cfg)
+	}
 
 	// Register storage pub service if configured.
 	if rpcRoleProvider != nil {
@@ -261,6 +428,18 @@ func NewNode(
 	return n, nil
 }
 
+// NewStorageSyncClient creates a new storage sync client for the runtime of the given common
+// node, configured as per the given storage worker configuration.
+func NewStorageSyncClient(commonNode *committee.Node, cfg *Config) storageSync.Client {
+	diffOpts := []rpc.ClientOption{
+		rpc.WithStreamPool(int(cfg.DiffStreamPoolSize), cfg.DiffStreamIdleTimeout),
+	}
+	if cfg.BandwidthLimiter != nil {
+		diffOpts = append(diffOpts, rpc.WithReadLimiter(newDiffReadLimiter(cfg.BandwidthLimiter, commonNode.Runtime.ID())))
+	}
+	return storageSync.NewClient(commonNode.P2P, commonNode.Runtime.ID(), cfg.DiffPeerSelector, diffOpts...)
+}
+
 // Service interface.
 
 // Name returns the service name.
@@ -275,12 +454,34 @@ func (n *Node) Start() error {
 	if n.checkpointer != nil {
 		go n.consensusCheckpointSyncer()
 	}
+	if n.cfg.ConsistencyCheckInterval > 0 && n.storageSync != nil {
+		go n.consistencyChecker()
+	}
+	if n.cfg.ScrubInterval > 0 && n.localStorage != nil {
+		go n.scrubber()
+	}
+	if n.cfg.BlockBacklogSampleInterval > 0 {
+		go n.blockBacklogSampler()
+	}
+	if n.events != nil {
+		go n.events.run(n.ctx)
+	}
 	return nil
 }
 
 // Stop causes the worker to stop watching and shut down.
 func (n *Node) Stop() {
 	n.ctxCancel()
+
+	// Give in-flight finalizations a chance to complete cleanly instead of aborting them while
+	// they are writing to local storage.
+	switch {
+	case n.finalizeCtxCancel == nil:
+	case n.cfg.FinalizeGracePeriod > 0:
+		time.AfterFunc(n.cfg.FinalizeGracePeriod, n.finalizeCtxCancel)
+	default:
+		n.finalizeCtxCancel()
+	}
 }
 
 // Quit returns a channel that will be closed when the worker stops.
@@ -289,15 +490,38 @@ func (n *Node) Quit() <-chan struct{} {
 }
 
 // Cleanup cleans up any leftover state after the worker is stopped.
+//
+// The node must not be used afterwards, e.g., no more blocks may be handled.
 func (n *Node) Cleanup() {
-	// Nothing to do here?
+	if n.pruneHandler != nil {
+		n.commonNode.Runtime.History().Pruner().UnregisterHandler(n.pruneHandler)
+	}
+	n.syncErrNotifier.Close()
+	n.roundProgressNotifier.Close()
+	n.blockCh.Close()
 }
 
 // Initialized returns a channel that will be closed once the worker finished starting up.
+//
+// The channel is also closed in case the worker fails to start up, in which case the channel
+// returned by Quit is closed as well.
 func (n *Node) Initialized() <-chan struct{} {
 	return n.initCh
 }
 
+// markInitialized closes the initialization channel, unless it has already been closed.
+func (n *Node) markInitialized() {
+	n.initOnce.Do(func() {
+		close(n.initCh)
+	})
+}
+
+// CaughtUp returns a channel that will be closed once the worker caught up and exited in case it
+// is running in the catch-up and exit mode.
+func (n *Node) CaughtUp() <-chan struct{} {
+	return n.caughtUpCh
+}
+
 // GetStatus returns the storage committee node status.
 func (n *Node) GetStatus(ctx context.Context) (*api.Status, error) {
 	n.syncedLock.RLock()
@@ -305,6 +529,7 @@ func (n *Node) GetStatus(ctx context.Context) (*api.Status, error) {
 
 	return &api.Status{
 		LastFinalizedRound: n.syncedState.Round,
+		SyncCeiling:        n.SyncCeiling(),
 	}, nil
 }
 
@@ -317,6 +542,8 @@ func (n *Node) PauseCheckpointer(pause bool) error {
 }
 
 // GetLocalStorage returns the local storage backend used by this storage node.
+//
+// In observer mode, there is no local storage backend and nil is returned.
 func (n *Node) GetLocalStorage() storageApi.LocalBackend {
 	return n.localStorage
 }
@@ -341,7 +568,7 @@ func (n *Node) HandleNewBlockEarlyLocked(*block.Block) {
 // HandleNewBlockLocked is guarded by CrossNode.
 func (n *Node) HandleNewBlockLocked(blk *block.Block) {
 	// Notify the state syncer that there is a new block.
-	n.blockCh.In() <- blk
+	n.enqueueBlock(blk)
 }
 
 // HandleNewEventLocked is guarded by CrossNode.
@@ -374,7 +601,224 @@ func (n *Node) GetLastSynced() (uint64, storageApi.Root, storageApi.Root) {
 	return n.syncedState.Round, io, state
 }
 
-func (n *Node) fetchDiff(round uint64, prevRoot, thisRoot storageApi.Root) {
+// LastSyncError returns the most recent error encountered while syncing rounds and the time at
+// which it occurred, if any. The error is cleared once the round it was encountered for has been
+// finalized.
+func (n *Node) LastSyncError() (*SyncError, time.Time) {
+	n.syncedLock.RLock()
+	defer n.syncedLock.RUnlock()
+
+	return n.lastSyncErr, n.lastSyncErrAt
+}
+
+// WatchSyncErrors returns a channel that receives errors encountered while syncing rounds.
+//
+// Use IsFatal to distinguish errors which prevent further sync from transient ones.
+func (n *Node) WatchSyncErrors() (<-chan *SyncError, pubsub.ClosableSubscription) {
+	typedCh := make(chan *SyncError)
+	sub := n.syncErrNotifier.Subscribe()
+	sub.Unwrap(typedCh)
+
+	return typedCh, sub
+}
+
+// newAppliedWriteLogKey returns the key identifying the write log of the given fetched diff.
+func newAppliedWriteLogKey(diff *fetchedDiff) appliedWriteLogKey {
+	return appliedWriteLogKey{
+		prevRoot: diff.prevRoot,
+		thisRoot: diff.thisRoot,
+		writeLog: hash.NewFrom(diff.writeLog),
+	}
+}
+
+// checkDiffNamespace makes sure that the given diff is only applied to roots of the runtime that is
+// being synced, so that runtimes sharing a local storage backend never write into each other.
+func (n *Node) checkDiffNamespace(diff *fetchedDiff) error {
+	runtimeID := n.commonNode.Runtime.ID()
+	for _, root := range []storageApi.Root{diff.prevRoot, diff.thisRoot} {
+		if !root.Namespace.Equal(&runtimeID) {
+			return fmt.Errorf("%w: %s root has namespace %s", ErrApplyNamespaceMismatch, root.Type, root.Namespace)
+		}
+	}
+	return nil
+}
+
+// applyDiff applies the given diff to local storage. In case the progress callback is set and the
+// local storage backend supports it, the progress of applying large write logs is reported.
+func (n *Node) applyDiff(ctx context.Context, applied appliedWriteLogs, diff *fetchedDiff, progress storageApi.ApplyProgressFunc) error {
+	if err := n.checkDiffNamespace(diff); err != nil {
+		return err
+	}
+
+	key := newAppliedWriteLogKey(diff)
+	if applied.contains(diff.round, key) {
+		n.logger.Debug("skipping already applied write log",
+			"round", diff.round,
+			"old_root", diff.prevRoot,
+			"new_root", diff.thisRoot,
+		)
+		return nil
+	}
+
+	writeLog, err := n.interceptWriteLog(diff)
+	if err != nil {
+		// Make sure that a rejected write log is fetched again.
+		n.evictCachedWriteLog(diff.prevRoot, diff.thisRoot)
+		return err
+	}
+
+	ctx, span := n.startSpan(ctx, SpanApply, SpanAttributes{Round: diff.round, RootType: diff.thisRoot.Type})
+	request := &storageApi.ApplyRequest{
+		Namespace: diff.thisRoot.Namespace,
+		RootType:  diff.thisRoot.Type,
+		SrcRound:  diff.prevRoot.Version,
+		SrcRoot:   diff.prevRoot.Hash,
+		DstRound:  diff.thisRoot.Version,
+		DstRoot:   diff.thisRoot.Hash,
+		WriteLog:  writeLog,
+	}
+	var release func()
+	if release, err = n.writeLimiter.acquire(ctx); err == nil {
+		if pb, ok := n.localStorage.(storageApi.ApplyProgressBackend); ok && progress != nil {
+			err = pb.ApplyWithProgress(ctx, request, progress)
+		} else {
+			err = n.localStorage.Apply(ctx, request)
+		}
+		release()
+	}
+	span.End(err)
+	if err != nil {
+		// Make sure that a write log which could not be applied is fetched again.
+		n.evictCachedWriteLog(diff.prevRoot, diff.thisRoot)
+		if errors.Is(err, mkvsDB.ErrBadNamespace) {
+			return fmt.Errorf("%w: %s", ErrApplyNamespaceMismatch, err)
+		}
+		return err
+	}
+	applied.add(diff.round, key)
+	return nil
+}
+
+// applyDiffBatch applies the given diff together with state diffs for directly following rounds
+// which have already been fetched (as found in the pending queue) using a single batched apply.
+// Diffs applied as part of the batch are recorded as applied so that they are skipped once they
+// are processed individually.
+//
+// In case batching is disabled, not supported by the local storage backend or there are no
+// suitable pending diffs, or the batched apply fails or any write log in the batch is rejected by
+// the write log interceptor, only the given diff is applied.
+func (n *Node) applyDiffBatch(ctx context.Context, applied appliedWriteLogs, diff *fetchedDiff, pending outOfOrderRoundQueue, progress storageApi.ApplyProgressFunc) error {
+	batch := n.collectDiffBatch(applied, diff, pending)
+	if len(batch) <= 1 {
+		return n.applyDiff(ctx, applied, diff, progress)
+	}
+
+	requests := make([]*storageApi.ApplyRequest, 0, len(batch))
+	for _, d := range batch {
+		if err := n.checkDiffNamespace(d); err != nil {
+			n.logger.Warn("write log in batch has foreign roots, falling back to applying individually",
+				"err", err,
+				"start_round", diff.round,
+				"round", d.round,
+			)
+			return n.applyDiff(ctx, applied, diff, progress)
+		}
+		writeLog, err := n.interceptWriteLog(d)
+		if err != nil {
+			n.logger.Warn("write log in batch rejected, falling back to applying individually",
+				"err", err,
+				"start_round", diff.round,
+				"round", d.round,
+			)
+			return n.applyDiff(ctx, applied, diff, progress)
+		}
+		requests = append(requests, &storageApi.ApplyRequest{
+			Namespace: d.thisRoot.Namespace,
+			RootType:  d.thisRoot.Type,
+			SrcRound:  d.prevRoot.Version,
+			SrcRoot:   d.prevRoot.Hash,
+			DstRound:  d.thisRoot.Version,
+			DstRoot:   d.thisRoot.Hash,
+			WriteLog:  writeLog,
+		})
+	}
+	batchCtx, span := n.startSpan(ctx, SpanApply, SpanAttributes{Round: diff.round, RootType: diff.thisRoot.Type})
+	release, err := n.writeLimiter.acquire(batchCtx)
+	if err == nil {
+		err = n.localStorage.(storageApi.ApplyBatchBackend).ApplyBatch(batchCtx, requests)
+		release()
+	}
+	span.End(err)
+	if err != nil {
+		n.logger.Warn("failed to apply batch of write logs, falling back to applying individually",
+			"err", err,
+			"start_round", diff.round,
+			"end_round", batch[len(batch)-1].round,
+		)
+		return n.applyDiff(ctx, applied, diff, progress)
+	}
+
+	for _, d := range batch {
+		applied.add(d.round, newAppliedWriteLogKey(d))
+	}
+	return nil
+}
+
+// collectDiffBatch returns the given diff followed by already fetched state diffs for directly
+// following rounds that chain on top of it, up to the configured maximum apply batch size.
+func (n *Node) collectDiffBatch(applied appliedWriteLogs, diff *fetchedDiff, pending outOfOrderRoundQueue) []*fetchedDiff {
+	if n.cfg.ApplyBatchSize <= 1 || !diff.fetched || diff.thisRoot.Type != storageApi.RootTypeState {
+		return nil
+	}
+	if _, ok := n.localStorage.(storageApi.ApplyBatchBackend); !ok {
+		return nil
+	}
+	if applied.contains(diff.round, newAppliedWriteLogKey(diff)) {
+		return nil
+	}
+
+	stateDiffs := make(map[uint64]*fetchedDiff)
+	for _, item := range pending {
+		d := item.(*fetchedDiff)
+		if d.fetched && d.thisRoot.Type == storageApi.RootTypeState {
+			stateDiffs[d.round] = d
+		}
+	}
+
+	batch := []*fetchedDiff{diff}
+	for uint64(len(batch)) < n.cfg.ApplyBatchSize {
+		last := batch[len(batch)-1]
+		next, ok := stateDiffs[last.round+1]
+		if !ok || next.prevRoot != last.thisRoot {
+			break
+		}
+		if applied.contains(next.round, newAppliedWriteLogKey(next)) {
+			break
+		}
+		batch = append(batch, next)
+	}
+	return batch
+}
+
+func (n *Node) reportSyncError(err *SyncError) {
+	n.syncedLock.Lock()
+	n.lastSyncErr = err
+	n.lastSyncErrAt = time.Now()
+	n.syncedLock.Unlock()
+
+	n.syncErrNotifier.Broadcast(err)
+	if n.events != nil {
+		n.events.emit(&Event{
+			Kind:     EventSyncError,
+			Round:    err.Round,
+			RootType: err.RootType,
+			Err:      err,
+		})
+	}
+}
+
+func (n *Node) fetchDiff(ctx context.Context, round uint64, prevRoot, thisRoot storageApi.Root) {
+	ctx, span := n.startSpan(ctx, SpanFetchDiff, SpanAttributes{Round: round, RootType: thisRoot.Type})
 	result := &fetchedDiff{
 		fetched:  false,
 		pf:       rpc.NewNopPeerFeedback(),
@@ -383,16 +827,27 @@ func (n *Node) fetchDiff(round uint64, prevRoot, thisRoot storageApi.Root) {
 		thisRoot: thisRoot,
 	}
 	defer func() {
-		n.diffCh <- result
+		span.End(result.err)
+		// Make sure not to block forever on shutdown when the main loop is no longer receiving.
+		select {
+		case n.diffCh <- result:
+		case <-n.ctx.Done():
+		}
 	}()
-	// Check if the new root doesn't already exist.
+	// Check if the new root doesn't already exist. This includes roots of rounds that have been
+	// applied but not yet finalized before a restart, so those are not fetched again.
 	if !n.localStorage.NodeDB().HasRoot(thisRoot) {
 		result.fetched = true
 		if thisRoot.Hash.Equal(&prevRoot.Hash) {
 			// Even if HasRoot returns false the root can still exist if it is equal
 			// to the previous root and the root was emitted by the consensus committee
 			// directly (e.g., during an epoch transition). In this case we need to
-			// still apply the (empty) write log.
+			// still apply the (empty) write log, unless the backend confirms that the
+			// root is already satisfied.
+			if eb, ok := n.localStorage.(storageApi.EqualRootBackend); ok && n.cfg.SkipEqualRootApply && eb.HasEqualRoot(prevRoot, thisRoot) {
+				result.fetched = false
+				return
+			}
 			result.writeLog = storageApi.WriteLog{}
 		} else {
 			// New root does not yet exist in storage and we need to fetch it from a
@@ -402,53 +857,235 @@ func (n *Node) fetchDiff(round uint64, prevRoot, thisRoot storageApi.Root) {
 				"new_root", thisRoot,
 			)
 
-			ctx, cancel := context.WithCancel(n.ctx)
+			if writeLog, ok := n.getCachedWriteLog(prevRoot, thisRoot); ok {
+				result.writeLog = writeLog
+				return
+			}
+			if n.storageSync == nil {
+				// There is nowhere else to get the diff from, so retrying would not help.
+				result.err = ErrDiffUnavailable
+				return
+			}
+
+			diffCtx, cancel := context.WithCancel(ctx)
+			if timeout := n.Tunables().GetDiffTimeout; timeout > 0 {
+				diffCtx, cancel = context.WithTimeout(ctx, timeout)
+			}
 			defer cancel()
 
-			rsp, pf, err := n.storageSync.GetDiff(ctx, &storageSync.GetDiffRequest{StartRoot: prevRoot, EndRoot: thisRoot})
+			request := &storageSync.GetDiffRequest{
+				StartRoot: prevRoot,
+				EndRoot:   thisRoot,
+				Compact:   n.cfg.CompactDiffs,
+			}
+			rsp, pf, err := n.storageSync.GetDiff(diffCtx, request)
+			if err != nil && request.Compact && errors.Is(err, rpc.ErrBadRequest) {
+				// The peer may not support the compact format, fall back to the plain format.
+				n.logger.Debug("compact GetDiff request rejected, retrying with plain format",
+					"err", err,
+				)
+				request.Compact = false
+				rsp, pf, err = n.storageSync.GetDiff(diffCtx, request)
+			}
+			if err != nil {
+				result.err = err
+				return
+			}
+			if rsp == nil {
+				// Misbehaving client, treat this as a failure so the diff gets retried.
+				if pf != nil {
+					pf.RecordFailure()
+				}
+				result.err = errNoDiffResponse
+				return
+			}
+			if n.membership != nil && !n.membership.IsCommitteePeer(pf.PeerID()) {
+				// The peer is not a committee member, reject the diff so that it is fetched
+				// again from a different peer.
+				n.logger.Warn("rejecting diff served by a non-committee peer",
+					"round", round,
+					"peer_id", pf.PeerID(),
+				)
+				pf.RecordBadPeer()
+				result.err = errNonCommitteePeer
+				return
+			}
+			writeLog, err := rsp.DecodeWriteLog()
 			if err != nil {
+				n.logger.Warn("failed to decode write log",
+					"round", round,
+					"peer_id", pf.PeerID(),
+					"err", err,
+				)
+				pf.RecordBadPeer()
 				result.err = err
 				return
 			}
 			result.pf = pf
-			result.writeLog = rsp.WriteLog
+			result.writeLog = writeLog
+			n.cacheWriteLog(prevRoot, thisRoot, writeLog)
 		}
 	}
 }
 
-func (n *Node) finalize(summary *blockSummary) {
-	err := n.localStorage.NodeDB().Finalize(n.ctx, summary.Roots)
-	switch err {
-	case nil:
-		n.logger.Debug("storage round finalized",
-			"round", summary.Round,
-		)
-	case storageApi.ErrAlreadyFinalized:
-		// This can happen if we are restoring after a roothash migration or if
-		// we crashed before updating the sync state.
-		n.logger.Warn("storage round already finalized",
-			"round", summary.Round,
-		)
-		err = nil
-	default:
-		n.logger.Error("failed to finalize storage round",
-			"err", err,
-			"round", summary.Round,
-		)
+// checkLocalStorageWritable verifies that the node database of the given local storage backend
+// accepts writes, without writing anything.
+func checkLocalStorageWritable(localStorage storageApi.LocalBackend) error {
+	var emptyRoot storageApi.Root
+	emptyRoot.Hash.Empty()
+
+	batch, err := localStorage.NodeDB().NewBatch(emptyRoot, 0, false)
+	if err != nil {
+		return fmt.Errorf("local storage is not writable: %w", err)
+	}
+	batch.Reset()
+	return nil
+}
+
+// finalize finalizes the given consecutive rounds in order and reports the outcome through
+// finalizeCh. The reported summary is either the last round or the first one that failed, in
+// which case the rounds finalized before the failure are reported as well.
+func (n *Node) finalize(ctx context.Context, summaries ...*blockSummary) {
+	var result finalizeResult
+	if len(summaries) > 0 {
+		result.firstRound = summaries[0].Round
+	}
+	result.stats = make(map[uint64]finalizeStats, len(summaries))
+	for i, summary := range summaries {
+		var stats finalizeStats
+		result.summary = summary
+		if stats, result.err = n.finalizeRound(ctx, summary); result.err != nil {
+			result.unfinalized = summaries[i:]
+			break
+		}
+		result.lastFinalized = summary
+		result.stats[summary.Round] = stats
+	}
+
+	// Make sure not to block forever on shutdown when the main loop is no longer receiving.
+	select {
+	case n.finalizeCh <- result:
+	case <-n.ctx.Done():
+	}
+}
+
+// redundantFinalizeSource returns the likely source of a redundant finalization of the given round,
+// for diagnostic purposes.
+func (n *Node) redundantFinalizeSource(round uint64, attempt uint64) string {
+	if attempt > 0 {
+		// A previous attempt may have finalized the round before failing.
+		return "retry"
+	}
+
+	n.syncedLock.RLock()
+	lastSynced := n.syncedState.Round
+	n.syncedLock.RUnlock()
+
+	if lastSynced != defaultUndefinedRound && round <= lastSynced {
+		// The worker itself already finalized and recorded the round.
+		return "worker"
+	}
+	// The round was finalized outside of the worker or before the sync state was updated.
+	return "storage"
+}
+
+func (n *Node) finalizeRound(ctx context.Context, summary *blockSummary) (finalizeStats, error) {
+	ctx, span := n.startSpan(ctx, SpanFinalize, SpanAttributes{Round: summary.Round})
+
+	// Failed finalizations are retried with exponential backoff as a persistently failing
+	// finalization is fatal for syncing.
+	boff := cmnBackoff.NewExponentialBackOff()
+	if n.cfg.FinalizeRetryInterval > 0 {
+		boff.InitialInterval = n.cfg.FinalizeRetryInterval
 	}
+	boff.Reset()
+
+	var (
+		stats finalizeStats
+		err   error
+	)
+	for attempt := uint64(0); ; attempt++ {
+		var (
+			result  *mkvsDB.FinalizeResult
+			start   time.Time
+			release func()
+		)
+		if release, err = n.writeLimiter.acquire(ctx); err == nil {
+			start = time.Now()
+			unlock := n.lockFinalize()
+			result, err = mkvsDB.FinalizeWithResult(ctx, n.localStorage.NodeDB(), summary.Roots)
+			unlock()
+			release()
+		}
+		switch err {
+		case nil:
+			stats.duration = time.Since(start)
+			if result != nil {
+				stats.nodes = result.Nodes
+			}
+			n.logger.Debug("storage round finalized",
+				"round", summary.Round,
+				"nodes", stats.nodes,
+				"duration", stats.duration,
+			)
+		case storageApi.ErrAlreadyFinalized:
+			// This can happen if we are restoring after a roothash migration or if
+			// we crashed before updating the sync state.
+			storageWorkerRedundantFinalizes.With(n.getMetricLabels()).Inc()
+			n.logger.Warn("storage round already finalized",
+				"round", summary.Round,
+			)
+			n.logger.Debug("redundant storage round finalization",
+				"round", summary.Round,
+				"source", n.redundantFinalizeSource(summary.Round, attempt),
+			)
+			err = nil
+		default:
+			if attempt < n.cfg.MaxFinalizeRetries {
+				delay := boff.NextBackOff()
+				n.logger.Warn("failed to finalize storage round, retrying",
+					"err", err,
+					"round", summary.Round,
+					"attempt", attempt+1,
+					"retry_in", delay,
+				)
+				select {
+				case <-time.After(delay):
+					continue
+				case <-ctx.Done():
+					err = ctx.Err()
+				}
+			}
 
-	n.finalizeCh <- finalizeResult{
-		summary: summary,
-		err:     err,
+			n.logger.Error("failed to finalize storage round",
+				"err", err,
+				"round", summary.Round,
+				"attempts", attempt+1,
+			)
+		}
+		break
 	}
+
+	span.End(err)
+	return stats, err
 }
 
 func (n *Node) initGenesis(rt *registryApi.Runtime, genesisBlock *block.Block) error {
 	n.logger.Info("initializing storage at genesis")
 
+	// Make sure we never initialize local storage with another runtime's genesis state.
+	runtimeID := n.commonNode.Runtime.ID()
+	if !rt.ID.Equal(&runtimeID) || !genesisBlock.Header.Namespace.Equal(&runtimeID) {
+		return fmt.Errorf("runtime mismatch (expected: %s descriptor: %s genesis block: %s)",
+			runtimeID,
+			rt.ID,
+			genesisBlock.Header.Namespace,
+		)
+	}
+
 	// Check what the latest finalized version in the database is as we may be using a database
 	// from a previous version or network.
-	latestVersion, _ := n.localStorage.NodeDB().GetLatestVersion()
+	latestVersion, dbNonEmpty := n.localStorage.NodeDB().GetLatestVersion()
 
 	stateRoot := storageApi.Root{
 		Namespace: rt.ID,
@@ -506,7 +1143,10 @@ func (n *Node) initGenesis(rt *registryApi.Runtime, genesisBlock *block.Block) e
 	// If we are incompatible and the local version is greater or the same as the genesis version,
 	// we cannot do anything. If the local version is lower we assume the node will sync from a
 	// different node.
-	if !compatible && latestVersion >= stateRoot.Version {
+	//
+	// Note that an empty database also reports a latest version of zero, which must not be
+	// mistaken for existing state in case the genesis round is zero.
+	if !compatible && dbNonEmpty && latestVersion >= stateRoot.Version {
 		n.logger.Error("existing state is incompatible with runtime genesis state",
 			"genesis_state_root", genesisBlock.Header.StateRoot,
 			"genesis_round", genesisBlock.Header.Round,
@@ -530,6 +1170,10 @@ func (n *Node) flushSyncedState(summary *blockSummary) (uint64, error) {
 	defer n.syncedLock.Unlock()
 
 	n.syncedState = *summary
+	if n.lastSyncErr != nil && n.lastSyncErr.Round <= summary.Round {
+		n.lastSyncErr = nil
+		n.lastSyncErrAt = time.Time{}
+	}
 	if err := n.commonNode.Runtime.History().StorageSyncCheckpoint(n.ctx, n.syncedState.Round); err != nil {
 		return 0, err
 	}
@@ -639,7 +1283,7 @@ func (n *Node) consensusCheckpointSyncer() {
 
 				// Lookup what runtime round corresponds to the given consensus layer version and make
 				// sure we checkpoint it.
-				blk, err := n.commonNode.Consensus.RootHash().GetLatestBlock(n.ctx, &roothashApi.RuntimeRequest{
+				blk, err := n.blockSource.GetLatestBlock(n.ctx, &roothashApi.RuntimeRequest{
 					RuntimeID: n.commonNode.Runtime.ID(),
 					Height:    int64(version),
 				})
@@ -679,6 +1323,53 @@ func (n *Node) consensusCheckpointSyncer() {
 	}
 }
 
+// pollLatestBlock is called by the block watchdog when no blocks have been received from the
+// block subscription for a while. It checks whether the chain is advancing and, in case it is,
+// returns the latest block from local history so that it can be used to drive the sync.
+func (n *Node) pollLatestBlock(latestRound uint64, polling bool) (*block.Block, bool) {
+	chainBlk, err := n.blockSource.GetLatestBlock(n.ctx, &roothashApi.RuntimeRequest{
+		RuntimeID: n.commonNode.Runtime.ID(),
+		Height:    consensus.HeightLatest,
+	})
+	if err != nil {
+		n.logger.Warn("failed to query latest block",
+			"err", err,
+		)
+		return nil, false
+	}
+	if latestRound != n.undefinedRound && chainBlk.Header.Round <= latestRound {
+		// Chain is not advancing, nothing to do.
+		return nil, false
+	}
+
+	if !polling {
+		n.logger.Warn("no blocks received from block subscription while chain is advancing, falling back to polling",
+			"latest_round", latestRound,
+			"chain_round", chainBlk.Header.Round,
+			"interval", n.cfg.BlockWatchdogInterval,
+		)
+		storageWorkerBlockPollingFallbacks.With(n.getMetricLabels()).Inc()
+	}
+
+	// Use the local history as the source of blocks as it is also used for fetching any missing
+	// block summaries.
+	blk, err := n.commonNode.Runtime.History().GetCommittedBlock(n.ctx, roothashApi.RoundLatest)
+	if err != nil {
+		n.logger.Warn("failed to query latest block from local history",
+			"err", err,
+		)
+		return nil, true
+	}
+	if latestRound != n.undefinedRound && blk.Header.Round <= latestRound {
+		n.logger.Debug("local history has not advanced yet",
+			"latest_round", latestRound,
+			"chain_round", chainBlk.Header.Round,
+		)
+		return nil, true
+	}
+	return blk, true
+}
+
 // This is only called from the main worker goroutine, so no locking should be necessary.
 func (n *Node) nudgeAvailability(lastSynced, latest uint64) {
 	if lastSynced == n.undefinedRound || latest == n.undefinedRound {
@@ -704,39 +1395,268 @@ func (n *Node) nudgeAvailability(lastSynced, latest uint64) {
 	}
 }
 
-func (n *Node) worker() { // nolint: gocyclo
-	defer close(n.workerQuitCh)
-	defer close(n.diffCh)
+// reconcileSyncedState reconciles the last synced state restored from local storage with the
+// given genesis block and returns the round from which syncing should continue.
+//
+// In case the synced state predates genesis (e.g., the network went through a dump/restore
+// upgrade), the synced state is discarded and syncing starts from genesis. Otherwise the synced
+// state is verified to be consistent with genesis and local storage.
+func (n *Node) reconcileSyncedState(genesisBlock *block.Block) (uint64, error) {
+	n.syncedLock.Lock()
+	defer n.syncedLock.Unlock()
 
-	// Wait for the common node to be initialized.
-	select {
-	case <-n.commonNode.Initialized():
-	case <-n.ctx.Done():
-		close(n.initCh)
+	synced := n.syncedState
+	switch {
+	case synced.Round == defaultUndefinedRound || synced.Round == n.undefinedRound:
+		return n.undefinedRound, nil
+	case synced.Round < genesisBlock.Header.Round:
+		n.logger.Info("local storage predates genesis, starting from genesis",
+			"last_synced", synced.Round,
+			"genesis_round", genesisBlock.Header.Round,
+		)
+		n.syncedState = blockSummary{
+			Namespace: genesisBlock.Header.Namespace,
+			Round:     n.undefinedRound,
+		}
+		return n.undefinedRound, nil
+	}
+
+	for _, root := range synced.Roots {
+		if synced.Round == genesisBlock.Header.Round && root.Type == storageApi.RootTypeState &&
+			!root.Hash.Equal(&genesisBlock.Header.StateRoot) {
+			return 0, fmt.Errorf("state root at genesis round %d does not match genesis (expected: %s got: %s)",
+				genesisBlock.Header.Round,
+				genesisBlock.Header.StateRoot,
+				root.Hash,
+			)
+		}
+		// Empty roots are always implicitly present.
+		if root.Hash.IsEmpty() {
+			continue
+		}
+		if !n.localStorage.NodeDB().HasRoot(root) {
+			return 0, fmt.Errorf("root for last synced round %d is missing from local storage (root: %s)",
+				synced.Round,
+				root.Hash,
+			)
+		}
+	}
+	return synced.Round, nil
+}
+
+// missingRoot returns the first root of the given summary that is missing from local storage, if
+// any.
+func (n *Node) missingRoot(summary *blockSummary) *storageApi.Root {
+	for _, root := range summary.Roots {
+		// Empty roots are always implicitly present.
+		if root.Hash.IsEmpty() {
+			continue
+		}
+		if !n.localStorage.NodeDB().HasRoot(root) {
+			return &root
+		}
+	}
+	return nil
+}
+
+// rewindToVerifiedRound returns the summary of the highest round, not above the round of the given
+// summary, whose roots are all present in local storage.
+//
+// In case local storage and the runtime history got out of sync (e.g., they were restored from
+// mismatched backups), the roots of the latest finalized version in local storage do not match
+// the roots of the corresponding block, so the sync position can't be trusted. Instead of failing,
+// the sync position is conservatively rewound to the last round that can be verified against local
+// storage. The sync position is never rewound past the earliest version in local storage or the
+// genesis round.
+func (n *Node) rewindToVerifiedRound(summary *blockSummary) (*blockSummary, error) {
+	lastRound := summary.Round
+	earliestVersion := n.localStorage.NodeDB().GetEarliestVersion()
+	for {
+		root := n.missingRoot(summary)
+		if root == nil {
+			break
+		}
+		n.logger.Warn("root for last synced round is missing from local storage",
+			"round", summary.Round,
+			"root", root.Hash,
+			"root_type", root.Type,
+		)
+		if summary.Round <= earliestVersion || summary.Round == n.undefinedRound+1 {
+			return nil, fmt.Errorf("roots of all rounds up to %d are missing from local storage", lastRound)
+		}
+
+		blk, err := n.commonNode.Runtime.History().GetCommittedBlock(n.ctx, summary.Round-1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block for round %d: %w", summary.Round-1, err)
+		}
+		if summary, err = summaryFromBlock(n.commonNode.Runtime.ID(), blk); err != nil {
+			return nil, err
+		}
+	}
+
+	if summary.Round != lastRound {
+		n.logger.Warn("rewound last synced round to the last round verified against local storage",
+			"last_finalized_version", lastRound,
+			"round", summary.Round,
+		)
+	}
+	return summary, nil
+}
+
+// fetchBlockSummaries populates the hash cache with summaries of all the blocks in the (inclusive)
+// range from startRound to endRound that are not already cached. In case the runtime history
+// supports it, blocks are fetched in batches of at most maxBlockSummaryBatchSize blocks.
+func (n *Node) fetchBlockSummaries(hashCache map[uint64]*blockSummary, startRound, endRound uint64) error {
+	rh := n.commonNode.Runtime.History()
+	batchHistory, canBatch := rh.(history.BatchBlockHistory)
+
+	for round := startRound; round <= endRound; round++ {
+		if _, ok := hashCache[round]; ok {
+			continue
+		}
+
+		if !canBatch {
+			blk, err := rh.GetCommittedBlock(n.ctx, round)
+			if err != nil {
+				return fmt.Errorf("failed to get block for round %d: %w", round, err)
+			}
+			if hashCache[round], err = summaryFromBlock(n.commonNode.Runtime.ID(), blk); err != nil {
+				return err
+			}
+			continue
+		}
+
+		batchEnd := endRound
+		if batchEnd-round >= maxBlockSummaryBatchSize {
+			batchEnd = round + maxBlockSummaryBatchSize - 1
+		}
+		blks, err := batchHistory.GetCommittedBlocks(n.ctx, round, batchEnd)
+		if err != nil {
+			return fmt.Errorf("failed to get blocks for rounds %d-%d: %w", round, batchEnd, err)
+		}
+		for _, blk := range blks {
+			if _, ok := hashCache[blk.Header.Round]; ok {
+				continue
+			}
+			summary, err := summaryFromBlock(n.commonNode.Runtime.ID(), blk)
+			if err != nil {
+				return err
+			}
+			hashCache[blk.Header.Round] = summary
+		}
+		round = batchEnd
+	}
+	return nil
+}
+
+// prioritizedTipRounds returns the number of in-flight round slots reserved for the most recent
+// rounds, given the maximum number of rounds in flight. At least one slot is always left for
+// syncing the oldest rounds, as otherwise syncing could not make progress.
+func (n *Node) prioritizedTipRounds(maxRounds int) int {
+	if maxRounds <= 1 {
+		return 0
+	}
+	if n.cfg.PrioritizedTipRounds >= uint64(maxRounds) {
+		return maxRounds - 1
+	}
+	return int(n.cfg.PrioritizedTipRounds)
+}
+
+// getGenesisBlock retrieves the genesis block of the runtime, retrying failures with exponential
+// backoff as configured, so that transient consensus layer issues do not fail startup.
+func (n *Node) getGenesisBlock() (*block.Block, error) {
+	boff := cmnBackoff.NewExponentialBackOff()
+	if n.cfg.GenesisBlockRetryInterval > 0 {
+		boff.InitialInterval = n.cfg.GenesisBlockRetryInterval
+	}
+	boff.Reset()
+
+	for attempt := uint64(0); ; attempt++ {
+		blk, err := n.blockSource.GetGenesisBlock(n.ctx, &roothashApi.RuntimeRequest{
+			RuntimeID: n.commonNode.Runtime.ID(),
+			Height:    consensus.HeightLatest,
+		})
+		if err == nil {
+			return blk, nil
+		}
+		if attempt >= n.cfg.MaxGenesisBlockRetries {
+			return nil, err
+		}
+
+		delay := boff.NextBackOff()
+		n.logger.Warn("failed to retrieve genesis block, retrying",
+			"err", err,
+			"attempt", attempt+1,
+			"retry_in", delay,
+		)
+		select {
+		case <-time.After(delay):
+		case <-n.ctx.Done():
+			return nil, n.ctx.Err()
+		}
+	}
+}
+
+func (n *Node) worker() {
+	defer close(n.workerQuitCh)
+	defer close(n.diffCh)
+
+	// Wait for the common node to be initialized.
+	select {
+	case <-n.commonNode.Initialized():
+	case <-n.ctx.Done():
+		n.markInitialized()
+		return
+	}
+
+	if n.cfg.ObserverMode {
+		n.observe()
 		return
 	}
+	n.syncWorker()
+}
+
+// syncWorker initializes local storage and then syncs rounds as new blocks are received until the
+// node is stopped. The common node must already be initialized.
+func (n *Node) syncWorker() { // nolint: gocyclo
+	// Make sure that initialization is never waited for in vain in case startup fails.
+	defer n.markInitialized()
 
 	n.logger.Info("starting committee node")
 
 	// Determine genesis block.
-	genesisBlock, err := n.commonNode.Consensus.RootHash().GetGenesisBlock(n.ctx, &roothashApi.RuntimeRequest{
-		RuntimeID: n.commonNode.Runtime.ID(),
-		Height:    consensus.HeightLatest,
-	})
+	genesisBlock, err := n.getGenesisBlock()
 	if err != nil {
 		n.logger.Error("can't retrieve genesis block", "err", err)
 		return
 	}
 	n.undefinedRound = genesisBlock.Header.Round - 1
 
-	// Determine last finalized storage version.
-	if version, dbNonEmpty := n.localStorage.NodeDB().GetLatestVersion(); dbNonEmpty {
+	// Determine last finalized storage version, unless the synced state has been imported.
+	if imported := n.startSync(); imported != nil {
+		n.logger.Info("using imported synced state",
+			"round", imported.Round,
+		)
+		if _, err = n.flushSyncedState(imported); err != nil {
+			n.logger.Error("failed to flush synced state", "err", err)
+			return
+		}
+	} else if version, dbNonEmpty := n.localStorage.NodeDB().GetLatestVersion(); dbNonEmpty {
 		var blk *block.Block
 		blk, err = n.commonNode.Runtime.History().GetCommittedBlock(n.ctx, version)
 		switch err {
 		case nil:
 			// Set last synced version to last finalized storage version.
-			if _, err = n.flushSyncedState(summaryFromBlock(blk)); err != nil {
+			var summary *blockSummary
+			if summary, err = summaryFromBlock(n.commonNode.Runtime.ID(), blk); err != nil {
+				n.logger.Error("can't summarize last finalized block", "err", err)
+				return
+			}
+			if summary, err = n.rewindToVerifiedRound(summary); err != nil {
+				n.logger.Error("local storage is inconsistent with runtime history", "err", err)
+				return
+			}
+			if _, err = n.flushSyncedState(summary); err != nil {
 				n.logger.Error("failed to flush synced state", "err", err)
 				return
 			}
@@ -753,11 +1673,10 @@ func (n *Node) worker() { // nolint: gocyclo
 
 	var fetcherGroup sync.WaitGroup
 
-	n.syncedLock.RLock()
-	cachedLastRound := n.syncedState.Round
-	n.syncedLock.RUnlock()
-	if cachedLastRound == defaultUndefinedRound || cachedLastRound < genesisBlock.Header.Round {
-		cachedLastRound = n.undefinedRound
+	cachedLastRound, err := n.reconcileSyncedState(genesisBlock)
+	if err != nil {
+		n.logger.Error("local storage is inconsistent with genesis", "err", err)
+		return
 	}
 
 	// Initialize genesis from the runtime descriptor.
@@ -861,7 +1780,14 @@ func (n *Node) worker() { // nolint: gocyclo
 						return
 					}
 				}
-				cachedLastRound, err = n.flushSyncedState(summaryFromBlock(earlyBlk))
+				var summary *blockSummary
+				if summary, err = summaryFromBlock(n.commonNode.Runtime.ID(), earlyBlk); err != nil {
+					n.logger.Error("can't summarize block",
+						"err", err,
+					)
+					return
+				}
+				cachedLastRound, err = n.flushSyncedState(summary)
 				if err != nil {
 					n.logger.Error("failed to flush synced state",
 						"err", err,
@@ -899,6 +1825,7 @@ func (n *Node) worker() { // nolint: gocyclo
 	outOfOrderDoneDiffs := &outOfOrderRoundQueue{}
 	outOfOrderFinalizable := &outOfOrderRoundQueue{}
 	syncingRounds := make(map[uint64]*inFlight)
+	appliedDiffs := make(appliedWriteLogs)
 	hashCache := make(map[uint64]*blockSummary)
 	lastFullyAppliedRound := cachedLastRound
 
@@ -911,7 +1838,7 @@ func (n *Node) worker() { // nolint: gocyclo
 	//
 	// - We haven't synced anything yet and checkpoint sync is not disabled.
 	//
-	if (isInitialStartup && !n.checkpointSyncCfg.Disabled) || n.checkpointSyncForced {
+	if (isInitialStartup && !n.checkpointSyncCfg.Disabled && n.storageSync != nil) || n.checkpointSyncForced {
 		var (
 			summary *blockSummary
 			attempt int
@@ -966,84 +1893,509 @@ func (n *Node) worker() { // nolint: gocyclo
 			)
 		}
 	}
-	close(n.initCh)
+
+	// Prewarm the hash cache with the persisted summaries of blocks seen before a restart.
+	var summaries *summaryStore
+	if n.cfg.PersistedSummaries > 0 && n.cfg.PersistedSummariesPath != "" {
+		summaries = newSummaryStore(n.cfg.PersistedSummariesPath, n.cfg.PersistedSummaries)
+		if err = summaries.load(n.commonNode.Runtime.ID()); err != nil {
+			n.logger.Warn("failed to load persisted block summaries",
+				"err", err,
+			)
+			summaries = newSummaryStore(n.cfg.PersistedSummariesPath, n.cfg.PersistedSummaries)
+		}
+		if count := summaries.prewarm(hashCache, lastFullyAppliedRound); count > 0 {
+			n.logger.Info("prewarmed hash cache with persisted block summaries",
+				"summaries", count,
+				"last_finalized", lastFullyAppliedRound,
+			)
+		}
+	}
+	n.markInitialized()
 
 	// Don't register availability immediately, we want to know first how far behind consensus we are.
 	latestBlockRound := n.undefinedRound
 
+	// Fully applied rounds waiting to be finalized, as directed by the finalize policy.
+	var pendingFinalize []*blockSummary
+	lastFinalizableRound := cachedLastRound
+	// Batches of rounds being finalized, which may complete out of order in case finalization is
+	// concurrent.
+	finalizing := newFinalizeTracker(cachedLastRound)
+	maxFinalizes := n.finalizeConcurrency()
+	// Batches of rounds that failed to finalize, ordered by round, which are retried on the next
+	// heartbeat (as their own batches, so that batches always consist of consecutive rounds). No
+	// following rounds are finalized until these have been finalized.
+	var (
+		requeuedFinalize [][]*blockSummary
+		retryFinalize    bool
+	)
+	// Write log statistics of fully applied rounds which are not yet finalized.
+	pendingStats := make(map[uint64]writeLogStats)
+
 	heartbeat := heartbeat{}
-	heartbeat.reset()
+	heartbeat.reset(n.Tunables())
 
-	triggerRoundFetches := func() {
-		for i := lastFullyAppliedRound + 1; i <= latestBlockRound; i++ {
-			syncing, ok := syncingRounds[i]
-			if ok && syncing.outstanding.hasAll() {
-				continue
-			}
+	// The latest received block, which is processed again when the sync ceiling is raised.
+	var lastBlock *block.Block
 
-			if !ok {
-				if len(syncingRounds) >= maxInFlightRounds {
-					break
-				}
+	var verifier *blockVerifier
+	if n.cfg.VerifyBlocks {
+		verifier = newBlockVerifier(n.commonNode.Runtime.History())
+	}
 
-				syncing = &inFlight{
-					startedAt:     time.Now(),
-					awaitingRetry: outstandingMaskFull,
-				}
-				syncingRounds[i] = syncing
+	var backlogLimited bool
 
-				if i == latestBlockRound {
-					storageWorkerLastPendingRound.With(n.getMetricLabels()).Set(float64(i))
+	// In case the node falls too far behind, incremental sync is paused until all fetches and
+	// finalizations in progress have completed, after which the state is restored from a
+	// checkpoint. A failed restore is only retried once the node falls behind the round at which
+	// it was attempted by the threshold again.
+	var (
+		restorePending     bool
+		restoreAttemptedAt uint64
+		fetchesInFlight    int
+	)
+
+	// Total size of the write logs of fetched diffs that are waiting to be applied.
+	var bufferedDiffBytes uint64
+	var bufferLimited bool
+	trackBufferedDiff := func(writeLog storageApi.WriteLog, added bool) {
+		size := cachedWriteLog(writeLog).Size()
+		if added {
+			bufferedDiffBytes += size
+		} else {
+			bufferedDiffBytes -= size
+		}
+		storageWorkerBufferedDiffBytes.With(n.getMetricLabels()).Set(float64(bufferedDiffBytes))
+	}
+
+	// Fetches scheduled by fetchRound that have not been submitted to the fetch queue yet.
+	var pendingFetches []scheduledFetch
+
+	// fetchRound schedules fetches of all diffs of the given round that are not being fetched yet.
+	// It returns false in case the round can't be scheduled as too many rounds are in flight.
+	fetchRound := func(i, syncRound uint64, maxRounds int) bool {
+		syncing, ok := syncingRounds[i]
+		if ok && syncing.fetching() {
+			return true
+		}
+
+		if !ok {
+			if len(syncingRounds) >= maxRounds {
+				if !backlogLimited {
+					n.logger.Warn("too many rounds in flight, waiting for earlier rounds to be applied",
+						"round", i,
+						"in_flight_rounds", len(syncingRounds),
+					)
+					n.reportSyncError(newSyncError(ErrBacklogTooLarge, i, storageApi.RootTypeInvalid, nil))
+					backlogLimited = true
 				}
+				return false
 			}
-			n.logger.Debug("preparing round sync",
-				"round", i,
-				"outstanding_mask", syncing.outstanding,
-				"awaiting_retry", syncing.awaitingRetry,
-			)
+			backlogLimited = false
 
-			prev := hashCache[i-1] // Closures take refs, so they need new variables here.
-			this := hashCache[i]
-			prevRoots := make([]storageApi.Root, len(prev.Roots))
-			copy(prevRoots, prev.Roots)
-			for i := range prevRoots {
-				if prevRoots[i].Type == storageApi.RootTypeIO {
-					// IO roots aren't chained, so clear it (but leave cache intact).
-					prevRoots[i] = storageApi.Root{
-						Namespace: this.Namespace,
-						Version:   this.Round,
-						Type:      storageApi.RootTypeIO,
-					}
-					prevRoots[i].Hash.Empty()
-					break
+			syncing = newInFlight(outstandingMaskFull)
+			syncing.ctx, syncing.span = n.startSpan(n.ctx, SpanSyncRound, SpanAttributes{Round: i})
+			syncingRounds[i] = syncing
+
+			if i == syncRound {
+				storageWorkerLastPendingRound.With(n.getMetricLabels()).Set(float64(i))
+			}
+		}
+		if n.isRoundQuarantined(i) {
+			// Quarantined rounds are not retried until they are released by the operator.
+			return true
+		}
+		n.logger.Debug("preparing round sync",
+			"round", i,
+			"outstanding_mask", syncing.outstanding,
+			"awaiting_retry", syncing.awaitingRetry,
+		)
+
+		prev := hashCache[i-1] // Closures take refs, so they need new variables here.
+		this := hashCache[i]
+		prevRoots := make([]storageApi.Root, len(prev.Roots))
+		copy(prevRoots, prev.Roots)
+		for i := range prevRoots {
+			if prevRoots[i].Type == storageApi.RootTypeIO {
+				// IO roots aren't chained, so clear it (but leave cache intact).
+				prevRoots[i] = storageApi.Root{
+					Namespace: this.Namespace,
+					Version:   this.Round,
+					Type:      storageApi.RootTypeIO,
 				}
+				prevRoots[i].Hash.Empty()
+				break
 			}
+		}
 
-			for i := range prevRoots {
-				rootType := prevRoots[i].Type
-				if !syncing.outstanding.contains(rootType) && syncing.awaitingRetry.contains(rootType) {
-					syncing.scheduleDiff(rootType)
-					fetcherGroup.Add(1)
-					n.fetchPool.Submit(func(round uint64, prevRoot, thisRoot storageApi.Root) func() {
+		for i := range prevRoots {
+			rootType := prevRoots[i].Type
+			if !syncing.outstanding.contains(rootType) && syncing.awaitingRetry.contains(rootType) {
+				syncing.scheduleDiff(rootType)
+				n.transitions.record(TransitionFetchSubmitted, this.Round, rootType, syncing.outstanding)
+				priority := FetchPriorityNormal
+				if n.cfg.PrioritizeStateFetches && rootType == storageApi.RootTypeState {
+					priority = FetchPriorityHigh
+				}
+				fetcherGroup.Add(1)
+				fetchesInFlight++
+				pendingFetches = append(pendingFetches, scheduledFetch{
+					round:    this.Round,
+					rootType: rootType,
+					priority: priority,
+					job: func(ctx context.Context, round uint64, prevRoot, thisRoot storageApi.Root) func() {
 						return func() {
 							defer fetcherGroup.Done()
-							n.fetchDiff(round, prevRoot, thisRoot)
+							n.fetchDiff(ctx, round, prevRoot, thisRoot)
 						}
-					}(this.Round, prevRoots[i], this.Roots[i]))
+					}(syncing.ctx, this.Round, prevRoots[i], this.Roots[i]),
+				})
+			}
+		}
+		return true
+	}
+
+	// submitFetches submits the fetches scheduled by fetchRound to the fetch queue, in the
+	// configured order.
+	submitFetches := func() {
+		for _, fetch := range orderFetches(pendingFetches, n.cfg.FetchOrdering) {
+			n.fetchQueue.SubmitWithPriority(n.commonNode.Runtime.ID(), fetch.priority, fetch.job)
+		}
+		pendingFetches = nil
+	}
+
+	triggerRoundFetches := func() {
+		if restorePending {
+			return
+		}
+		defer submitFetches()
+		syncRound := n.capSyncRound(latestBlockRound)
+		maxRounds := int(n.Tunables().MaxInFlightRounds)
+		firstRound := lastFullyAppliedRound + 1
+
+		// In case too much fetched data is waiting to be applied, only fetch the round that is
+		// applied next (which is needed to drain the buffer) until applying catches up.
+		if limit := n.cfg.MaxBufferedDiffBytes; limit > 0 && bufferedDiffBytes >= limit {
+			if !bufferLimited {
+				n.logger.Warn("too much fetched data waiting to be applied, pausing fetches",
+					"buffered_bytes", bufferedDiffBytes,
+					"limit", limit,
+				)
+				bufferLimited = true
+			}
+			if firstRound <= syncRound {
+				fetchRound(firstRound, syncRound, maxRounds)
+			}
+			return
+		}
+		bufferLimited = false
+
+		// Rounds up to backfillRound are synced oldest-first. In case near-tip rounds are
+		// prioritized and the backlog does not fit into the in-flight rounds, some in-flight
+		// slots are reserved for the most recent rounds, which are fetched first.
+		backfillRound := syncRound
+		tipRounds := n.prioritizedTipRounds(maxRounds)
+		if tipRounds > 0 && syncRound >= firstRound && syncRound-firstRound >= uint64(maxRounds) {
+			backfillRound = firstRound + uint64(maxRounds-tipRounds) - 1
+
+			var tipInFlight int
+			for round := range syncingRounds {
+				if round > backfillRound {
+					tipInFlight++
 				}
 			}
+			for i := syncRound; i > syncRound-uint64(tipRounds); i-- {
+				if _, ok := syncingRounds[i]; !ok {
+					if tipInFlight >= tipRounds {
+						break
+					}
+					tipInFlight++
+				}
+				if !fetchRound(i, syncRound, maxRounds) {
+					break
+				}
+			}
+		}
+
+		for i := firstRound; i <= backfillRound; i++ {
+			if !fetchRound(i, syncRound, maxRounds) {
+				break
+			}
 		}
 	}
 
+	processBlock := func(blk *block.Block) {
+		if runtimeID := n.commonNode.Runtime.ID(); !blk.Header.Namespace.Equal(&runtimeID) {
+			// Never sync data of other runtimes.
+			n.logger.Error("ignoring block for a different runtime",
+				"round", blk.Header.Round,
+				"namespace", blk.Header.Namespace,
+			)
+			n.reportSyncError(newSyncError(ErrNamespaceMismatch, blk.Header.Round, storageApi.RootTypeInvalid, nil))
+			return
+		}
+		if latestBlockRound != n.undefinedRound && blk.Header.Round < latestBlockRound {
+			// This can happen when the block subscription recovers after we have already
+			// obtained later blocks by polling.
+			n.logger.Debug("ignoring stale block",
+				"round", blk.Header.Round,
+				"latest_round", latestBlockRound,
+			)
+			return
+		}
+		if verifier != nil {
+			if verr := verifier.verify(n.ctx, blk); verr != nil {
+				n.logger.Error("ignoring block that failed verification",
+					"err", verr,
+					"round", blk.Header.Round,
+				)
+				n.reportSyncError(newSyncError(ErrBlockVerificationFailed, blk.Header.Round, storageApi.RootTypeInvalid, verr))
+				return
+			}
+		}
+
+		n.logger.Debug("incoming block",
+			"round", blk.Header.Round,
+			"last_synced", lastFullyAppliedRound,
+			"last_finalized", cachedLastRound,
+		)
+		n.transitions.record(TransitionBlockReceived, blk.Header.Round, storageApi.RootTypeInvalid, 0)
+
+		// Check if we're far enough to reasonably register as available.
+		latestBlockRound = blk.Header.Round
+		lastBlock = blk
+		n.nudgeAvailability(cachedLastRound, latestBlockRound)
+
+		// Blocks received while a checkpoint restore is pending are processed once it completes.
+		if !restorePending && n.exceedsCheckpointRestoreLag(cachedLastRound, restoreAttemptedAt, latestBlockRound) {
+			n.logger.Info("too far behind, pausing incremental sync to restore from a checkpoint",
+				"last_finalized", cachedLastRound,
+				"latest_round", latestBlockRound,
+			)
+			restorePending = true
+		}
+		if restorePending {
+			return
+		}
+
+		// Only sync rounds up to the sync ceiling, if any. Only the latest block is kept around so
+		// that sync can resume once the ceiling is raised.
+		syncRound := n.capSyncRound(blk.Header.Round)
+		if syncRound < blk.Header.Round {
+			n.logger.Debug("block beyond sync ceiling, not syncing further",
+				"round", blk.Header.Round,
+				"sync_ceiling", syncRound,
+			)
+		}
+
+		if _, ok := hashCache[lastFullyAppliedRound]; !ok && lastFullyAppliedRound == n.undefinedRound {
+			dummy := blockSummary{
+				Namespace: blk.Header.Namespace,
+				Round:     lastFullyAppliedRound + 1,
+				Roots: []storageApi.Root{
+					{
+						Version: lastFullyAppliedRound + 1,
+						Type:    storageApi.RootTypeIO,
+					},
+					{
+						Version: lastFullyAppliedRound + 1,
+						Type:    storageApi.RootTypeState,
+					},
+				},
+			}
+			dummy.Roots[0].Empty()
+			dummy.Roots[1].Empty()
+			hashCache[lastFullyAppliedRound] = &dummy
+		}
+		// Determine if we need to fetch any old block summaries. In case the first
+		// round is an undefined round, we need to start with the following round
+		// since the undefined round may be unsigned -1 and in this case the loop
+		// would not do any iterations.
+		startSummaryRound := lastFullyAppliedRound
+		if startSummaryRound == n.undefinedRound {
+			startSummaryRound++
+		}
+		endSummaryRound := blk.Header.Round - 1
+		if syncRound < blk.Header.Round {
+			endSummaryRound = syncRound
+		}
+		if endSummaryRound >= startSummaryRound && blk.Header.Round > startSummaryRound {
+			if err = n.fetchBlockSummaries(hashCache, startSummaryRound, endSummaryRound); err != nil {
+				n.logger.Error("can't get blocks for rounds",
+					"err", err,
+					"start_round", startSummaryRound,
+					"current_round", blk.Header.Round,
+				)
+				panic("can't get block in storage worker")
+			}
+			for round := startSummaryRound; round <= endSummaryRound; round++ {
+				summaries.add(hashCache[round])
+			}
+		}
+		if _, ok := hashCache[blk.Header.Round]; !ok && syncRound == blk.Header.Round {
+			var summary *blockSummary
+			if summary, err = summaryFromBlock(n.commonNode.Runtime.ID(), blk); err != nil {
+				n.logger.Error("can't summarize block",
+					"err", err,
+					"round", blk.Header.Round,
+				)
+				panic("unsupported block in storage worker")
+			}
+			hashCache[blk.Header.Round] = summary
+			summaries.add(summary)
+		}
+
+		triggerRoundFetches()
+		heartbeat.reset(n.Tunables())
+	}
+
+	// restoreCheckpoint restores the state from the most recent usable checkpoint, abandoning
+	// incremental sync of all rounds that were not finalized yet, and resumes incremental sync
+	// from the checkpoint round. In case the restore fails, incremental sync resumes where it was
+	// paused. No fetches or finalizations may be in progress.
+	restoreCheckpoint := func() {
+		restorePending = false
+		restoreAttemptedAt = latestBlockRound
+
+		summary, cerr := n.syncCheckpoints(genesisBlock.Header.Round)
+		if cerr != nil {
+			n.logger.Warn("checkpoint restore failed, resuming incremental sync",
+				"err", cerr,
+				"last_finalized", cachedLastRound,
+			)
+			processBlock(lastBlock)
+			return
+		}
+
+		for round, syncing := range syncingRounds {
+			syncing.span.End(nil)
+			delete(syncingRounds, round)
+		}
+		for len(*outOfOrderDoneDiffs) > 0 {
+			trackBufferedDiff(heap.Pop(outOfOrderDoneDiffs).(*fetchedDiff).writeLog, false)
+		}
+		*outOfOrderFinalizable = (*outOfOrderFinalizable)[:0]
+		appliedDiffs = make(appliedWriteLogs)
+		pendingStats = make(map[uint64]writeLogStats)
+		pendingFinalize = nil
+		requeuedFinalize = nil
+		retryFinalize = false
+		for round := range hashCache {
+			if round < summary.Round {
+				delete(hashCache, round)
+			}
+		}
+
+		if cachedLastRound, cerr = n.flushSyncedState(summary); cerr != nil {
+			n.logger.Error("failed to flush synced state",
+				"err", cerr,
+			)
+		}
+		lastFullyAppliedRound = summary.Round
+		lastFinalizableRound = summary.Round
+		finalizing = newFinalizeTracker(summary.Round)
+		storageWorkerLastSyncedRound.With(n.getMetricLabels()).Set(float64(summary.Round))
+		storageWorkerLastFullRound.With(n.getMetricLabels()).Set(float64(summary.Round))
+		if n.checkpointer != nil {
+			n.checkpointer.NotifyNewVersion(summary.Round)
+		}
+		n.logger.Info("checkpoint restore succeeded, resuming incremental sync",
+			"round", summary.Round,
+		)
+
+		processBlock(lastBlock)
+	}
+
+	// Set up the block watchdog which detects a stalled block subscription.
+	var blockWatchdogCh <-chan time.Time
+	if n.cfg.BlockWatchdogInterval > 0 {
+		blockWatchdog := time.NewTicker(n.cfg.BlockWatchdogInterval)
+		defer blockWatchdog.Stop()
+		blockWatchdogCh = blockWatchdog.C
+	}
+	lastBlockReceivedAt := time.Now()
+	var pollingFallback bool
+
+	// In the catch-up and exit mode, sync up to the latest round known at this point and then exit.
+	var catchUpRound uint64
+	caughtUp := func() bool {
+		if !n.cfg.CatchUpAndExit || cachedLastRound == n.undefinedRound {
+			return false
+		}
+		targetRound := catchUpRound
+		if ceiling := n.SyncCeiling(); ceiling != 0 && ceiling < targetRound {
+			targetRound = ceiling
+		}
+		if cachedLastRound < targetRound {
+			return false
+		}
+
+		n.logger.Info("caught up, exiting",
+			"round", cachedLastRound,
+			"catch_up_round", catchUpRound,
+		)
+		close(n.caughtUpCh)
+		// Stop all remaining background work of the node and its fetchers.
+		n.Stop()
+		return true
+	}
+	if n.cfg.CatchUpAndExit {
+		var tip *block.Block
+		tip, err = n.blockSource.GetLatestBlock(n.ctx, &roothashApi.RuntimeRequest{
+			RuntimeID: n.commonNode.Runtime.ID(),
+			Height:    consensus.HeightLatest,
+		})
+		if err != nil {
+			n.logger.Error("can't retrieve latest block", "err", err)
+			return
+		}
+		catchUpRound = tip.Header.Round
+		n.logger.Info("catching up before exiting",
+			"catch_up_round", catchUpRound,
+		)
+
+		if caughtUp() {
+			return
+		}
+		// Don't wait for the next block to start syncing.
+		processBlock(tip)
+	}
+
 	// Main processing loop. When a new block comes in, its state and io roots are inspected and their
 	// writelogs fetched from remote storage nodes in case we don't have them locally yet. Fetches are
 	// asynchronous and, once complete, trigger local Apply operations. These are serialized
 	// per round (all applies for a given round have to be complete before applying anyting for following
 	// rounds) using the outOfOrderDoneDiffs priority queue and outOfOrderFinalizable. Once a round has all its write
-	// logs applied, a Finalize for it is triggered, again serialized by round but otherwise asynchronous
-	// (outOfOrderFinalizable and cachedLastRound).
+	// logs applied, it is queued for finalization, again serialized by round but otherwise asynchronous
+	// (outOfOrderFinalizable, pendingFinalize and cachedLastRound). The finalize policy decides whether
+	// queued rounds are finalized immediately or in batches.
+
+	// Set up the watchdog which detects a stalled main loop.
+	var watchdog *loopWatchdog
+	if n.cfg.LoopWatchdogInterval > 0 {
+		watchdog = newLoopWatchdog(n.logger, n.cfg.LoopWatchdogInterval, n.getMetricLabels())
+		watchdogCtx, cancelWatchdog := context.WithCancel(n.ctx)
+		defer cancelWatchdog()
+		go watchdog.run(watchdogCtx)
+	}
+
 mainLoop:
 	for {
+		if watchdog != nil {
+			watchdog.beat(len(syncingRounds) > 0 || len(*outOfOrderDoneDiffs) > 0 || len(*outOfOrderFinalizable) > 0 ||
+				len(pendingFinalize) > 0 || len(requeuedFinalize) > 0)
+		}
+
+		// Restore from a checkpoint once all fetches and finalizations in progress have completed.
+		if restorePending && fetchesInFlight == 0 && finalizing.inFlight == 0 {
+			restoreCheckpoint()
+			if caughtUp() {
+				break mainLoop
+			}
+			continue
+		}
+
 		// Drain the Apply and Finalize queues first, before waiting for new events in the select
 		// below. Applies are drained first, followed by finalizations (which are asynchronous
 		// but serialized, i.e. only one Finalize can be in progress at a time).
@@ -1052,23 +2404,39 @@ mainLoop:
 		// after the last fully applied one (lastFullyAppliedRound).
 		if len(*outOfOrderDoneDiffs) > 0 && lastFullyAppliedRound+1 == (*outOfOrderDoneDiffs)[0].GetRound() {
 			lastDiff := heap.Pop(outOfOrderDoneDiffs).(*fetchedDiff)
+			trackBufferedDiff(lastDiff.writeLog, false)
+			if n.cfg.DebugValidateRootChaining {
+				if cerr := validateRootChaining(hashCache[lastFullyAppliedRound], lastDiff.prevRoot, lastDiff.thisRoot); cerr != nil {
+					n.logger.Error("storage root chaining assumption violated",
+						"err", cerr,
+						"round", lastDiff.round,
+						"old_root", lastDiff.prevRoot,
+						"new_root", lastDiff.thisRoot,
+					)
+				}
+			}
 			// Apply the write log if one exists.
+			syncing := syncingRounds[lastDiff.round]
 			err = nil
 			if lastDiff.fetched {
-				err = n.localStorage.Apply(n.ctx, &storageApi.ApplyRequest{
-					Namespace: lastDiff.thisRoot.Namespace,
-					RootType:  lastDiff.thisRoot.Type,
-					SrcRound:  lastDiff.prevRoot.Version,
-					SrcRoot:   lastDiff.prevRoot.Hash,
-					DstRound:  lastDiff.thisRoot.Version,
-					DstRoot:   lastDiff.thisRoot.Hash,
-					WriteLog:  lastDiff.writeLog,
-				})
+				progress := n.applyProgress(lastDiff.round, lastDiff.thisRoot.Type, syncing)
+				err = n.applyDiffBatch(syncing.ctx, appliedDiffs, lastDiff, *outOfOrderDoneDiffs, progress)
 				switch {
 				case err == nil:
 					lastDiff.pf.RecordSuccess()
 				case errors.Is(err, storageApi.ErrExpectedRootMismatch):
 					lastDiff.pf.RecordBadPeer()
+					n.reportSyncError(newSyncError(ErrApplyMismatch, lastDiff.round, lastDiff.thisRoot.Type, err))
+					n.recordApplyMismatch(lastDiff, err)
+				case errors.Is(err, ErrApplyNamespaceMismatch):
+					n.logger.Error("refusing to apply write log for a different namespace",
+						"err", err,
+						"old_root", lastDiff.prevRoot,
+						"new_root", lastDiff.thisRoot,
+					)
+					storageWorkerApplyNamespaceMismatches.With(n.getMetricLabels()).Inc()
+					lastDiff.pf.RecordSuccess()
+					n.reportSyncError(newSyncError(ErrApplyNamespaceMismatch, lastDiff.round, lastDiff.thisRoot.Type, err))
 				default:
 					n.logger.Error("can't apply write log",
 						"err", err,
@@ -1076,19 +2444,29 @@ mainLoop:
 						"new_root", lastDiff.thisRoot,
 					)
 					lastDiff.pf.RecordSuccess()
+					n.reportSyncError(newSyncError(ErrApplyFailed, lastDiff.round, lastDiff.thisRoot.Type, err))
 				}
 			}
 
-			syncing := syncingRounds[lastDiff.round]
 			if err != nil {
 				syncing.retry(lastDiff.thisRoot.Type)
+				n.transitions.record(TransitionRetried, lastDiff.round, lastDiff.thisRoot.Type, syncing.awaitingRetry)
 			} else {
 				// Check if we have fully synced the given round. If we have, we can proceed
 				// with the Finalize operation.
-				syncing.outstanding.remove(lastDiff.thisRoot.Type)
-				if syncing.outstanding.isEmpty() && syncing.awaitingRetry.isEmpty() {
+				syncing.markApplied(lastDiff.thisRoot.Type)
+				n.transitions.record(TransitionApplied, lastDiff.round, lastDiff.thisRoot.Type, syncing.outstanding)
+				syncing.stats.add(lastDiff.writeLog)
+				n.notifyRootApplied(lastDiff.round, syncing)
+				if syncing.complete() {
 					n.logger.Debug("finished syncing round", "round", lastDiff.round)
+					n.emitEvent(EventRoundApplied, lastDiff.round, storageApi.RootTypeInvalid)
+					if n.quarantine != nil {
+						n.quarantine.clear(lastDiff.round)
+					}
+					syncing.span.End(nil)
 					delete(syncingRounds, lastDiff.round)
+					delete(appliedDiffs, lastDiff.round)
 					summary := hashCache[lastDiff.round]
 					delete(hashCache, lastDiff.round-1)
 
@@ -1099,97 +2477,110 @@ mainLoop:
 					// with respect to Apply operations for subsequent rounds.
 					lastFullyAppliedRound = lastDiff.round
 					heap.Push(outOfOrderFinalizable, summary)
+					pendingStats[lastDiff.round] = syncing.stats
+
+					// In case fetches were held back due to too many rounds being in flight,
+					// start fetching the following rounds without waiting for the heartbeat.
+					if backlogLimited {
+						triggerRoundFetches()
+					}
 				}
 			}
+			// The same applies in case fetches were held back due to too much fetched data
+			// waiting to be applied.
+			if bufferLimited && bufferedDiffBytes < n.cfg.MaxBufferedDiffBytes {
+				triggerRoundFetches()
+			}
 
 			continue
 		}
 
-		// Check if any new rounds were fully applied and need to be finalized. Only finalize
-		// if it's the round after the one that was finalized last (cachedLastRound).
-		// The finalization happens asynchronously with respect to this worker loop and any
+		// Check if any new rounds were fully applied and need to be finalized. Only collect
+		// the round after the one that was collected last (lastFinalizableRound), so that
+		// pendingFinalize always contains consecutive rounds following cachedLastRound.
+		if len(*outOfOrderFinalizable) > 0 && lastFinalizableRound+1 == (*outOfOrderFinalizable)[0].GetRound() {
+			lastSummary := heap.Pop(outOfOrderFinalizable).(*blockSummary)
+			pendingFinalize = append(pendingFinalize, lastSummary)
+			lastFinalizableRound = lastSummary.Round
+			continue
+		}
+
+		// Finalize the collected rounds once the finalize policy says so. Unless the local storage
+		// backend supports concurrent finalization, only one batch of rounds is finalized at a
+		// time. The finalization happens asynchronously with respect to this worker loop and any
 		// applies that happen for subsequent rounds (which can proceed while earlier rounds are
 		// still finalizing).
-		if len(*outOfOrderFinalizable) > 0 && cachedLastRound+1 == (*outOfOrderFinalizable)[0].GetRound() {
-			lastSummary := heap.Pop(outOfOrderFinalizable).(*blockSummary)
+		if retryFinalize && finalizing.inFlight < maxFinalizes && len(requeuedFinalize) > 0 {
+			batch := requeuedFinalize[0]
+			requeuedFinalize = requeuedFinalize[1:]
+			retryFinalize = len(requeuedFinalize) > 0
+			n.logger.Info("retrying finalization of storage rounds",
+				"first_round", batch[0].Round,
+				"last_round", batch[len(batch)-1].Round,
+			)
+			finalizing.inFlight++
+			fetcherGroup.Add(1)
+			go func(batch []*blockSummary) {
+				defer fetcherGroup.Done()
+				n.finalize(n.finalizeCtx, batch...)
+			}(batch)
+			continue
+		}
+		if finalizing.inFlight < maxFinalizes && len(requeuedFinalize) == 0 && len(pendingFinalize) > 0 && n.shouldFinalize(pendingFinalize[0].Round, lastFinalizableRound) {
+			batch := pendingFinalize
+			pendingFinalize = nil
+			finalizing.inFlight++
 			fetcherGroup.Add(1)
-			go func(lastSummary *blockSummary) {
+			go func(batch []*blockSummary) {
 				defer fetcherGroup.Done()
-				n.finalize(lastSummary)
-			}(lastSummary)
+				n.finalize(n.finalizeCtx, batch...)
+			}(batch)
 			continue
 		}
 
 		select {
 		case inBlk := <-n.blockCh.Out():
 			blk := inBlk.(*block.Block)
-			n.logger.Debug("incoming block",
-				"round", blk.Header.Round,
-				"last_synced", lastFullyAppliedRound,
-				"last_finalized", cachedLastRound,
-			)
-
-			// Check if we're far enough to reasonably register as available.
-			latestBlockRound = blk.Header.Round
-			n.nudgeAvailability(cachedLastRound, latestBlockRound)
-
-			if _, ok := hashCache[lastFullyAppliedRound]; !ok && lastFullyAppliedRound == n.undefinedRound {
-				dummy := blockSummary{
-					Namespace: blk.Header.Namespace,
-					Round:     lastFullyAppliedRound + 1,
-					Roots: []storageApi.Root{
-						{
-							Version: lastFullyAppliedRound + 1,
-							Type:    storageApi.RootTypeIO,
-						},
-						{
-							Version: lastFullyAppliedRound + 1,
-							Type:    storageApi.RootTypeState,
-						},
-					},
-				}
-				dummy.Roots[0].Empty()
-				dummy.Roots[1].Empty()
-				hashCache[lastFullyAppliedRound] = &dummy
+			lastBlockReceivedAt = time.Now()
+			if pollingFallback {
+				n.logger.Info("block subscription recovered, no longer polling for blocks",
+					"round", blk.Header.Round,
+				)
+				pollingFallback = false
 			}
-			// Determine if we need to fetch any old block summaries. In case the first
-			// round is an undefined round, we need to start with the following round
-			// since the undefined round may be unsigned -1 and in this case the loop
-			// would not do any iterations.
-			startSummaryRound := lastFullyAppliedRound
-			if startSummaryRound == n.undefinedRound {
-				startSummaryRound++
+			processBlock(blk)
+
+		case <-blockWatchdogCh:
+			if time.Since(lastBlockReceivedAt) < n.cfg.BlockWatchdogInterval {
+				break
 			}
-			for i := startSummaryRound; i < blk.Header.Round; i++ {
-				if _, ok := hashCache[i]; ok {
-					continue
-				}
-				var oldBlock *block.Block
-				oldBlock, err = n.commonNode.Runtime.History().GetCommittedBlock(n.ctx, i)
-				if err != nil {
-					n.logger.Error("can't get block for round",
-						"err", err,
-						"round", i,
-						"current_round", blk.Header.Round,
-					)
-					panic("can't get block in storage worker")
-				}
-				hashCache[i] = summaryFromBlock(oldBlock)
+			blk, advancing := n.pollLatestBlock(latestBlockRound, pollingFallback)
+			if advancing {
+				pollingFallback = true
 			}
-			if _, ok := hashCache[blk.Header.Round]; !ok {
-				hashCache[blk.Header.Round] = summaryFromBlock(blk)
+			if blk != nil {
+				processBlock(blk)
 			}
 
-			triggerRoundFetches()
-			heartbeat.reset()
+		case <-n.syncCeilingCh:
+			if lastBlock != nil {
+				n.logger.Info("sync ceiling raised, resuming sync",
+					"sync_ceiling", n.SyncCeiling(),
+					"latest_round", latestBlockRound,
+				)
+				processBlock(lastBlock)
+			}
 
 		case <-heartbeat.C:
+			retryFinalize = len(requeuedFinalize) > 0
 			if latestBlockRound != n.undefinedRound {
 				n.logger.Debug("heartbeat", "in_flight_rounds", len(syncingRounds))
+				n.checkRetryingRounds(syncingRounds)
 				triggerRoundFetches()
 			}
 
 		case item := <-n.diffCh:
+			fetchesInFlight--
 			if item.err != nil {
 				n.logger.Error("error calling getdiff",
 					"err", item.err,
@@ -1198,48 +2589,97 @@ mainLoop:
 					"new_root", item.thisRoot,
 					"fetched", item.fetched,
 				)
-				syncingRounds[item.round].retry(item.thisRoot.Type)
+				switch {
+				case errors.Is(item.err, ErrDiffUnavailable):
+					// The root remains outstanding, so the round is not fetched again and syncing
+					// can't proceed past it.
+					n.reportSyncError(newSyncError(ErrDiffUnavailable, item.round, item.thisRoot.Type, nil))
+				default:
+					syncingRounds[item.round].retry(item.thisRoot.Type)
+					n.transitions.record(TransitionRetried, item.round, item.thisRoot.Type, syncingRounds[item.round].awaitingRetry)
+					n.reportSyncError(newSyncError(ErrDiffFetchFailed, item.round, item.thisRoot.Type, item.err))
+				}
 			} else {
+				n.transitions.record(TransitionDiffReceived, item.round, item.thisRoot.Type, syncingRounds[item.round].outstanding)
+				if item.fetched {
+					n.emitEvent(EventRoundFetched, item.round, item.thisRoot.Type)
+				}
 				heap.Push(outOfOrderDoneDiffs, item)
+				trackBufferedDiff(item.writeLog, true)
 			}
 
 			triggerRoundFetches()
 
-		case finalized := <-n.finalizeCh:
-			// If finalization failed, things start falling apart.
-			// There's no point redoing it, since it's probably not a transient
-			// error, and cachedLastRound also can't be updated legitimately.
-			if finalized.err == nil {
-				// No further sync or out of order handling needed here, since
-				// only one finalize at a time is triggered (for round cachedLastRound+1)
-				cachedLastRound, err = n.flushSyncedState(finalized.summary)
-				if err != nil {
-					n.logger.Error("failed to flush synced state",
-						"err", err,
-					)
-				}
-				storageWorkerLastFullRound.With(n.getMetricLabels()).Set(float64(finalized.summary.Round))
+		case result := <-n.finalizeCh:
+			// Batches are only processed once all preceding rounds have been finalized, so that
+			// the synced state only ever advances over a contiguous prefix of finalized rounds.
+			for _, finalized := range finalizing.complete(result) {
+				// If finalization failed even after retrying, things start falling apart and
+				// cachedLastRound can't be updated legitimately past the failed round. Rounds of
+				// the batch that were finalized before the failure are released separately.
+				if finalized.err == nil {
+					for round := cachedLastRound + 1; round <= finalized.summary.Round; round++ {
+						if stats, ok := pendingStats[round]; ok {
+							n.recordFinalizedRound(round, stats, finalized.stats[round])
+							delete(pendingStats, round)
+						}
+						n.emitEvent(EventRoundFinalized, round, storageApi.RootTypeInvalid)
+						n.transitions.record(TransitionFinalized, round, storageApi.RootTypeInvalid, 0)
+						n.notifyRoundFinalized(round)
+					}
 
-				// Check if we're far enough to reasonably register as available.
-				n.nudgeAvailability(cachedLastRound, latestBlockRound)
+					// No further sync or out of order handling needed here, since the
+					// finalize tracker only releases batches following cachedLastRound.
+					cachedLastRound, err = n.flushSyncedState(finalized.summary)
+					if err != nil {
+						n.logger.Error("failed to flush synced state",
+							"err", err,
+						)
+					}
+					storageWorkerLastFullRound.With(n.getMetricLabels()).Set(float64(finalized.summary.Round))
+					if serr := summaries.save(cachedLastRound); serr != nil {
+						n.logger.Warn("failed to persist block summaries",
+							"err", serr,
+						)
+					}
 
-				// Notify the checkpointer that there is a new finalized round.
-				if n.checkpointer != nil {
-					n.checkpointer.NotifyNewVersion(finalized.summary.Round)
+					// Check if we're far enough to reasonably register as available.
+					n.nudgeAvailability(cachedLastRound, latestBlockRound)
+
+					// Notify the checkpointer that there is a new finalized round.
+					if n.checkpointer != nil {
+						n.checkpointer.NotifyNewVersion(finalized.summary.Round)
+					}
+
+					if caughtUp() {
+						break mainLoop
+					}
+				} else {
+					// This is a cant-happen situation and there's no useful way to recover from it,
+					// so request a node shutdown as, from this point onwards, syncing is effectively
+					// blocked. The rounds that were not finalized are still re-queued, so that
+					// finalization resumes from the failed round in case the failure was transient.
+					n.reportSyncError(newSyncError(ErrFinalizeFailed, finalized.summary.Round, storageApi.RootTypeInvalid, finalized.err))
+					requeuedFinalize = requeueFinalizeBatch(requeuedFinalize, finalized.unfinalized)
+					n.dumpTransitionLog("finalization failed")
+					_, _ = n.commonNode.HostNode.RequestShutdown()
 				}
-			} else {
-				// This is a cant-happen situation and there's no useful way
-				// to recover from it. Just request a node shutdown and stop fussing
-				// since, from this point onwards, syncing is effectively blocked.
-				_, _ = n.commonNode.HostNode.RequestShutdown()
 			}
 
+		case rspCh := <-n.queueSnapshotCh:
+			rspCh <- newQueueSnapshot(outOfOrderDoneDiffs, outOfOrderFinalizable, syncingRounds, bufferedDiffBytes)
+
 		case <-n.ctx.Done():
 			break mainLoop
 		}
 	}
 
 	fetcherGroup.Wait()
+	if err = summaries.save(cachedLastRound); err != nil {
+		n.logger.Warn("failed to persist block summaries",
+			"err", err,
+		)
+	}
 	// blockCh will be garbage-collected without being closed. It can potentially still contain
 	// some new blocks, but only as many as were already in-flight at the point when the main
 	// context was canceled.
@@ -1250,9 +2690,20 @@ type pruneHandler struct {
 	node   *Node
 }
 
+// Prune prunes the given rounds from local storage.
+//
+// Only rounds strictly below the last finalized round are ever pruned, so a round is never pruned
+// before the following round has been finalized. Pruning requests are handled by the history
+// pruner independently of the worker loop, so their ordering with respect to finalizations that
+// are in progress is defined by the configured prune ordering.
 func (p *pruneHandler) Prune(ctx context.Context, rounds []uint64) error {
 	// Make sure we never prune past what was synced.
 	lastSycnedRound, _, _ := p.node.GetLastSynced()
+	if lastSycnedRound == defaultUndefinedRound {
+		// Nothing has been synced yet (also the case at a genesis round of zero), the comparison
+		// below can't be used as the undefined round is the largest possible round.
+		return fmt.Errorf("worker/storage: tried to prune before anything was synced")
+	}
 
 	for _, round := range rounds {
 		if round >= lastSycnedRound {
@@ -1266,9 +2717,12 @@ func (p *pruneHandler) Prune(ctx context.Context, rounds []uint64) error {
 		p.logger.Debug("pruning storage for round", "round", round)
 
 		// Prune given block.
+		unlock := p.node.lockPrune()
 		err := p.node.localStorage.NodeDB().Prune(ctx, round)
+		unlock()
 		switch err {
 		case nil:
+			p.node.transitions.record(TransitionPruned, round, storageApi.RootTypeInvalid, 0)
 		case mkvsDB.ErrNotEarliest:
 			p.logger.Debug("skipping non-earliest round",
 				"round", round,
@@ -0,0 +1,1240 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	registryApi "github.com/oasisprotocol/oasis-core/go/registry/api"
+	roothashApi "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/history"
+	runtimeRegistry "github.com/oasisprotocol/oasis-core/go/runtime/registry"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/database"
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	storageTests "github.com/oasisprotocol/oasis-core/go/storage/tests"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+var testNs = common.NewTestNamespaceFromSeed([]byte("storage worker test ns"), 0)
+
+// testState is a helper for building a chain of finalized state roots in local storage.
+type testState struct {
+	t        *testing.T
+	n        *Node
+	writeLog storageApi.WriteLog
+	root     storageApi.Root
+}
+
+func newTestNode(t testing.TB) *Node {
+	localStorage, err := database.New(&storageApi.Config{
+		Backend:   database.BackendNameInMemory,
+		DB:        t.TempDir(),
+		Namespace: testNs,
+	})
+	require.NoError(t, err, "database.New()")
+	t.Cleanup(localStorage.Cleanup)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return &Node{
+		commonNode:   &committee.Node{Runtime: &testBatchRuntime{}},
+		logger:       logging.GetLogger("worker/storage/committee/test"),
+		localStorage: localStorage,
+		cfg:          &Config{},
+		finalizeCh:   make(chan finalizeResult, 1),
+		ctx:          ctx,
+		ctxCancel:    cancel,
+	}
+}
+
+func newTestState(t *testing.T, n *Node) *testState {
+	s := &testState{
+		t: t,
+		n: n,
+		root: storageApi.Root{
+			Namespace: testNs,
+			Type:      storageApi.RootTypeState,
+		},
+	}
+	s.root.Hash.Empty()
+	return s
+}
+
+// advance applies a new write log entry on top of the current root and finalizes the next round.
+func (s *testState) advance() *blockSummary {
+	require := require.New(s.t)
+
+	summary := s.apply()
+	s.n.finalize(s.n.ctx, summary)
+	result := <-s.n.finalizeCh
+	require.NoError(result.err, "finalize()")
+
+	return summary
+}
+
+// apply applies a new write log entry on top of the current root without finalizing the round.
+func (s *testState) apply() *blockSummary {
+	require := require.New(s.t)
+
+	round := s.root.Version + 1
+	s.writeLog = append(s.writeLog, storageApi.LogEntry{
+		Key:   []byte(fmt.Sprintf("key %d", round)),
+		Value: []byte(fmt.Sprintf("value %d", round)),
+	})
+	newRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   round,
+		Type:      storageApi.RootTypeState,
+		Hash:      storageTests.CalculateExpectedNewRoot(s.t, s.writeLog, testNs, round),
+	}
+
+	err := s.n.localStorage.Apply(s.n.ctx, &storageApi.ApplyRequest{
+		Namespace: testNs,
+		RootType:  storageApi.RootTypeState,
+		SrcRound:  s.root.Version,
+		SrcRoot:   s.root.Hash,
+		DstRound:  newRoot.Version,
+		DstRoot:   newRoot.Hash,
+		WriteLog:  s.writeLog[len(s.writeLog)-1:],
+	})
+	require.NoError(err, "Apply()")
+	s.root = newRoot
+
+	return &blockSummary{
+		Namespace: testNs,
+		Round:     round,
+		Roots:     []storageApi.Root{newRoot},
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	s := newTestState(t, n)
+
+	summary := s.advance()
+	latest, exists := n.localStorage.NodeDB().GetLatestVersion()
+	require.True(exists, "latest version should exist")
+	require.EqualValues(summary.Round, latest, "latest version should be the finalized round")
+
+	// Finalizing an already finalized round should be tolerated.
+	n.finalize(n.ctx, summary)
+	result := <-n.finalizeCh
+	require.NoError(result.err, "finalize() of an already finalized round")
+
+	// Finalizing a round with missing roots should fail.
+	bogusRoot := s.root
+	bogusRoot.Version++
+	bogusRoot.Hash[0]++
+	n.finalize(n.ctx, &blockSummary{
+		Namespace: testNs,
+		Round:     bogusRoot.Version,
+		Roots:     []storageApi.Root{bogusRoot},
+	})
+	result = <-n.finalizeCh
+	require.ErrorIs(result.err, mkvsDB.ErrRootNotFound, "finalize() with missing roots")
+}
+
+func TestRedundantFinalize(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	s := newTestState(t, n)
+	s.advance()
+	summary := s.advance()
+
+	n.syncedLock.Lock()
+	n.syncedState = *summary
+	n.syncedLock.Unlock()
+	require.Equal("worker", n.redundantFinalizeSource(summary.Round, 0), "source of a synced round")
+	require.Equal("storage", n.redundantFinalizeSource(summary.Round+1, 0), "source of an unsynced round")
+	require.Equal("retry", n.redundantFinalizeSource(summary.Round, 1), "source of a retried round")
+
+	// A double finalization should be counted, but should otherwise be a no-op.
+	redundant := storageWorkerRedundantFinalizes.With(n.getMetricLabels())
+	before := testutil.ToFloat64(redundant)
+	n.finalize(n.ctx, summary)
+	result := <-n.finalizeCh
+	require.NoError(result.err, "finalize() of an already finalized round")
+	require.EqualValues(before+1, testutil.ToFloat64(redundant), "redundant finalization should be counted")
+
+	round, _, state := n.GetLastSynced()
+	require.Equal(summary.Round, round, "synced round should be unchanged")
+	require.Equal(summary.Roots[0], state, "synced state root should be unchanged")
+	latest, _ := n.localStorage.NodeDB().GetLatestVersion()
+	require.EqualValues(summary.Round, latest, "latest version should be unchanged")
+}
+
+// failingFinalizeBackend is a local storage backend whose finalizations fail a given number of
+// times before being passed through.
+type failingFinalizeBackend struct {
+	storageApi.LocalBackend
+
+	nodeDB *failingFinalizeNodeDB
+}
+
+func (b *failingFinalizeBackend) NodeDB() storageApi.NodeDB {
+	return b.nodeDB
+}
+
+type failingFinalizeNodeDB struct {
+	storageApi.NodeDB
+
+	failures  int
+	finalizes int
+}
+
+func (d *failingFinalizeNodeDB) Finalize(ctx context.Context, roots []storageApi.Root) error {
+	d.finalizes++
+	if d.finalizes <= d.failures {
+		return fmt.Errorf("finalize failure %d", d.finalizes)
+	}
+	return d.NodeDB.Finalize(ctx, roots)
+}
+
+func TestFinalizeRetry(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		name              string
+		failures          int
+		maxRetries        uint64
+		expectedFinalizes int
+		expectedErr       bool
+	}{
+		{"NoRetries", 1, 0, 1, true},
+		{"SuccessOnRetry", 2, 3, 3, false},
+		{"Exhausted", 5, 3, 4, true},
+	} {
+		n := newTestNode(t)
+		n.cfg = &Config{
+			MaxFinalizeRetries:    tc.maxRetries,
+			FinalizeRetryInterval: time.Millisecond,
+		}
+		s := newTestState(t, n)
+		summary := s.apply()
+
+		nodeDB := &failingFinalizeNodeDB{NodeDB: n.localStorage.NodeDB(), failures: tc.failures}
+		n.localStorage = &failingFinalizeBackend{LocalBackend: n.localStorage, nodeDB: nodeDB}
+
+		n.finalize(n.ctx, summary)
+		result := <-n.finalizeCh
+		require.Equal(tc.expectedFinalizes, nodeDB.finalizes, "%s: number of finalize attempts", tc.name)
+		if tc.expectedErr {
+			require.Error(result.err, "%s: finalize() should fail", tc.name)
+			continue
+		}
+		require.NoError(result.err, "%s: finalize()", tc.name)
+		latest, _ := nodeDB.GetLatestVersion()
+		require.EqualValues(summary.Round, latest, "%s: latest version should be the finalized round", tc.name)
+	}
+
+	// An already finalized round should be treated as finalized without retrying.
+	n := newTestNode(t)
+	n.cfg = &Config{MaxFinalizeRetries: 3, FinalizeRetryInterval: time.Millisecond}
+	s := newTestState(t, n)
+	summary := s.advance()
+
+	nodeDB := &failingFinalizeNodeDB{NodeDB: n.localStorage.NodeDB()}
+	n.localStorage = &failingFinalizeBackend{LocalBackend: n.localStorage, nodeDB: nodeDB}
+	n.finalize(n.ctx, summary)
+	result := <-n.finalizeCh
+	require.NoError(result.err, "finalize() of an already finalized round")
+	require.Equal(1, nodeDB.finalizes, "already finalized rounds should not be retried")
+
+	// Retries should stop when the context is canceled.
+	n = newTestNode(t)
+	n.cfg = &Config{MaxFinalizeRetries: 3, FinalizeRetryInterval: time.Hour}
+	s = newTestState(t, n)
+	summary = s.apply()
+
+	nodeDB = &failingFinalizeNodeDB{NodeDB: n.localStorage.NodeDB(), failures: 1}
+	n.localStorage = &failingFinalizeBackend{LocalBackend: n.localStorage, nodeDB: nodeDB}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := n.finalizeRound(ctx, summary)
+	require.ErrorIs(err, context.Canceled, "finalizeRound() with canceled context")
+	require.Equal(1, nodeDB.finalizes, "finalization should not be retried after cancellation")
+}
+
+func TestCheckLocalStorageWritable(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	// Starting from an empty database should succeed without writing anything.
+	localStorage, err := database.New(&storageApi.Config{
+		Backend:   database.BackendNameBadgerDB,
+		DB:        dir,
+		Namespace: testNs,
+	})
+	require.NoError(err, "database.New()")
+	require.NoError(checkLocalStorageWritable(localStorage), "empty database should be writable")
+	_, exists := localStorage.NodeDB().GetLatestVersion()
+	require.False(exists, "checking writability should not create any versions")
+	localStorage.Cleanup()
+
+	localStorage, err = database.New(&storageApi.Config{
+		Backend:   database.BackendNameBadgerDB,
+		DB:        dir,
+		Namespace: testNs,
+		ReadOnly:  true,
+	})
+	require.NoError(err, "database.New(read-only)")
+	defer localStorage.Cleanup()
+	err = checkLocalStorageWritable(localStorage)
+	require.ErrorIs(err, mkvsDB.ErrReadOnly, "read-only database should not be writable")
+}
+
+func TestInitGenesis(t *testing.T) {
+	rt := &registryApi.Runtime{ID: testNs}
+
+	t.Run("FillVersions", func(t *testing.T) {
+		require := require.New(t)
+		n := newTestNode(t)
+		n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+		s := newTestState(t, n)
+		s.advance()
+
+		genesisBlock := block.NewGenesisBlock(testNs, 0)
+		genesisBlock.Header.Round = 5
+		genesisBlock.Header.StateRoot = s.root.Hash
+
+		err := n.initGenesis(rt, genesisBlock)
+		require.NoError(err, "initGenesis()")
+		require.False(n.checkpointSyncForced, "checkpoint sync should not be forced")
+
+		latest, _ := n.localStorage.NodeDB().GetLatestVersion()
+		require.EqualValues(5, latest, "missing versions should be filled in")
+		for v := s.root.Version; v <= latest; v++ {
+			root := s.root
+			root.Version = v
+			require.True(n.localStorage.NodeDB().HasRoot(root), "state root should exist in version %d", v)
+		}
+	})
+
+	t.Run("Replicate", func(t *testing.T) {
+		require := require.New(t)
+		n := newTestNode(t)
+		n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+
+		genesisBlock := block.NewGenesisBlock(testNs, 0)
+		genesisBlock.Header.Round = 5
+		genesisBlock.Header.StateRoot.FromBytes([]byte("non-empty genesis state"))
+
+		err := n.initGenesis(rt, genesisBlock)
+		require.NoError(err, "initGenesis()")
+		require.True(n.checkpointSyncForced, "checkpoint sync should be forced")
+	})
+
+	t.Run("ZeroGenesisReplicate", func(t *testing.T) {
+		require := require.New(t)
+		n := newTestNode(t)
+		n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+
+		// An empty database must not be mistaken for existing state at round zero.
+		genesisBlock := block.NewGenesisBlock(testNs, 0)
+		genesisBlock.Header.StateRoot.FromBytes([]byte("non-empty genesis state"))
+
+		err := n.initGenesis(rt, genesisBlock)
+		require.NoError(err, "initGenesis()")
+		require.True(n.checkpointSyncForced, "checkpoint sync should be forced")
+	})
+
+	t.Run("ZeroGenesisEmpty", func(t *testing.T) {
+		require := require.New(t)
+		n := newTestNode(t)
+		n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+
+		err := n.initGenesis(rt, block.NewGenesisBlock(testNs, 0))
+		require.NoError(err, "initGenesis()")
+		require.False(n.checkpointSyncForced, "checkpoint sync should not be forced for an empty genesis state")
+	})
+
+	t.Run("ZeroGenesisIncompatible", func(t *testing.T) {
+		require := require.New(t)
+		n := newTestNode(t)
+		n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+		s := newTestState(t, n)
+		s.advance()
+
+		genesisBlock := block.NewGenesisBlock(testNs, 0)
+		genesisBlock.Header.StateRoot.FromBytes([]byte("other genesis state"))
+
+		err := n.initGenesis(rt, genesisBlock)
+		require.Error(err, "initGenesis() with incompatible state")
+	})
+
+	t.Run("Incompatible", func(t *testing.T) {
+		require := require.New(t)
+		n := newTestNode(t)
+		n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+		s := newTestState(t, n)
+		s.advance()
+		s.advance()
+
+		genesisBlock := block.NewGenesisBlock(testNs, 0)
+		genesisBlock.Header.Round = 1
+		genesisBlock.Header.StateRoot = s.root.Hash
+
+		err := n.initGenesis(rt, genesisBlock)
+		require.Error(err, "initGenesis() with incompatible state")
+	})
+
+	t.Run("RuntimeMismatch", func(t *testing.T) {
+		require := require.New(t)
+		n := newTestNode(t)
+		n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+
+		otherNs := common.NewTestNamespaceFromSeed([]byte("other storage worker test ns"), 0)
+		otherRt := &registryApi.Runtime{ID: otherNs}
+
+		err := n.initGenesis(otherRt, block.NewGenesisBlock(otherNs, 0))
+		require.Error(err, "initGenesis() with another runtime's descriptor")
+		err = n.initGenesis(rt, block.NewGenesisBlock(otherNs, 0))
+		require.Error(err, "initGenesis() with another runtime's genesis block")
+		_, exists := n.localStorage.NodeDB().GetLatestVersion()
+		require.False(exists, "local storage should not be modified")
+	})
+}
+
+func TestPruneHandler(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	s := newTestState(t, n)
+	for i := 0; i < 3; i++ {
+		n.syncedState = *s.advance()
+	}
+	ph := &pruneHandler{
+		logger: n.logger,
+		node:   n,
+	}
+
+	err := ph.Prune(n.ctx, []uint64{1})
+	require.NoError(err, "Prune(1)")
+	require.EqualValues(2, n.localStorage.NodeDB().GetEarliestVersion(), "earliest version should be updated")
+
+	// Non-earliest rounds should be skipped.
+	err = ph.Prune(n.ctx, []uint64{1})
+	require.NoError(err, "Prune(1) again")
+
+	// Pruning past the last synced round should fail.
+	err = ph.Prune(n.ctx, []uint64{2, 3})
+	require.Error(err, "Prune(2, 3)")
+	require.EqualValues(3, n.localStorage.NodeDB().GetEarliestVersion(), "earliest version should be updated")
+
+	// Latest state should still be available.
+	require.True(n.localStorage.NodeDB().HasRoot(s.root), "latest state root should exist")
+
+	// Pruning before anything was synced should fail, including at a genesis round of zero where
+	// the undefined round is the largest possible round.
+	n.undefinedRound = defaultUndefinedRound
+	n.syncedState = blockSummary{Round: n.undefinedRound}
+	err = ph.Prune(n.ctx, []uint64{3})
+	require.Error(err, "Prune(3) before anything was synced")
+	require.EqualValues(3, n.localStorage.NodeDB().GetEarliestVersion(), "earliest version should not change")
+}
+
+// countingBackend is a local storage backend which counts Apply invocations.
+type countingBackend struct {
+	storageApi.LocalBackend
+
+	applies int
+}
+
+func (b *countingBackend) Apply(ctx context.Context, request *storageApi.ApplyRequest) error {
+	b.applies++
+	return b.LocalBackend.Apply(ctx, request)
+}
+
+func TestApplyDiffDedup(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	backend := &countingBackend{LocalBackend: n.localStorage}
+	n.localStorage = backend
+
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+	writeLog := storageApi.WriteLog{{Key: []byte("key"), Value: []byte("value")}}
+	thisRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      storageApi.RootTypeState,
+		Hash:      storageTests.CalculateExpectedNewRoot(t, writeLog, testNs, 1),
+	}
+	diff := &fetchedDiff{
+		fetched:  true,
+		round:    1,
+		prevRoot: prevRoot,
+		thisRoot: thisRoot,
+		writeLog: writeLog,
+	}
+
+	applied := make(appliedWriteLogs)
+	err := n.applyDiff(n.ctx, applied, diff, nil)
+	require.NoError(err, "applyDiff()")
+	require.Equal(1, backend.applies, "write log should be applied")
+	require.True(n.localStorage.NodeDB().HasRoot(thisRoot), "root should exist after apply")
+
+	// Applying an identical write log again should be skipped.
+	duplicate := *diff
+	duplicate.writeLog = storageApi.WriteLog{{Key: []byte("key"), Value: []byte("value")}}
+	err = n.applyDiff(n.ctx, applied, &duplicate, nil)
+	require.NoError(err, "applyDiff() with duplicate write log")
+	require.Equal(1, backend.applies, "duplicate write log should not be applied again")
+
+	// A different write log for the same root must still be passed to storage.
+	different := *diff
+	different.writeLog = storageApi.WriteLog{{Key: []byte("other key"), Value: []byte("value")}}
+	err = n.applyDiff(n.ctx, applied, &different, nil)
+	require.NoError(err, "applyDiff() with a different write log")
+	require.Equal(2, backend.applies, "different write log should be applied")
+
+	// Failed applies should not be recorded.
+	bogus := *diff
+	bogus.round = 2
+	bogus.prevRoot = thisRoot
+	bogus.thisRoot.Version = 2
+	bogus.thisRoot.Hash.FromBytes([]byte("bogus root"))
+	for i := 0; i < 2; i++ {
+		err = n.applyDiff(n.ctx, applied, &bogus, nil)
+		require.ErrorIs(err, storageApi.ErrExpectedRootMismatch, "applyDiff() with a mismatched root")
+	}
+	require.Equal(4, backend.applies, "failed write log should be applied again")
+}
+
+func TestApplyDiffInterceptor(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+	writeLog := storageApi.WriteLog{{Key: []byte("key"), Value: []byte("value")}}
+	thisRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      storageApi.RootTypeState,
+		Hash:      storageTests.CalculateExpectedNewRoot(t, writeLog, testNs, 1),
+	}
+	diff := &fetchedDiff{
+		fetched:  true,
+		round:    1,
+		prevRoot: prevRoot,
+		thisRoot: thisRoot,
+		writeLog: writeLog,
+	}
+
+	errRejected := fmt.Errorf("rejected")
+	var intercepted []uint64
+	reject := true
+	n.cfg.WriteLogInterceptor = func(round uint64, root storageApi.Root, wl storageApi.WriteLog) (storageApi.WriteLog, error) {
+		intercepted = append(intercepted, round)
+		require.Equal(thisRoot, root, "interceptor should be passed the target root")
+		if reject {
+			return nil, errRejected
+		}
+		return wl, nil
+	}
+
+	applied := make(appliedWriteLogs)
+	err := n.applyDiff(n.ctx, applied, diff, nil)
+	require.ErrorIs(err, errRejected, "applyDiff() with a rejected write log")
+	require.False(n.localStorage.NodeDB().HasRoot(thisRoot), "rejected write log should not be applied")
+	require.False(applied.contains(diff.round, newAppliedWriteLogKey(diff)), "rejected write log should not be recorded")
+
+	// Once the interceptor accepts the write log, applying it should be retried.
+	reject = false
+	err = n.applyDiff(n.ctx, applied, diff, nil)
+	require.NoError(err, "applyDiff() with an accepted write log")
+	require.True(n.localStorage.NodeDB().HasRoot(thisRoot), "accepted write log should be applied")
+	require.Equal([]uint64{1, 1}, intercepted, "interceptor should be invoked before each apply")
+}
+
+// testBlockSource is a block source which returns blocks with a fixed round.
+type testBlockSource struct {
+	round uint64
+}
+
+func (bs *testBlockSource) GetGenesisBlock(ctx context.Context, request *roothashApi.RuntimeRequest) (*block.Block, error) {
+	return block.NewGenesisBlock(request.RuntimeID, 0), nil
+}
+
+func (bs *testBlockSource) GetLatestBlock(ctx context.Context, request *roothashApi.RuntimeRequest) (*block.Block, error) {
+	blk := block.NewGenesisBlock(request.RuntimeID, 0)
+	blk.Header.Round = bs.round
+	return blk, nil
+}
+
+// testHistory is a runtime history which returns blocks with a fixed round.
+type testHistory struct {
+	history.History
+
+	round uint64
+}
+
+func (h *testHistory) GetCommittedBlock(ctx context.Context, round uint64) (*block.Block, error) {
+	blk := block.NewGenesisBlock(testNs, 0)
+	blk.Header.Round = h.round
+	return blk, nil
+}
+
+type testRuntime struct {
+	runtimeRegistry.Runtime
+
+	history *testHistory
+}
+
+func (rt *testRuntime) ID() common.Namespace {
+	return testNs
+}
+
+func (rt *testRuntime) History() history.History {
+	return rt.history
+}
+
+func TestPollLatestBlockSource(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.cfg = &Config{}
+	n.undefinedRound = defaultUndefinedRound
+	rt := &testRuntime{history: &testHistory{round: 5}}
+	n.commonNode = &committee.Node{Runtime: rt}
+	source := &testBlockSource{round: 5}
+	n.blockSource = source
+
+	// Chain is not advancing according to the block source.
+	blk, polling := n.pollLatestBlock(5, false)
+	require.Nil(blk, "no block should be returned when the chain is not advancing")
+	require.False(polling, "polling should not be enabled when the chain is not advancing")
+
+	// Chain is advancing according to the block source, but local history is not.
+	source.round = 7
+	blk, polling = n.pollLatestBlock(5, false)
+	require.Nil(blk, "no block should be returned when local history has not advanced")
+	require.True(polling, "polling should be enabled when the chain is advancing")
+
+	// Both the block source and local history are advancing.
+	rt.history.round = 7
+	blk, polling = n.pollLatestBlock(5, true)
+	require.NotNil(blk, "latest block should be returned")
+	require.EqualValues(7, blk.Header.Round, "latest block should come from local history")
+	require.True(polling, "polling should remain enabled")
+}
+
+// testBatchHistory is a runtime history which supports batch block fetches and counts calls.
+type testBatchHistory struct {
+	history.History
+
+	batchCalls  int
+	singleCalls int
+}
+
+func (h *testBatchHistory) GetCommittedBlock(ctx context.Context, round uint64) (*block.Block, error) {
+	h.singleCalls++
+	blk := block.NewGenesisBlock(testNs, 0)
+	blk.Header.Round = round
+	return blk, nil
+}
+
+func (h *testBatchHistory) GetCommittedBlocks(ctx context.Context, startRound, endRound uint64) ([]*block.Block, error) {
+	h.batchCalls++
+	if endRound-startRound+1 > maxBlockSummaryBatchSize {
+		return nil, fmt.Errorf("batch too large")
+	}
+	var blks []*block.Block
+	for round := startRound; round <= endRound; round++ {
+		blk := block.NewGenesisBlock(testNs, 0)
+		blk.Header.Round = round
+		blks = append(blks, blk)
+	}
+	return blks, nil
+}
+
+type testBatchRuntime struct {
+	runtimeRegistry.Runtime
+
+	history history.History
+}
+
+func (rt *testBatchRuntime) ID() common.Namespace {
+	return testNs
+}
+
+func (rt *testBatchRuntime) History() history.History {
+	return rt.history
+}
+
+func TestFetchBlockSummaries(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+
+	const (
+		startRound = uint64(10)
+		endRound   = startRound + 2*maxBlockSummaryBatchSize + 43
+	)
+
+	// Batch fetches should be used in capped chunks.
+	h := &testBatchHistory{}
+	n.commonNode = &committee.Node{Runtime: &testBatchRuntime{history: h}}
+	hashCache := make(map[uint64]*blockSummary)
+	err := n.fetchBlockSummaries(hashCache, startRound, endRound)
+	require.NoError(err, "fetchBlockSummaries")
+	require.Len(hashCache, int(endRound-startRound+1), "all rounds in the gap should be populated")
+	for round := startRound; round <= endRound; round++ {
+		require.EqualValues(round, hashCache[round].Round, "summary should be for the correct round")
+	}
+	require.Equal(3, h.batchCalls, "rounds should be fetched in capped batches")
+	require.Equal(0, h.singleCalls, "single block fetches should not be used")
+
+	// Already cached rounds should not be fetched again.
+	h.batchCalls = 0
+	err = n.fetchBlockSummaries(hashCache, startRound, endRound)
+	require.NoError(err, "fetchBlockSummaries")
+	require.Equal(0, h.batchCalls, "cached rounds should not be fetched")
+
+	// Single block fetches should be used when batch fetches are not supported.
+	single := &testHistory{}
+	n.commonNode = &committee.Node{Runtime: &testRuntime{history: single}}
+	hashCache = map[uint64]*blockSummary{
+		startRound + 1: {Round: startRound + 1},
+	}
+	err = n.fetchBlockSummaries(hashCache, startRound, startRound+4)
+	require.NoError(err, "fetchBlockSummaries")
+	require.Len(hashCache, 5, "all rounds in the gap should be populated")
+}
+
+// testNilDiffClient is a misbehaving storage sync client which returns neither a response nor an
+// error.
+type testNilDiffClient struct {
+	storageSync.Client
+}
+
+func (c *testNilDiffClient) GetDiff(ctx context.Context, request *storageSync.GetDiffRequest) (*storageSync.GetDiffResponse, rpc.PeerFeedback, error) {
+	return nil, nil, nil
+}
+
+func TestFetchDiffNoResponse(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.diffCh = make(chan *fetchedDiff, 1)
+	n.storageSync = &testNilDiffClient{}
+
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+	thisRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      storageApi.RootTypeState,
+	}
+	thisRoot.Hash.FromBytes([]byte("this root"))
+
+	n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+	result := <-n.diffCh
+	require.ErrorIs(result.err, errNoDiffResponse, "fetchDiff() should fail without a response")
+	require.True(result.fetched, "result should be marked as fetched")
+	require.NotNil(result.pf, "peer feedback should be set")
+}
+
+// testCompactDiffClient is a storage sync client which serves a fixed write log, using the compact
+// format if requested. In case legacy is set, it rejects compact requests like peers which don't
+// support the compact format.
+type testCompactDiffClient struct {
+	storageSync.Client
+
+	writeLog storageApi.WriteLog
+	legacy   bool
+	requests []storageSync.GetDiffRequest
+}
+
+func (c *testCompactDiffClient) GetDiff(ctx context.Context, request *storageSync.GetDiffRequest) (*storageSync.GetDiffResponse, rpc.PeerFeedback, error) {
+	c.requests = append(c.requests, *request)
+	switch {
+	case request.Compact && c.legacy:
+		return nil, nil, rpc.ErrBadRequest
+	case request.Compact:
+		return &storageSync.GetDiffResponse{CompactWriteLog: storageSync.EncodeCompactWriteLog(c.writeLog)}, rpc.NewNopPeerFeedback(), nil
+	default:
+		return &storageSync.GetDiffResponse{WriteLog: c.writeLog}, rpc.NewNopPeerFeedback(), nil
+	}
+}
+
+func TestFetchDiffCompact(t *testing.T) {
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+	thisRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      storageApi.RootTypeState,
+	}
+	thisRoot.Hash.FromBytes([]byte("this root"))
+
+	writeLog := storageApi.WriteLog{
+		{Key: []byte("key/1"), Value: []byte("value 1")},
+		{Key: []byte("key/2"), Value: []byte("value 2")},
+		{Key: []byte("key/3"), Value: nil},
+	}
+
+	for _, tc := range []struct {
+		name             string
+		compact          bool
+		legacy           bool
+		expectedRequests []bool
+	}{
+		{"Disabled", false, false, []bool{false}},
+		{"Compact", true, false, []bool{true}},
+		{"Fallback", true, true, []bool{true, false}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			n := newTestNode(t)
+			n.cfg = &Config{CompactDiffs: tc.compact}
+			n.diffCh = make(chan *fetchedDiff, 1)
+			client := &testCompactDiffClient{writeLog: writeLog, legacy: tc.legacy}
+			n.storageSync = client
+
+			n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+			result := <-n.diffCh
+			require.NoError(result.err, "fetchDiff()")
+			require.True(writeLog.Equal(result.writeLog), "fetched write log should be correct")
+			require.Nil(result.writeLog[2].Value, "deletions should be preserved")
+
+			require.Len(client.requests, len(tc.expectedRequests), "number of GetDiff requests")
+			for i, compact := range tc.expectedRequests {
+				require.Equal(compact, client.requests[i].Compact, "compact flag of request %d", i)
+			}
+		})
+	}
+}
+
+func TestReconcileSyncedStateBelowGenesis(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	s := newTestState(t, n)
+	s.advance()
+	n.syncedState = *s.advance()
+
+	// Restored local state predates the new genesis.
+	genesisBlock := block.NewGenesisBlock(testNs, 0)
+	genesisBlock.Header.Round = 10
+	n.undefinedRound = genesisBlock.Header.Round - 1
+
+	round, err := n.reconcileSyncedState(genesisBlock)
+	require.NoError(err, "reconcileSyncedState")
+	require.Equal(n.undefinedRound, round, "syncing should start from genesis")
+	lastSynced, _, _ := n.GetLastSynced()
+	require.Equal(n.undefinedRound, lastSynced, "stale synced state should be discarded")
+
+	// Without any synced state, syncing should also start from genesis.
+	n.syncedState = blockSummary{Round: defaultUndefinedRound}
+	round, err = n.reconcileSyncedState(genesisBlock)
+	require.NoError(err, "reconcileSyncedState")
+	require.Equal(n.undefinedRound, round, "syncing should start from genesis")
+}
+
+func TestReconcileSyncedStateZeroGenesis(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	s := newTestState(t, n)
+
+	// The round before a genesis round of zero wraps around to the default undefined round.
+	genesisBlock := block.NewGenesisBlock(testNs, 0)
+	n.undefinedRound = genesisBlock.Header.Round - 1
+	require.EqualValues(defaultUndefinedRound, n.undefinedRound, "undefined round should wrap around")
+	require.Zero(n.undefinedRound+1, "syncing should start at round zero")
+
+	n.syncedState = blockSummary{Namespace: testNs, Round: defaultUndefinedRound}
+	round, err := n.reconcileSyncedState(genesisBlock)
+	require.NoError(err, "reconcileSyncedState")
+	require.Equal(n.undefinedRound, round, "syncing should start from genesis")
+
+	// Synced state at genesis round zero should be kept.
+	n.syncedState = blockSummary{
+		Namespace: testNs,
+		Round:     0,
+		Roots:     []storageApi.Root{s.root},
+	}
+	round, err = n.reconcileSyncedState(genesisBlock)
+	require.NoError(err, "reconcileSyncedState")
+	require.EqualValues(0, round, "syncing should continue from genesis round zero")
+
+	genesisBlock.Header.StateRoot.FromBytes([]byte("other genesis state root"))
+	_, err = n.reconcileSyncedState(genesisBlock)
+	require.Error(err, "reconcileSyncedState should fail for a mismatched genesis state root")
+}
+
+func TestReconcileSyncedStateAboveGenesis(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	s := newTestState(t, n)
+	genesisSummary := s.advance()
+	n.syncedState = *s.advance()
+
+	genesisBlock := block.NewGenesisBlock(testNs, 0)
+	genesisBlock.Header.Round = genesisSummary.Round
+	genesisBlock.Header.StateRoot = genesisSummary.Roots[0].Hash
+	n.undefinedRound = genesisBlock.Header.Round - 1
+
+	// Local state which is consistent should be kept.
+	round, err := n.reconcileSyncedState(genesisBlock)
+	require.NoError(err, "reconcileSyncedState")
+	require.EqualValues(n.syncedState.Round, round, "syncing should continue from the last synced round")
+
+	// Local state at genesis must match the genesis state root.
+	n.syncedState = *genesisSummary
+	round, err = n.reconcileSyncedState(genesisBlock)
+	require.NoError(err, "reconcileSyncedState")
+	require.EqualValues(genesisSummary.Round, round, "syncing should continue from genesis round")
+
+	genesisBlock.Header.StateRoot.FromBytes([]byte("other genesis state root"))
+	_, err = n.reconcileSyncedState(genesisBlock)
+	require.Error(err, "reconcileSyncedState should fail for a mismatched genesis state root")
+
+	// Local state whose roots are missing should be rejected.
+	bogusRoot := s.root
+	bogusRoot.Version++
+	bogusRoot.Hash.FromBytes([]byte("bogus root"))
+	n.syncedState = blockSummary{
+		Namespace: testNs,
+		Round:     bogusRoot.Version,
+		Roots:     []storageApi.Root{bogusRoot},
+	}
+	_, err = n.reconcileSyncedState(genesisBlock)
+	require.Error(err, "reconcileSyncedState should fail for missing roots")
+}
+
+// batchCountingBackend is a local storage backend which supports batched applies and counts
+// Apply and ApplyBatch invocations.
+type batchCountingBackend struct {
+	storageApi.LocalBackend
+
+	applies      int
+	batchApplies int
+}
+
+func (b *batchCountingBackend) Apply(ctx context.Context, request *storageApi.ApplyRequest) error {
+	b.applies++
+	return b.LocalBackend.Apply(ctx, request)
+}
+
+func (b *batchCountingBackend) ApplyBatch(ctx context.Context, requests []*storageApi.ApplyRequest) error {
+	b.batchApplies++
+	return b.LocalBackend.(storageApi.ApplyBatchBackend).ApplyBatch(ctx, requests)
+}
+
+// chainedStateDiffs returns fetched state diffs for the given number of consecutive rounds, each
+// chaining on top of the previous one.
+func chainedStateDiffs(t *testing.T, rounds int) []*fetchedDiff {
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+
+	var (
+		fullWriteLog storageApi.WriteLog
+		diffs        []*fetchedDiff
+	)
+	for i := 1; i <= rounds; i++ {
+		entry := storageApi.LogEntry{
+			Key:   []byte(fmt.Sprintf("key %d", i)),
+			Value: []byte(fmt.Sprintf("value %d", i)),
+		}
+		fullWriteLog = append(fullWriteLog, entry)
+		thisRoot := storageApi.Root{
+			Namespace: testNs,
+			Version:   uint64(i),
+			Type:      storageApi.RootTypeState,
+			Hash:      storageTests.CalculateExpectedNewRoot(t, fullWriteLog, testNs, uint64(i)),
+		}
+		diffs = append(diffs, &fetchedDiff{
+			fetched:  true,
+			pf:       rpc.NewNopPeerFeedback(),
+			round:    uint64(i),
+			prevRoot: prevRoot,
+			thisRoot: thisRoot,
+			writeLog: storageApi.WriteLog{entry},
+		})
+		prevRoot = thisRoot
+	}
+	return diffs
+}
+
+func TestApplyDiffBatch(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.cfg = &Config{ApplyBatchSize: 3}
+	backend := &batchCountingBackend{LocalBackend: n.localStorage}
+	n.localStorage = backend
+
+	diffs := chainedStateDiffs(t, 4)
+	ioRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   2,
+		Type:      storageApi.RootTypeIO,
+	}
+	ioRoot.Hash.Empty()
+	ioDiff := &fetchedDiff{
+		fetched:  true,
+		round:    2,
+		prevRoot: ioRoot,
+		thisRoot: ioRoot,
+	}
+	pending := outOfOrderRoundQueue{ioDiff, diffs[1], diffs[2], diffs[3]}
+
+	// Already fetched consecutive state diffs should be applied in a single capped batch.
+	applied := make(appliedWriteLogs)
+	err := n.applyDiffBatch(n.ctx, applied, diffs[0], pending, nil)
+	require.NoError(err, "applyDiffBatch()")
+	require.Equal(1, backend.batchApplies, "diffs should be applied in a single batch")
+	require.Equal(0, backend.applies, "no individual applies should be issued")
+	for _, d := range diffs[:3] {
+		require.True(n.localStorage.NodeDB().HasRoot(d.thisRoot), "batched root should exist (round %d)", d.round)
+	}
+	require.False(n.localStorage.NodeDB().HasRoot(diffs[3].thisRoot), "batch should be capped")
+	require.False(applied.contains(ioDiff.round, newAppliedWriteLogKey(ioDiff)), "I/O diffs should not be batched")
+
+	// Diffs applied as part of the batch should be skipped when processed individually.
+	for _, d := range diffs[1:3] {
+		err = n.applyDiffBatch(n.ctx, applied, d, pending, nil)
+		require.NoError(err, "applyDiffBatch()")
+	}
+	require.Equal(1, backend.batchApplies, "batched diffs should not be applied again")
+	require.Equal(0, backend.applies, "batched diffs should not be applied again")
+
+	// All batched rounds should still be finalizable individually.
+	for _, d := range diffs[:3] {
+		n.finalize(n.ctx, &blockSummary{Namespace: testNs, Round: d.round, Roots: []storageApi.Root{d.thisRoot}})
+		result := <-n.finalizeCh
+		require.NoError(result.err, "finalize() (round %d)", d.round)
+	}
+
+	// Without any following pending diffs, only the single diff should be applied.
+	err = n.applyDiffBatch(n.ctx, applied, diffs[3], nil, nil)
+	require.NoError(err, "applyDiffBatch()")
+	require.Equal(1, backend.applies, "single diff should be applied individually")
+	require.Equal(1, backend.batchApplies, "no batch should be used for a single diff")
+}
+
+func TestApplyDiffBatchMismatch(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.cfg = &Config{ApplyBatchSize: 10}
+	backend := &batchCountingBackend{LocalBackend: n.localStorage}
+	n.localStorage = backend
+
+	diffs := chainedStateDiffs(t, 3)
+	// Corrupt the last write log so the batched result does not match the expected root.
+	diffs[2].writeLog = storageApi.WriteLog{{Key: []byte("bogus key"), Value: []byte("bogus value")}}
+
+	applied := make(appliedWriteLogs)
+	err := n.applyDiffBatch(n.ctx, applied, diffs[0], outOfOrderRoundQueue{diffs[1], diffs[2]}, nil)
+	require.NoError(err, "applyDiffBatch() should fall back to applying the diff individually")
+	require.Equal(1, backend.batchApplies, "batch should be attempted")
+	require.Equal(1, backend.applies, "diff should be applied individually after batch failure")
+	require.True(n.localStorage.NodeDB().HasRoot(diffs[0].thisRoot), "first root should exist")
+	require.False(n.localStorage.NodeDB().HasRoot(diffs[2].thisRoot), "mismatched root should not exist")
+	require.False(applied.contains(diffs[1].round, newAppliedWriteLogKey(diffs[1])), "failed batch should not be recorded")
+}
+
+// testMembership is a committee membership check with a fixed set of member peers.
+type testMembership struct {
+	members map[core.PeerID]bool
+}
+
+func (m *testMembership) IsCommitteePeer(peerID core.PeerID) bool {
+	return m.members[peerID]
+}
+
+// testPeerDiffClient is a storage sync client which serves a fixed write log from a given peer.
+type testPeerDiffClient struct {
+	storageSync.Client
+
+	writeLog storageApi.WriteLog
+	peer     *testRecordingPeerFeedback
+}
+
+func (c *testPeerDiffClient) GetDiff(ctx context.Context, request *storageSync.GetDiffRequest) (*storageSync.GetDiffResponse, rpc.PeerFeedback, error) {
+	return &storageSync.GetDiffResponse{WriteLog: c.writeLog}, c.peer, nil
+}
+
+// testRecordingPeerFeedback is a peer feedback which records whether the peer was reported as bad.
+type testRecordingPeerFeedback struct {
+	testPeerFeedback
+
+	badPeer bool
+}
+
+func (pf *testRecordingPeerFeedback) RecordBadPeer() { pf.badPeer = true }
+
+func TestFetchDiffNonCommitteePeer(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.diffCh = make(chan *fetchedDiff, 1)
+	n.membership = &testMembership{members: map[core.PeerID]bool{"member": true}}
+
+	writeLog := storageApi.WriteLog{{Key: []byte("key"), Value: []byte("value")}}
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+	thisRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      storageApi.RootTypeState,
+		Hash:      storageTests.CalculateExpectedNewRoot(t, writeLog, testNs, 1),
+	}
+
+	// Diffs served by non-members should be rejected and the peer reported.
+	nonMember := &testRecordingPeerFeedback{testPeerFeedback: testPeerFeedback{peerID: "non-member"}}
+	n.storageSync = &testPeerDiffClient{writeLog: writeLog, peer: nonMember}
+	n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+	result := <-n.diffCh
+	require.ErrorIs(result.err, errNonCommitteePeer, "fetchDiff() from a non-committee peer")
+	require.Nil(result.writeLog, "write log from a non-committee peer should not be used")
+	require.True(nonMember.badPeer, "non-committee peer should be reported")
+
+	// Diffs served by members should be accepted.
+	member := &testRecordingPeerFeedback{testPeerFeedback: testPeerFeedback{peerID: "member"}}
+	n.storageSync = &testPeerDiffClient{writeLog: writeLog, peer: member}
+	n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+	result = <-n.diffCh
+	require.NoError(result.err, "fetchDiff() from a committee peer")
+	require.EqualValues(writeLog, result.writeLog, "write log from a committee peer should be used")
+	require.False(member.badPeer, "committee peer should not be reported")
+}
+
+func TestShutdownBufferedChannels(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.diffCh = make(chan *fetchedDiff, 2)
+	n.finalizeCh = make(chan finalizeResult, 1)
+	n.storageSync = &testDiffClient{writeLog: storageApi.WriteLog{{Key: []byte("key"), Value: []byte("value")}}}
+
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+	thisRoot := prevRoot
+	thisRoot.Version = 1
+	thisRoot.Hash.FromBytes([]byte("this root"))
+
+	// Start more fetchers and finalizations than the channels can buffer with nobody receiving.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+		}()
+		go func() {
+			defer wg.Done()
+			n.finalize(n.ctx, &blockSummary{Namespace: testNs, Round: 1})
+		}()
+	}
+
+	// Wait for the channel buffers to fill up.
+	require.Eventually(func() bool {
+		return len(n.diffCh) == cap(n.diffCh) && len(n.finalizeCh) == cap(n.finalizeCh)
+	}, time.Second, 10*time.Millisecond, "channel buffers should fill up")
+
+	// On shutdown, all blocked producers must terminate so the channel can be safely closed.
+	n.ctxCancel()
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+	select {
+	case <-doneCh:
+	case <-time.After(time.Second):
+		require.Fail("producers should terminate on shutdown")
+	}
+	close(n.diffCh)
+
+	var buffered int
+	for range n.diffCh {
+		buffered++
+	}
+	require.Equal(2, buffered, "buffered results should still be drainable after close")
+}
+
+// BenchmarkDiffChannelSize measures how the size of the diff channel buffer affects the rate at
+// which fetched diffs are processed by a consumer with a fixed processing cost.
+func BenchmarkDiffChannelSize(b *testing.B) {
+	const (
+		fetchers    = 4
+		applyCost   = 20 * time.Microsecond
+		fetchJitter = 50 * time.Microsecond
+	)
+
+	for _, size := range []uint{0, 4, 16, 128} {
+		b.Run(fmt.Sprintf("Size%d", size), func(b *testing.B) {
+			n := newTestNode(b)
+			n.diffCh = make(chan *fetchedDiff, size)
+
+			var wg sync.WaitGroup
+			jobCh := make(chan struct{})
+			for i := 0; i < fetchers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for range jobCh {
+						// Simulate bursty fetch completion.
+						if i%2 == 0 {
+							time.Sleep(fetchJitter)
+						}
+						n.diffCh <- &fetchedDiff{}
+					}
+				}(i)
+			}
+
+			b.ResetTimer()
+			go func() {
+				for i := 0; i < b.N; i++ {
+					jobCh <- struct{}{}
+				}
+				close(jobCh)
+			}()
+			for i := 0; i < b.N; i++ {
+				<-n.diffCh
+				start := time.Now()
+				for time.Since(start) < applyCost { // nolint: revive
+				}
+			}
+			b.StopTimer()
+			wg.Wait()
+		})
+	}
+}
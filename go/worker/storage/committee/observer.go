@@ -0,0 +1,74 @@
+package committee
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// initObserver finishes setting up a node running in observer mode.
+//
+// Observers do not have local storage, so they do not serve storage, create checkpoints or prune.
+func (n *Node) initObserver() {
+	n.logger.Warn("running in observer mode, runtime state will not be synced locally")
+
+	n.localStorage = nil
+	n.syncedState.Round = defaultUndefinedRound
+}
+
+// observe tracks the sync position from the headers of new blocks until the node is stopped,
+// without fetching, applying or finalizing any state. The common node must already be
+// initialized.
+//
+// The synced state of an observer is the state of the latest block seen. Since nothing is stored
+// locally, runtime history is never notified of storage being synced.
+func (n *Node) observe() {
+	// Make sure that initialization is never waited for in vain in case startup fails.
+	defer n.markInitialized()
+
+	n.logger.Info("starting committee node in observer mode")
+
+	genesisBlock, err := n.getGenesisBlock()
+	if err != nil {
+		n.logger.Error("can't retrieve genesis block", "err", err)
+		return
+	}
+	n.undefinedRound = genesisBlock.Header.Round - 1
+
+	n.markInitialized()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case inBlk := <-n.blockCh.Out():
+			n.observeBlock(inBlk.(*block.Block))
+		}
+	}
+}
+
+func (n *Node) observeBlock(blk *block.Block) {
+	logger := n.logger.With("round", blk.Header.Round)
+
+	summary, err := summaryFromBlock(n.commonNode.Runtime.ID(), blk)
+	if err != nil {
+		// Never track blocks of other runtimes.
+		logger.Error("ignoring block for a different runtime",
+			"namespace", blk.Header.Namespace,
+		)
+		n.reportSyncError(newSyncError(ErrNamespaceMismatch, blk.Header.Round, storageApi.RootTypeInvalid, nil))
+		return
+	}
+
+	n.syncedLock.Lock()
+	defer n.syncedLock.Unlock()
+
+	if n.syncedState.Round != defaultUndefinedRound && summary.Round <= n.syncedState.Round {
+		logger.Debug("ignoring stale block",
+			"last_round", n.syncedState.Round,
+		)
+		return
+	}
+	n.syncedState = *summary
+
+	logger.Debug("observed new block")
+}
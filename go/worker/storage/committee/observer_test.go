@@ -0,0 +1,83 @@
+package committee
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	workerCommon "github.com/oasisprotocol/oasis-core/go/worker/common"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+)
+
+func newTestObserver(chain *testChain, observerMode bool) (*Node, error) {
+	return NewNode(
+		&committee.Node{
+			Runtime: &testChainRuntime{history: &testChainHistory{chain: chain}},
+		},
+		nil,
+		nil,
+		nil,
+		workerCommon.Config{},
+		nil,
+		nil,
+		&CheckpointSyncConfig{Disabled: true},
+		&Config{
+			BlockSource:  chain,
+			ObserverMode: observerMode,
+		},
+	)
+}
+
+func TestNewNodeObserverMode(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(3)
+
+	// Without local storage, construction fails unless observer mode is enabled.
+	_, err := newTestObserver(chain, false)
+	require.ErrorIs(err, ErrNonLocalBackend, "NewNode should require local storage")
+
+	n, err := newTestObserver(chain, true)
+	require.NoError(err, "NewNode in observer mode")
+	require.Nil(n.GetLocalStorage(), "observers should not have local storage")
+	require.Nil(n.checkpointer, "observers should not create checkpoints")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.observe()
+	}()
+	defer func() {
+		n.Stop()
+		<-done
+	}()
+
+	select {
+	case <-n.Initialized():
+	case <-time.After(workerTestTimeout):
+		require.FailNow("observer should be initialized")
+	}
+
+	// The sync position follows block headers, but nothing is fetched or stored.
+	for round := uint64(1); round <= 3; round++ {
+		n.HandleNewBlockLocked(chain.block(round))
+	}
+	n.HandleNewBlockLocked(chain.block(2))
+	require.Eventually(func() bool {
+		synced, _, _ := n.GetLastSynced()
+		return synced == 3
+	}, workerTestTimeout, 10*time.Millisecond, "observer should track the latest round")
+
+	_, io, state := n.GetLastSynced()
+	require.Equal(blockRoot(chain.block(3), storageApi.RootTypeIO), io, "last synced I/O root")
+	require.Equal(blockRoot(chain.block(3), storageApi.RootTypeState), state, "last synced state root")
+
+	chain.Lock()
+	syncCheckpoint := chain.syncCheckpoint
+	chain.Unlock()
+	require.Zero(syncCheckpoint, "history should not be notified of storage being synced")
+
+	require.ErrorIs(n.VerifyLocalState(n.ctx, 3), ErrNonLocalBackend, "observers have no local state to verify")
+}
@@ -0,0 +1,98 @@
+package committee
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// roundProgressBufferSize is the number of round progress updates that are buffered for each
+// subscriber. In case a subscriber falls behind, the oldest updates are dropped.
+const roundProgressBufferSize = 128
+
+// RoundProgress is the sync progress of a single round.
+type RoundProgress struct {
+	// Round is the round the progress refers to.
+	Round uint64
+	// IOApplied is true when the I/O root of the round has been applied to local storage.
+	IOApplied bool
+	// StateApplied is true when the state root of the round has been applied to local storage.
+	StateApplied bool
+	// Finalized is true when the round has been finalized.
+	Finalized bool
+	// Applying is the progress of applying the write log of one of the roots of the round, in
+	// case the write log is large enough for its progress to be reported. It is nil otherwise.
+	Applying *ApplyProgress
+}
+
+// ApplyProgress is the progress of applying the write log of a single root.
+type ApplyProgress struct {
+	// RootType is the type of the root being applied.
+	RootType storageApi.RootType
+	// Entries is the number of write log entries applied so far.
+	Entries uint64
+	// Total is the total number of write log entries.
+	Total uint64
+}
+
+// Applied returns the components of the round that have been applied to local storage, in order.
+func (p *RoundProgress) Applied() []SyncComponent {
+	var mask outstandingMask
+	if p.IOApplied {
+		mask.add(storageApi.RootTypeIO)
+	}
+	if p.StateApplied {
+		mask.add(storageApi.RootTypeState)
+	}
+	return mask.components()
+}
+
+// WatchRoundProgress subscribes to per-round sync progress updates, emitted each time one of the
+// roots of a round is applied and when the round is finalized. In case the local storage backend
+// supports it, updates are also emitted while applying large write logs.
+//
+// Each update contains the complete progress of its round, so a later update for the same round
+// supersedes all earlier ones. Slow subscribers do not stall syncing, instead they only receive
+// the most recent updates.
+func (n *Node) WatchRoundProgress() (<-chan *RoundProgress, pubsub.ClosableSubscription) {
+	typedCh := make(chan *RoundProgress)
+	sub := n.roundProgressNotifier.SubscribeBuffered(roundProgressBufferSize)
+	sub.Unwrap(typedCh)
+
+	return typedCh, sub
+}
+
+// notifyRootApplied notifies round progress subscribers of a root of a round being applied.
+func (n *Node) notifyRootApplied(round uint64, syncing *inFlight) {
+	n.roundProgressNotifier.Broadcast(&RoundProgress{
+		Round:        round,
+		IOApplied:    syncing.applied(storageApi.RootTypeIO),
+		StateApplied: syncing.applied(storageApi.RootTypeState),
+	})
+}
+
+// applyProgress returns a callback which notifies round progress subscribers of the progress of
+// applying the write log of the given root of a round.
+func (n *Node) applyProgress(round uint64, rootType storageApi.RootType, syncing *inFlight) storageApi.ApplyProgressFunc {
+	return func(applied, total uint64) {
+		n.roundProgressNotifier.Broadcast(&RoundProgress{
+			Round:        round,
+			IOApplied:    syncing.applied(storageApi.RootTypeIO),
+			StateApplied: syncing.applied(storageApi.RootTypeState),
+			Applying: &ApplyProgress{
+				RootType: rootType,
+				Entries:  applied,
+				Total:    total,
+			},
+		})
+	}
+}
+
+// notifyRoundFinalized notifies round progress subscribers of a round being finalized.
+func (n *Node) notifyRoundFinalized(round uint64) {
+	n.roundProgressNotifier.Broadcast(&RoundProgress{
+		Round:        round,
+		IOApplied:    true,
+		StateApplied: true,
+		Finalized:    true,
+	})
+}
@@ -0,0 +1,72 @@
+package committee
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PruneOrdering defines how pruning of finalized rounds is ordered with respect to finalization of
+// later rounds that is in progress at the same time.
+//
+// Independent of the ordering, a round is only ever pruned once it is strictly below the last
+// finalized round, so the round following a pruned round is always finalized before the pruned
+// round is removed.
+type PruneOrdering uint8
+
+const (
+	// PruneAfterFinalize makes pruning wait for any finalizations that are in progress to complete,
+	// and holds back further finalizations until pruning is done. This is the default.
+	PruneAfterFinalize PruneOrdering = iota
+	// PruneConcurrentWithFinalize prunes rounds as soon as requested, which may happen while later
+	// rounds are being finalized.
+	PruneConcurrentWithFinalize
+)
+
+const (
+	// PruneOrderingAfterFinalize is the name of the PruneAfterFinalize prune ordering.
+	PruneOrderingAfterFinalize = "after_finalize"
+	// PruneOrderingConcurrent is the name of the PruneConcurrentWithFinalize prune ordering.
+	PruneOrderingConcurrent = "concurrent"
+)
+
+// String returns a string representation of the prune ordering.
+func (o PruneOrdering) String() string {
+	switch o {
+	case PruneAfterFinalize:
+		return PruneOrderingAfterFinalize
+	case PruneConcurrentWithFinalize:
+		return PruneOrderingConcurrent
+	default:
+		return fmt.Sprintf("[unknown: %d]", uint8(o))
+	}
+}
+
+// NewPruneOrdering returns the prune ordering with the given name.
+func NewPruneOrdering(name string) (PruneOrdering, error) {
+	switch strings.ToLower(name) {
+	case PruneOrderingAfterFinalize:
+		return PruneAfterFinalize, nil
+	case PruneOrderingConcurrent:
+		return PruneConcurrentWithFinalize, nil
+	default:
+		return 0, fmt.Errorf("unsupported prune ordering: '%s'", name)
+	}
+}
+
+// lockFinalize must be held while finalizing a round and returns the function releasing it.
+func (n *Node) lockFinalize() func() {
+	if n.cfg.PruneOrdering != PruneAfterFinalize {
+		return func() {}
+	}
+	n.pruneLock.RLock()
+	return n.pruneLock.RUnlock
+}
+
+// lockPrune must be held while pruning a round and returns the function releasing it.
+func (n *Node) lockPrune() func() {
+	if n.cfg.PruneOrdering != PruneAfterFinalize {
+		return func() {}
+	}
+	n.pruneLock.Lock()
+	return n.pruneLock.Unlock
+}
@@ -0,0 +1,169 @@
+package committee
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+)
+
+// LogEventRoundQuarantined is a log event value that signals that a round has been quarantined
+// after its diffs repeatedly failed to apply.
+const LogEventRoundQuarantined = "worker/storage/round-quarantined"
+
+// roundQuarantine tracks rounds whose fetched diffs repeatedly fail root verification across
+// distinct peers. Quarantined rounds are no longer retried until explicitly released.
+type roundQuarantine struct {
+	sync.Mutex
+
+	threshold   int
+	mismatches  map[uint64]map[core.PeerID]struct{}
+	quarantined map[uint64]struct{}
+}
+
+func newRoundQuarantine(threshold uint64) *roundQuarantine {
+	if threshold == 0 {
+		return nil
+	}
+	return &roundQuarantine{
+		threshold:   int(threshold),
+		mismatches:  make(map[uint64]map[core.PeerID]struct{}),
+		quarantined: make(map[uint64]struct{}),
+	}
+}
+
+// recordMismatch records that a diff for the given round served by the given peer failed root
+// verification. In case this causes the round to be quarantined, the distinct peers that served
+// mismatching diffs are returned together with true.
+func (q *roundQuarantine) recordMismatch(round uint64, peer core.PeerID) ([]core.PeerID, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if _, ok := q.quarantined[round]; ok {
+		return nil, false
+	}
+
+	peers, ok := q.mismatches[round]
+	if !ok {
+		peers = make(map[core.PeerID]struct{})
+		q.mismatches[round] = peers
+	}
+	peers[peer] = struct{}{}
+	if len(peers) < q.threshold {
+		return nil, false
+	}
+
+	q.quarantined[round] = struct{}{}
+	delete(q.mismatches, round)
+
+	quarantinedPeers := make([]core.PeerID, 0, len(peers))
+	for p := range peers {
+		quarantinedPeers = append(quarantinedPeers, p)
+	}
+	sort.Slice(quarantinedPeers, func(i, j int) bool {
+		return quarantinedPeers[i] < quarantinedPeers[j]
+	})
+	return quarantinedPeers, true
+}
+
+func (q *roundQuarantine) isQuarantined(round uint64) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	_, ok := q.quarantined[round]
+	return ok
+}
+
+// clear removes any mismatch tracking for a round that has been successfully synced.
+func (q *roundQuarantine) clear(round uint64) {
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.mismatches, round)
+	delete(q.quarantined, round)
+}
+
+func (q *roundQuarantine) release(round uint64) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if _, ok := q.quarantined[round]; !ok {
+		return api.ErrRoundNotQuarantined
+	}
+	delete(q.quarantined, round)
+	return nil
+}
+
+func (q *roundQuarantine) rounds() []uint64 {
+	q.Lock()
+	defer q.Unlock()
+
+	rounds := make([]uint64, 0, len(q.quarantined))
+	for round := range q.quarantined {
+		rounds = append(rounds, round)
+	}
+	sort.Slice(rounds, func(i, j int) bool {
+		return rounds[i] < rounds[j]
+	})
+	return rounds
+}
+
+// recordApplyMismatch records a root mismatch when applying the given diff and quarantines the
+// round in case diffs from enough distinct peers failed to apply.
+func (n *Node) recordApplyMismatch(diff *fetchedDiff, err error) {
+	if n.quarantine == nil {
+		return
+	}
+	peers, quarantined := n.quarantine.recordMismatch(diff.round, diff.pf.PeerID())
+	if !quarantined {
+		return
+	}
+
+	n.logger.Error("quarantining round after repeated apply mismatches, operator intervention required",
+		"err", err,
+		"round", diff.round,
+		"root_type", diff.thisRoot.Type,
+		"peers", peers,
+		logging.LogEvent, LogEventRoundQuarantined,
+	)
+	n.reportSyncError(&SyncError{
+		Kind:     ErrRoundQuarantined,
+		Round:    diff.round,
+		RootType: diff.thisRoot.Type,
+		Cause:    err,
+		Peers:    peers,
+	})
+}
+
+func (n *Node) isRoundQuarantined(round uint64) bool {
+	if n.quarantine == nil {
+		return false
+	}
+	return n.quarantine.isQuarantined(round)
+}
+
+// Unquarantine releases a quarantined round so that syncing it is retried.
+func (n *Node) Unquarantine(round uint64) error {
+	if n.quarantine == nil {
+		return api.ErrRoundNotQuarantined
+	}
+	if err := n.quarantine.release(round); err != nil {
+		return err
+	}
+
+	n.logger.Info("round released from quarantine",
+		"round", round,
+	)
+	return nil
+}
+
+// QuarantinedRounds returns the rounds that are currently quarantined, ordered by round.
+func (n *Node) QuarantinedRounds() []uint64 {
+	if n.quarantine == nil {
+		return nil
+	}
+	return n.quarantine.rounds()
+}
@@ -0,0 +1,98 @@
+package committee
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+)
+
+func TestRoundQuarantine(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(newRoundQuarantine(0), "zero threshold should disable quarantining")
+
+	q := newRoundQuarantine(2)
+	peers, quarantined := q.recordMismatch(5, "a")
+	require.False(quarantined, "single mismatch should not quarantine the round")
+	require.Nil(peers)
+	_, quarantined = q.recordMismatch(5, "a")
+	require.False(quarantined, "repeated mismatches from the same peer should not quarantine the round")
+	_, quarantined = q.recordMismatch(6, "b")
+	require.False(quarantined, "mismatches should be tracked per round")
+	require.False(q.isQuarantined(5))
+
+	peers, quarantined = q.recordMismatch(5, "b")
+	require.True(quarantined, "mismatches from distinct peers should quarantine the round")
+	require.Equal([]core.PeerID{"a", "b"}, peers, "peers serving mismatching diffs should be reported")
+	require.True(q.isQuarantined(5))
+	require.False(q.isQuarantined(6))
+	require.Equal([]uint64{5}, q.rounds())
+
+	_, quarantined = q.recordMismatch(5, "c")
+	require.False(quarantined, "already quarantined round should not be reported again")
+
+	require.ErrorIs(q.release(6), api.ErrRoundNotQuarantined, "releasing a non-quarantined round should fail")
+	require.NoError(q.release(5), "release")
+	require.False(q.isQuarantined(5))
+	require.Empty(q.rounds())
+
+	// Mismatch tracking starts from scratch after a release.
+	_, quarantined = q.recordMismatch(5, "a")
+	require.False(quarantined, "mismatch tracking should be reset after release")
+
+	// Successfully synced rounds are forgotten.
+	q.clear(5)
+	q.clear(6)
+	_, quarantined = q.recordMismatch(6, "a")
+	require.False(quarantined, "mismatch tracking should be reset after the round is synced")
+}
+
+func TestNodeUnquarantine(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.syncErrNotifier = pubsub.NewBroker(false)
+
+	// Quarantine is disabled by default.
+	require.ErrorIs(n.Unquarantine(1), api.ErrRoundNotQuarantined)
+	require.Nil(n.QuarantinedRounds())
+	require.False(n.isRoundQuarantined(1))
+
+	n.quarantine = newRoundQuarantine(2)
+
+	errCh, sub := n.WatchSyncErrors()
+	defer sub.Close()
+
+	root := storageApi.Root{Namespace: testNs, Version: 1, Type: storageApi.RootTypeState}
+	for _, peerID := range []core.PeerID{"a", "b"} {
+		n.recordApplyMismatch(&fetchedDiff{
+			round:    1,
+			thisRoot: root,
+			pf:       &testPeerFeedback{peerID},
+		}, storageApi.ErrExpectedRootMismatch)
+	}
+
+	select {
+	case syncErr := <-errCh:
+		require.True(errors.Is(syncErr, ErrRoundQuarantined), "sync error should be a quarantine")
+		require.True(syncErr.IsFatal(), "quarantine should be fatal")
+		require.ErrorIs(syncErr, storageApi.ErrExpectedRootMismatch, "cause should be the mismatch")
+		require.EqualValues(1, syncErr.Round)
+		require.Equal(storageApi.RootTypeState, syncErr.RootType)
+		require.Equal([]core.PeerID{"a", "b"}, syncErr.Peers)
+	case <-time.After(time.Second):
+		require.Fail("quarantine should be reported")
+	}
+
+	require.True(n.isRoundQuarantined(1))
+	require.Equal([]uint64{1}, n.QuarantinedRounds())
+	require.NoError(n.Unquarantine(1), "Unquarantine")
+	require.False(n.isRoundQuarantined(1))
+	require.ErrorIs(n.Unquarantine(1), api.ErrRoundNotQuarantined, "Unquarantine should fail for released round")
+}
@@ -0,0 +1,95 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// queuedDiff identifies a fetched diff waiting to be applied.
+type queuedDiff struct {
+	round    uint64
+	rootType storageApi.RootType
+}
+
+// queueSnapshot is a copy of the contents of the internal queues of the worker loop at a given
+// point, used for white-box test assertions of the ordering logic.
+type queueSnapshot struct {
+	// doneDiffs are the fetched diffs waiting to be applied, ordered by round and root type.
+	doneDiffs []queuedDiff
+	// finalizable are the fully applied rounds waiting to be finalized, in order.
+	finalizable []uint64
+	// outstanding are the masks of roots being fetched for each in-flight round.
+	outstanding map[uint64]outstandingMask
+	// awaitingRetry are the masks of roots awaiting a retry for each in-flight round.
+	awaitingRetry map[uint64]outstandingMask
+	// bufferedDiffBytes is the total size of the write logs of the fetched diffs.
+	bufferedDiffBytes uint64
+}
+
+func newQueueSnapshot(
+	doneDiffs *outOfOrderRoundQueue,
+	finalizable *outOfOrderRoundQueue,
+	syncingRounds map[uint64]*inFlight,
+	bufferedDiffBytes uint64,
+) *queueSnapshot {
+	snapshot := &queueSnapshot{
+		outstanding:       make(map[uint64]outstandingMask, len(syncingRounds)),
+		awaitingRetry:     make(map[uint64]outstandingMask, len(syncingRounds)),
+		bufferedDiffBytes: bufferedDiffBytes,
+	}
+	for _, item := range *doneDiffs {
+		diff := item.(*fetchedDiff)
+		snapshot.doneDiffs = append(snapshot.doneDiffs, queuedDiff{
+			round:    diff.round,
+			rootType: diff.thisRoot.Type,
+		})
+	}
+	sort.Slice(snapshot.doneDiffs, func(i, j int) bool {
+		if snapshot.doneDiffs[i].round != snapshot.doneDiffs[j].round {
+			return snapshot.doneDiffs[i].round < snapshot.doneDiffs[j].round
+		}
+		return snapshot.doneDiffs[i].rootType < snapshot.doneDiffs[j].rootType
+	})
+	for _, item := range *finalizable {
+		snapshot.finalizable = append(snapshot.finalizable, item.GetRound())
+	}
+	sort.Slice(snapshot.finalizable, func(i, j int) bool {
+		return snapshot.finalizable[i] < snapshot.finalizable[j]
+	})
+	for round, syncing := range syncingRounds {
+		snapshot.outstanding[round] = syncing.outstanding
+		snapshot.awaitingRetry[round] = syncing.awaitingRetry
+	}
+	return snapshot
+}
+
+// snapshotQueues returns a copy of the current contents of the internal queues of the worker loop.
+//
+// Snapshots are only served in case the worker was set up with a snapshot request channel, which
+// is never the case in production.
+func (n *Node) snapshotQueues(ctx context.Context) (*queueSnapshot, error) {
+	if n.queueSnapshotCh == nil {
+		return nil, fmt.Errorf("queue snapshots not enabled")
+	}
+
+	rspCh := make(chan *queueSnapshot, 1)
+	select {
+	case n.queueSnapshotCh <- rspCh:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.ctx.Done():
+		return nil, n.ctx.Err()
+	}
+
+	select {
+	case snapshot := <-rspCh:
+		return snapshot, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.ctx.Done():
+		return nil, n.ctx.Err()
+	}
+}
@@ -0,0 +1,43 @@
+package committee
+
+import (
+	"time"
+)
+
+// checkRetryingRounds updates the metrics of the oldest in-flight round whose diffs keep failing to
+// be fetched or applied, and warns about rounds that have been retrying for longer than the
+// configured threshold.
+func (n *Node) checkRetryingRounds(syncingRounds map[uint64]*inFlight) {
+	var (
+		oldestRound uint64
+		oldestSince time.Time
+		found       bool
+	)
+	for round, syncing := range syncingRounds {
+		since, ok := syncing.oldestRetry()
+		if !ok {
+			continue
+		}
+		if !found || round < oldestRound {
+			oldestRound, oldestSince, found = round, since, true
+		}
+
+		if n.cfg.RetryWarningThreshold > 0 && !syncing.retryWarned && time.Since(since) >= n.cfg.RetryWarningThreshold {
+			n.logger.Warn("round diffs keep failing to be fetched or applied",
+				"round", round,
+				"retrying_for", time.Since(since),
+				"awaiting_retry", syncing.awaitingRetry,
+			)
+			syncing.retryWarned = true
+		}
+	}
+
+	labels := n.getMetricLabels()
+	if !found {
+		storageWorkerOldestRetryingRound.With(labels).Set(0)
+		storageWorkerOldestRetryingRoundAge.With(labels).Set(0)
+		return
+	}
+	storageWorkerOldestRetryingRound.With(labels).Set(float64(oldestRound))
+	storageWorkerOldestRetryingRoundAge.With(labels).Set(time.Since(oldestSince).Seconds())
+}
@@ -0,0 +1,309 @@
+package committee
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
+	nodedb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// rootFilterHashCount is the number of bits set in the root filter for each root.
+const rootFilterHashCount = 4
+
+// rootFilter is a bloom filter of storage roots known to be present in local storage.
+//
+// The filter may report roots that are not present (false positives), but never fails to report
+// a root that has been added to it.
+type rootFilter struct {
+	sync.RWMutex
+
+	bits []uint64
+}
+
+func newRootFilter(size uint64) *rootFilter {
+	words := size / 8
+	if words == 0 {
+		words = 1
+	}
+	return &rootFilter{
+		bits: make([]uint64, words),
+	}
+}
+
+func (f *rootFilter) positions(root node.Root) [rootFilterHashCount]uint64 {
+	var version [8]byte
+	binary.LittleEndian.PutUint64(version[:], root.Version)
+	h := hash.NewFromBytes(root.Hash[:], version[:], []byte{byte(root.Type)})
+
+	// Derive the bit positions from two independent hashes (Kirsch-Mitzenmacher).
+	h1 := binary.LittleEndian.Uint64(h[0:8])
+	h2 := binary.LittleEndian.Uint64(h[8:16])
+	nbits := uint64(len(f.bits)) * 64
+
+	var positions [rootFilterHashCount]uint64
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % nbits
+	}
+	return positions
+}
+
+// add adds a root to the filter.
+func (f *rootFilter) add(root node.Root) {
+	positions := f.positions(root)
+
+	f.Lock()
+	defer f.Unlock()
+
+	for _, pos := range positions {
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// mayContain returns false if the root has definitely not been added to the filter.
+func (f *rootFilter) mayContain(root node.Root) bool {
+	positions := f.positions(root)
+
+	f.RLock()
+	defer f.RUnlock()
+
+	for _, pos := range positions {
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// rootFilterBackend is a local storage backend wrapper which maintains a bloom filter of roots
+// present in local storage so that lookups of missing roots can be answered without querying the
+// underlying database.
+type rootFilterBackend struct {
+	storageApi.LocalBackend
+
+	filter       *rootFilter
+	nodeDB       *rootFilterNodeDB
+	checkpointer *rootFilterCheckpointer
+}
+
+// NewRootFilterBackend wraps the given local storage backend with a bloom filter of size bytes
+// used as a fast path for root lookups.
+//
+// The filter is rebuilt from the roots in local storage so it must be created before the backend
+// is used by anyone else.
+func NewRootFilterBackend(
+	ctx context.Context,
+	localStorage storageApi.LocalBackend,
+	runtimeID common.Namespace,
+	size uint64,
+) (storageApi.LocalBackend, error) {
+	initMetrics()
+
+	filter := newRootFilter(size)
+	b := &rootFilterBackend{
+		LocalBackend: localStorage,
+		filter:       filter,
+		nodeDB: &rootFilterNodeDB{
+			NodeDB: localStorage.NodeDB(),
+			filter: filter,
+			labels: prometheus.Labels{"runtime": runtimeID.String()},
+		},
+		checkpointer: &rootFilterCheckpointer{
+			CreateRestorer: localStorage.Checkpointer(),
+			filter:         filter,
+		},
+	}
+	if err := b.rebuild(ctx); err != nil {
+		return nil, fmt.Errorf("failed to rebuild root filter: %w", err)
+	}
+	return b, nil
+}
+
+func (b *rootFilterBackend) rebuild(ctx context.Context) error {
+	ndb := b.LocalBackend.NodeDB()
+	latestVersion, ok := ndb.GetLatestVersion()
+	if !ok {
+		return nil
+	}
+
+	// Also include versions past the last finalized version as those may have already been
+	// applied, but not yet finalized.
+	for version := ndb.GetEarliestVersion(); version <= latestVersion+maxInFlightRounds; version++ {
+		roots, err := ndb.GetRootsForVersion(ctx, version)
+		if err != nil {
+			return err
+		}
+		for _, root := range roots {
+			b.filter.add(root)
+		}
+	}
+	return nil
+}
+
+// Implements storageApi.LocalBackend.
+func (b *rootFilterBackend) Apply(ctx context.Context, request *storageApi.ApplyRequest) error {
+	if err := b.LocalBackend.Apply(ctx, request); err != nil {
+		return err
+	}
+	b.filter.add(rootFromApplyRequest(request))
+	return nil
+}
+
+// Implements storageApi.ApplyProgressBackend.
+func (b *rootFilterBackend) ApplyWithProgress(ctx context.Context, request *storageApi.ApplyRequest, progress storageApi.ApplyProgressFunc) error {
+	pb, ok := b.LocalBackend.(storageApi.ApplyProgressBackend)
+	if !ok {
+		// Backend doesn't support reporting progress, apply without it.
+		return b.Apply(ctx, request)
+	}
+
+	if err := pb.ApplyWithProgress(ctx, request, progress); err != nil {
+		return err
+	}
+	b.filter.add(rootFromApplyRequest(request))
+	return nil
+}
+
+// Implements storageApi.ApplyBatchBackend.
+func (b *rootFilterBackend) ApplyBatch(ctx context.Context, requests []*storageApi.ApplyRequest) error {
+	bb, ok := b.LocalBackend.(storageApi.ApplyBatchBackend)
+	if !ok {
+		// Backend doesn't support batching, apply requests one by one.
+		for _, request := range requests {
+			if err := b.Apply(ctx, request); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := bb.ApplyBatch(ctx, requests); err != nil {
+		return err
+	}
+	for _, request := range requests {
+		b.filter.add(rootFromApplyRequest(request))
+	}
+	return nil
+}
+
+// Implements storageApi.ConcurrentFinalizeBackend.
+func (b *rootFilterBackend) SupportsConcurrentFinalize() bool {
+	cb, ok := b.LocalBackend.(storageApi.ConcurrentFinalizeBackend)
+	return ok && cb.SupportsConcurrentFinalize()
+}
+
+// Implements storageApi.EqualRootBackend.
+func (b *rootFilterBackend) HasEqualRoot(prevRoot, root storageApi.Root) bool {
+	eb, ok := b.LocalBackend.(storageApi.EqualRootBackend)
+	if !ok || !eb.HasEqualRoot(prevRoot, root) {
+		return false
+	}
+	// The root is present without being applied, so make sure the filter knows about it.
+	b.filter.add(root)
+	return true
+}
+
+// Implements storageApi.LocalBackend.
+func (b *rootFilterBackend) Checkpointer() checkpoint.CreateRestorer {
+	return b.checkpointer
+}
+
+// Implements storageApi.LocalBackend.
+func (b *rootFilterBackend) NodeDB() storageApi.NodeDB {
+	return b.nodeDB
+}
+
+// Implements storageApi.WrappedLocalBackend.
+func (b *rootFilterBackend) Unwrap() storageApi.LocalBackend {
+	return b.LocalBackend
+}
+
+func rootFromApplyRequest(request *storageApi.ApplyRequest) node.Root {
+	return node.Root{
+		Namespace: request.Namespace,
+		Version:   request.DstRound,
+		Type:      request.RootType,
+		Hash:      request.DstRoot,
+	}
+}
+
+type rootFilterNodeDB struct {
+	nodedb.NodeDB
+
+	filter *rootFilter
+	labels prometheus.Labels
+}
+
+// Implements nodedb.NodeDB.
+func (d *rootFilterNodeDB) HasRoot(root node.Root) bool {
+	if !d.filter.mayContain(root) {
+		storageWorkerRootFilterHits.With(d.labels).Inc()
+		return false
+	}
+
+	storageWorkerRootFilterMisses.With(d.labels).Inc()
+	if !d.NodeDB.HasRoot(root) {
+		storageWorkerRootFilterFalsePositives.With(d.labels).Inc()
+		return false
+	}
+	return true
+}
+
+// Implements nodedb.FinalizeResultNodeDB.
+func (d *rootFilterNodeDB) FinalizeWithResult(ctx context.Context, roots []node.Root) (*nodedb.FinalizeResult, error) {
+	return nodedb.FinalizeWithResult(ctx, d.NodeDB, roots)
+}
+
+// Implements nodedb.NodeDB.
+func (d *rootFilterNodeDB) NewBatch(oldRoot node.Root, version uint64, chunk bool) (nodedb.Batch, error) {
+	batch, err := d.NodeDB.NewBatch(oldRoot, version, chunk)
+	if err != nil {
+		return nil, err
+	}
+	return &rootFilterBatch{Batch: batch, filter: d.filter}, nil
+}
+
+type rootFilterBatch struct {
+	nodedb.Batch
+
+	filter *rootFilter
+}
+
+// Implements nodedb.Batch.
+func (b *rootFilterBatch) Commit(root node.Root) error {
+	if err := b.Batch.Commit(root); err != nil {
+		return err
+	}
+	b.filter.add(root)
+	return nil
+}
+
+type rootFilterCheckpointer struct {
+	checkpoint.CreateRestorer
+
+	filter *rootFilter
+}
+
+// Implements checkpoint.Restorer.
+func (c *rootFilterCheckpointer) RestoreChunk(ctx context.Context, index uint64, r io.Reader) (bool, error) {
+	// Remember the root being restored as the restoration is cleaned up once it is done.
+	cp := c.CreateRestorer.GetCurrentCheckpoint()
+
+	done, err := c.CreateRestorer.RestoreChunk(ctx, index, r)
+	if err != nil {
+		return false, err
+	}
+	if done && cp != nil {
+		c.filter.add(cp.Root)
+	}
+	return done, nil
+}
@@ -0,0 +1,72 @@
+package committee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestRootFilter(t *testing.T) {
+	require := require.New(t)
+
+	// Use a tiny filter to make sure that it saturates without ever reporting false negatives.
+	filter := newRootFilter(8)
+	var roots []storageApi.Root
+	for i := 0; i < 1000; i++ {
+		root := storageApi.Root{
+			Namespace: testNs,
+			Version:   uint64(i),
+			Type:      storageApi.RootTypeState,
+			Hash:      hash.NewFromBytes([]byte{byte(i), byte(i >> 8)}),
+		}
+		filter.add(root)
+		roots = append(roots, root)
+	}
+	for _, root := range roots {
+		require.True(filter.mayContain(root), "added roots should always be reported")
+	}
+
+	filter = newRootFilter(1024)
+	require.False(filter.mayContain(roots[0]), "empty filter should not report any roots")
+}
+
+func TestRootFilterBackend(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	underlying := n.localStorage
+
+	var err error
+	n.localStorage, err = NewRootFilterBackend(n.ctx, underlying, testNs, 1024)
+	require.NoError(err, "NewRootFilterBackend()")
+
+	s := newTestState(t, n)
+	var roots []storageApi.Root
+	for i := 0; i < 5; i++ {
+		roots = append(roots, s.advance().Roots...)
+	}
+	// Applied, but not yet finalized roots should also be known.
+	roots = append(roots, s.apply().Roots...)
+
+	for _, root := range roots {
+		require.True(n.localStorage.NodeDB().HasRoot(root), "applied roots should be present")
+	}
+	missing := storageApi.Root{
+		Namespace: testNs,
+		Version:   s.root.Version + 1,
+		Type:      storageApi.RootTypeState,
+		Hash:      hash.NewFromBytes([]byte("missing root")),
+	}
+	require.False(n.localStorage.NodeDB().HasRoot(missing), "missing roots should not be present")
+
+	// Rebuilding the filter from local storage should not lose any roots.
+	rebuilt, err := NewRootFilterBackend(n.ctx, underlying, testNs, 1024)
+	require.NoError(err, "NewRootFilterBackend(rebuild)")
+	for _, root := range roots {
+		require.True(rebuilt.(*rootFilterBackend).filter.mayContain(root), "rebuilt filter should contain all roots")
+		require.True(rebuilt.NodeDB().HasRoot(root), "roots should be present after rebuild")
+	}
+	require.Equal(underlying, rebuilt.(storageApi.WrappedLocalBackend).Unwrap(), "Unwrap should return the underlying backend")
+}
@@ -0,0 +1,163 @@
+package committee
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// ScrubProgress is the progress of the background scrubber of local storage.
+type ScrubProgress struct {
+	// RoundsScrubbed is the number of rounds verified so far.
+	RoundsScrubbed uint64 `json:"rounds_scrubbed"`
+	// LastScrubbedRound is the last verified round, only valid if RoundsScrubbed is non-zero.
+	LastScrubbedRound uint64 `json:"last_scrubbed_round"`
+	// PassesCompleted is the number of completed passes over all finalized rounds.
+	PassesCompleted uint64 `json:"passes_completed"`
+	// Corruptions is the number of rounds found to be missing or corrupted.
+	Corruptions uint64 `json:"corruptions"`
+}
+
+// scrubCursor is the position of the scrubber within the current pass over finalized rounds.
+type scrubCursor struct {
+	// active is true while a pass is in progress.
+	active bool
+	// next is the next round to verify in the current pass.
+	next uint64
+	// end is the last round of the current pass.
+	end uint64
+	// passStart is the time the last pass started.
+	passStart time.Time
+}
+
+// GetScrubProgress returns the progress of the background scrubber of local storage.
+func (n *Node) GetScrubProgress() ScrubProgress {
+	n.scrubLock.Lock()
+	defer n.scrubLock.Unlock()
+
+	return n.scrubProgress
+}
+
+// scrubber periodically verifies the next finalized round of local storage, walking all finalized
+// rounds in passes, and reports any missing or corrupted state as sync errors.
+func (n *Node) scrubber() {
+	// Wait for the common node to be initialized.
+	select {
+	case <-n.commonNode.Initialized():
+	case <-n.ctx.Done():
+		return
+	}
+
+	ticker := time.NewTicker(n.cfg.ScrubInterval)
+	defer ticker.Stop()
+
+	var cursor scrubCursor
+	for {
+		select {
+		case <-n.quitCh:
+			return
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n.scrubNext(n.ctx, &cursor)
+	}
+}
+
+// scrubNext verifies the next round of the current scrubber pass, starting a new pass in case the
+// previous one has completed and started long enough ago.
+func (n *Node) scrubNext(ctx context.Context, cursor *scrubCursor) {
+	n.syncedLock.RLock()
+	lastSynced := n.syncedState.Round
+	n.syncedLock.RUnlock()
+
+	if lastSynced == defaultUndefinedRound || lastSynced == n.undefinedRound {
+		return
+	}
+	earliest := n.localStorage.NodeDB().GetEarliestVersion()
+
+	if !cursor.active {
+		if !cursor.passStart.IsZero() && time.Since(cursor.passStart) < n.cfg.ScrubRecheckInterval {
+			return
+		}
+		cursor.active = true
+		cursor.next = earliest
+		cursor.end = lastSynced
+		cursor.passStart = time.Now()
+
+		n.logger.Debug("starting local storage scrubber pass",
+			"start_round", cursor.next,
+			"end_round", cursor.end,
+		)
+	}
+
+	// Rounds that have been pruned in the meantime no longer need to be verified.
+	if cursor.next < earliest {
+		cursor.next = earliest
+	}
+	if cursor.next <= cursor.end {
+		round := cursor.next
+		cursor.next++
+
+		n.scrubRound(ctx, round)
+	}
+	if cursor.next > cursor.end {
+		cursor.active = false
+
+		n.scrubLock.Lock()
+		n.scrubProgress.PassesCompleted++
+		n.scrubLock.Unlock()
+
+		n.logger.Debug("local storage scrubber pass completed",
+			"end_round", cursor.end,
+		)
+	}
+}
+
+// scrubRound verifies the local state of the given finalized round.
+func (n *Node) scrubRound(ctx context.Context, round uint64) {
+	summary, err := n.committedSummary(ctx, round)
+	if err != nil {
+		n.logger.Warn("failed to get block to scrub",
+			"err", err,
+			"round", round,
+		)
+		return
+	}
+
+	ndb := n.localStorage.NodeDB()
+	for _, root := range summary.Roots {
+		if _, err = mkvsDB.Verify(ctx, ndb, root, nil); err == nil {
+			continue
+		}
+		switch {
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			return
+		case round < ndb.GetEarliestVersion():
+			// The round has been pruned while it was being verified.
+			return
+		}
+
+		n.logger.Error("local storage scrubber found corrupted state",
+			"err", err,
+			"round", round,
+			"root", root,
+		)
+		storageWorkerScrubCorruptions.With(n.getMetricLabels()).Inc()
+		n.scrubLock.Lock()
+		n.scrubProgress.Corruptions++
+		n.scrubLock.Unlock()
+
+		n.reportSyncError(newSyncError(ErrLocalStateCorrupted, round, root.Type, err))
+		break
+	}
+
+	n.scrubLock.Lock()
+	n.scrubProgress.RoundsScrubbed++
+	n.scrubProgress.LastScrubbedRound = round
+	n.scrubLock.Unlock()
+	storageWorkerLastScrubbedRound.With(n.getMetricLabels()).Set(float64(round))
+}
@@ -0,0 +1,140 @@
+package committee
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+)
+
+// persistedSummaries is the serialized form of the persisted block summaries.
+type persistedSummaries struct {
+	Summaries []*blockSummary `json:"summaries"`
+}
+
+// summaryStore persists the summaries of recently seen blocks that have not yet been finalized, so
+// that the hash cache can be prewarmed after a restart instead of querying the runtime history for
+// the same blocks again.
+//
+// It is only used from the worker loop. A nil summary store persists nothing.
+type summaryStore struct {
+	path string
+	size uint64
+
+	summaries map[uint64]*blockSummary
+	dirty     bool
+}
+
+func newSummaryStore(path string, size uint64) *summaryStore {
+	return &summaryStore{
+		path:      path,
+		size:      size,
+		summaries: make(map[uint64]*blockSummary),
+	}
+}
+
+// load loads the persisted summaries of the given runtime, if any.
+func (s *summaryStore) load(runtimeID common.Namespace) error {
+	if s == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(s.path)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	default:
+		return fmt.Errorf("failed to read persisted block summaries: %w", err)
+	}
+	var persisted persistedSummaries
+	if err = CBORSyncStateCodec.Unmarshal(data, &persisted); err != nil {
+		return fmt.Errorf("malformed persisted block summaries: %w", err)
+	}
+	for _, summary := range persisted.Summaries {
+		if !summary.Namespace.Equal(&runtimeID) {
+			return fmt.Errorf("persisted block summaries are for a different runtime (expected: %s got: %s)", runtimeID, summary.Namespace)
+		}
+		s.summaries[summary.Round] = summary
+	}
+	return nil
+}
+
+// add records the summary of a seen block.
+func (s *summaryStore) add(summary *blockSummary) {
+	if s == nil {
+		return
+	}
+	if _, ok := s.summaries[summary.Round]; ok {
+		return
+	}
+	s.summaries[summary.Round] = summary
+	s.dirty = true
+}
+
+// prewarm adds the recorded summaries of rounds starting with the given last finalized round to
+// the given hash cache.
+func (s *summaryStore) prewarm(hashCache map[uint64]*blockSummary, lastFinalized uint64) int {
+	if s == nil {
+		return 0
+	}
+
+	var count int
+	for round, summary := range s.summaries {
+		if round < lastFinalized {
+			continue
+		}
+		if _, ok := hashCache[round]; ok {
+			continue
+		}
+		hashCache[round] = summary
+		count++
+	}
+	return count
+}
+
+// save prunes summaries of rounds before the given last finalized round and persists at most the
+// configured number of the oldest remaining summaries (which are needed first when catching up) in
+// case they changed.
+func (s *summaryStore) save(lastFinalized uint64) error {
+	if s == nil {
+		return nil
+	}
+
+	rounds := make([]uint64, 0, len(s.summaries))
+	for round := range s.summaries {
+		rounds = append(rounds, round)
+	}
+	sort.Slice(rounds, func(i, j int) bool { return rounds[i] < rounds[j] })
+
+	var persisted persistedSummaries
+	for _, round := range rounds {
+		switch {
+		case round < lastFinalized:
+			delete(s.summaries, round)
+			s.dirty = true
+		case uint64(len(persisted.Summaries)) < s.size:
+			persisted.Summaries = append(persisted.Summaries, s.summaries[round])
+		}
+	}
+	if !s.dirty {
+		return nil
+	}
+
+	data, err := CBORSyncStateCodec.Marshal(&persisted)
+	if err != nil {
+		return fmt.Errorf("failed to serialize block summaries: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write block summaries: %w", err)
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to write block summaries: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
@@ -0,0 +1,55 @@
+package committee
+
+import (
+	"sync/atomic"
+
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+)
+
+// noSyncCeiling is the sync ceiling value meaning that rounds are synced without limit.
+const noSyncCeiling = 0
+
+// SyncCeiling returns the round up to which the node syncs and finalizes rounds. Zero means that
+// there is no ceiling.
+func (n *Node) SyncCeiling() uint64 {
+	return atomic.LoadUint64(&n.syncCeiling)
+}
+
+// SetSyncCeiling changes the round up to which the node syncs and finalizes rounds, resuming sync
+// in case the node was idling at the previous ceiling. Zero removes the ceiling.
+//
+// The ceiling can only be raised as rounds that have already been synced cannot be reverted.
+func (n *Node) SetSyncCeiling(round uint64) error {
+	for {
+		current := atomic.LoadUint64(&n.syncCeiling)
+		switch {
+		case round == current:
+			return nil
+		case current == noSyncCeiling, round != noSyncCeiling && round < current:
+			return api.ErrSyncCeilingLowered
+		}
+		if atomic.CompareAndSwapUint64(&n.syncCeiling, current, round) {
+			break
+		}
+	}
+
+	n.logger.Info("sync ceiling changed",
+		"sync_ceiling", round,
+	)
+
+	// Notify the worker without blocking, a single pending notification is enough as the worker
+	// always uses the latest ceiling.
+	select {
+	case n.syncCeilingCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// capSyncRound limits the given round to the sync ceiling, if any.
+func (n *Node) capSyncRound(round uint64) uint64 {
+	if ceiling := n.SyncCeiling(); ceiling != noSyncCeiling && round > ceiling {
+		return ceiling
+	}
+	return round
+}
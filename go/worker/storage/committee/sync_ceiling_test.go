@@ -0,0 +1,41 @@
+package committee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+)
+
+func TestSyncCeiling(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.syncCeilingCh = make(chan struct{}, 1)
+
+	// Without a ceiling, rounds are not limited.
+	require.EqualValues(noSyncCeiling, n.SyncCeiling(), "there should be no ceiling by default")
+	require.EqualValues(100, n.capSyncRound(100), "rounds should not be limited without a ceiling")
+	require.ErrorIs(n.SetSyncCeiling(10), api.ErrSyncCeilingLowered, "ceiling can't be introduced at runtime")
+
+	n.syncCeiling = 10
+	require.EqualValues(9, n.capSyncRound(9), "rounds below the ceiling should not be limited")
+	require.EqualValues(10, n.capSyncRound(10), "the ceiling round should be synced")
+	require.EqualValues(10, n.capSyncRound(11), "rounds above the ceiling should be limited")
+
+	require.ErrorIs(n.SetSyncCeiling(9), api.ErrSyncCeilingLowered, "ceiling can't be lowered")
+	require.NoError(n.SetSyncCeiling(10), "setting the same ceiling should succeed")
+	require.Len(n.syncCeilingCh, 0, "setting the same ceiling should not resume sync")
+
+	// Raising the ceiling multiple times should only queue a single notification.
+	require.NoError(n.SetSyncCeiling(11), "SetSyncCeiling")
+	require.NoError(n.SetSyncCeiling(12), "SetSyncCeiling")
+	require.Len(n.syncCeilingCh, 1, "raising the ceiling should resume sync")
+	require.EqualValues(12, n.capSyncRound(100), "rounds above the raised ceiling should be limited")
+	<-n.syncCeilingCh
+
+	// Removing the ceiling resumes sync without limits.
+	require.NoError(n.SetSyncCeiling(noSyncCeiling), "SetSyncCeiling(none)")
+	require.Len(n.syncCeilingCh, 1, "removing the ceiling should resume sync")
+	require.EqualValues(100, n.capSyncRound(100), "rounds should not be limited after removing the ceiling")
+}
@@ -0,0 +1,102 @@
+package committee
+
+import (
+	"fmt"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// SyncComponent is a part of a round that is synced separately, i.e. one of its storage roots.
+type SyncComponent uint8
+
+const (
+	// SyncComponentIO is the I/O root of a round.
+	SyncComponentIO SyncComponent = iota + 1
+	// SyncComponentState is the state root of a round.
+	SyncComponentState
+)
+
+// SyncComponents are all the components of a round, in order.
+var SyncComponents = []SyncComponent{SyncComponentIO, SyncComponentState}
+
+// String returns a string representation of the sync component.
+func (c SyncComponent) String() string {
+	switch c {
+	case SyncComponentIO:
+		return "io"
+	case SyncComponentState:
+		return "state"
+	default:
+		return fmt.Sprintf("[unknown sync component: %d]", uint8(c))
+	}
+}
+
+// MarshalText encodes the sync component into text form.
+func (c SyncComponent) MarshalText() ([]byte, error) {
+	switch c {
+	case SyncComponentIO, SyncComponentState:
+		return []byte(c.String()), nil
+	default:
+		return nil, fmt.Errorf("invalid sync component: %d", uint8(c))
+	}
+}
+
+// UnmarshalText decodes a text marshalled sync component.
+func (c *SyncComponent) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case SyncComponentIO.String():
+		*c = SyncComponentIO
+	case SyncComponentState.String():
+		*c = SyncComponentState
+	default:
+		return fmt.Errorf("invalid sync component: %s", string(text))
+	}
+	return nil
+}
+
+// RootType returns the type of the storage root corresponding to the sync component.
+func (c SyncComponent) RootType() storageApi.RootType {
+	switch c {
+	case SyncComponentIO:
+		return storageApi.RootTypeIO
+	case SyncComponentState:
+		return storageApi.RootTypeState
+	default:
+		return storageApi.RootTypeInvalid
+	}
+}
+
+// SyncComponentFromRootType returns the sync component corresponding to the given type of storage
+// root.
+func SyncComponentFromRootType(rootType storageApi.RootType) (SyncComponent, error) {
+	switch rootType {
+	case storageApi.RootTypeIO:
+		return SyncComponentIO, nil
+	case storageApi.RootTypeState:
+		return SyncComponentState, nil
+	default:
+		return 0, fmt.Errorf("no sync component for root type %s", rootType)
+	}
+}
+
+// components returns the sync components of all roots in the mask, in order.
+func (o outstandingMask) components() []SyncComponent {
+	var components []SyncComponent
+	for _, c := range SyncComponents {
+		if o.contains(c.RootType()) {
+			components = append(components, c)
+		}
+	}
+	return components
+}
+
+// maskFromComponents returns the mask containing the roots of all given sync components.
+func maskFromComponents(components []SyncComponent) outstandingMask {
+	var mask outstandingMask
+	for _, c := range components {
+		if rootType := c.RootType(); rootType != storageApi.RootTypeInvalid {
+			mask.add(rootType)
+		}
+	}
+	return mask
+}
@@ -0,0 +1,67 @@
+package committee
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestSyncComponentRootType(t *testing.T) {
+	require := require.New(t)
+
+	for _, c := range SyncComponents {
+		c2, err := SyncComponentFromRootType(c.RootType())
+		require.NoError(err, "SyncComponentFromRootType(%s)", c)
+		require.Equal(c, c2, "root type conversion should round-trip")
+	}
+	require.Equal(storageApi.RootTypeIO, SyncComponentIO.RootType())
+	require.Equal(storageApi.RootTypeState, SyncComponentState.RootType())
+	require.Equal(storageApi.RootTypeInvalid, SyncComponent(0).RootType())
+
+	_, err := SyncComponentFromRootType(storageApi.RootTypeInvalid)
+	require.Error(err, "SyncComponentFromRootType should fail for invalid root types")
+}
+
+func TestSyncComponentText(t *testing.T) {
+	require := require.New(t)
+
+	raw, err := json.Marshal(SyncComponents)
+	require.NoError(err, "json.Marshal")
+	require.Equal(`["io","state"]`, string(raw))
+
+	var decoded []SyncComponent
+	err = json.Unmarshal(raw, &decoded)
+	require.NoError(err, "json.Unmarshal")
+	require.Equal(SyncComponents, decoded, "text encoding should round-trip")
+
+	_, err = SyncComponent(0).MarshalText()
+	require.Error(err, "MarshalText should fail for invalid components")
+	var c SyncComponent
+	require.Error(c.UnmarshalText([]byte("io-root")), "UnmarshalText should fail for unknown components")
+}
+
+func TestOutstandingMaskComponents(t *testing.T) {
+	require := require.New(t)
+
+	var mask outstandingMask
+	require.Empty(mask.components(), "empty mask should have no components")
+	require.Equal("outstanding_mask{}", mask.String())
+
+	mask.add(storageApi.RootTypeState)
+	require.Equal([]SyncComponent{SyncComponentState}, mask.components())
+	require.Equal("outstanding_mask{state}", mask.String())
+
+	mask.add(storageApi.RootTypeIO)
+	require.Equal(SyncComponents, mask.components())
+	require.Equal("outstanding_mask{io, state}", mask.String())
+	require.True(mask.hasAll(), "mask should contain all roots")
+
+	require.Equal(mask, maskFromComponents(SyncComponents), "component conversion should round-trip")
+	require.Equal(bitForType(storageApi.RootTypeIO), maskFromComponents([]SyncComponent{SyncComponentIO, 0}))
+
+	progress := RoundProgress{Round: 1, StateApplied: true}
+	require.Equal([]SyncComponent{SyncComponentState}, progress.Applied())
+}
@@ -0,0 +1,118 @@
+package committee
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// corruptSyncStateSuffix is the suffix of the backup of a synced state file that failed to decode.
+const corruptSyncStateSuffix = ".corrupt"
+
+// ExportSyncState writes the last synced state (the round and its roots) to the given file, so
+// that the sync position can later be restored via ImportSyncState.
+func (n *Node) ExportSyncState(path string) error {
+	n.syncedLock.RLock()
+	synced := n.syncedState
+	n.syncedLock.RUnlock()
+
+	if synced.Round == defaultUndefinedRound {
+		return fmt.Errorf("no synced state to export")
+	}
+	data, err := CBORSyncStateCodec.Marshal(&synced)
+	if err != nil {
+		return fmt.Errorf("failed to serialize synced state: %w", err)
+	}
+	if err = ioutil.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write synced state: %w", err)
+	}
+
+	n.logger.Info("exported synced state",
+		"round", synced.Round,
+		"path", path,
+	)
+	return nil
+}
+
+// ImportSyncState reads the synced state previously written by ExportSyncState from the given file
+// and uses it as the sync position instead of the one derived from local storage.
+//
+// The synced state must be imported before the worker starts syncing. Roots that are missing from
+// local storage are only reported as they may still be restored before the worker is started, but
+// syncing will not start while any of them is missing.
+//
+// In case the synced state can't be decoded and RecoverCorruptState is enabled, the file is moved
+// to a backup file with the corruptSyncStateSuffix and nothing is imported.
+func (n *Node) ImportSyncState(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read synced state: %w", err)
+	}
+	var synced blockSummary
+	if err = CBORSyncStateCodec.Unmarshal(data, &synced); err != nil {
+		if !n.cfg.RecoverCorruptState {
+			return fmt.Errorf("malformed synced state: %w", err)
+		}
+		return n.discardCorruptSyncState(path, err)
+	}
+
+	runtimeID := n.commonNode.Runtime.ID()
+	if !synced.Namespace.Equal(&runtimeID) {
+		return fmt.Errorf("synced state is for a different runtime (expected: %s got: %s)", runtimeID, synced.Namespace)
+	}
+	if synced.Round == defaultUndefinedRound {
+		return fmt.Errorf("synced state has an undefined round")
+	}
+	for _, root := range synced.Roots {
+		if !root.Namespace.Equal(&runtimeID) || root.Version != synced.Round {
+			return fmt.Errorf("synced state has a root not belonging to round %d (root: %s)", synced.Round, root)
+		}
+		// Empty roots are always implicitly present.
+		if !root.Hash.IsEmpty() && !n.localStorage.NodeDB().HasRoot(root) {
+			n.logger.Warn("imported synced state root is missing from local storage",
+				"round", synced.Round,
+				"root", root,
+			)
+		}
+	}
+
+	n.syncedLock.Lock()
+	defer n.syncedLock.Unlock()
+
+	if n.syncStarted {
+		return fmt.Errorf("synced state can only be imported before syncing starts")
+	}
+	n.importedSyncState = &synced
+
+	n.logger.Info("imported synced state",
+		"round", synced.Round,
+		"path", path,
+	)
+	return nil
+}
+
+// discardCorruptSyncState moves the synced state file that failed to decode aside, so that it is
+// kept for inspection but not imported again.
+func (n *Node) discardCorruptSyncState(path string, decodeErr error) error {
+	backupPath := path + corruptSyncStateSuffix
+	if err := os.Rename(path, backupPath); err != nil {
+		return fmt.Errorf("failed to back up malformed synced state: %w", err)
+	}
+
+	n.logger.Error("imported synced state is corrupted, deriving sync position from local storage instead",
+		"err", decodeErr,
+		"path", path,
+		"backup_path", backupPath,
+	)
+	return nil
+}
+
+// startSync marks the start of syncing, after which the synced state can no longer be imported,
+// and returns the imported synced state, if any.
+func (n *Node) startSync() *blockSummary {
+	n.syncedLock.Lock()
+	defer n.syncedLock.Unlock()
+
+	n.syncStarted = true
+	return n.importedSyncState
+}
@@ -0,0 +1,89 @@
+package committee
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+const (
+	// SyncStateCodecCBOR is the name of the CBOR synced state codec.
+	SyncStateCodecCBOR = "cbor"
+	// SyncStateCodecJSON is the name of the JSON synced state codec.
+	SyncStateCodecJSON = "json"
+)
+
+// SyncStateCodec is a serialization format of the exported synced state.
+//
+// The synced state is always persisted using CBOR, other codecs are only meant for inspecting and
+// editing it with external tools.
+type SyncStateCodec interface {
+	// Name returns the name of the codec.
+	Name() string
+
+	// Marshal serializes the given value.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal deserializes the given data into the given value.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type cborSyncStateCodec struct{}
+
+func (c cborSyncStateCodec) Name() string {
+	return SyncStateCodecCBOR
+}
+
+func (c cborSyncStateCodec) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v), nil
+}
+
+func (c cborSyncStateCodec) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
+
+type jsonSyncStateCodec struct{}
+
+func (c jsonSyncStateCodec) Name() string {
+	return SyncStateCodecJSON
+}
+
+func (c jsonSyncStateCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (c jsonSyncStateCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+var (
+	// CBORSyncStateCodec is the CBOR synced state codec, used for persisting the synced state.
+	CBORSyncStateCodec SyncStateCodec = cborSyncStateCodec{}
+	// JSONSyncStateCodec is the JSON synced state codec, meant for inspection.
+	JSONSyncStateCodec SyncStateCodec = jsonSyncStateCodec{}
+)
+
+// NewSyncStateCodec returns the synced state codec with the given name.
+func NewSyncStateCodec(name string) (SyncStateCodec, error) {
+	switch strings.ToLower(name) {
+	case SyncStateCodecCBOR:
+		return CBORSyncStateCodec, nil
+	case SyncStateCodecJSON:
+		return JSONSyncStateCodec, nil
+	default:
+		return nil, fmt.Errorf("unsupported synced state codec: '%s'", name)
+	}
+}
+
+// TranscodeSyncState converts a serialized synced state between codecs, e.g., to present a synced
+// state exported via ExportSyncState as JSON, or to convert an edited JSON synced state back so
+// that it can be used with ImportSyncState.
+func TranscodeSyncState(data []byte, from, to SyncStateCodec) ([]byte, error) {
+	var synced blockSummary
+	if err := from.Unmarshal(data, &synced); err != nil {
+		return nil, fmt.Errorf("malformed %s synced state: %w", from.Name(), err)
+	}
+	return to.Marshal(&synced)
+}
@@ -0,0 +1,146 @@
+package committee
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+)
+
+func TestSyncStateExportImport(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "sync_state")
+
+	n := newTestNode(t)
+	n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+	n.syncedState.Round = defaultUndefinedRound
+	require.Error(n.ExportSyncState(path), "ExportSyncState() without a synced state")
+
+	s := newTestState(t, n)
+	s.advance()
+	summary := s.advance()
+	n.syncedState = *summary
+	require.NoError(n.ExportSyncState(path), "ExportSyncState()")
+
+	// The synced state should be restored by a node using the same local storage.
+	restored := newTestNode(t)
+	restored.commonNode = n.commonNode
+	restored.localStorage = n.localStorage
+	require.NoError(restored.ImportSyncState(path), "ImportSyncState()")
+	require.Equal(summary, restored.startSync(), "imported synced state should be used on start")
+
+	// Importing should be rejected once syncing started.
+	require.Error(restored.ImportSyncState(path), "ImportSyncState() after syncing started")
+}
+
+func TestSyncStateImportValidation(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	n := newTestNode(t)
+	n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+	s := newTestState(t, n)
+	summary := s.advance()
+
+	writeState := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(ioutil.WriteFile(path, data, 0o600), "WriteFile()")
+		return path
+	}
+
+	require.Error(n.ImportSyncState(filepath.Join(dir, "missing")), "ImportSyncState() of a missing file")
+	require.Error(n.ImportSyncState(writeState("malformed", []byte("not cbor"))), "ImportSyncState() of a malformed file")
+
+	otherRuntime := *summary
+	otherRuntime.Namespace = common.NewTestNamespaceFromSeed([]byte("other storage worker test ns"), 0)
+	require.Error(n.ImportSyncState(writeState("other_runtime", cbor.Marshal(&otherRuntime))), "ImportSyncState() for another runtime")
+
+	otherRound := *summary
+	otherRound.Round++
+	require.Error(n.ImportSyncState(writeState("other_round", cbor.Marshal(&otherRound))), "ImportSyncState() with roots of another round")
+	require.Nil(n.importedSyncState, "invalid synced states should not be imported")
+
+	// Missing roots should only be reported as they may still be restored before starting.
+	missingRoot := *summary
+	missingRoot.Roots = []storageApi.Root{summary.Roots[0]}
+	missingRoot.Roots[0].Hash = hash.NewFromBytes([]byte("missing root"))
+	require.NoError(n.ImportSyncState(writeState("missing_root", cbor.Marshal(&missingRoot))), "ImportSyncState() with a missing root")
+	require.Equal(&missingRoot, n.importedSyncState, "synced state with a missing root should be imported")
+}
+
+func TestSyncStateImportCorrupt(t *testing.T) {
+	require := require.New(t)
+	corrupt := []byte("not cbor")
+
+	for _, recoverState := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "sync_state")
+		require.NoError(ioutil.WriteFile(path, corrupt, 0o600), "WriteFile()")
+
+		n := newTestNode(t)
+		n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+		n.cfg = &Config{RecoverCorruptState: recoverState}
+
+		err := n.ImportSyncState(path)
+		require.Nil(n.importedSyncState, "corrupted synced state should not be imported")
+		if !recoverState {
+			require.Error(err, "ImportSyncState() of corrupted state should fail without recovery")
+			data, rerr := ioutil.ReadFile(path)
+			require.NoError(rerr, "corrupted synced state should be left in place")
+			require.Equal(corrupt, data)
+			continue
+		}
+
+		require.NoError(err, "ImportSyncState() of corrupted state should succeed with recovery")
+		require.NoFileExists(path, "corrupted synced state should be moved aside")
+		data, rerr := ioutil.ReadFile(path + corruptSyncStateSuffix)
+		require.NoError(rerr, "corrupted synced state should be backed up")
+		require.Equal(corrupt, data, "backup should contain the corrupted bytes")
+		require.Nil(n.startSync(), "sync position should be derived from local storage")
+	}
+}
+
+func TestSyncStateCodecs(t *testing.T) {
+	require := require.New(t)
+
+	summary := &blockSummary{
+		Namespace: testNs,
+		Round:     42,
+		Roots: []storageApi.Root{
+			{Namespace: testNs, Version: 42, Type: storageApi.RootTypeIO, Hash: hash.NewFromBytes([]byte("io root"))},
+			{Namespace: testNs, Version: 42, Type: storageApi.RootTypeState, Hash: hash.NewFromBytes([]byte("state root"))},
+		},
+	}
+
+	for _, name := range []string{SyncStateCodecCBOR, SyncStateCodecJSON} {
+		codec, err := NewSyncStateCodec(name)
+		require.NoError(err, "NewSyncStateCodec(%s)", name)
+		require.Equal(name, codec.Name(), "codec name")
+
+		data, err := codec.Marshal(summary)
+		require.NoError(err, "Marshal(%s)", name)
+		var decoded blockSummary
+		require.NoError(codec.Unmarshal(data, &decoded), "Unmarshal(%s)", name)
+		require.Equal(summary, &decoded, "%s synced state should round-trip", name)
+		require.Error(codec.Unmarshal([]byte("{malformed"), &decoded), "Unmarshal(%s) of malformed data", name)
+	}
+
+	_, err := NewSyncStateCodec("yaml")
+	require.Error(err, "NewSyncStateCodec() with an unsupported codec")
+
+	// The persisted format should remain CBOR and transcoding back and forth should be lossless.
+	persisted := cbor.Marshal(summary)
+	inspected, err := TranscodeSyncState(persisted, CBORSyncStateCodec, JSONSyncStateCodec)
+	require.NoError(err, "TranscodeSyncState(cbor, json)")
+	restored, err := TranscodeSyncState(inspected, JSONSyncStateCodec, CBORSyncStateCodec)
+	require.NoError(err, "TranscodeSyncState(json, cbor)")
+	require.Equal(persisted, restored, "transcoded synced state should match the persisted one")
+	_, err = TranscodeSyncState(persisted, JSONSyncStateCodec, CBORSyncStateCodec)
+	require.Error(err, "TranscodeSyncState() with the wrong source codec")
+}
@@ -0,0 +1,149 @@
+package committee
+
+import (
+	"sync"
+	"time"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+)
+
+// writeLogStats are the accumulated statistics of applied write logs.
+type writeLogStats struct {
+	entries uint64
+	bytes   uint64
+}
+
+func (s *writeLogStats) add(writeLog storageApi.WriteLog) {
+	s.entries += uint64(len(writeLog))
+	for _, entry := range writeLog {
+		s.bytes += uint64(len(entry.Key) + len(entry.Value))
+	}
+}
+
+// growthEstimateWindowSize is the number of most recently finalized rounds over which the storage
+// growth per round is averaged.
+const growthEstimateWindowSize = 100
+
+// growthEstimator keeps a moving average of the number of bytes added per finalized round.
+type growthEstimator struct {
+	sync.Mutex
+
+	sizes [growthEstimateWindowSize]uint64
+	next  int
+	count int
+	sum   uint64
+}
+
+// add records the number of bytes added by a finalized round and returns the updated estimate.
+func (e *growthEstimator) add(bytes uint64) uint64 {
+	e.Lock()
+	defer e.Unlock()
+
+	e.sum -= e.sizes[e.next]
+	e.sizes[e.next] = bytes
+	e.sum += bytes
+	e.next = (e.next + 1) % len(e.sizes)
+	if e.count < len(e.sizes) {
+		e.count++
+	}
+	return e.sum / uint64(e.count)
+}
+
+// estimate returns the average number of bytes added per recently finalized round.
+func (e *growthEstimator) estimate() uint64 {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.count == 0 {
+		return 0
+	}
+	return e.sum / uint64(e.count)
+}
+
+// finalizeStats are the statistics of a single round finalization.
+type finalizeStats struct {
+	nodes    uint64
+	duration time.Duration
+}
+
+// syncStatsWindow is a bounded rolling window of per-round write log statistics.
+type syncStatsWindow struct {
+	sync.Mutex
+
+	rounds []api.RoundWriteLogStats
+	next   int
+	full   bool
+}
+
+func newSyncStatsWindow(size uint64) *syncStatsWindow {
+	if size == 0 {
+		return nil
+	}
+	return &syncStatsWindow{
+		rounds: make([]api.RoundWriteLogStats, size),
+	}
+}
+
+func (w *syncStatsWindow) add(stats api.RoundWriteLogStats) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.rounds[w.next] = stats
+	w.next = (w.next + 1) % len(w.rounds)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+func (w *syncStatsWindow) snapshot() []api.RoundWriteLogStats {
+	w.Lock()
+	defer w.Unlock()
+
+	if !w.full {
+		return append([]api.RoundWriteLogStats{}, w.rounds[:w.next]...)
+	}
+	rounds := make([]api.RoundWriteLogStats, 0, len(w.rounds))
+	rounds = append(rounds, w.rounds[w.next:]...)
+	rounds = append(rounds, w.rounds[:w.next]...)
+	return rounds
+}
+
+// recordFinalizedRound records the write log and finalization statistics of a finalized round.
+func (n *Node) recordFinalizedRound(round uint64, stats writeLogStats, fstats finalizeStats) {
+	storageWorkerRoundWriteLogEntries.With(n.getMetricLabels()).Observe(float64(stats.entries))
+	storageWorkerRoundWriteLogBytes.With(n.getMetricLabels()).Observe(float64(stats.bytes))
+	storageWorkerRoundFinalizedNodes.With(n.getMetricLabels()).Observe(float64(fstats.nodes))
+	storageWorkerRoundFinalizeLatency.With(n.getMetricLabels()).Observe(fstats.duration.Seconds())
+	storageWorkerGrowthPerRound.With(n.getMetricLabels()).Set(float64(n.growth.add(stats.bytes)))
+
+	if n.syncStats != nil {
+		n.syncStats.add(api.RoundWriteLogStats{
+			Round:            round,
+			Entries:          stats.entries,
+			Bytes:            stats.bytes,
+			FinalizedNodes:   fstats.nodes,
+			FinalizeDuration: fstats.duration,
+		})
+	}
+}
+
+// EstimateGrowthPerRound returns the average number of bytes added to local storage per round over
+// the most recently finalized rounds, which can be used to forecast disk usage growth.
+//
+// The estimate is based on the total size of the keys and values of the write logs of the rounds,
+// so it does not account for the overhead of the storage backend. Zero is returned in case no
+// rounds have been finalized yet.
+func (n *Node) EstimateGrowthPerRound() uint64 {
+	return n.growth.estimate()
+}
+
+// SyncStats returns the write log statistics of recently finalized rounds. In case the rolling
+// window of statistics is disabled, no rounds are returned.
+func (n *Node) SyncStats() *api.SyncStats {
+	stats := &api.SyncStats{}
+	if n.syncStats != nil {
+		stats.Rounds = n.syncStats.snapshot()
+	}
+	return stats
+}
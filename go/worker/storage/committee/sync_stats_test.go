@@ -0,0 +1,78 @@
+package committee
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+)
+
+func TestWriteLogStats(t *testing.T) {
+	require := require.New(t)
+
+	var stats writeLogStats
+	stats.add(nil)
+	stats.add(storageApi.WriteLog{
+		{Key: []byte("key"), Value: []byte("value")},
+		{Key: []byte("deleted"), Value: nil},
+	})
+	stats.add(storageApi.WriteLog{{Key: []byte("k"), Value: []byte("v")}})
+	require.EqualValues(3, stats.entries, "entries should be counted")
+	require.EqualValues(3+5+7+1+1, stats.bytes, "key and value sizes should be counted")
+}
+
+func TestEstimateGrowthPerRound(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+
+	require.Zero(n.EstimateGrowthPerRound(), "estimate should be zero initially")
+
+	n.recordFinalizedRound(1, writeLogStats{bytes: 100}, finalizeStats{})
+	n.recordFinalizedRound(2, writeLogStats{bytes: 300}, finalizeStats{})
+	require.EqualValues(200, n.EstimateGrowthPerRound(), "estimate should average finalized rounds")
+	require.EqualValues(200, testutil.ToFloat64(storageWorkerGrowthPerRound.With(n.getMetricLabels())))
+
+	// The estimate should only consider the most recent rounds.
+	for round := uint64(3); round <= growthEstimateWindowSize+2; round++ {
+		n.recordFinalizedRound(round, writeLogStats{bytes: 1000}, finalizeStats{})
+	}
+	require.EqualValues(1000, n.EstimateGrowthPerRound(), "estimate should adapt to changing churn")
+	n.recordFinalizedRound(growthEstimateWindowSize+3, writeLogStats{bytes: 1000 + growthEstimateWindowSize*10}, finalizeStats{})
+	require.EqualValues(1010, n.EstimateGrowthPerRound(), "estimate should be a moving average")
+	require.EqualValues(1010, testutil.ToFloat64(storageWorkerGrowthPerRound.With(n.getMetricLabels())))
+}
+
+func TestSyncStats(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+
+	// Statistics should not be kept when disabled.
+	n.recordFinalizedRound(1, writeLogStats{entries: 1, bytes: 10}, finalizeStats{})
+	require.Empty(n.SyncStats().Rounds, "no rounds should be reported when disabled")
+
+	n.syncStats = newSyncStatsWindow(3)
+	require.Empty(n.SyncStats().Rounds, "no rounds should be reported initially")
+	for round := uint64(1); round <= 2; round++ {
+		n.recordFinalizedRound(round, writeLogStats{entries: round, bytes: 10 * round}, finalizeStats{})
+	}
+	require.Equal([]api.RoundWriteLogStats{
+		{Round: 1, Entries: 1, Bytes: 10},
+		{Round: 2, Entries: 2, Bytes: 20},
+	}, n.SyncStats().Rounds, "recorded rounds should be reported")
+
+	// The window should be bounded and keep the most recent rounds in order.
+	for round := uint64(3); round <= 7; round++ {
+		n.recordFinalizedRound(round, writeLogStats{entries: round, bytes: 10 * round}, finalizeStats{})
+	}
+	require.Equal([]api.RoundWriteLogStats{
+		{Round: 5, Entries: 5, Bytes: 50},
+		{Round: 6, Entries: 6, Bytes: 60},
+		{Round: 7, Entries: 7, Bytes: 70},
+	}, n.SyncStats().Rounds, "only the most recent rounds should be kept")
+}
@@ -0,0 +1,54 @@
+package committee
+
+import (
+	"context"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+const (
+	// SpanSyncRound is the name of the span covering the sync of a single round, from the time
+	// its diffs are first scheduled to the time all of them have been applied.
+	SpanSyncRound = "storage.sync_round"
+	// SpanFetchDiff is the name of the span covering a single diff fetch.
+	SpanFetchDiff = "storage.fetch_diff"
+	// SpanApply is the name of the span covering a single (possibly batched) write log apply.
+	SpanApply = "storage.apply"
+	// SpanFinalize is the name of the span covering the finalization of a single round.
+	SpanFinalize = "storage.finalize"
+)
+
+// SpanAttributes are the attributes of a storage sync tracing span.
+type SpanAttributes struct {
+	// Round is the round that is being synced.
+	Round uint64
+	// RootType is the type of the root that is being synced (if applicable).
+	RootType storageApi.RootType
+}
+
+// Tracer is an optional tracer (e.g., backed by OpenTelemetry) used to create tracing spans
+// around storage sync operations.
+type Tracer interface {
+	// Start starts a new span with the given name as a child of the span contained in the given
+	// context (if any) and returns a derived context containing the new span.
+	Start(ctx context.Context, name string, attrs SpanAttributes) (context.Context, Span)
+}
+
+// Span is a tracing span.
+type Span interface {
+	// End ends the span, recording the given error in case it is non-nil.
+	End(err error)
+}
+
+type nopSpan struct{}
+
+func (nopSpan) End(error) {}
+
+// startSpan starts a new tracing span in case a tracer is configured. Otherwise the given context
+// is returned unchanged together with a no-op span.
+func (n *Node) startSpan(ctx context.Context, name string, attrs SpanAttributes) (context.Context, Span) {
+	if n.cfg == nil || n.cfg.Tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return n.cfg.Tracer.Start(ctx, name, attrs)
+}
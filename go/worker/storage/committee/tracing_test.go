@@ -0,0 +1,112 @@
+package committee
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	storageTests "github.com/oasisprotocol/oasis-core/go/storage/tests"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	tracer *testTracer
+
+	name   string
+	parent string
+	attrs  SpanAttributes
+	ended  bool
+	err    error
+}
+
+func (s *testSpan) End(err error) {
+	s.tracer.Lock()
+	defer s.tracer.Unlock()
+
+	s.ended = true
+	s.err = err
+}
+
+// testTracer is a tracer which records all started spans.
+type testTracer struct {
+	sync.Mutex
+
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs SpanAttributes) (context.Context, Span) {
+	t.Lock()
+	defer t.Unlock()
+
+	span := &testSpan{tracer: t, name: name, attrs: attrs}
+	if parent, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		span.parent = parent.name
+	}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func TestTracing(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	tracer := &testTracer{}
+	n.cfg = &Config{Tracer: tracer}
+	n.diffCh = make(chan *fetchedDiff, 1)
+
+	writeLog := storageApi.WriteLog{{Key: []byte("key"), Value: []byte("value")}}
+	n.storageSync = &testDiffClient{writeLog: writeLog}
+
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+	thisRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      storageApi.RootTypeState,
+		Hash:      storageTests.CalculateExpectedNewRoot(t, writeLog, testNs, 1),
+	}
+
+	roundCtx, roundSpan := n.startSpan(n.ctx, SpanSyncRound, SpanAttributes{Round: 1})
+	n.fetchDiff(roundCtx, 1, prevRoot, thisRoot)
+	diff := <-n.diffCh
+	require.NoError(diff.err, "fetchDiff()")
+	err := n.applyDiff(roundCtx, make(appliedWriteLogs), diff, nil)
+	require.NoError(err, "applyDiff()")
+	roundSpan.End(nil)
+
+	n.finalize(n.ctx, &blockSummary{Namespace: testNs, Round: 1, Roots: []storageApi.Root{thisRoot}})
+	result := <-n.finalizeCh
+	require.NoError(result.err, "finalize()")
+
+	require.Len(tracer.spans, 4, "all operations should be traced")
+	for i, expected := range []struct {
+		name   string
+		parent string
+	}{
+		{SpanSyncRound, ""},
+		{SpanFetchDiff, SpanSyncRound},
+		{SpanApply, SpanSyncRound},
+		{SpanFinalize, ""},
+	} {
+		span := tracer.spans[i]
+		require.Equal(expected.name, span.name, "span name")
+		require.Equal(expected.parent, span.parent, "span parent (%s)", span.name)
+		require.EqualValues(1, span.attrs.Round, "span round (%s)", span.name)
+		require.True(span.ended, "span should be ended (%s)", span.name)
+		require.NoError(span.err, "span error (%s)", span.name)
+	}
+	require.Equal(storageApi.RootTypeState, tracer.spans[1].attrs.RootType, "fetch span root type")
+
+	// No spans should be created without a tracer.
+	n.cfg.Tracer = nil
+	ctx, span := n.startSpan(n.ctx, SpanApply, SpanAttributes{})
+	require.Equal(n.ctx, ctx, "context should not be changed without a tracer")
+	require.IsType(nopSpan{}, span, "no-op span should be returned without a tracer")
+}
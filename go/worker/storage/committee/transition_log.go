@@ -0,0 +1,159 @@
+package committee
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// TransitionKind is the kind of a recorded worker state transition.
+type TransitionKind uint8
+
+const (
+	// TransitionBlockReceived is recorded when a new block is received.
+	TransitionBlockReceived TransitionKind = iota + 1
+	// TransitionFetchSubmitted is recorded when a diff fetch for one of the roots of a round is
+	// submitted.
+	TransitionFetchSubmitted
+	// TransitionDiffReceived is recorded when a fetched diff for one of the roots of a round is
+	// received.
+	TransitionDiffReceived
+	// TransitionApplied is recorded when a diff for one of the roots of a round has been applied.
+	TransitionApplied
+	// TransitionFinalized is recorded when a round has been finalized.
+	TransitionFinalized
+	// TransitionRetried is recorded when fetching or applying a diff for one of the roots of a
+	// round failed and will be retried.
+	TransitionRetried
+	// TransitionPruned is recorded when a round has been pruned from local storage.
+	TransitionPruned
+)
+
+// String returns a string representation of the transition kind.
+func (k TransitionKind) String() string {
+	switch k {
+	case TransitionBlockReceived:
+		return "block_received"
+	case TransitionFetchSubmitted:
+		return "fetch_submitted"
+	case TransitionDiffReceived:
+		return "diff_received"
+	case TransitionApplied:
+		return "applied"
+	case TransitionFinalized:
+		return "finalized"
+	case TransitionRetried:
+		return "retried"
+	case TransitionPruned:
+		return "pruned"
+	default:
+		return fmt.Sprintf("[unknown: %d]", uint8(k))
+	}
+}
+
+// MarshalText encodes a TransitionKind into text form.
+func (k TransitionKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Transition is a recorded worker state transition.
+type Transition struct {
+	// Time is the time at which the transition was recorded.
+	Time time.Time `json:"time"`
+	// Kind is the kind of the transition.
+	Kind TransitionKind `json:"kind"`
+	// Round is the round the transition refers to.
+	Round uint64 `json:"round"`
+	// RootType is the type of the root the transition refers to, in case the transition refers to
+	// a single root of the round (otherwise it is RootTypeInvalid).
+	RootType storageApi.RootType `json:"root_type,omitempty"`
+	// Mask is the mask of roots of the round that are being fetched, or awaiting a retry in case
+	// of TransitionRetried, after the transition.
+	Mask outstandingMask `json:"mask,omitempty"`
+}
+
+// transitionLog is a bounded ring buffer of the most recent worker state transitions, which
+// provides a trail for debugging sync issues without enabling debug logging.
+//
+// A nil transition log records nothing.
+type transitionLog struct {
+	lock sync.Mutex
+
+	entries []Transition
+	next    int
+	full    bool
+}
+
+func newTransitionLog(size uint) *transitionLog {
+	return &transitionLog{
+		entries: make([]Transition, size),
+	}
+}
+
+// record records a transition, overwriting the oldest one in case the log is full.
+func (l *transitionLog) record(kind TransitionKind, round uint64, rootType storageApi.RootType, mask outstandingMask) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.entries[l.next] = Transition{
+		Time:     time.Now(),
+		Kind:     kind,
+		Round:    round,
+		RootType: rootType,
+		Mask:     mask,
+	}
+	l.next++
+	if l.next == len(l.entries) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// snapshot returns a copy of the recorded transitions, oldest first.
+func (l *transitionLog) snapshot() []Transition {
+	if l == nil {
+		return nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !l.full {
+		return append([]Transition{}, l.entries[:l.next]...)
+	}
+	return append(append([]Transition{}, l.entries[l.next:]...), l.entries[:l.next]...)
+}
+
+// GetTransitionLog returns the most recent recorded worker state transitions, oldest first. In
+// case the transition log is not enabled, nil is returned.
+func (n *Node) GetTransitionLog() []Transition {
+	return n.transitions.snapshot()
+}
+
+// dumpTransitionLog logs all recorded worker state transitions, e.g., after a fatal error.
+func (n *Node) dumpTransitionLog(reason string) {
+	transitions := n.transitions.snapshot()
+	if len(transitions) == 0 {
+		return
+	}
+
+	n.logger.Error("dumping worker state transition log",
+		"reason", reason,
+		"transitions", len(transitions),
+	)
+	for _, t := range transitions {
+		n.logger.Error("worker state transition",
+			"time", t.Time,
+			"kind", t.Kind,
+			"round", t.Round,
+			"root_type", t.RootType,
+			"mask", t.Mask,
+		)
+	}
+}
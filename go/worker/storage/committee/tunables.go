@@ -0,0 +1,99 @@
+package committee
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+)
+
+// Tunables are the storage sync parameters that can be changed at runtime via Reconfigure.
+type Tunables struct {
+	// MaxInFlightRounds is the maximum number of rounds that are fetched before waiting for them
+	// to be applied. It must not exceed the default, which is also the hard limit.
+	MaxInFlightRounds uint64
+
+	// GetDiffTimeout is the timeout for fetching a single diff from remote peers. Zero means that
+	// diff fetches do not time out.
+	GetDiffTimeout time.Duration
+
+	// RetryInitialInterval is the initial interval after which rounds that are still being synced
+	// are retried in case no new blocks are received.
+	RetryInitialInterval time.Duration
+	// RetryMaxInterval is the maximum interval between retries of rounds that are still being
+	// synced, which grows exponentially from the initial interval.
+	RetryMaxInterval time.Duration
+}
+
+// DefaultTunables returns the default storage sync tunables.
+func DefaultTunables() Tunables {
+	return Tunables{
+		MaxInFlightRounds:    maxInFlightRounds,
+		RetryInitialInterval: 5 * time.Second,
+		RetryMaxInterval:     20 * time.Second,
+	}
+}
+
+// Validate performs tunables checks.
+func (t *Tunables) Validate() error {
+	if t.MaxInFlightRounds == 0 {
+		return fmt.Errorf("maximum number of in-flight rounds must be positive")
+	}
+	// Local storage may contain applied but not yet finalized rounds up to this limit past the
+	// last finalized round (e.g., the root filter relies on it when being rebuilt).
+	if t.MaxInFlightRounds > maxInFlightRounds {
+		return fmt.Errorf("maximum number of in-flight rounds must not exceed %d", maxInFlightRounds)
+	}
+	if t.GetDiffTimeout < 0 {
+		return fmt.Errorf("get diff timeout must not be negative")
+	}
+	if t.RetryInitialInterval <= 0 {
+		return fmt.Errorf("initial retry interval must be positive")
+	}
+	if t.RetryMaxInterval < t.RetryInitialInterval {
+		return fmt.Errorf("maximum retry interval must not be smaller than the initial retry interval")
+	}
+	return nil
+}
+
+// effectiveTunables returns the initial storage sync tunables for the given runtime.
+func (cfg *Config) effectiveTunables(runtimeID common.Namespace) Tunables {
+	if tunables, ok := cfg.RuntimeTunables[runtimeID]; ok {
+		return tunables
+	}
+	if cfg.Tunables != nil {
+		return *cfg.Tunables
+	}
+	return DefaultTunables()
+}
+
+// Tunables returns the storage sync tunables currently in effect.
+func (n *Node) Tunables() Tunables {
+	n.tunablesLock.RLock()
+	defer n.tunablesLock.RUnlock()
+
+	return n.tunables
+}
+
+// Reconfigure changes the storage sync tunables at runtime.
+//
+// The new tunables apply to subsequently started fetches and retries, fetches that are already in
+// progress are not affected. In case the tunables are invalid, nothing is changed.
+func (n *Node) Reconfigure(tunables Tunables) error {
+	if err := tunables.Validate(); err != nil {
+		return fmt.Errorf("bad storage sync tunables: %w", err)
+	}
+
+	n.tunablesLock.Lock()
+	defer n.tunablesLock.Unlock()
+
+	n.tunables = tunables
+
+	n.logger.Info("storage sync tunables changed",
+		"max_in_flight_rounds", tunables.MaxInFlightRounds,
+		"get_diff_timeout", tunables.GetDiffTimeout,
+		"retry_initial_interval", tunables.RetryInitialInterval,
+		"retry_max_interval", tunables.RetryMaxInterval,
+	)
+	return nil
+}
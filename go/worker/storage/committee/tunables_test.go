@@ -0,0 +1,106 @@
+package committee
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestTunablesValidate(t *testing.T) {
+	require := require.New(t)
+
+	defaults := DefaultTunables()
+	require.NoError(defaults.Validate(), "default tunables should be valid")
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*Tunables)
+	}{
+		{"NoInFlightRounds", func(t *Tunables) { t.MaxInFlightRounds = 0 }},
+		{"TooManyInFlightRounds", func(t *Tunables) { t.MaxInFlightRounds = maxInFlightRounds + 1 }},
+		{"NegativeGetDiffTimeout", func(t *Tunables) { t.GetDiffTimeout = -time.Second }},
+		{"NoRetryInterval", func(t *Tunables) { t.RetryInitialInterval = 0 }},
+		{"RetryMaxIntervalTooSmall", func(t *Tunables) { t.RetryMaxInterval = t.RetryInitialInterval - 1 }},
+	} {
+		tunables := DefaultTunables()
+		tc.modify(&tunables)
+		require.Error(tunables.Validate(), "%s: tunables should be invalid", tc.name)
+	}
+}
+
+func TestEffectiveTunables(t *testing.T) {
+	require := require.New(t)
+
+	otherNs := common.NewTestNamespaceFromSeed([]byte("storage worker test other ns"), 0)
+
+	cfg := &Config{}
+	require.Equal(DefaultTunables(), cfg.effectiveTunables(testNs), "default tunables should be used")
+
+	defaults := DefaultTunables()
+	defaults.GetDiffTimeout = time.Minute
+	cfg.Tunables = &defaults
+	require.Equal(defaults, cfg.effectiveTunables(testNs), "configured default tunables should be used")
+
+	override := DefaultTunables()
+	override.MaxInFlightRounds = 10
+	override.RetryInitialInterval = time.Second
+	override.RetryMaxInterval = time.Minute
+	cfg.RuntimeTunables = map[common.Namespace]Tunables{
+		testNs: override,
+	}
+	require.NoError(cfg.Validate(), "Validate")
+	require.Equal(override, cfg.effectiveTunables(testNs), "runtime override should take precedence")
+	require.Equal(defaults, cfg.effectiveTunables(otherNs), "other runtimes should use the defaults")
+
+	// Invalid tunables should be rejected.
+	override.MaxInFlightRounds = 0
+	cfg.RuntimeTunables[otherNs] = override
+	require.Error(cfg.Validate(), "Validate with invalid runtime tunables")
+	delete(cfg.RuntimeTunables, otherNs)
+	defaults.RetryMaxInterval = 0
+	require.Error(cfg.Validate(), "Validate with invalid default tunables")
+}
+
+func TestReconfigure(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.tunables = DefaultTunables()
+
+	tunables := DefaultTunables()
+	tunables.MaxInFlightRounds = 10
+	tunables.GetDiffTimeout = time.Second
+	tunables.RetryInitialInterval = time.Second
+	tunables.RetryMaxInterval = time.Second
+	require.NoError(n.Reconfigure(tunables), "Reconfigure")
+	require.Equal(tunables, n.Tunables(), "tunables should be changed")
+
+	// Invalid tunables should be rejected and leave the current ones in effect.
+	bogus := tunables
+	bogus.RetryMaxInterval = 0
+	require.Error(n.Reconfigure(bogus), "Reconfigure with invalid tunables")
+	require.Equal(tunables, n.Tunables(), "tunables should not be changed")
+}
+
+func TestFetchDiffTimeout(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(1)
+	chain.diffDelay = time.Hour
+
+	n := newTestNode(t)
+	n.diffCh = make(chan *fetchedDiff, 1)
+	n.storageSync = chain
+	n.tunables = DefaultTunables()
+	n.tunables.GetDiffTimeout = 10 * time.Millisecond
+
+	prevRoot := blockRoot(chain.block(0), storageApi.RootTypeState)
+	thisRoot := blockRoot(chain.block(1), storageApi.RootTypeState)
+	n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+	result := <-n.diffCh
+	require.ErrorIs(result.err, context.DeadlineExceeded, "diff fetch should time out")
+}
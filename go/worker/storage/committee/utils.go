@@ -1,6 +1,7 @@
 package committee
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -24,11 +25,10 @@ func bitForType(rootType storageApi.RootType) outstandingMask {
 var outstandingMaskFull = bitForType(storageApi.RootTypeMax+1) - 1
 
 func (o outstandingMask) String() string {
-	represented := make([]string, 0, storageApi.RootTypeMax)
-	for i := storageApi.RootType(1); i <= storageApi.RootTypeMax; i++ {
-		if (1<<bitForType(i))&o != 0 {
-			represented = append(represented, i.String())
-		}
+	components := o.components()
+	represented := make([]string, 0, len(components))
+	for _, c := range components {
+		represented = append(represented, c.String())
 	}
 	return fmt.Sprintf("outstanding_mask{%s}", strings.Join(represented, ", "))
 }
@@ -54,9 +54,34 @@ func (o outstandingMask) hasAll() bool {
 }
 
 type inFlight struct {
-	startedAt     time.Time
+	startedAt time.Time
+	// required are the roots that need to be synced for the round to be complete.
+	required      outstandingMask
 	outstanding   outstandingMask
 	awaitingRetry outstandingMask
+
+	// ctx is the context containing the round's tracing span (if any).
+	ctx  context.Context
+	span Span
+
+	// stats are the statistics of the write logs applied so far.
+	stats writeLogStats
+
+	// retryingSince are the times at which fetching or applying each of the roots first failed, for
+	// roots that have not been applied since.
+	retryingSince map[storageApi.RootType]time.Time
+	// retryWarned is true once the round has been reported as retrying for too long.
+	retryWarned bool
+}
+
+// newInFlight creates a new in-flight round which needs the given roots to be synced. All of the
+// required roots start out as awaiting to be fetched.
+func newInFlight(required outstandingMask) *inFlight {
+	return &inFlight{
+		startedAt:     time.Now(),
+		required:      required,
+		awaitingRetry: required,
+	}
 }
 
 func (i *inFlight) scheduleDiff(rootType storageApi.RootType) {
@@ -67,6 +92,50 @@ func (i *inFlight) scheduleDiff(rootType storageApi.RootType) {
 func (i *inFlight) retry(rootType storageApi.RootType) {
 	i.outstanding.remove(rootType)
 	i.awaitingRetry.add(rootType)
+
+	if i.retryingSince == nil {
+		i.retryingSince = make(map[storageApi.RootType]time.Time)
+	}
+	if _, ok := i.retryingSince[rootType]; !ok {
+		i.retryingSince[rootType] = time.Now()
+	}
+}
+
+// markApplied records that the given root has been applied to local storage.
+func (i *inFlight) markApplied(rootType storageApi.RootType) {
+	i.outstanding.remove(rootType)
+	delete(i.retryingSince, rootType)
+}
+
+// oldestRetry returns the time at which fetching or applying the longest retried root that has not
+// been applied since first failed, if any.
+func (i *inFlight) oldestRetry() (time.Time, bool) {
+	var (
+		oldest time.Time
+		ok     bool
+	)
+	for _, since := range i.retryingSince {
+		if !ok || since.Before(oldest) {
+			oldest, ok = since, true
+		}
+	}
+	return oldest, ok
+}
+
+// applied returns true when the given root has been applied to local storage.
+func (i *inFlight) applied(rootType storageApi.RootType) bool {
+	return i.required.contains(rootType) && !i.outstanding.contains(rootType) && !i.awaitingRetry.contains(rootType)
+}
+
+// fetching returns true when diffs for all of the required roots are being fetched.
+func (i *inFlight) fetching() bool {
+	return i.outstanding&i.required == i.required
+}
+
+// complete returns true when all of the required roots have been applied to local storage, so
+// that the round can be finalized. Roots that are not required are never waited for.
+func (i *inFlight) complete() bool {
+	return (i.outstanding|i.awaitingRetry)&i.required == 0
 }
 
 // blockSummary is a short summary of a single block.Block.
@@ -80,26 +149,91 @@ func (s *blockSummary) GetRound() uint64 {
 	return s.Round
 }
 
-func summaryFromBlock(blk *block.Block) *blockSummary {
-	return &blockSummary{
-		Namespace: blk.Header.Namespace,
-		Round:     blk.Header.Round,
-		Roots:     blk.Header.StorageRoots(),
+// summaryFromBlock returns a summary of the given block. Only known block header versions are
+// supported, as the layout of the header fields may change between versions. The block (and thus
+// all of its roots) must belong to the given runtime.
+func summaryFromBlock(runtimeID common.Namespace, blk *block.Block) (*blockSummary, error) {
+	if !blk.Header.Namespace.Equal(&runtimeID) {
+		return nil, fmt.Errorf("%w (expected: %s got: %s round: %d)",
+			ErrNamespaceMismatch,
+			runtimeID,
+			blk.Header.Namespace,
+			blk.Header.Round,
+		)
+	}
+
+	switch blk.Header.Version {
+	case 0:
+		return &blockSummary{
+			Namespace: blk.Header.Namespace,
+			Round:     blk.Header.Round,
+			Roots:     blk.Header.StorageRoots(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported block header version %d (round: %d)",
+			block.ErrInvalidVersion,
+			blk.Header.Version,
+			blk.Header.Round,
+		)
+	}
+}
+
+// validateRootChaining checks that the source root of a diff satisfies the chaining assumptions
+// given the summary of the previous round. State roots are chained, so the source root must be
+// the previous round's state root. I/O roots are not chained, so the source root must be an empty
+// root at the same version as the destination root.
+func validateRootChaining(prev *blockSummary, srcRoot, dstRoot storageApi.Root) error {
+	if srcRoot.Type != dstRoot.Type {
+		return fmt.Errorf("source root type %s does not match destination root type %s",
+			srcRoot.Type, dstRoot.Type,
+		)
+	}
+
+	switch dstRoot.Type {
+	case storageApi.RootTypeIO:
+		if !srcRoot.Hash.IsEmpty() {
+			return fmt.Errorf("source I/O root is not empty (hash: %s)", srcRoot.Hash)
+		}
+		if srcRoot.Version != dstRoot.Version {
+			return fmt.Errorf("source I/O root version %d does not match destination version %d",
+				srcRoot.Version, dstRoot.Version,
+			)
+		}
+	case storageApi.RootTypeState:
+		if prev == nil {
+			return fmt.Errorf("missing summary for the previous round")
+		}
+		for _, prevRoot := range prev.Roots {
+			if prevRoot.Type != storageApi.RootTypeState {
+				continue
+			}
+			if !prevRoot.Hash.Equal(&srcRoot.Hash) || prevRoot.Version != srcRoot.Version {
+				return fmt.Errorf("source state root %s@%d does not match previous state root %s@%d",
+					srcRoot.Hash, srcRoot.Version,
+					prevRoot.Hash, prevRoot.Version,
+				)
+			}
+			return nil
+		}
+		return fmt.Errorf("previous round %d has no state root", prev.Round)
+	default:
+		return fmt.Errorf("unsupported root type: %s", dstRoot.Type)
 	}
+	return nil
 }
 
 type heartbeat struct {
 	*backoff.Ticker
 }
 
-func (h *heartbeat) reset() {
+func (h *heartbeat) reset(tunables Tunables) {
 	if h.Ticker != nil {
 		h.Stop()
 	}
 
 	boff := cmnBackoff.NewExponentialBackOff()
-	boff.InitialInterval = 5 * time.Second
-	boff.MaxInterval = 20 * time.Second
+	boff.InitialInterval = tunables.RetryInitialInterval
+	boff.MaxInterval = tunables.RetryMaxInterval
 	h.Ticker = backoff.NewTicker(boff)
 
 	// Gobble the first tick, which is immediate.
@@ -0,0 +1,128 @@
+package committee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestSummaryFromBlock(t *testing.T) {
+	require := require.New(t)
+
+	ns := common.NewTestNamespaceFromSeed([]byte("storage worker block summary test ns"), 0)
+	blk := block.NewGenesisBlock(ns, 0)
+	blk.Header.Round = 5
+	blk.Header.StateRoot.FromBytes([]byte("state root"))
+
+	// Version 0.
+	summary, err := summaryFromBlock(ns, blk)
+	require.NoError(err, "summaryFromBlock(version 0)")
+	require.Equal(ns, summary.Namespace)
+	require.EqualValues(5, summary.Round)
+	require.Equal(blk.Header.StorageRoots(), summary.Roots, "summary should contain the storage roots")
+
+	// Blocks of other runtimes.
+	otherNs := common.NewTestNamespaceFromSeed([]byte("storage worker block summary other test ns"), 0)
+	_, err = summaryFromBlock(otherNs, blk)
+	require.ErrorIs(err, ErrNamespaceMismatch, "summaryFromBlock(mismatched namespace)")
+
+	// Unknown versions.
+	blk.Header.Version = 1
+	_, err = summaryFromBlock(ns, blk)
+	require.ErrorIs(err, block.ErrInvalidVersion, "summaryFromBlock(unknown version)")
+}
+
+func TestValidateRootChaining(t *testing.T) {
+	require := require.New(t)
+
+	ns := common.NewTestNamespaceFromSeed([]byte("storage worker root chaining test ns"), 0)
+
+	var emptyHash, stateHash, otherHash hash.Hash
+	emptyHash.Empty()
+	stateHash.FromBytes([]byte("state root"))
+	otherHash.FromBytes([]byte("other root"))
+
+	prev := &blockSummary{
+		Namespace: ns,
+		Round:     9,
+		Roots: []storageApi.Root{
+			{Namespace: ns, Version: 9, Type: storageApi.RootTypeIO, Hash: otherHash},
+			{Namespace: ns, Version: 9, Type: storageApi.RootTypeState, Hash: stateHash},
+		},
+	}
+	thisIO := storageApi.Root{Namespace: ns, Version: 10, Type: storageApi.RootTypeIO, Hash: otherHash}
+	thisState := storageApi.Root{Namespace: ns, Version: 10, Type: storageApi.RootTypeState, Hash: otherHash}
+
+	// Valid I/O root (empty at the same version).
+	srcIO := storageApi.Root{Namespace: ns, Version: 10, Type: storageApi.RootTypeIO, Hash: emptyHash}
+	require.NoError(validateRootChaining(prev, srcIO, thisIO), "empty I/O source root should be valid")
+
+	// I/O roots must not be chained.
+	srcIO = storageApi.Root{Namespace: ns, Version: 9, Type: storageApi.RootTypeIO, Hash: otherHash}
+	require.Error(validateRootChaining(prev, srcIO, thisIO), "chained I/O source root should be invalid")
+	srcIO = storageApi.Root{Namespace: ns, Version: 9, Type: storageApi.RootTypeIO, Hash: emptyHash}
+	require.Error(validateRootChaining(prev, srcIO, thisIO), "I/O source root at a different version should be invalid")
+
+	// Valid state root (previous state root).
+	srcState := prev.Roots[1]
+	require.NoError(validateRootChaining(prev, srcState, thisState), "previous state root should be valid")
+
+	// State roots must be chained.
+	srcState = storageApi.Root{Namespace: ns, Version: 9, Type: storageApi.RootTypeState, Hash: otherHash}
+	require.Error(validateRootChaining(prev, srcState, thisState), "unchained state source root should be invalid")
+	srcState = storageApi.Root{Namespace: ns, Version: 8, Type: storageApi.RootTypeState, Hash: stateHash}
+	require.Error(validateRootChaining(prev, srcState, thisState), "state source root at a wrong version should be invalid")
+	require.Error(validateRootChaining(nil, prev.Roots[1], thisState), "missing previous summary should be invalid")
+
+	// Mismatched root types.
+	require.Error(validateRootChaining(prev, prev.Roots[1], thisIO), "mismatched root types should be invalid")
+}
+
+func TestInFlightCompletion(t *testing.T) {
+	t.Run("FullMask", func(t *testing.T) {
+		require := require.New(t)
+
+		syncing := newInFlight(outstandingMaskFull)
+		require.False(syncing.fetching(), "no roots should be fetched initially")
+		require.False(syncing.complete(), "round should not be complete initially")
+
+		syncing.scheduleDiff(storageApi.RootTypeIO)
+		require.False(syncing.fetching(), "state root should not be fetched yet")
+		syncing.scheduleDiff(storageApi.RootTypeState)
+		require.True(syncing.fetching(), "all roots should be fetched")
+
+		// A failed fetch needs to be retried before the round can complete.
+		syncing.retry(storageApi.RootTypeIO)
+		syncing.outstanding.remove(storageApi.RootTypeState)
+		require.False(syncing.complete(), "round should wait for the retried root")
+		require.True(syncing.applied(storageApi.RootTypeState), "state root should be applied")
+		require.False(syncing.applied(storageApi.RootTypeIO), "I/O root should not be applied")
+
+		syncing.scheduleDiff(storageApi.RootTypeIO)
+		require.False(syncing.complete(), "round should wait for the outstanding root")
+		syncing.outstanding.remove(storageApi.RootTypeIO)
+		require.True(syncing.complete(), "round should be complete")
+	})
+
+	t.Run("ReducedMask", func(t *testing.T) {
+		require := require.New(t)
+
+		syncing := newInFlight(bitForType(storageApi.RootTypeState))
+		require.True(syncing.awaitingRetry.contains(storageApi.RootTypeState), "state root should await fetching")
+		require.False(syncing.awaitingRetry.contains(storageApi.RootTypeIO), "I/O root should not be fetched")
+
+		syncing.scheduleDiff(storageApi.RootTypeState)
+		require.True(syncing.fetching(), "all required roots should be fetched")
+		require.False(syncing.complete(), "round should wait for the state root")
+
+		syncing.outstanding.remove(storageApi.RootTypeState)
+		require.True(syncing.complete(), "round should be complete without the I/O root")
+		require.True(syncing.applied(storageApi.RootTypeState), "state root should be applied")
+		require.False(syncing.applied(storageApi.RootTypeIO), "I/O root should never be applied")
+	})
+}
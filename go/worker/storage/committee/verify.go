@@ -0,0 +1,70 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// verifyProgressInterval is the number of nodes after which verification progress is logged.
+const verifyProgressInterval = 100_000
+
+// VerifyLocalState walks the local storage trees of the given finalized round and checks that all
+// of their nodes are present and consistent with their hashes.
+//
+// In case corruption is found, the returned error is a *mkvsDB.CorruptNodeError for the first
+// corrupted node found (matching mkvsDB.ErrNodeCorrupted).
+func (n *Node) VerifyLocalState(ctx context.Context, round uint64) error {
+	if n.localStorage == nil {
+		return ErrNonLocalBackend
+	}
+
+	n.syncedLock.RLock()
+	lastFinalized := n.syncedState.Round
+	n.syncedLock.RUnlock()
+
+	if lastFinalized == defaultUndefinedRound || round > lastFinalized {
+		return fmt.Errorf("round %d is not finalized yet", round)
+	}
+
+	summary, err := n.committedSummary(ctx, round)
+	if err != nil {
+		return err
+	}
+
+	ndb := n.localStorage.NodeDB()
+	for _, root := range summary.Roots {
+		logger := n.logger.With("round", round, "root", root)
+		logger.Info("verifying local state")
+
+		nodes, err := mkvsDB.Verify(ctx, ndb, root, func(nodes uint64) {
+			if nodes%verifyProgressInterval == 0 {
+				logger.Info("local state verification in progress",
+					"nodes", nodes,
+				)
+			}
+		})
+		if err != nil {
+			logger.Error("local state verification failed",
+				"err", err,
+				"nodes", nodes,
+			)
+			return fmt.Errorf("failed to verify %s root of round %d: %w", root.Type, round, err)
+		}
+
+		logger.Info("local state verified",
+			"nodes", nodes,
+		)
+	}
+	return nil
+}
+
+// committedSummary returns the summary of the committed block of the given round.
+func (n *Node) committedSummary(ctx context.Context, round uint64) (*blockSummary, error) {
+	blk, err := n.commonNode.Runtime.History().GetCommittedBlock(ctx, round)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block for round %d: %w", round, err)
+	}
+	return summaryFromBlock(n.commonNode.Runtime.ID(), blk)
+}
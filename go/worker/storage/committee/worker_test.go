@@ -0,0 +1,2427 @@
+package committee
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	"github.com/oasisprotocol/oasis-core/go/common/workerpool"
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
+	registryApi "github.com/oasisprotocol/oasis-core/go/registry/api"
+	roothashApi "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/history"
+	runtimeRegistry "github.com/oasisprotocol/oasis-core/go/runtime/registry"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/database"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	storageTests "github.com/oasisprotocol/oasis-core/go/storage/tests"
+	workerCommon "github.com/oasisprotocol/oasis-core/go/worker/common"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
+	"github.com/oasisprotocol/oasis-core/go/worker/registration"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+const workerTestTimeout = 10 * time.Second
+
+// testChain is a scripted chain of runtime blocks together with the write logs needed to sync
+// them. It serves as the block source, the runtime history and the diff source of test workers.
+type testChain struct {
+	sync.Mutex
+
+	t *testing.T
+
+	blocks    []*block.Block
+	writeLogs map[storageApi.Root]storageApi.WriteLog
+	stateLog  storageApi.WriteLog
+
+	// diffFailures is the number of times fetching any diff for a given round fails before it
+	// succeeds.
+	diffFailures map[uint64]int
+	diffFetches  map[uint64]int
+	// historyQueries is the number of times each block was queried from the runtime history.
+	historyQueries map[uint64]int
+
+	// diffDelay is the time it takes to serve a diff.
+	diffDelay time.Duration
+	// activeRounds are the rounds whose diffs are currently being served.
+	activeRounds map[uint64]int
+	// maxActiveRounds is the maximum number of rounds whose diffs were served concurrently.
+	maxActiveRounds int
+
+	syncCheckpoint uint64
+
+	// checkpoints are the checkpoints served by the chain, whose chunks are provided by the
+	// checkpoint creator.
+	checkpoints       []*storageSync.Checkpoint
+	checkpointCreator checkpoint.Creator
+
+	// genesisFailures is the number of times fetching the genesis block fails before it succeeds.
+	genesisFailures int
+}
+
+func newTestChain(t *testing.T) *testChain {
+	return &testChain{
+		t:            t,
+		blocks:       []*block.Block{block.NewGenesisBlock(testNs, 0)},
+		writeLogs:    make(map[storageApi.Root]storageApi.WriteLog),
+		diffFailures: make(map[uint64]int),
+		diffFetches:  make(map[uint64]int),
+		activeRounds: make(map[uint64]int),
+
+		historyQueries: make(map[uint64]int),
+	}
+}
+
+// extend adds the given number of rounds to the chain, each of which changes both the state and
+// the I/O root.
+func (c *testChain) extend(rounds int) {
+	c.extendWithEntries(rounds, 1)
+}
+
+// extendWithEntries adds the given number of rounds to the chain, each of which inserts the given
+// number of state entries.
+func (c *testChain) extendWithEntries(rounds int, entries int) {
+	c.Lock()
+	defer c.Unlock()
+
+	for i := 0; i < rounds; i++ {
+		blk := block.NewEmptyBlock(c.blocks[len(c.blocks)-1], 0, block.Normal)
+		round := blk.Header.Round
+
+		for j := 0; j < entries; j++ {
+			key := fmt.Sprintf("key %d", round)
+			if j > 0 {
+				key = fmt.Sprintf("key %d/%d", round, j)
+			}
+			c.stateLog = append(c.stateLog, storageApi.LogEntry{
+				Key:   []byte(key),
+				Value: []byte(fmt.Sprintf("value %d", round)),
+			})
+		}
+		blk.Header.StateRoot = storageTests.CalculateExpectedNewRoot(c.t, c.stateLog, testNs, round)
+		c.writeLogs[blockRoot(blk, storageApi.RootTypeState)] = c.stateLog[len(c.stateLog)-entries:]
+
+		ioLog := storageApi.WriteLog{{
+			Key:   []byte(fmt.Sprintf("io key %d", round)),
+			Value: []byte(fmt.Sprintf("io value %d", round)),
+		}}
+		blk.Header.IORoot = storageTests.CalculateExpectedNewRoot(c.t, ioLog, testNs, round)
+		c.writeLogs[blockRoot(blk, storageApi.RootTypeIO)] = ioLog
+
+		c.blocks = append(c.blocks, blk)
+	}
+}
+
+// extendEmpty adds the given number of rounds to the chain which do not change any roots.
+func (c *testChain) extendEmpty(rounds int) {
+	c.Lock()
+	defer c.Unlock()
+
+	for i := 0; i < rounds; i++ {
+		c.blocks = append(c.blocks, block.NewEmptyBlock(c.blocks[len(c.blocks)-1], 0, block.Normal))
+	}
+}
+
+func (c *testChain) block(round uint64) *block.Block {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.blocks[round]
+}
+
+func (c *testChain) latestRound() uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.blocks[len(c.blocks)-1].Header.Round
+}
+
+func blockRoot(blk *block.Block, rootType storageApi.RootType) storageApi.Root {
+	for _, root := range blk.Header.StorageRoots() {
+		if root.Type == rootType {
+			return root
+		}
+	}
+	panic("missing block root")
+}
+
+// Implements BlockSource.
+func (c *testChain) GetGenesisBlock(ctx context.Context, request *roothashApi.RuntimeRequest) (*block.Block, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.genesisFailures > 0 {
+		c.genesisFailures--
+		return nil, fmt.Errorf("genesis block not available")
+	}
+	return c.blocks[0], nil
+}
+
+// Implements BlockSource.
+func (c *testChain) GetLatestBlock(ctx context.Context, request *roothashApi.RuntimeRequest) (*block.Block, error) {
+	return c.block(c.latestRound()), nil
+}
+
+// Implements storageSync.Client.
+func (c *testChain) GetDiff(ctx context.Context, request *storageSync.GetDiffRequest) (*storageSync.GetDiffResponse, rpc.PeerFeedback, error) {
+	round := request.EndRoot.Version
+
+	c.Lock()
+	delay := c.diffDelay
+	c.activeRounds[round]++
+	if len(c.activeRounds) > c.maxActiveRounds {
+		c.maxActiveRounds = len(c.activeRounds)
+	}
+	c.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.activeRounds[round]--
+	if c.activeRounds[round] == 0 {
+		delete(c.activeRounds, round)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	c.diffFetches[round]++
+	if c.diffFetches[round] <= c.diffFailures[round] {
+		return nil, nil, fmt.Errorf("scripted diff fetch failure for round %d", round)
+	}
+
+	writeLog, ok := c.writeLogs[request.EndRoot]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown root %s", request.EndRoot)
+	}
+	return &storageSync.GetDiffResponse{WriteLog: writeLog}, rpc.NewNopPeerFeedback(), nil
+}
+
+// Implements storageSync.Client.
+func (c *testChain) GetCheckpoints(ctx context.Context, request *storageSync.GetCheckpointsRequest) ([]*storageSync.Checkpoint, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	return append([]*storageSync.Checkpoint{}, c.checkpoints...), nil
+}
+
+// Implements storageSync.Client.
+func (c *testChain) GetCheckpointChunk(
+	ctx context.Context,
+	request *storageSync.GetCheckpointChunkRequest,
+	cp *storageSync.Checkpoint,
+) (*storageSync.GetCheckpointChunkResponse, rpc.PeerFeedback, error) {
+	c.Lock()
+	creator := c.checkpointCreator
+	c.Unlock()
+
+	if creator == nil {
+		return nil, nil, fmt.Errorf("checkpoints not supported")
+	}
+	chunk, err := cp.GetChunkMetadata(request.Index)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	if err = creator.GetCheckpointChunk(ctx, chunk, &buf); err != nil {
+		return nil, nil, err
+	}
+	return &storageSync.GetCheckpointChunkResponse{Chunk: buf.Bytes()}, rpc.NewNopPeerFeedback(), nil
+}
+
+// serveCheckpoints makes the chain serve checkpoints of all roots of the given round, created
+// from the given local storage.
+func (c *testChain) serveCheckpoints(localStorage storageApi.LocalBackend, round uint64) {
+	creator, err := checkpoint.NewFileCreator(c.t.TempDir(), localStorage.NodeDB())
+	require.NoError(c.t, err, "NewFileCreator")
+
+	var checkpoints []*storageSync.Checkpoint
+	for _, root := range c.block(round).Header.StorageRoots() {
+		meta, err := creator.CreateCheckpoint(context.Background(), root, 16*1024)
+		require.NoError(c.t, err, "CreateCheckpoint")
+		checkpoints = append(checkpoints, &storageSync.Checkpoint{
+			Metadata: meta,
+			Peers:    []rpc.PeerFeedback{rpc.NewNopPeerFeedback()},
+		})
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.checkpoints = checkpoints
+	c.checkpointCreator = creator
+}
+
+// Implements storageSync.Client.
+func (c *testChain) GetRoots(ctx context.Context, request *storageSync.GetRootsRequest) ([]*storageSync.PeerRoots, error) {
+	return nil, nil
+}
+
+// testChainHistory is a runtime history backed by a test chain.
+type testChainHistory struct {
+	history.History
+
+	chain *testChain
+}
+
+func (h *testChainHistory) GetCommittedBlock(ctx context.Context, round uint64) (*block.Block, error) {
+	if round > h.chain.latestRound() {
+		return nil, roothashApi.ErrNotFound
+	}
+	h.chain.Lock()
+	h.chain.historyQueries[round]++
+	h.chain.Unlock()
+	return h.chain.block(round), nil
+}
+
+func (h *testChainHistory) Pruner() history.Pruner {
+	pruner, _ := history.NewNonePruner()(nil)
+	return pruner
+}
+
+func (h *testChainHistory) GetEarliestBlock(ctx context.Context) (*block.Block, error) {
+	return h.chain.block(0), nil
+}
+
+func (h *testChainHistory) StorageSyncCheckpoint(ctx context.Context, round uint64) error {
+	h.chain.Lock()
+	defer h.chain.Unlock()
+
+	h.chain.syncCheckpoint = round
+	return nil
+}
+
+type testChainRuntime struct {
+	runtimeRegistry.Runtime
+
+	history *testChainHistory
+}
+
+func (rt *testChainRuntime) ID() common.Namespace {
+	return testNs
+}
+
+func (rt *testChainRuntime) History() history.History {
+	return rt.history
+}
+
+func (rt *testChainRuntime) ActiveDescriptor(ctx context.Context) (*registryApi.Runtime, error) {
+	return &registryApi.Runtime{ID: testNs}, nil
+}
+
+// testRoleProvider is a role provider which only tracks availability.
+type testRoleProvider struct {
+	registration.RoleProvider
+
+	sync.Mutex
+	available bool
+}
+
+func (rp *testRoleProvider) SetAvailable(hook registration.RegisterNodeHook) {
+	rp.Lock()
+	defer rp.Unlock()
+	rp.available = true
+}
+
+func (rp *testRoleProvider) SetUnavailable() {
+	rp.Lock()
+	defer rp.Unlock()
+	rp.available = false
+}
+
+// finalizeRecordingNodeDB is a node database which records the versions that are finalized. It
+// can also be scripted to fail finalizing given versions.
+type finalizeRecordingNodeDB struct {
+	storageApi.NodeDB
+
+	sync.Mutex
+	finalized []uint64
+	// failures are the number of times finalizing each version should fail.
+	failures map[uint64]int
+	// failed are the number of times finalizing each version failed.
+	failed map[uint64]int
+	// blocked are channels on which finalizing each version blocks. Finalization first sends on
+	// the channel once it has started and then waits to receive on it before proceeding.
+	blocked map[uint64]chan struct{}
+}
+
+func (d *finalizeRecordingNodeDB) Finalize(ctx context.Context, roots []storageApi.Root) error {
+	_, err := d.FinalizeWithResult(ctx, roots)
+	return err
+}
+
+func (d *finalizeRecordingNodeDB) FinalizeWithResult(ctx context.Context, roots []storageApi.Root) (*mkvsDB.FinalizeResult, error) {
+	version := roots[0].Version
+	d.Lock()
+	if blockCh, ok := d.blocked[version]; ok {
+		d.Unlock()
+		blockCh <- struct{}{}
+		<-blockCh
+		if err := ctx.Err(); err != nil {
+			// Model storage aborting the finalization midway.
+			return nil, err
+		}
+		d.Lock()
+	}
+	if d.failed[version] < d.failures[version] {
+		d.failed[version]++
+		d.Unlock()
+		return nil, fmt.Errorf("scripted finalize failure for version %d", version)
+	}
+	d.Unlock()
+
+	result, err := mkvsDB.FinalizeWithResult(ctx, d.NodeDB, roots)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	d.finalized = append(d.finalized, roots[0].Version)
+	return result, nil
+}
+
+type finalizeRecordingBackend struct {
+	storageApi.LocalBackend
+
+	nodeDB *finalizeRecordingNodeDB
+}
+
+func (b *finalizeRecordingBackend) NodeDB() storageApi.NodeDB {
+	return b.nodeDB
+}
+
+func (b *finalizeRecordingBackend) ApplyWithProgress(ctx context.Context, request *storageApi.ApplyRequest, progress storageApi.ApplyProgressFunc) error {
+	pb, ok := b.LocalBackend.(storageApi.ApplyProgressBackend)
+	if !ok {
+		return b.Apply(ctx, request)
+	}
+	return pb.ApplyWithProgress(ctx, request, progress)
+}
+
+func (b *finalizeRecordingBackend) HasEqualRoot(prevRoot, root storageApi.Root) bool {
+	eb, ok := b.LocalBackend.(storageApi.EqualRootBackend)
+	return ok && eb.HasEqualRoot(prevRoot, root)
+}
+
+// equalRootNodeDB is a node database which, like node databases that do not keep roots per
+// version, treats roots equal to the root of the previous version as present once confirmed.
+type equalRootNodeDB struct {
+	storageApi.NodeDB
+
+	sync.Mutex
+	equal     map[storageApi.Root]storageApi.Root
+	finalized map[uint64][]storageApi.Root
+}
+
+func (d *equalRootNodeDB) HasRoot(root storageApi.Root) bool {
+	d.Lock()
+	prevRoot, ok := d.equal[root]
+	d.Unlock()
+	if ok {
+		return d.HasRoot(prevRoot)
+	}
+	return d.NodeDB.HasRoot(root)
+}
+
+func (d *equalRootNodeDB) Finalize(ctx context.Context, roots []storageApi.Root) error {
+	d.Lock()
+	d.finalized[roots[0].Version] = roots
+	var stored []storageApi.Root
+	for _, root := range roots {
+		if _, ok := d.equal[root]; !ok {
+			stored = append(stored, root)
+		}
+	}
+	d.Unlock()
+
+	return d.NodeDB.Finalize(ctx, stored)
+}
+
+// equalRootBackend is a local storage backend that confirms all equal roots and records applies.
+type equalRootBackend struct {
+	storageApi.LocalBackend
+
+	nodeDB *equalRootNodeDB
+
+	sync.Mutex
+	applied []*storageApi.ApplyRequest
+}
+
+func newEqualRootBackend(localStorage storageApi.LocalBackend) *equalRootBackend {
+	return &equalRootBackend{
+		LocalBackend: localStorage,
+		nodeDB: &equalRootNodeDB{
+			NodeDB:    localStorage.NodeDB(),
+			equal:     make(map[storageApi.Root]storageApi.Root),
+			finalized: make(map[uint64][]storageApi.Root),
+		},
+	}
+}
+
+func (b *equalRootBackend) Apply(ctx context.Context, request *storageApi.ApplyRequest) error {
+	b.Lock()
+	b.applied = append(b.applied, request)
+	b.Unlock()
+
+	return b.LocalBackend.Apply(ctx, request)
+}
+
+func (b *equalRootBackend) NodeDB() storageApi.NodeDB {
+	return b.nodeDB
+}
+
+func (b *equalRootBackend) HasEqualRoot(prevRoot, root storageApi.Root) bool {
+	b.nodeDB.Lock()
+	defer b.nodeDB.Unlock()
+
+	b.nodeDB.equal[root] = prevRoot
+	return true
+}
+
+// appliedStateRounds returns the rounds for which a state write log was applied.
+func (b *equalRootBackend) appliedStateRounds() []uint64 {
+	b.Lock()
+	defer b.Unlock()
+
+	var rounds []uint64
+	for _, request := range b.applied {
+		if request.RootType == storageApi.RootTypeState {
+			rounds = append(rounds, request.DstRound)
+		}
+	}
+	return rounds
+}
+
+// testControlledNode is a controlled node which records shutdown requests.
+type testControlledNode struct {
+	control.ControlledNode
+
+	shutdownRequests int32
+}
+
+func (n *testControlledNode) RequestShutdown() (<-chan struct{}, error) {
+	atomic.AddInt32(&n.shutdownRequests, 1)
+	return make(chan struct{}), nil
+}
+
+// recordingEventSink is an event sink which records all received events.
+type recordingEventSink struct {
+	sync.Mutex
+
+	events []*Event
+}
+
+func (s *recordingEventSink) HandleEvent(ev *Event) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.events = append(s.events, ev)
+}
+
+// rounds returns the rounds of the recorded events of the given kind, in order.
+func (s *recordingEventSink) rounds(kind EventKind) []uint64 {
+	s.Lock()
+	defer s.Unlock()
+
+	var rounds []uint64
+	for _, ev := range s.events {
+		if ev.Kind == kind {
+			rounds = append(rounds, ev.Round)
+		}
+	}
+	return rounds
+}
+
+// blockingEventSink is an event sink which blocks until released.
+type blockingEventSink struct {
+	releaseCh chan struct{}
+}
+
+func (s *blockingEventSink) HandleEvent(ev *Event) {
+	<-s.releaseCh
+}
+
+// corruptingNodeDB is a node database which returns a corrupted node in place of the node with
+// the given hash.
+type corruptingNodeDB struct {
+	storageApi.NodeDB
+
+	corrupted hash.Hash
+}
+
+func (d *corruptingNodeDB) GetNode(root storageApi.Root, ptr *node.Pointer) (node.Node, error) {
+	nd, err := d.NodeDB.GetNode(root, ptr)
+	if err != nil || !ptr.Hash.Equal(&d.corrupted) {
+		return nd, err
+	}
+	leaf := &node.LeafNode{Key: []byte("corrupted key"), Value: []byte("corrupted value")}
+	leaf.UpdateHash()
+	return leaf, nil
+}
+
+type corruptingBackend struct {
+	storageApi.LocalBackend
+
+	nodeDB *corruptingNodeDB
+}
+
+func (b *corruptingBackend) NodeDB() storageApi.NodeDB {
+	return b.nodeDB
+}
+
+// workerHarness drives the worker loop of a storage node using a test chain as its only
+// dependency besides local storage.
+type workerHarness struct {
+	t *testing.T
+
+	n      *Node
+	chain  *testChain
+	nodeDB *finalizeRecordingNodeDB
+	rp     *testRoleProvider
+
+	pool     *workerpool.Pool
+	doneCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func newTestLocalStorage(t *testing.T) storageApi.LocalBackend {
+	localStorage, err := database.New(&storageApi.Config{
+		Backend:   database.BackendNameInMemory,
+		DB:        t.TempDir(),
+		Namespace: testNs,
+	})
+	require.NoError(t, err, "database.New()")
+	t.Cleanup(localStorage.Cleanup)
+	return localStorage
+}
+
+func newWorkerHarness(t *testing.T, chain *testChain, localStorage storageApi.LocalBackend, cfg *Config) *workerHarness {
+	nodeDB := &finalizeRecordingNodeDB{
+		NodeDB:   localStorage.NodeDB(),
+		failures: make(map[uint64]int),
+		failed:   make(map[uint64]int),
+		blocked:  make(map[uint64]chan struct{}),
+	}
+	rp := &testRoleProvider{}
+
+	pool := workerpool.New("storage_fetch_test")
+	pool.Resize(4)
+
+	n := &Node{
+		commonNode: &committee.Node{
+			Runtime: &testChainRuntime{history: &testChainHistory{chain: chain}},
+		},
+		roleProvider:          rp,
+		logger:                logging.GetLogger("worker/storage/committee/test"),
+		localStorage:          &finalizeRecordingBackend{LocalBackend: localStorage, nodeDB: nodeDB},
+		storageSync:           chain,
+		blockSource:           chain,
+		fetchQueue:            NewFetchQueue(pool, 4),
+		cfg:                   cfg,
+		syncStats:             newSyncStatsWindow(cfg.SyncStatsWindowSize),
+		checkpointSyncCfg:     &CheckpointSyncConfig{Disabled: true},
+		syncCeiling:           cfg.SyncCeiling,
+		syncCeilingCh:         make(chan struct{}, 1),
+		tunables:              DefaultTunables(),
+		syncErrNotifier:       pubsub.NewBroker(false),
+		roundProgressNotifier: pubsub.NewBroker(false),
+		blockCh:               channels.NewInfiniteChannel(),
+		diffCh:                make(chan *fetchedDiff, cfg.DiffChannelSize),
+		finalizeCh:            make(chan finalizeResult, cfg.FinalizeChannelSize),
+		queueSnapshotCh:       make(chan chan *queueSnapshot),
+		initCh:                make(chan struct{}),
+		caughtUpCh:            make(chan struct{}),
+	}
+	n.syncedState.Round = defaultUndefinedRound
+	n.ctx, n.ctxCancel = context.WithCancel(context.Background())
+	n.finalizeCtx, n.finalizeCtxCancel = context.WithCancel(context.Background())
+	if cfg.EventSink != nil {
+		n.events = newEventDispatcher(cfg.EventSink, n.getMetricLabels())
+	}
+	if cfg.MaxConcurrentWrites > 0 {
+		n.writeLimiter = newStorageWriteLimiter(cfg.MaxConcurrentWrites, n.getMetricLabels())
+	}
+	if cfg.TransitionLogSize > 0 {
+		n.transitions = newTransitionLog(cfg.TransitionLogSize)
+	}
+
+	h := &workerHarness{
+		t:      t,
+		n:      n,
+		chain:  chain,
+		nodeDB: nodeDB,
+		rp:     rp,
+		pool:   pool,
+		doneCh: make(chan struct{}),
+	}
+	t.Cleanup(h.stop)
+	return h
+}
+
+// start starts the worker loop and waits for it to be initialized.
+func (h *workerHarness) start() {
+	if h.n.events != nil {
+		go h.n.events.run(h.n.ctx)
+	}
+	go func() {
+		defer close(h.doneCh)
+		h.n.syncWorker()
+	}()
+
+	select {
+	case <-h.n.initCh:
+	case <-time.After(workerTestTimeout):
+		require.FailNow(h.t, "worker failed to initialize")
+	}
+}
+
+// stop stops the worker loop and waits for it to terminate.
+func (h *workerHarness) stop() {
+	h.stopOnce.Do(func() {
+		h.n.Stop()
+		select {
+		case <-h.doneCh:
+		case <-time.After(workerTestTimeout):
+			require.FailNow(h.t, "worker failed to terminate")
+		}
+		h.pool.Stop()
+	})
+}
+
+// deliver delivers the chain blocks for the given rounds to the worker, in order.
+func (h *workerHarness) deliver(rounds ...uint64) {
+	for _, round := range rounds {
+		h.n.blockCh.In() <- h.chain.block(round)
+	}
+}
+
+// prune prunes the given rounds as the runtime history pruner would.
+func (h *workerHarness) prune(rounds ...uint64) error {
+	ph := &pruneHandler{
+		logger: h.n.logger,
+		node:   h.n,
+	}
+	return ph.Prune(h.n.ctx, rounds)
+}
+
+// waitSynced waits until the given round has been synced and finalized.
+func (h *workerHarness) waitSynced(round uint64) {
+	require.Eventually(h.t, func() bool {
+		synced, _, _ := h.n.GetLastSynced()
+		return synced != defaultUndefinedRound && synced >= round
+	}, workerTestTimeout, 10*time.Millisecond, "round %d should be synced", round)
+}
+
+// finalized returns the versions finalized by the worker, in order.
+func (h *workerHarness) finalized() []uint64 {
+	h.nodeDB.Lock()
+	defer h.nodeDB.Unlock()
+
+	return append([]uint64{}, h.nodeDB.finalized...)
+}
+
+// requireSyncedTo checks that the worker has synced exactly the given round, that its roots match
+// the chain and that all preceding rounds (since the given first round) have been finalized in
+// order.
+func (h *workerHarness) requireSyncedTo(firstRound, round uint64) {
+	require := require.New(h.t)
+
+	synced, io, state := h.n.GetLastSynced()
+	require.EqualValues(round, synced, "last synced round")
+	blk := h.chain.block(round)
+	require.Equal(blockRoot(blk, storageApi.RootTypeIO), io, "last synced I/O root")
+	require.Equal(blockRoot(blk, storageApi.RootTypeState), state, "last synced state root")
+	require.True(h.nodeDB.HasRoot(state), "last synced state root should be in local storage")
+
+	h.chain.Lock()
+	syncCheckpoint := h.chain.syncCheckpoint
+	h.chain.Unlock()
+	require.EqualValues(round, syncCheckpoint, "history should be notified of the last synced round")
+
+	var expected []uint64
+	for r := firstRound; r <= round; r++ {
+		expected = append(expected, r)
+	}
+	require.Equal(expected, h.finalized(), "rounds should be finalized in order")
+}
+
+func TestWorkerSequentialBlocks(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(10)
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{})
+	h.start()
+	for round := uint64(1); round <= 10; round++ {
+		h.deliver(round)
+	}
+	h.waitSynced(10)
+	h.requireSyncedTo(0, 10)
+
+	h.rp.Lock()
+	defer h.rp.Unlock()
+	require.True(t, h.rp.available, "role should be available once caught up")
+}
+
+func TestWorkerFinalizeStats(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(5)
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{SyncStatsWindowSize: 10})
+	h.start()
+	h.deliver(5)
+	h.waitSynced(5)
+	h.requireSyncedTo(0, 5)
+
+	// The genesis round has an empty state so only the following rounds commit any nodes.
+	rounds := h.n.SyncStats().Rounds
+	require.Len(t, rounds, 6, "all finalized rounds should be recorded")
+	for i, stats := range rounds {
+		require.EqualValues(t, i, stats.Round, "rounds should be recorded in order")
+		require.NotZero(t, stats.FinalizeDuration, "round %d: finalize duration should be reported", stats.Round)
+		if stats.Round > 0 {
+			require.NotZero(t, stats.FinalizedNodes, "round %d: finalized nodes should be reported", stats.Round)
+		}
+	}
+}
+
+func TestWorkerBlockGaps(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(3)
+	chain.extendEmpty(3)
+	chain.extend(6)
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{})
+	h.start()
+
+	// Only some blocks are received, the rest need to be fetched from history.
+	h.deliver(2, 9)
+	h.waitSynced(9)
+	h.deliver(12)
+	h.waitSynced(12)
+	h.requireSyncedTo(0, 12)
+}
+
+func TestWorkerStaleBlocks(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(8)
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{})
+	h.start()
+
+	// Blocks for earlier rounds may be redelivered (e.g., when the block subscription recovers
+	// after polling) and must not cause any rounds to be synced or finalized again.
+	h.deliver(5, 2, 4, 5)
+	h.waitSynced(5)
+	h.deliver(3, 8, 6)
+	h.waitSynced(8)
+	h.deliver(1, 8)
+
+	// Give the worker a chance to (wrongly) process the stale blocks.
+	time.Sleep(100 * time.Millisecond)
+	h.requireSyncedTo(0, 8)
+}
+
+func TestWorkerDiffFetchFailures(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(6)
+	chain.diffFailures[2] = 3
+	chain.diffFailures[5] = 1
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{})
+	errCh, sub := h.n.WatchSyncErrors()
+	defer sub.Close()
+	h.start()
+
+	h.deliver(6)
+	h.waitSynced(6)
+	h.requireSyncedTo(0, 6)
+
+	// Failed fetches should be reported as transient errors.
+	select {
+	case syncErr := <-errCh:
+		require.ErrorIs(t, syncErr, ErrDiffFetchFailed, "diff fetch failures should be reported")
+		require.False(t, syncErr.IsFatal(), "diff fetch failures should not be fatal")
+	case <-time.After(workerTestTimeout):
+		require.FailNow(t, "diff fetch failures should be reported")
+	}
+}
+
+func TestNewNodeDisableRemoteClient(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	localStorage := newTestLocalStorage(t)
+	commonNode := &committee.Node{
+		Runtime: &testChainRuntime{history: &testChainHistory{chain: chain}},
+		P2P:     p2p.NewNop(),
+	}
+	newNode := func(cfg *Config) (*Node, error) {
+		return NewNode(commonNode, nil, nil, nil, workerCommon.Config{}, localStorage, nil,
+			&CheckpointSyncConfig{Disabled: true},
+			cfg,
+		)
+	}
+
+	n, err := newNode(&Config{BlockSource: chain, DisableRemoteClient: true})
+	require.NoError(err, "NewNode without a remote client")
+	defer n.Cleanup()
+	require.Nil(n.storageSync, "no remote client should be created")
+	require.NotNil(n.GetLocalStorage(), "local storage should be used")
+
+	_, err = newNode(&Config{BlockSource: chain, DisableRemoteClient: true, StorageSyncClient: chain})
+	require.Error(err, "NewNode should reject a remote client when it is disabled")
+}
+
+func TestWorkerDisableRemoteClient(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(4)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(2)
+	h.waitSynced(2)
+	h.stop()
+
+	// Restart without a remote client, so only the rounds that are available locally can be
+	// replayed.
+	h = newWorkerHarness(t, chain, localStorage, &Config{DisableRemoteClient: true})
+	h.n.storageSync = nil
+	errCh, sub := h.n.WatchSyncErrors()
+	defer sub.Close()
+	h.start()
+	h.deliver(4)
+
+	select {
+	case syncErr := <-errCh:
+		require.ErrorIs(syncErr, ErrDiffUnavailable, "missing diffs should be reported")
+		// Rounds 3 and 4 are fetched concurrently, so either may be reported first.
+		require.Contains([]uint64{3, 4}, syncErr.Round, "a round that is not available locally")
+		require.True(syncErr.IsFatal(), "missing diffs should be fatal")
+	case <-time.After(workerTestTimeout):
+		require.FailNow("missing diffs should be reported")
+	}
+	h.stop()
+	synced, _, _ := h.n.GetLastSynced()
+	require.EqualValues(2, synced, "syncing should not proceed past rounds that are not available locally")
+	require.Empty(h.finalized(), "no rounds should be finalized")
+
+	chain.Lock()
+	defer chain.Unlock()
+	require.Zero(chain.diffFetches[3], "diffs should not be fetched remotely")
+	require.Zero(chain.diffFetches[4], "diffs should not be fetched remotely")
+}
+
+func TestWorkerLastSyncError(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(2)
+	chain.diffFailures[2] = 1
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{})
+	syncErr, at := h.n.LastSyncError()
+	require.Nil(syncErr, "there should be no sync error before syncing")
+	require.True(at.IsZero())
+
+	// Block finalization of the failed round, so that the error can be observed.
+	blockCh := make(chan struct{})
+	h.nodeDB.Lock()
+	h.nodeDB.blocked[2] = blockCh
+	h.nodeDB.Unlock()
+
+	start := time.Now()
+	h.start()
+	h.deliver(2)
+	<-blockCh
+
+	// Finalizing earlier rounds should not clear the error of a later round.
+	syncErr, at = h.n.LastSyncError()
+	require.ErrorIs(syncErr, ErrDiffFetchFailed, "failed diff fetch should be the last sync error")
+	require.EqualValues(2, syncErr.Round)
+	require.False(at.Before(start), "time of the last sync error")
+	require.Equal([]uint64{0, 1}, h.finalized())
+
+	blockCh <- struct{}{}
+	h.waitSynced(2)
+	syncErr, at = h.n.LastSyncError()
+	require.Nil(syncErr, "the last sync error should be cleared once its round is finalized")
+	require.True(at.IsZero())
+}
+
+func TestWorkerDeferredFinalize(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(12)
+
+	policy, err := NewFinalizePolicy(FinalizePolicyDeferred, 4)
+	require.NoError(t, err, "NewFinalizePolicy")
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{FinalizePolicy: policy})
+	h.start()
+
+	// Rounds are finalized in batches, but still in order.
+	h.deliver(12)
+	h.waitSynced(12)
+	h.requireSyncedTo(0, 12)
+}
+
+func TestWorkerSyncCeiling(t *testing.T) {
+	require := require.New(t)
+
+	chain := newTestChain(t)
+	chain.extend(10)
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{SyncCeiling: 4})
+	h.start()
+
+	h.deliver(10)
+	h.waitSynced(4)
+	time.Sleep(100 * time.Millisecond)
+	h.requireSyncedTo(0, 4)
+
+	require.NoError(h.n.SetSyncCeiling(0), "SetSyncCeiling")
+	h.waitSynced(10)
+	h.requireSyncedTo(0, 10)
+}
+
+func TestWorkerPrune(t *testing.T) {
+	require := require.New(t)
+
+	chain := newTestChain(t)
+	chain.extend(15)
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{})
+	h.start()
+
+	h.deliver(10)
+	h.waitSynced(10)
+
+	// Pruning rounds that have not been synced yet should fail.
+	require.Error(h.prune(11), "pruning rounds that have not been synced yet should fail")
+
+	err := h.prune(0, 1, 2, 3, 4)
+	require.NoError(err, "pruning synced rounds")
+	require.EqualValues(5, h.nodeDB.GetEarliestVersion(), "earliest version after pruning")
+
+	// Syncing should continue after pruning.
+	h.deliver(15)
+	h.waitSynced(15)
+	h.requireSyncedTo(0, 15)
+}
+
+func TestWorkerPruneOrdering(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		ordering PruneOrdering
+	}{
+		{"AfterFinalize", PruneAfterFinalize},
+		{"Concurrent", PruneConcurrentWithFinalize},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			chain := newTestChain(t)
+			chain.extend(12)
+
+			h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{PruneOrdering: tc.ordering})
+			h.start()
+
+			h.deliver(10)
+			h.waitSynced(10)
+
+			// Block finalization of the next round once it has started.
+			blockCh := make(chan struct{})
+			h.nodeDB.Lock()
+			h.nodeDB.blocked[11] = blockCh
+			h.nodeDB.Unlock()
+
+			h.deliver(11)
+			<-blockCh
+
+			// Rounds that are not strictly below the last finalized round must never be pruned,
+			// even while later rounds are being finalized.
+			require.Error(h.prune(10), "pruning the last finalized round should fail")
+			require.Error(h.prune(11), "pruning a round being finalized should fail")
+
+			pruneErrCh := make(chan error, 1)
+			go func() {
+				pruneErrCh <- h.prune(0, 1, 2, 3, 4)
+			}()
+
+			if tc.ordering == PruneAfterFinalize {
+				select {
+				case err := <-pruneErrCh:
+					require.FailNow("pruning should wait for in-progress finalization", "err: %v", err)
+				case <-time.After(100 * time.Millisecond):
+				}
+				blockCh <- struct{}{}
+				require.NoError(<-pruneErrCh, "pruning finalized rounds")
+			} else {
+				require.NoError(<-pruneErrCh, "pruning finalized rounds")
+				blockCh <- struct{}{}
+			}
+			require.EqualValues(5, h.nodeDB.GetEarliestVersion(), "earliest version after pruning")
+
+			h.waitSynced(11)
+			h.deliver(12)
+			h.waitSynced(12)
+		})
+	}
+}
+
+func TestWorkerFinalizeGracePeriod(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		gracePeriod time.Duration
+		finalized   []uint64
+	}{
+		{"NoGracePeriod", 0, []uint64{0, 1}},
+		{"GracePeriod", workerTestTimeout, []uint64{0, 1, 2}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			chain := newTestChain(t)
+			chain.extend(2)
+
+			h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{FinalizeGracePeriod: tc.gracePeriod})
+			h.start()
+			defer h.stop()
+
+			h.deliver(1)
+			h.waitSynced(1)
+
+			// Block finalization of the next round once it has started.
+			blockCh := make(chan struct{})
+			h.nodeDB.Lock()
+			h.nodeDB.blocked[2] = blockCh
+			h.nodeDB.Unlock()
+
+			h.deliver(2)
+			<-blockCh
+
+			// Stop the worker while the round is being finalized.
+			h.n.Stop()
+			blockCh <- struct{}{}
+			h.stop()
+
+			require.Equal(tc.finalized, h.finalized(), "finalized rounds after stopping")
+		})
+	}
+}
+
+func TestWorkerMaxConcurrentWrites(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		limit uint
+	}{
+		{"Unlimited", 0},
+		{"Limit1", 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			chain := newTestChain(t)
+			chain.extend(12)
+
+			h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{MaxConcurrentWrites: tc.limit})
+			h.start()
+
+			h.deliver(10)
+			h.waitSynced(10)
+
+			// Block finalization of the next round once it has started.
+			blockCh := make(chan struct{})
+			h.nodeDB.Lock()
+			h.nodeDB.blocked[11] = blockCh
+			h.nodeDB.Unlock()
+
+			h.deliver(11)
+			<-blockCh
+			h.deliver(12)
+
+			stateRoot := blockRoot(chain.block(12), storageApi.RootTypeState)
+			if tc.limit == 1 {
+				time.Sleep(100 * time.Millisecond)
+				require.False(h.nodeDB.HasRoot(stateRoot), "applies should wait for in-progress finalization")
+			} else {
+				require.Eventually(func() bool {
+					return h.nodeDB.HasRoot(stateRoot)
+				}, workerTestTimeout, 10*time.Millisecond, "applies should proceed during finalization")
+			}
+			blockCh <- struct{}{}
+
+			h.waitSynced(12)
+			h.requireSyncedTo(0, 12)
+		})
+	}
+}
+
+func TestNewPruneOrdering(t *testing.T) {
+	require := require.New(t)
+
+	for _, ordering := range []PruneOrdering{PruneAfterFinalize, PruneConcurrentWithFinalize} {
+		parsed, err := NewPruneOrdering(ordering.String())
+		require.NoError(err, "NewPruneOrdering(%s)", ordering)
+		require.Equal(ordering, parsed)
+	}
+	_, err := NewPruneOrdering("before_finalize")
+	require.Error(err, "unsupported prune ordering should fail")
+}
+
+func TestWorkerRestart(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(10)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(5)
+	h.waitSynced(5)
+	h.stop()
+	h.requireSyncedTo(0, 5)
+
+	// A restarted worker should resume from the last finalized round in local storage.
+	h = newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(10)
+	h.waitSynced(10)
+	h.requireSyncedTo(6, 10)
+}
+
+func TestWorkerRestartPersistedSummaries(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(6)
+	localStorage := newTestLocalStorage(t)
+	cfg := &Config{
+		PersistedSummaries:     3,
+		PersistedSummariesPath: filepath.Join(t.TempDir(), "summaries"),
+	}
+
+	// Diffs of round 3 fail, so only rounds up to 2 are finalized before the worker is stopped.
+	chain.diffFailures[3] = math.MaxInt32
+	h := newWorkerHarness(t, chain, localStorage, cfg)
+	h.start()
+	h.deliver(6)
+	h.waitSynced(2)
+	h.stop()
+	h.requireSyncedTo(0, 2)
+
+	chain.Lock()
+	for _, round := range []uint64{3, 4} {
+		require.NotZero(chain.historyQueries[round], "round %d should be queried from history", round)
+	}
+	chain.diffFailures[3] = 0
+	chain.historyQueries = make(map[uint64]int)
+	chain.Unlock()
+
+	// A restarted worker should use the persisted summaries of rounds 2-4, but only those, as
+	// the number of persisted summaries is bounded.
+	h = newWorkerHarness(t, chain, localStorage, cfg)
+	h.start()
+	h.deliver(6)
+	h.waitSynced(6)
+	h.requireSyncedTo(3, 6)
+
+	chain.Lock()
+	defer chain.Unlock()
+	for _, round := range []uint64{3, 4} {
+		require.Zero(chain.historyQueries[round], "round %d should not be queried from history again", round)
+	}
+	require.NotZero(chain.historyQueries[5], "round 5 should be queried from history")
+}
+
+func TestWorkerCheckpointRestoreLag(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(20)
+
+	// Serve checkpoints of round 15 created by another worker that has synced the whole chain.
+	source := newTestLocalStorage(t)
+	h := newWorkerHarness(t, chain, source, &Config{})
+	h.start()
+	h.deliver(20)
+	h.waitSynced(20)
+	h.stop()
+	chain.serveCheckpoints(source, 15)
+
+	localStorage := newTestLocalStorage(t)
+	h = newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(2)
+	h.waitSynced(2)
+	h.stop()
+
+	chain.Lock()
+	chain.diffFetches = make(map[uint64]int)
+	chain.Unlock()
+
+	// A restarted worker that is too far behind the latest round should restore the state from
+	// the checkpoint instead of syncing all missed rounds, and resume incremental sync after it.
+	h = newWorkerHarness(t, chain, localStorage, &Config{CheckpointRestoreLagThreshold: 5})
+	h.n.checkpointSyncCfg = &CheckpointSyncConfig{ChunkFetcherCount: 1}
+	h.start()
+	h.deliver(20)
+	h.waitSynced(20)
+	h.requireSyncedTo(15, 20)
+
+	// Rounds skipped by the checkpoint restore are a gap in the finalized rounds.
+	_, err := h.n.FinalizedRoots(context.Background(), 0, 20)
+	require.ErrorIs(err, ErrRoundNotFinalized, "FinalizedRoots() should fail for a range with a gap")
+	roots, err := h.n.FinalizedRoots(context.Background(), 15, 20)
+	require.NoError(err, "FinalizedRoots() after the gap")
+	require.Len(roots, 6)
+
+	chain.Lock()
+	defer chain.Unlock()
+	for round := uint64(3); round <= 15; round++ {
+		require.Zero(chain.diffFetches[round], "round %d should be restored from the checkpoint", round)
+	}
+	for round := uint64(16); round <= 20; round++ {
+		require.NotZero(chain.diffFetches[round], "round %d should be synced incrementally", round)
+	}
+}
+
+func TestWorkerRestartInconsistentHistory(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(5)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(5)
+	h.waitSynced(5)
+	require.NoError(h.prune(0, 1), "pruning synced rounds")
+	h.stop()
+	h.requireSyncedTo(0, 5)
+
+	// The history of the restarted worker only matches local storage up to round 3, so the roots
+	// of the last finalized version in local storage are missing for the corresponding block.
+	other := newTestChain(t)
+	other.extend(3)
+	other.extendWithEntries(2, 2)
+	require.Equal(chain.block(3).Header.StateRoot, other.block(3).Header.StateRoot)
+	require.NotEqual(chain.block(5).Header.StateRoot, other.block(5).Header.StateRoot)
+
+	// The restarted worker should conservatively rewind to the last round that verifies.
+	h = newWorkerHarness(t, other, localStorage, &Config{})
+	h.start()
+	synced, _, _ := h.n.GetLastSynced()
+	require.EqualValues(3, synced, "last synced round should be rewound")
+	other.Lock()
+	require.EqualValues(3, other.syncCheckpoint, "storage sync checkpoint should be rewound")
+	other.Unlock()
+	h.stop()
+
+	// In case no round following the earliest version in local storage verifies, the worker
+	// should refuse to start syncing.
+	unrelated := newTestChain(t)
+	unrelated.extendWithEntries(5, 2)
+	h = newWorkerHarness(t, unrelated, localStorage, &Config{})
+	h.start()
+	select {
+	case <-h.doneCh:
+	case <-time.After(workerTestTimeout):
+		require.FailNow("worker should stop")
+	}
+	synced, _, _ = h.n.GetLastSynced()
+	require.EqualValues(defaultUndefinedRound, synced, "nothing should be synced")
+}
+
+func TestWorkerSkipEqualRootApply(t *testing.T) {
+	require := require.New(t)
+
+	// The last two rounds are epoch transitions which keep the state root unchanged.
+	chain := newTestChain(t)
+	chain.extend(2)
+	chain.extendEmpty(2)
+
+	for _, skip := range []bool{false, true} {
+		localStorage := newEqualRootBackend(newTestLocalStorage(t))
+		h := newWorkerHarness(t, chain, localStorage, &Config{SkipEqualRootApply: skip})
+		h.start()
+		h.deliver(4)
+		h.waitSynced(4)
+		h.requireSyncedTo(0, 4)
+		h.stop()
+
+		// Finalization should still include the unchanged roots.
+		for round := uint64(3); round <= 4; round++ {
+			require.ElementsMatch(chain.block(round).Header.StorageRoots(), localStorage.nodeDB.finalized[round],
+				"skip %t: round %d should be finalized with the block roots", skip, round)
+		}
+
+		if skip {
+			require.Equal([]uint64{1, 2}, localStorage.appliedStateRounds(), "write logs for unchanged roots should not be applied")
+		} else {
+			require.Equal([]uint64{1, 2, 3, 4}, localStorage.appliedStateRounds(), "empty write logs should be applied when not skipping")
+			require.Empty(localStorage.nodeDB.equal, "backend should not be asked to confirm equal roots when not skipping")
+		}
+	}
+}
+
+func TestWorkerRestartApplied(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(7)
+	localStorage := newTestLocalStorage(t)
+
+	// Rounds following the last boundary round are applied, but not finalized.
+	policy, err := NewFinalizePolicy(FinalizePolicyDeferred, 4)
+	require.NoError(err, "NewFinalizePolicy")
+	h := newWorkerHarness(t, chain, localStorage, &Config{FinalizePolicy: policy})
+	h.start()
+	h.deliver(7)
+	h.waitSynced(4)
+	require.Eventually(func() bool {
+		blk := chain.block(7)
+		return h.nodeDB.HasRoot(blockRoot(blk, storageApi.RootTypeIO)) && h.nodeDB.HasRoot(blockRoot(blk, storageApi.RootTypeState))
+	}, workerTestTimeout, 10*time.Millisecond, "round 7 should be applied")
+	h.stop()
+	h.requireSyncedTo(0, 4)
+
+	// A root that does not match the chain is also present in local storage.
+	chain.Lock()
+	staleLog := storageApi.WriteLog{{Key: []byte("stale key"), Value: []byte("stale value")}}
+	staleRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   5,
+		Type:      storageApi.RootTypeState,
+		Hash:      storageTests.CalculateExpectedNewRoot(t, append(append(storageApi.WriteLog{}, chain.stateLog[:4]...), staleLog...), testNs, 5),
+	}
+	fetches := make(map[uint64]int)
+	for round, count := range chain.diffFetches {
+		fetches[round] = count
+	}
+	chain.Unlock()
+	prevRoot := blockRoot(chain.block(4), storageApi.RootTypeState)
+	err = localStorage.Apply(context.Background(), &storageApi.ApplyRequest{
+		Namespace: testNs,
+		RootType:  storageApi.RootTypeState,
+		SrcRound:  prevRoot.Version,
+		SrcRoot:   prevRoot.Hash,
+		DstRound:  staleRoot.Version,
+		DstRoot:   staleRoot.Hash,
+		WriteLog:  staleLog,
+	})
+	require.NoError(err, "Apply(stale)")
+
+	// A restarted worker should finalize the applied rounds without fetching their diffs again
+	// and discard roots that do not match the chain.
+	h = newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(7)
+	h.waitSynced(7)
+	h.requireSyncedTo(5, 7)
+
+	chain.Lock()
+	defer chain.Unlock()
+	require.Equal(fetches, chain.diffFetches, "applied rounds should not be fetched again")
+	require.False(localStorage.NodeDB().HasRoot(staleRoot), "stale roots should be discarded")
+}
+
+func TestWorkerImportSyncState(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(8)
+	localStorage := newTestLocalStorage(t)
+	path := filepath.Join(t.TempDir(), "sync_state")
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(3)
+	h.waitSynced(3)
+	require.NoError(t, h.n.ExportSyncState(path), "ExportSyncState()")
+	h.deliver(5)
+	h.waitSynced(5)
+	h.stop()
+
+	// A restarted worker should resume from the imported round instead of the last finalized
+	// round in local storage, tolerating rounds that are already finalized.
+	h = newWorkerHarness(t, chain, localStorage, &Config{})
+	require.NoError(t, h.n.ImportSyncState(path), "ImportSyncState()")
+	h.start()
+	h.deliver(8)
+	h.waitSynced(8)
+	h.requireSyncedTo(6, 8)
+}
+
+func TestWorkerReconfigure(t *testing.T) {
+	require := require.New(t)
+
+	chain := newTestChain(t)
+	chain.extend(40)
+	chain.diffDelay = 5 * time.Millisecond
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{})
+	tunables := DefaultTunables()
+	tunables.MaxInFlightRounds = 1
+	require.NoError(h.n.Reconfigure(tunables), "Reconfigure")
+	h.start()
+
+	h.deliver(40)
+	h.waitSynced(10)
+
+	chain.Lock()
+	require.Equal(1, chain.maxActiveRounds, "only a single round should be fetched at a time")
+	chain.maxActiveRounds = 0
+	chain.Unlock()
+
+	// Increasing the number of in-flight rounds should take effect without disrupting the sync.
+	tunables.MaxInFlightRounds = 8
+	require.NoError(h.n.Reconfigure(tunables), "Reconfigure")
+	h.waitSynced(40)
+	h.requireSyncedTo(0, 40)
+
+	chain.Lock()
+	defer chain.Unlock()
+	require.Greater(chain.maxActiveRounds, 1, "multiple rounds should be fetched concurrently")
+}
+
+func TestWorkerVerifyLocalState(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(5)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(5)
+	h.waitSynced(5)
+	h.stop()
+
+	ctx := context.Background()
+	for round := uint64(0); round <= 5; round++ {
+		require.NoError(h.n.VerifyLocalState(ctx, round), "VerifyLocalState(%d)", round)
+	}
+	require.Error(h.n.VerifyLocalState(ctx, 6), "VerifyLocalState() should fail for rounds that are not finalized")
+
+	// Corrupt one of the leaves of the state tree.
+	stateRoot := blockRoot(chain.block(5), storageApi.RootTypeState)
+	var corrupted node.LeafNode
+	err := mkvsDB.Visit(ctx, h.nodeDB, stateRoot, func(ctx context.Context, n node.Node) bool {
+		if leaf, ok := n.(*node.LeafNode); ok {
+			corrupted = *leaf
+		}
+		return true
+	})
+	require.NoError(err, "Visit()")
+	ndb := &corruptingNodeDB{NodeDB: h.nodeDB, corrupted: corrupted.GetHash()}
+	h.n.localStorage = &corruptingBackend{LocalBackend: localStorage, nodeDB: ndb}
+
+	err = h.n.VerifyLocalState(ctx, 5)
+	require.ErrorIs(err, mkvsDB.ErrNodeCorrupted, "VerifyLocalState() should detect the corrupted node")
+	var corruptErr *mkvsDB.CorruptNodeError
+	require.ErrorAs(err, &corruptErr)
+	require.Equal(corrupted.GetHash(), corruptErr.Hash, "the corrupted node should be reported")
+	require.LessOrEqual(corruptErr.PathBitLength, corrupted.Key.BitLength(), "the reported path should lead to the corrupted node")
+	require.Equal(
+		corruptErr.PathBitLength,
+		corrupted.Key.CommonPrefixLen(corrupted.Key.BitLength(), corruptErr.Path, corruptErr.PathBitLength),
+		"the reported path should lead to the corrupted node",
+	)
+}
+
+func TestWorkerFinalizedRoots(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(5)
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{})
+	h.start()
+	h.deliver(5)
+	h.waitSynced(5)
+
+	ctx := context.Background()
+	roots, err := h.n.FinalizedRoots(ctx, 0, 5)
+	require.NoError(err, "FinalizedRoots()")
+	require.Len(roots, 6)
+	for i, rr := range roots {
+		blk := chain.block(uint64(i))
+		require.Equal(uint64(i), rr.Round)
+		require.Equal(blockRoot(blk, storageApi.RootTypeIO).Hash, rr.IORoot, "I/O root of round %d", i)
+		require.Equal(blockRoot(blk, storageApi.RootTypeState).Hash, rr.StateRoot, "state root of round %d", i)
+	}
+
+	_, err = h.n.FinalizedRoots(ctx, 3, 6)
+	require.ErrorIs(err, ErrRoundNotFinalized, "FinalizedRoots() should fail for rounds that are not finalized")
+	_, err = h.n.FinalizedRoots(ctx, 4, 3)
+	require.Error(err, "FinalizedRoots() should fail for an invalid range")
+
+	require.NoError(h.prune(0, 1), "pruning synced rounds")
+	_, err = h.n.FinalizedRoots(ctx, 0, 5)
+	require.ErrorIs(err, ErrRoundNotFinalized, "FinalizedRoots() should fail for pruned rounds")
+	roots, err = h.n.FinalizedRoots(ctx, 2, 5)
+	require.NoError(err, "FinalizedRoots() after pruning")
+	require.Len(roots, 4)
+}
+
+func TestWorkerVerifyBlocks(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(6)
+
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{VerifyBlocks: true})
+	errCh, sub := h.n.WatchSyncErrors()
+	defer sub.Close()
+	h.start()
+	defer h.stop()
+
+	h.deliver(3)
+	h.waitSynced(3)
+
+	requireRejected := func(blk *block.Block) {
+		h.n.blockCh.In() <- blk
+		select {
+		case syncErr := <-errCh:
+			require.ErrorIs(syncErr, ErrBlockVerificationFailed, "sync error should be a verification failure")
+			require.EqualValues(blk.Header.Round, syncErr.Round)
+		case <-time.After(workerTestTimeout):
+			require.FailNow("invalid block should be rejected")
+		}
+	}
+
+	// A block that does not match the committed block should be rejected.
+	forged := *chain.block(4)
+	forged.Header.StateRoot.FromBytes([]byte("forged state root"))
+	requireRejected(&forged)
+	round, _, _ := h.n.GetLastSynced()
+	require.EqualValues(3, round, "rejected blocks should not be synced")
+
+	h.deliver(4)
+	h.waitSynced(4)
+
+	// A block that does not extend the verified chain should be rejected, even if the runtime
+	// history agrees with it.
+	genuine := chain.block(5)
+	unchained := *genuine
+	unchained.Header.PreviousHash.FromBytes([]byte("forged previous hash"))
+	setBlock := func(blk *block.Block) {
+		chain.Lock()
+		defer chain.Unlock()
+		chain.blocks[5] = blk
+	}
+	setBlock(&unchained)
+	requireRejected(&unchained)
+
+	// The same holds for blocks that skip rounds, which are verified through the history.
+	requireRejected(chain.block(6))
+
+	// Once the history is intact, blocks skipping rounds should be accepted and synced.
+	setBlock(genuine)
+	h.deliver(6)
+	h.waitSynced(6)
+}
+
+func TestWorkerScrubber(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(5)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{ScrubRecheckInterval: time.Hour})
+	h.start()
+	h.deliver(5)
+	h.waitSynced(5)
+	h.stop()
+
+	errCh, sub := h.n.WatchSyncErrors()
+	defer sub.Close()
+
+	// scrubPass runs the scrubber until the current pass is completed and returns the rounds that
+	// were reported as corrupted.
+	ctx := context.Background()
+	scrubPass := func(cursor *scrubCursor) []uint64 {
+		passes := h.n.GetScrubProgress().PassesCompleted
+		for h.n.GetScrubProgress().PassesCompleted == passes {
+			h.n.scrubNext(ctx, cursor)
+		}
+
+		var corrupted []uint64
+		for {
+			select {
+			case syncErr := <-errCh:
+				require.ErrorIs(syncErr, ErrLocalStateCorrupted, "sync error should be a corruption")
+				require.ErrorIs(syncErr, mkvsDB.ErrNodeCorrupted, "sync error should contain the cause")
+				corrupted = append(corrupted, syncErr.Round)
+			case <-time.After(100 * time.Millisecond):
+				return corrupted
+			}
+		}
+	}
+
+	var cursor scrubCursor
+	require.Empty(scrubPass(&cursor), "intact local state should not be reported")
+	progress := h.n.GetScrubProgress()
+	require.EqualValues(6, progress.RoundsScrubbed, "all finalized rounds should be scrubbed")
+	require.EqualValues(5, progress.LastScrubbedRound)
+	require.EqualValues(0, progress.Corruptions)
+
+	// Recently scrubbed rounds should be skipped.
+	h.n.scrubNext(ctx, &cursor)
+	require.Equal(progress, h.n.GetScrubProgress(), "recently scrubbed rounds should be skipped")
+
+	// Corrupt one of the leaves of the state tree.
+	stateRoot := blockRoot(chain.block(5), storageApi.RootTypeState)
+	var corrupted node.LeafNode
+	err := mkvsDB.Visit(ctx, h.nodeDB, stateRoot, func(ctx context.Context, n node.Node) bool {
+		if leaf, ok := n.(*node.LeafNode); ok {
+			corrupted = *leaf
+		}
+		return true
+	})
+	require.NoError(err, "Visit()")
+	ndb := &corruptingNodeDB{NodeDB: h.nodeDB, corrupted: corrupted.GetHash()}
+	h.n.localStorage = &corruptingBackend{LocalBackend: localStorage, nodeDB: ndb}
+
+	// Start a new pass right away, without waiting for the recheck interval.
+	cursor = scrubCursor{}
+	rounds := scrubPass(&cursor)
+	require.Contains(rounds, uint64(5), "the corruption should be reported")
+	progress = h.n.GetScrubProgress()
+	require.EqualValues(12, progress.RoundsScrubbed, "all finalized rounds should be scrubbed again")
+	require.EqualValues(len(rounds), progress.Corruptions, "all corrupted rounds should be counted")
+}
+
+func TestWorkerEventSink(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(5)
+	chain.diffFailures[3] = 1
+	localStorage := newTestLocalStorage(t)
+
+	sink := &recordingEventSink{}
+	h := newWorkerHarness(t, chain, localStorage, &Config{EventSink: sink})
+	h.start()
+	h.deliver(5)
+	h.waitSynced(5)
+
+	require.Eventually(func() bool {
+		return len(sink.rounds(EventRoundFinalized)) == 6
+	}, workerTestTimeout, 10*time.Millisecond, "all rounds should be reported as finalized")
+	h.stop()
+
+	require.Equal([]uint64{0, 1, 2, 3, 4, 5}, sink.rounds(EventRoundFinalized), "finalized rounds")
+	require.Equal([]uint64{0, 1, 2, 3, 4, 5}, sink.rounds(EventRoundApplied), "applied rounds")
+	// Each non-genesis round has two roots that need to be fetched.
+	require.Len(sink.rounds(EventRoundFetched), 10, "fetched roots")
+
+	sink.Lock()
+	defer sink.Unlock()
+	var syncErrors []*Event
+	for _, ev := range sink.events {
+		if ev.Kind == EventSyncError {
+			syncErrors = append(syncErrors, ev)
+		}
+	}
+	require.NotEmpty(syncErrors, "the failed diff fetch should be reported")
+	require.EqualValues(3, syncErrors[0].Round, "sync error round")
+	require.ErrorIs(syncErrors[0].Err, ErrDiffFetchFailed, "sync error kind")
+}
+
+func TestWorkerEventSinkSlow(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(10)
+	localStorage := newTestLocalStorage(t)
+
+	sink := &blockingEventSink{releaseCh: make(chan struct{})}
+	defer close(sink.releaseCh)
+	h := newWorkerHarness(t, chain, localStorage, &Config{EventSink: sink})
+	h.start()
+	h.deliver(10)
+
+	// A sink that does not keep up must not stall syncing.
+	h.waitSynced(10)
+	h.stop()
+	h.requireSyncedTo(0, 10)
+}
+
+func TestWorkerForeignBlock(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(5)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	syncErrCh, sub := h.n.WatchSyncErrors()
+	defer sub.Close()
+	h.start()
+	h.deliver(2)
+	h.waitSynced(2)
+
+	// A block of a different runtime (e.g., due to a misconfiguration) must be rejected.
+	foreign := *chain.block(3)
+	foreign.Header.Namespace = common.NewTestNamespaceFromSeed([]byte("storage worker foreign test ns"), 0)
+	h.n.blockCh.In() <- &foreign
+
+	select {
+	case syncErr := <-syncErrCh:
+		require.ErrorIs(syncErr, ErrNamespaceMismatch, "foreign block should be reported")
+		require.EqualValues(3, syncErr.Round, "sync error round")
+	case <-time.After(workerTestTimeout):
+		require.FailNow("foreign block should be reported")
+	}
+
+	// Syncing continues with blocks of the correct runtime.
+	h.deliver(3, 4, 5)
+	h.waitSynced(5)
+	h.stop()
+	h.requireSyncedTo(0, 5)
+}
+
+func TestWorkerRetryingRounds(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(3)
+
+	// Diffs of round 2 fail until released.
+	chain.diffFailures[2] = math.MaxInt32
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{RetryWarningThreshold: 50 * time.Millisecond})
+	tunables := DefaultTunables()
+	tunables.RetryInitialInterval = 10 * time.Millisecond
+	tunables.RetryMaxInterval = 20 * time.Millisecond
+	require.NoError(h.n.Reconfigure(tunables), "Reconfigure")
+	h.start()
+	defer h.stop()
+	h.deliver(3)
+
+	labels := h.n.getMetricLabels()
+	oldestRound := storageWorkerOldestRetryingRound.With(labels)
+	oldestAge := storageWorkerOldestRetryingRoundAge.With(labels)
+	require.Eventually(func() bool {
+		return testutil.ToFloat64(oldestRound) == 2 && testutil.ToFloat64(oldestAge) >= 0.05
+	}, workerTestTimeout, 10*time.Millisecond, "persistently failing round should be reported")
+
+	// The age should keep increasing while the round keeps failing.
+	age := testutil.ToFloat64(oldestAge)
+	require.Eventually(func() bool {
+		return testutil.ToFloat64(oldestAge) > age
+	}, workerTestTimeout, 10*time.Millisecond, "age of the retrying round should increase")
+	require.EqualValues(2, testutil.ToFloat64(oldestRound), "oldest retrying round")
+
+	// Once the round can be fetched, it should no longer be reported.
+	chain.Lock()
+	chain.diffFailures[2] = 0
+	chain.Unlock()
+	h.waitSynced(3)
+	require.Eventually(func() bool {
+		return testutil.ToFloat64(oldestRound) == 0 && testutil.ToFloat64(oldestAge) == 0
+	}, workerTestTimeout, 10*time.Millisecond, "no rounds should be reported once synced")
+}
+
+func TestWorkerTransitionLog(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(2)
+
+	// One of the diffs of round 1 fails once.
+	chain.diffFailures[1] = 1
+	h := newWorkerHarness(t, chain, newTestLocalStorage(t), &Config{TransitionLogSize: 1024})
+	tunables := DefaultTunables()
+	tunables.RetryInitialInterval = 10 * time.Millisecond
+	tunables.RetryMaxInterval = 20 * time.Millisecond
+	require.NoError(h.n.Reconfigure(tunables), "Reconfigure")
+	h.start()
+	defer h.stop()
+	h.deliver(2)
+	h.waitSynced(2)
+	require.NoError(h.prune(0, 1), "prune")
+
+	transitions := h.n.GetTransitionLog()
+	require.NotEmpty(transitions, "transitions should be recorded")
+	require.Equal(TransitionBlockReceived, transitions[0].Kind, "first transition")
+	require.EqualValues(2, transitions[0].Round, "first transition round")
+	last := transitions[len(transitions)-1]
+	require.Equal(TransitionPruned, last.Kind, "last transition")
+	require.EqualValues(1, last.Round, "last transition round")
+
+	// Each root of round 1 should be fetched, received and applied, with the failed fetch being
+	// retried, and the round should be finalized once all roots have been applied.
+	var (
+		retries   int
+		perRoot   = make(map[storageApi.RootType][]TransitionKind)
+		finalized = -1
+	)
+	for i, tr := range transitions {
+		require.False(i > 0 && tr.Time.Before(transitions[i-1].Time), "transitions should be ordered")
+		if tr.Round != 1 {
+			continue
+		}
+		switch tr.Kind {
+		case TransitionRetried:
+			retries++
+		case TransitionFinalized:
+			finalized = i
+		case TransitionFetchSubmitted, TransitionDiffReceived, TransitionApplied:
+			kinds := perRoot[tr.RootType]
+			if tr.Kind == TransitionFetchSubmitted && len(kinds) > 0 && kinds[len(kinds)-1] == TransitionFetchSubmitted {
+				// Fetch resubmitted after a retry.
+				continue
+			}
+			perRoot[tr.RootType] = append(kinds, tr.Kind)
+			if tr.Kind == TransitionApplied {
+				require.Equal(-1, finalized, "round should only be finalized after all roots are applied")
+			}
+		}
+	}
+	require.Equal(1, retries, "failed fetch should be retried once")
+	require.NotEqual(-1, finalized, "round should be finalized")
+	expected := []TransitionKind{TransitionFetchSubmitted, TransitionDiffReceived, TransitionApplied}
+	require.Equal(map[storageApi.RootType][]TransitionKind{
+		storageApi.RootTypeState: expected,
+		storageApi.RootTypeIO:    expected,
+	}, perRoot, "transitions of round 1 roots")
+
+	// The log should only keep the most recent transitions.
+	tl := newTransitionLog(2)
+	for round := uint64(1); round <= 3; round++ {
+		tl.record(TransitionFinalized, round, storageApi.RootTypeInvalid, 0)
+	}
+	transitions = tl.snapshot()
+	require.Len(transitions, 2, "log size should be bounded")
+	require.EqualValues(2, transitions[0].Round, "oldest kept transition")
+	require.EqualValues(3, transitions[1].Round, "newest transition")
+}
+
+func TestWorkerQueueOrdering(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(4)
+	localStorage := newTestLocalStorage(t)
+
+	// Diffs of round 1 fail until released, so later rounds cannot be applied.
+	chain.diffFailures[1] = math.MaxInt32
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	tunables := DefaultTunables()
+	tunables.RetryInitialInterval = 10 * time.Millisecond
+	tunables.RetryMaxInterval = 20 * time.Millisecond
+	require.NoError(h.n.Reconfigure(tunables), "Reconfigure")
+	h.start()
+	h.deliver(4)
+
+	ctx := context.Background()
+	var snapshot *queueSnapshot
+	require.Eventually(func() bool {
+		var err error
+		snapshot, err = h.n.snapshotQueues(ctx)
+		require.NoError(err, "snapshotQueues")
+		return len(snapshot.doneDiffs) == 6
+	}, workerTestTimeout, 10*time.Millisecond, "diffs of following rounds should be queued")
+
+	// Diffs of following rounds should wait in order for round 1 to be applied.
+	var expectedDiffs []queuedDiff
+	for round := uint64(2); round <= 4; round++ {
+		expectedDiffs = append(expectedDiffs,
+			queuedDiff{round: round, rootType: storageApi.RootTypeState},
+			queuedDiff{round: round, rootType: storageApi.RootTypeIO},
+		)
+	}
+	require.Equal(expectedDiffs, snapshot.doneDiffs, "queued diffs")
+	require.Empty(snapshot.finalizable, "no rounds should be waiting for finalization")
+	require.Len(snapshot.outstanding, 4, "rounds 1-4 should be in flight")
+	require.False((snapshot.outstanding[1] | snapshot.awaitingRetry[1]).isEmpty(), "round 1 should still be syncing")
+	for round := uint64(2); round <= 4; round++ {
+		require.True(snapshot.awaitingRetry[round].isEmpty(), "round %d should not be retried", round)
+	}
+	synced, _, _ := h.n.GetLastSynced()
+	require.EqualValues(0, synced, "only genesis should be synced")
+
+	// Once round 1 can be fetched, all queues should drain.
+	chain.Lock()
+	chain.diffFailures[1] = 0
+	chain.Unlock()
+	h.waitSynced(4)
+
+	snapshot, err := h.n.snapshotQueues(ctx)
+	require.NoError(err, "snapshotQueues")
+	require.Empty(snapshot.doneDiffs, "no diffs should be queued")
+	require.Empty(snapshot.finalizable, "no rounds should be waiting for finalization")
+	require.Empty(snapshot.outstanding, "no rounds should be in flight")
+	h.stop()
+	h.requireSyncedTo(0, 4)
+}
+
+func TestWorkerPartialFinalizeFailure(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(8)
+	localStorage := newTestLocalStorage(t)
+
+	policy, err := NewFinalizePolicy(FinalizePolicyDeferred, 4)
+	require.NoError(err, "NewFinalizePolicy")
+	h := newWorkerHarness(t, chain, localStorage, &Config{FinalizePolicy: policy})
+	hostNode := &testControlledNode{}
+	h.n.commonNode.HostNode = hostNode
+	tunables := DefaultTunables()
+	tunables.RetryInitialInterval = 10 * time.Millisecond
+	tunables.RetryMaxInterval = 20 * time.Millisecond
+	require.NoError(h.n.Reconfigure(tunables), "Reconfigure")
+
+	// Finalizing a round in the middle of the first batch (rounds 1-4) fails.
+	h.nodeDB.Lock()
+	h.nodeDB.failures[3] = math.MaxInt32
+	h.nodeDB.Unlock()
+	syncErrCh, sub := h.n.WatchSyncErrors()
+	defer sub.Close()
+	h.start()
+	h.deliver(8)
+
+	select {
+	case syncErr := <-syncErrCh:
+		require.ErrorIs(syncErr, ErrFinalizeFailed, "finalize failure should be reported")
+		require.EqualValues(3, syncErr.Round, "sync error round")
+	case <-time.After(workerTestTimeout):
+		require.FailNow("finalize failure should be reported")
+	}
+	require.Eventually(func() bool {
+		h.nodeDB.Lock()
+		defer h.nodeDB.Unlock()
+		return h.nodeDB.failed[3] >= 3
+	}, workerTestTimeout, 10*time.Millisecond, "failed rounds should be retried")
+
+	// The synced state should stop at the last round finalized before the failure.
+	synced, _, _ := h.n.GetLastSynced()
+	require.EqualValues(2, synced, "synced state should stop at the last finalized round")
+	require.Equal([]uint64{0, 1, 2}, h.finalized(), "no rounds following the failed round should be finalized")
+	require.NotZero(atomic.LoadInt32(&hostNode.shutdownRequests), "shutdown should be requested")
+
+	// Once the failure goes away, finalization should resume from the failed round.
+	h.nodeDB.Lock()
+	h.nodeDB.failures[3] = 0
+	h.nodeDB.Unlock()
+	h.waitSynced(8)
+	h.stop()
+	h.requireSyncedTo(0, 8)
+}
+
+func TestWorkerRoundProgress(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(2)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	progressCh, sub := h.n.WatchRoundProgress()
+	defer sub.Close()
+	h.start()
+
+	progress := make(map[uint64][]RoundProgress)
+	waitFinalized := func(round uint64) {
+		for {
+			select {
+			case p := <-progressCh:
+				progress[p.Round] = append(progress[p.Round], *p)
+				if p.Round == round && p.Finalized {
+					return
+				}
+			case <-time.After(workerTestTimeout):
+				require.FailNow("round should be finalized", "round %d", round)
+			}
+		}
+	}
+
+	// Deliver rounds one by one, so that each round is finalized before the next one starts.
+	for round := uint64(1); round <= 2; round++ {
+		h.deliver(round)
+		waitFinalized(round)
+
+		updates := progress[round]
+		require.Len(updates, 3, "round %d should have one update per applied root and finalization", round)
+		require.NotEqual(updates[0].IOApplied, updates[0].StateApplied, "round %d should first have a single root applied", round)
+		require.False(updates[0].Finalized, "round %d should not be finalized before applying all roots", round)
+		require.Equal(RoundProgress{Round: round, IOApplied: true, StateApplied: true}, updates[1], "round %d should have all roots applied", round)
+		require.Equal(RoundProgress{Round: round, IOApplied: true, StateApplied: true, Finalized: true}, updates[2], "round %d should be finalized", round)
+	}
+	h.stop()
+	h.requireSyncedTo(0, 2)
+}
+
+func TestWorkerApplyProgress(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extendWithEntries(1, 2*storageApi.ApplyProgressInterval+1)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	progressCh, sub := h.n.WatchRoundProgress()
+	defer sub.Close()
+	h.start()
+
+	h.deliver(1)
+
+	var applying []ApplyProgress
+	for finalized := false; !finalized; {
+		select {
+		case p := <-progressCh:
+			if p.Applying != nil {
+				applying = append(applying, *p.Applying)
+			}
+			finalized = p.Finalized
+		case <-time.After(workerTestTimeout):
+			require.FailNow("round should be finalized")
+		}
+	}
+
+	total := uint64(2*storageApi.ApplyProgressInterval + 1)
+	require.Equal([]ApplyProgress{
+		{RootType: storageApi.RootTypeState, Entries: storageApi.ApplyProgressInterval, Total: total},
+		{RootType: storageApi.RootTypeState, Entries: 2 * storageApi.ApplyProgressInterval, Total: total},
+		{RootType: storageApi.RootTypeState, Entries: total, Total: total},
+	}, applying, "progress of applying the large state write log should be reported")
+
+	h.stop()
+	h.requireSyncedTo(0, 1)
+}
+
+func TestWorkerApplyNamespaceMismatch(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(1)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+
+	thisRoot := blockRoot(chain.block(1), storageApi.RootTypeIO)
+	prevRoot := storageApi.Root{
+		Namespace: thisRoot.Namespace,
+		Version:   thisRoot.Version,
+		Type:      thisRoot.Type,
+	}
+	prevRoot.Hash.Empty()
+	writeLog := chain.writeLogs[thisRoot]
+
+	// A diff for roots of a different runtime must never be applied, even though the write log
+	// itself would result in the expected root.
+	foreignNs := common.NewTestNamespaceFromSeed([]byte("storage worker foreign test ns"), 0)
+	foreignPrevRoot, foreignThisRoot := prevRoot, thisRoot
+	foreignPrevRoot.Namespace = foreignNs
+	foreignThisRoot.Namespace = foreignNs
+
+	err := h.n.applyDiff(h.n.ctx, make(appliedWriteLogs), &fetchedDiff{
+		fetched:  true,
+		round:    1,
+		prevRoot: foreignPrevRoot,
+		thisRoot: foreignThisRoot,
+		writeLog: writeLog,
+	}, nil)
+	require.ErrorIs(err, ErrApplyNamespaceMismatch, "applyDiff should reject foreign roots")
+	require.False(localStorage.NodeDB().HasRoot(foreignThisRoot), "foreign root should not be written")
+
+	// Diffs are also rejected in case only one of the roots is foreign.
+	err = h.n.applyDiff(h.n.ctx, make(appliedWriteLogs), &fetchedDiff{
+		fetched:  true,
+		round:    1,
+		prevRoot: foreignPrevRoot,
+		thisRoot: thisRoot,
+		writeLog: writeLog,
+	}, nil)
+	require.ErrorIs(err, ErrApplyNamespaceMismatch, "applyDiff should reject a foreign previous root")
+	require.False(localStorage.NodeDB().HasRoot(thisRoot), "root should not be written")
+
+	err = h.n.applyDiff(h.n.ctx, make(appliedWriteLogs), &fetchedDiff{
+		fetched:  true,
+		round:    1,
+		prevRoot: prevRoot,
+		thisRoot: thisRoot,
+		writeLog: writeLog,
+	}, nil)
+	require.NoError(err, "applyDiff should apply roots of the runtime")
+	require.True(localStorage.NodeDB().HasRoot(thisRoot), "root should be written")
+}
+
+func TestWorkerCatchUpAndExit(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(5)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{CatchUpAndExit: true})
+	h.start()
+
+	// The worker syncs up to the tip without any blocks being delivered and then exits.
+	select {
+	case <-h.doneCh:
+	case <-time.After(workerTestTimeout):
+		require.FailNow("worker should exit once caught up")
+	}
+	select {
+	case <-h.n.CaughtUp():
+	default:
+		require.FailNow("worker should report having caught up")
+	}
+	require.ErrorIs(h.n.ctx.Err(), context.Canceled, "worker context should be canceled")
+	h.requireSyncedTo(0, 5)
+
+	// Later rounds are not synced.
+	chain.extend(2)
+	h.deliver(6, 7)
+	synced, _, _ := h.n.GetLastSynced()
+	require.EqualValues(5, synced, "worker should not sync after exiting")
+}
+
+func TestWorkerCatchUpAndExitSynced(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(3)
+	localStorage := newTestLocalStorage(t)
+
+	h := newWorkerHarness(t, chain, localStorage, &Config{})
+	h.start()
+	h.deliver(3)
+	h.waitSynced(3)
+	h.stop()
+
+	// A worker that is already synced to the tip exits immediately.
+	h = newWorkerHarness(t, chain, localStorage, &Config{CatchUpAndExit: true})
+	h.start()
+	select {
+	case <-h.doneCh:
+	case <-time.After(workerTestTimeout):
+		require.FailNow("worker should exit when already caught up")
+	}
+	select {
+	case <-h.n.CaughtUp():
+	default:
+		require.FailNow("worker should report having caught up")
+	}
+	synced, _, _ := h.n.GetLastSynced()
+	require.EqualValues(3, synced, "last synced round")
+	require.Empty(h.finalized(), "no rounds should be finalized again")
+}
+
+func TestWorkerGenesisBlockRetry(t *testing.T) {
+	chain := newTestChain(t)
+	chain.extend(3)
+	chain.genesisFailures = 2
+	localStorage := newTestLocalStorage(t)
+
+	// Transient failures to retrieve the genesis block are retried.
+	h := newWorkerHarness(t, chain, localStorage, &Config{
+		MaxGenesisBlockRetries:    2,
+		GenesisBlockRetryInterval: 10 * time.Millisecond,
+	})
+	h.start()
+	h.deliver(3)
+	h.waitSynced(3)
+	h.stop()
+	h.requireSyncedTo(0, 3)
+}
+
+func TestWorkerGenesisBlockFailure(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(3)
+	chain.genesisFailures = 3
+	localStorage := newTestLocalStorage(t)
+
+	// In case the genesis block can't be retrieved even after retrying, the worker exits but does
+	// not leave anyone waiting for initialization.
+	h := newWorkerHarness(t, chain, localStorage, &Config{
+		MaxGenesisBlockRetries:    2,
+		GenesisBlockRetryInterval: 10 * time.Millisecond,
+	})
+	h.start()
+	select {
+	case <-h.doneCh:
+	case <-time.After(workerTestTimeout):
+		require.FailNow("worker should exit after failing to start")
+	}
+	synced, _, _ := h.n.GetLastSynced()
+	require.EqualValues(defaultUndefinedRound, synced, "nothing should be synced")
+}
+
+func TestWorkerPrioritizedTipRounds(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		tipRounds  uint64
+		inFlight   []uint64
+		lastRounds uint64
+	}{
+		{"Disabled", 0, []uint64{1, 2, 3, 4}, 0},
+		{"Enabled", 2, []uint64{1, 2, 11, 12}, 2},
+		// At least one in-flight round is always left for the backlog.
+		{"Capped", 10, []uint64{1, 10, 11, 12}, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			chain := newTestChain(t)
+			chain.extend(12)
+			localStorage := newTestLocalStorage(t)
+
+			// Diffs of round 1 fail until released, so no rounds can be applied.
+			chain.diffFailures[1] = math.MaxInt32
+			h := newWorkerHarness(t, chain, localStorage, &Config{PrioritizedTipRounds: tc.tipRounds})
+			tunables := DefaultTunables()
+			tunables.MaxInFlightRounds = 4
+			tunables.RetryInitialInterval = 10 * time.Millisecond
+			tunables.RetryMaxInterval = 20 * time.Millisecond
+			require.NoError(h.n.Reconfigure(tunables), "Reconfigure")
+			h.start()
+			h.deliver(12)
+
+			ctx := context.Background()
+			var snapshot *queueSnapshot
+			require.Eventually(func() bool {
+				var err error
+				snapshot, err = h.n.snapshotQueues(ctx)
+				require.NoError(err, "snapshotQueues")
+				return len(snapshot.outstanding) == len(tc.inFlight) && len(snapshot.doneDiffs) == 2*(len(tc.inFlight)-1)
+			}, workerTestTimeout, 10*time.Millisecond, "diffs of in-flight rounds should be queued")
+
+			var inFlight []uint64
+			for round := range snapshot.outstanding {
+				inFlight = append(inFlight, round)
+			}
+			sort.Slice(inFlight, func(i, j int) bool {
+				return inFlight[i] < inFlight[j]
+			})
+			require.Equal(tc.inFlight, inFlight, "rounds in flight")
+			var tipDiffs uint64
+			for _, diff := range snapshot.doneDiffs {
+				if diff.round > 12-tc.lastRounds {
+					tipDiffs++
+				}
+			}
+			require.EqualValues(2*tc.lastRounds, tipDiffs, "diffs of the most recent rounds should be fetched")
+			synced, _, _ := h.n.GetLastSynced()
+			require.EqualValues(0, synced, "only genesis should be synced")
+
+			// Once round 1 can be fetched, the backlog is filled in and rounds are still applied
+			// and finalized in order.
+			chain.Lock()
+			chain.diffFailures[1] = 0
+			chain.Unlock()
+			h.waitSynced(12)
+			h.stop()
+			h.requireSyncedTo(0, 12)
+		})
+	}
+}
+
+func TestWorkerBufferedDiffLimit(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(10)
+	localStorage := newTestLocalStorage(t)
+
+	// Diffs of round 1 fail until released, so fetched diffs of later rounds stay buffered. Any
+	// buffered diff exceeds the limit.
+	chain.diffFailures[1] = math.MaxInt32
+	h := newWorkerHarness(t, chain, localStorage, &Config{MaxBufferedDiffBytes: 1})
+	tunables := DefaultTunables()
+	tunables.RetryInitialInterval = 10 * time.Millisecond
+	tunables.RetryMaxInterval = 20 * time.Millisecond
+	require.NoError(h.n.Reconfigure(tunables), "Reconfigure")
+	h.start()
+	h.deliver(3)
+
+	ctx := context.Background()
+	var snapshot *queueSnapshot
+	require.Eventually(func() bool {
+		var err error
+		snapshot, err = h.n.snapshotQueues(ctx)
+		require.NoError(err, "snapshotQueues")
+		return len(snapshot.doneDiffs) == 4
+	}, workerTestTimeout, 10*time.Millisecond, "diffs of rounds 2-3 should be buffered")
+	var expectedBytes uint64
+	for round := uint64(2); round <= 3; round++ {
+		blk := chain.block(round)
+		for _, rootType := range []storageApi.RootType{storageApi.RootTypeIO, storageApi.RootTypeState} {
+			expectedBytes += cachedWriteLog(chain.writeLogs[blockRoot(blk, rootType)]).Size()
+		}
+	}
+	require.Equal(expectedBytes, snapshot.bufferedDiffBytes, "buffered diff bytes")
+
+	// Once over the limit, rounds of new blocks are not fetched while round 1 is still retried.
+	h.deliver(10)
+	time.Sleep(100 * time.Millisecond)
+	snapshot, err := h.n.snapshotQueues(ctx)
+	require.NoError(err, "snapshotQueues")
+	require.Len(snapshot.outstanding, 3, "only rounds 1-3 should be in flight")
+	for round := uint64(1); round <= 3; round++ {
+		require.Contains(snapshot.outstanding, round, "round %d should be in flight", round)
+	}
+	chain.Lock()
+	fetches := chain.diffFetches[1]
+	chain.Unlock()
+	require.Greater(fetches, 2, "round 1 should still be retried")
+
+	// Once round 1 can be fetched, applying drains the buffer and fetching resumes.
+	chain.Lock()
+	chain.diffFailures[1] = 0
+	chain.Unlock()
+	h.waitSynced(10)
+
+	snapshot, err = h.n.snapshotQueues(ctx)
+	require.NoError(err, "snapshotQueues")
+	require.Zero(snapshot.bufferedDiffBytes, "no diffs should be buffered")
+	h.stop()
+	h.requireSyncedTo(0, 10)
+}
+
+func TestWorkerLoopWatchdog(t *testing.T) {
+	require := require.New(t)
+	chain := newTestChain(t)
+	chain.extend(3)
+	localStorage := newTestLocalStorage(t)
+
+	// Applying the diffs of round 2 blocks the main loop until released.
+	releaseCh := make(chan struct{})
+	h := newWorkerHarness(t, chain, localStorage, &Config{
+		LoopWatchdogInterval: 50 * time.Millisecond,
+		WriteLogInterceptor: func(round uint64, root storageApi.Root, writeLog storageApi.WriteLog) (storageApi.WriteLog, error) {
+			if round == 2 {
+				<-releaseCh
+			}
+			return writeLog, nil
+		},
+	})
+	stalls := storageWorkerMainLoopStalls.With(h.n.getMetricLabels())
+	initialStalls := testutil.ToFloat64(stalls)
+	h.start()
+
+	// An idle loop is not stalled.
+	h.deliver(1)
+	h.waitSynced(1)
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(initialStalls, testutil.ToFloat64(stalls), "idle main loop should not be reported")
+
+	// A blocked main loop with work pending is reported once.
+	h.deliver(3)
+	require.Eventually(func() bool {
+		return testutil.ToFloat64(stalls) == initialStalls+1
+	}, workerTestTimeout, 10*time.Millisecond, "stalled main loop should be reported")
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(initialStalls+1, testutil.ToFloat64(stalls), "stall should only be reported once")
+
+	// Once released, the main loop proceeds.
+	close(releaseCh)
+	h.waitSynced(3)
+	h.stop()
+	h.requireSyncedTo(0, 3)
+}
@@ -0,0 +1,45 @@
+package committee
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// storageWriteLimiter limits the number of write operations (applies and finalizations) that are
+// performed on local storage concurrently, so that they do not contend for disk IO.
+//
+// A nil limiter does not limit writes.
+type storageWriteLimiter struct {
+	slots  chan struct{}
+	waited prometheus.Counter
+}
+
+func newStorageWriteLimiter(limit uint, labels prometheus.Labels) *storageWriteLimiter {
+	return &storageWriteLimiter{
+		slots:  make(chan struct{}, limit),
+		waited: storageWorkerWriteSlotWait.With(labels),
+	}
+}
+
+// acquire waits for a free write slot and returns the function releasing it.
+func (l *storageWriteLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		// Only account for the time spent waiting in case all slots are taken.
+		start := time.Now()
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		l.waited.Add(time.Since(start).Seconds())
+	}
+	return func() { <-l.slots }, nil
+}
@@ -0,0 +1,70 @@
+package committee
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/common/cache/lru"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// writeLogCacheKey is the key of a cached write log, identifying the diff between two roots.
+type writeLogCacheKey struct {
+	prevRoot storageApi.Root
+	thisRoot storageApi.Root
+}
+
+// cachedWriteLog is a write log stored in the write log cache.
+type cachedWriteLog storageApi.WriteLog
+
+// Size implements lru.Sizeable.
+func (wl cachedWriteLog) Size() uint64 {
+	var size uint64
+	for _, entry := range wl {
+		size += uint64(len(entry.Key) + len(entry.Value))
+	}
+	return size
+}
+
+func newWriteLogCache(size uint64) (*lru.Cache, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	return lru.New(lru.Capacity(size, true))
+}
+
+// getCachedWriteLog returns the cached write log for the diff between the given roots, if any.
+func (n *Node) getCachedWriteLog(prevRoot, thisRoot storageApi.Root) (storageApi.WriteLog, bool) {
+	if n.writeLogCache == nil {
+		return nil, false
+	}
+
+	value, ok := n.writeLogCache.Get(writeLogCacheKey{prevRoot, thisRoot})
+	if !ok {
+		storageWorkerWriteLogCacheMisses.With(n.getMetricLabels()).Inc()
+		return nil, false
+	}
+	storageWorkerWriteLogCacheHits.With(n.getMetricLabels()).Inc()
+	return storageApi.WriteLog(value.(cachedWriteLog)), true
+}
+
+// cacheWriteLog stores the fetched write log for the diff between the given roots.
+func (n *Node) cacheWriteLog(prevRoot, thisRoot storageApi.Root, writeLog storageApi.WriteLog) {
+	if n.writeLogCache == nil {
+		return
+	}
+
+	if err := n.writeLogCache.Put(writeLogCacheKey{prevRoot, thisRoot}, cachedWriteLog(writeLog)); err != nil {
+		// The write log is too large to be cached.
+		n.logger.Debug("not caching write log",
+			"err", err,
+			"old_root", prevRoot,
+			"new_root", thisRoot,
+		)
+	}
+}
+
+// evictCachedWriteLog removes the cached write log for the diff between the given roots, if any.
+func (n *Node) evictCachedWriteLog(prevRoot, thisRoot storageApi.Root) {
+	if n.writeLogCache == nil {
+		return
+	}
+	n.writeLogCache.Remove(writeLogCacheKey{prevRoot, thisRoot})
+}
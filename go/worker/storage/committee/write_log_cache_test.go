@@ -0,0 +1,84 @@
+package committee
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+// testDiffClient is a storage sync client which serves a fixed write log and counts GetDiff calls.
+type testDiffClient struct {
+	storageSync.Client
+
+	writeLog storageApi.WriteLog
+	calls    int
+}
+
+func (c *testDiffClient) GetDiff(ctx context.Context, request *storageSync.GetDiffRequest) (*storageSync.GetDiffResponse, rpc.PeerFeedback, error) {
+	c.calls++
+	return &storageSync.GetDiffResponse{WriteLog: c.writeLog}, rpc.NewNopPeerFeedback(), nil
+}
+
+func TestWriteLogCache(t *testing.T) {
+	require := require.New(t)
+	n := newTestNode(t)
+	n.commonNode = &committee.Node{Runtime: &testRuntime{}}
+	n.diffCh = make(chan *fetchedDiff, 1)
+
+	writeLog := storageApi.WriteLog{{Key: []byte("key"), Value: []byte("value")}}
+	client := &testDiffClient{writeLog: writeLog}
+	n.storageSync = client
+
+	var err error
+	n.writeLogCache, err = newWriteLogCache(1024)
+	require.NoError(err, "newWriteLogCache()")
+
+	prevRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      storageApi.RootTypeState,
+	}
+	prevRoot.Hash.Empty()
+	thisRoot := storageApi.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      storageApi.RootTypeState,
+	}
+	thisRoot.Hash.FromBytes([]byte("this root"))
+
+	n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+	result := <-n.diffCh
+	require.NoError(result.err, "fetchDiff()")
+	require.EqualValues(writeLog, result.writeLog, "fetched write log should be correct")
+	require.Equal(1, client.calls, "first fetch should issue GetDiff")
+
+	// A cache hit should avoid the network call.
+	n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+	result = <-n.diffCh
+	require.NoError(result.err, "fetchDiff()")
+	require.EqualValues(writeLog, result.writeLog, "cached write log should be correct")
+	require.Equal(1, client.calls, "cache hit should not issue GetDiff")
+
+	// A write log which fails to apply should be evicted.
+	err = n.applyDiff(n.ctx, make(appliedWriteLogs), result, nil)
+	require.ErrorIs(err, storageApi.ErrExpectedRootMismatch, "applyDiff()")
+	n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+	result = <-n.diffCh
+	require.NoError(result.err, "fetchDiff()")
+	require.Equal(2, client.calls, "evicted write log should be fetched again")
+
+	// Write logs which don't fit into the cache should not be cached.
+	n.writeLogCache, err = newWriteLogCache(1)
+	require.NoError(err, "newWriteLogCache()")
+	for i := 0; i < 2; i++ {
+		n.fetchDiff(n.ctx, 1, prevRoot, thisRoot)
+		<-n.diffCh
+	}
+	require.Equal(4, client.calls, "write logs too large for the cache should not be cached")
+}
@@ -0,0 +1,28 @@
+package committee
+
+import (
+	"fmt"
+
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// WriteLogInterceptor is invoked with each fetched write log before it is applied to local storage.
+// It may inspect the write log and either return it (possibly transformed) to be applied instead
+// or return an error to reject it, in which case syncing the given root is retried.
+//
+// Note that a transformed write log must still produce the given root, otherwise applying it will
+// fail. The interceptor may be invoked multiple times for the same write log (e.g., on retries).
+type WriteLogInterceptor func(round uint64, root storageApi.Root, writeLog storageApi.WriteLog) (storageApi.WriteLog, error)
+
+// interceptWriteLog passes the write log of the given diff through the configured interceptor.
+func (n *Node) interceptWriteLog(diff *fetchedDiff) (storageApi.WriteLog, error) {
+	if n.cfg.WriteLogInterceptor == nil {
+		return diff.writeLog, nil
+	}
+
+	writeLog, err := n.cfg.WriteLogInterceptor(diff.round, diff.thisRoot, diff.writeLog)
+	if err != nil {
+		return nil, fmt.Errorf("write log rejected by interceptor: %w", err)
+	}
+	return writeLog, nil
+}
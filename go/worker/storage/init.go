@@ -4,6 +4,7 @@ package storage
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,10 +17,14 @@ import (
 	cmdFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
 	"github.com/oasisprotocol/oasis-core/go/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/storage/database"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/committee"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
 )
 
 const (
 	cfgWorkerFetcherCount = "worker.storage.fetcher_count"
+	// cfgWorkerFetcherWeights configures per-runtime weights used when sharing diff fetchers.
+	cfgWorkerFetcherWeights = "worker.storage.fetcher_weights"
 
 	// CfgWorkerPublicRPCEnabled enables storage state access for all nodes instead of just
 	// storage committee members.
@@ -30,8 +35,176 @@ const (
 	// CfgWorkerCheckpointCheckInterval configures the checkpointer check interval.
 	CfgWorkerCheckpointCheckInterval = "worker.storage.checkpointer.check_interval"
 
+	// CfgWorkerBlockWatchdogInterval configures the interval after which the worker falls back to
+	// polling for blocks in case the block subscription stalls.
+	CfgWorkerBlockWatchdogInterval = "worker.storage.block_watchdog_interval"
+
+	// CfgWorkerLoopWatchdogInterval configures the interval after which the worker main loop is
+	// considered stalled in case it made no progress while work is pending.
+	CfgWorkerLoopWatchdogInterval = "worker.storage.loop_watchdog_interval"
+
+	// CfgWorkerRetryWarningThreshold configures the duration after which a round whose diffs keep
+	// failing to be fetched or applied is reported with a warning.
+	CfgWorkerRetryWarningThreshold = "worker.storage.retry_warning_threshold"
+
+	// CfgWorkerConsistencyCheckInterval configures the interval at which local roots are compared
+	// against roots reported by remote peers.
+	CfgWorkerConsistencyCheckInterval = "worker.storage.consistency_check_interval"
+
+	// CfgWorkerScrubInterval configures the interval at which the background scrubber verifies the
+	// next finalized round of local storage.
+	CfgWorkerScrubInterval = "worker.storage.scrub_interval"
+
+	// CfgWorkerScrubRecheckInterval configures the minimum interval between the starts of
+	// consecutive scrubber passes.
+	CfgWorkerScrubRecheckInterval = "worker.storage.scrub_recheck_interval"
+
+	// CfgWorkerBlockBacklogSampleInterval configures the interval at which the backlog of received
+	// blocks waiting to be processed is sampled.
+	CfgWorkerBlockBacklogSampleInterval = "worker.storage.block_backlog_sample_interval"
+
+	// CfgWorkerWriteLogCacheSize configures the maximum size of the cache of recently fetched
+	// write logs.
+	CfgWorkerWriteLogCacheSize = "worker.storage.write_log_cache_size"
+
+	// CfgWorkerRootFilterSize configures the size of the bloom filter of local storage roots used
+	// to quickly answer lookups of missing roots.
+	CfgWorkerRootFilterSize = "worker.storage.root_filter_size"
+
+	// CfgWorkerApplyBatchSize configures the maximum number of consecutive rounds whose write logs
+	// are applied in a single batch.
+	CfgWorkerApplyBatchSize = "worker.storage.apply_batch_size"
+
+	// CfgWorkerDiffChannelSize configures the buffer size of the channel for fetched diffs.
+	CfgWorkerDiffChannelSize = "worker.storage.diff_channel_size"
+	// CfgWorkerFinalizeChannelSize configures the buffer size of the channel for finalization
+	// results.
+	CfgWorkerFinalizeChannelSize = "worker.storage.finalize_channel_size"
+
+	// CfgWorkerRequireCommitteePeers enables rejecting diffs served by non-committee peers.
+	CfgWorkerRequireCommitteePeers = "worker.storage.require_committee_peers"
+
+	// CfgWorkerSyncCeiling configures the round up to which rounds are synced.
+	CfgWorkerSyncCeiling = "worker.storage.sync_ceiling"
+
+	// CfgWorkerDiffStreamPoolSize configures the maximum number of idle streams per peer that are
+	// reused for fetching diffs.
+	CfgWorkerDiffStreamPoolSize = "worker.storage.diff_stream_pool.size"
+	// CfgWorkerDiffStreamIdleTimeout configures the amount of time after which pooled idle streams
+	// are closed.
+	CfgWorkerDiffStreamIdleTimeout = "worker.storage.diff_stream_pool.idle_timeout"
+
+	// CfgWorkerMaxDiffBandwidth configures the maximum aggregate bandwidth (in MB/s) used for
+	// fetching diffs.
+	CfgWorkerMaxDiffBandwidth = "worker.storage.max_diff_bandwidth"
+
+	// CfgWorkerCompactDiffs enables requesting diffs in the delta-encoded write log format.
+	CfgWorkerCompactDiffs = "worker.storage.compact_diffs"
+
+	// CfgWorkerDiffPeerSelector configures the strategy for selecting the peer from which each
+	// diff is requested first.
+	CfgWorkerDiffPeerSelector = "worker.storage.diff_peer_selector"
+
+	// CfgWorkerSkipEqualRootApply enables skipping the apply of empty write logs for roots equal
+	// to the root of the previous round when supported by the storage backend.
+	CfgWorkerSkipEqualRootApply = "worker.storage.skip_equal_root_apply"
+
+	// CfgWorkerVerifyBlocks enables verification of incoming blocks before they are synced.
+	CfgWorkerVerifyBlocks = "worker.storage.verify_blocks"
+
+	// CfgWorkerFinalizePolicy configures the policy deciding when fully applied rounds are
+	// finalized.
+	CfgWorkerFinalizePolicy = "worker.storage.finalize_policy"
+	// CfgWorkerPruneOrdering configures how pruning of finalized rounds is ordered with respect
+	// to finalization of later rounds.
+	CfgWorkerPruneOrdering = "worker.storage.prune_ordering"
+	// CfgWorkerFetchOrdering configures the order in which diff fetches of the rounds being
+	// synced are submitted.
+	CfgWorkerFetchOrdering = "worker.storage.fetch_ordering"
+	// CfgWorkerFinalizeInterval configures the round interval at which the deferred finalize
+	// policy finalizes rounds.
+	CfgWorkerFinalizeInterval = "worker.storage.finalize_interval"
+
+	// CfgWorkerMaxConcurrentFinalizes configures the maximum number of batches of rounds that are
+	// finalized concurrently, in case the storage backend supports it.
+	CfgWorkerMaxConcurrentFinalizes = "worker.storage.max_concurrent_finalizes"
+
+	// CfgWorkerMaxConcurrentWrites configures the maximum number of write operations (applies and
+	// finalizations) that are performed on local storage concurrently.
+	CfgWorkerMaxConcurrentWrites = "worker.storage.max_concurrent_writes"
+
+	// CfgWorkerMaxFinalizeRetries configures the number of times a failed finalization of a round
+	// is retried before the failure is considered fatal.
+	CfgWorkerMaxFinalizeRetries = "worker.storage.max_finalize_retries"
+	// CfgWorkerFinalizeRetryInterval configures the initial interval between finalization retries.
+	CfgWorkerFinalizeRetryInterval = "worker.storage.finalize_retry_interval"
+	// CfgWorkerFinalizeGracePeriod configures the time that in-progress finalizations are given to
+	// complete when the worker is stopped.
+	CfgWorkerFinalizeGracePeriod = "worker.storage.finalize_grace_period"
+
+	// CfgWorkerMaxBufferedDiffBytes configures the soft limit on the total size of fetched diffs
+	// waiting to be applied.
+	CfgWorkerMaxBufferedDiffBytes = "worker.storage.max_buffered_diff_bytes"
+
+	// CfgWorkerPrioritizedTipRounds configures the number of the most recent rounds whose diffs
+	// are fetched before those of older rounds when catching up.
+	CfgWorkerPrioritizedTipRounds = "worker.storage.prioritized_tip_rounds"
+
+	// CfgWorkerPrioritizeStateFetches enables fetching diffs of state roots ahead of diffs of IO
+	// roots in case the fetch pool is saturated.
+	CfgWorkerPrioritizeStateFetches = "worker.storage.prioritize_state_fetches"
+
+	// CfgWorkerCheckpointRestoreLagThreshold configures the number of rounds by which a running
+	// worker may fall behind before restoring the state from a checkpoint.
+	CfgWorkerCheckpointRestoreLagThreshold = "worker.storage.checkpoint_restore_lag_threshold"
+
+	// CfgWorkerMaxInFlightRounds configures the initial maximum number of rounds that are fetched
+	// before waiting for them to be applied.
+	CfgWorkerMaxInFlightRounds = "worker.storage.max_in_flight_rounds"
+	// CfgWorkerGetDiffTimeout configures the initial timeout for fetching a single diff.
+	CfgWorkerGetDiffTimeout = "worker.storage.get_diff_timeout"
+	// CfgWorkerRetryInitialInterval configures the initial interval after which rounds that are
+	// still being synced are retried.
+	CfgWorkerRetryInitialInterval = "worker.storage.retry_initial_interval"
+	// CfgWorkerRetryMaxInterval configures the maximum interval between retries of rounds that are
+	// still being synced.
+	CfgWorkerRetryMaxInterval = "worker.storage.retry_max_interval"
+	// CfgWorkerRuntimeTunables configures per-runtime overrides of the initial storage sync
+	// tunables.
+	CfgWorkerRuntimeTunables = "worker.storage.runtime_tunables"
+
+	// CfgWorkerDisableRemoteClient disables fetching diffs and checkpoints from remote nodes, so
+	// that the worker only replays state that is available locally.
+	CfgWorkerDisableRemoteClient = "worker.storage.disable_remote_client"
+
+	// CfgWorkerTransitionLogSize configures the number of the most recent worker state
+	// transitions that are kept for debugging.
+	CfgWorkerTransitionLogSize = "worker.storage.transition_log_size"
+
+	// CfgWorkerPersistedSummaries configures the maximum number of summaries of seen blocks that
+	// are persisted for prewarming the worker after a restart.
+	CfgWorkerPersistedSummaries = "worker.storage.persisted_summaries"
+
+	// CfgWorkerMaxGenesisBlockRetries configures the number of times a failure to retrieve the
+	// genesis block at startup is retried.
+	CfgWorkerMaxGenesisBlockRetries = "worker.storage.max_genesis_block_retries"
+	// CfgWorkerGenesisBlockRetryInterval configures the initial interval between genesis block
+	// retrieval retries.
+	CfgWorkerGenesisBlockRetryInterval = "worker.storage.genesis_block_retry_interval"
+
+	// CfgWorkerSyncStatsWindowSize configures the number of most recently finalized rounds for
+	// which write log statistics are kept.
+	CfgWorkerSyncStatsWindowSize = "worker.storage.sync_stats_window_size"
+
+	// CfgWorkerQuarantineThreshold configures the number of distinct peers whose diffs for a round
+	// need to fail to apply before the round is quarantined.
+	CfgWorkerQuarantineThreshold = "worker.storage.quarantine_threshold"
+
 	// CfgWorkerCheckpointSyncDisabled disables syncing from checkpoints on worker startup.
 	CfgWorkerCheckpointSyncDisabled = "worker.storage.checkpoint_sync.disabled"
+	// CfgWorkerCheckpointSyncTrustedProviders configures the node IDs of the only peers from which
+	// checkpoints are restored.
+	CfgWorkerCheckpointSyncTrustedProviders = "worker.storage.checkpoint_sync.trusted_providers"
 
 	// CfgBackend configures the storage backend flag.
 	CfgBackend = "worker.storage.backend"
@@ -40,6 +213,9 @@ const (
 	CfgMaxCacheSize = "worker.storage.max_cache_size"
 
 	cfgCrashEnabled = "worker.storage.crash.enabled"
+
+	// CfgWorkerDebugValidateRootChaining enables validation of storage root chaining assumptions.
+	CfgWorkerDebugValidateRootChaining = "worker.storage.debug.validate_root_chaining"
 )
 
 // Flags has the configuration flags.
@@ -68,7 +244,7 @@ func NewLocalBackend(
 		impl api.LocalBackend
 	)
 	switch cfg.Backend {
-	case database.BackendNameBadgerDB:
+	case database.BackendNameBadgerDB, database.BackendNameInMemory:
 		cfg.DB = GetLocalBackendDBDir(dataDir, cfg.Backend)
 		impl, err = database.New(cfg)
 	default:
@@ -86,12 +262,111 @@ func NewLocalBackend(
 	return api.NewMetricsWrapper(impl).(api.LocalBackend), nil
 }
 
+// tunablesFromFlags returns the initial storage sync tunables configured via the flags.
+func tunablesFromFlags() committee.Tunables {
+	return committee.Tunables{
+		MaxInFlightRounds:    viper.GetUint64(CfgWorkerMaxInFlightRounds),
+		GetDiffTimeout:       viper.GetDuration(CfgWorkerGetDiffTimeout),
+		RetryInitialInterval: viper.GetDuration(CfgWorkerRetryInitialInterval),
+		RetryMaxInterval:     viper.GetDuration(CfgWorkerRetryMaxInterval),
+	}
+}
+
+// parseRuntimeTunables parses the per-runtime tunables overrides, which are given as
+// <runtime-id>=<name>:<value>;... where name is the name of one of the tunables flags without the
+// worker.storage prefix. Tunables that are not overridden are taken from the given base tunables.
+func parseRuntimeTunables(raw map[string]string, base committee.Tunables) (map[common.Namespace]committee.Tunables, error) {
+	runtimeTunables := make(map[common.Namespace]committee.Tunables, len(raw))
+	for idStr, overrides := range raw {
+		var id common.Namespace
+		if err := id.UnmarshalText([]byte(idStr)); err != nil {
+			return nil, fmt.Errorf("malformed runtime ID in runtime tunables: %w", err)
+		}
+
+		tunables := base
+		for _, override := range strings.Split(overrides, ";") {
+			name, value, ok := strings.Cut(override, ":")
+			if !ok {
+				return nil, fmt.Errorf("malformed tunable for runtime %s: %s", id, override)
+			}
+
+			var err error
+			switch "worker.storage." + name {
+			case CfgWorkerMaxInFlightRounds:
+				tunables.MaxInFlightRounds, err = strconv.ParseUint(value, 10, 64)
+			case CfgWorkerGetDiffTimeout:
+				tunables.GetDiffTimeout, err = time.ParseDuration(value)
+			case CfgWorkerRetryInitialInterval:
+				tunables.RetryInitialInterval, err = time.ParseDuration(value)
+			case CfgWorkerRetryMaxInterval:
+				tunables.RetryMaxInterval, err = time.ParseDuration(value)
+			default:
+				return nil, fmt.Errorf("unknown tunable for runtime %s: %s", id, name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("malformed tunable %s for runtime %s: %w", name, id, err)
+			}
+		}
+		runtimeTunables[id] = tunables
+	}
+	return runtimeTunables, nil
+}
+
 func init() {
 	Flags.Uint(cfgWorkerFetcherCount, 4, "Number of concurrent storage diff fetchers")
+	Flags.StringToString(cfgWorkerFetcherWeights, map[string]string{}, "Per-runtime storage diff fetcher weights (format: <runtime-id>=<weight>,...)")
 	Flags.Bool(CfgWorkerPublicRPCEnabled, false, "Enable storage RPC access for all nodes")
 	Flags.Bool(CfgWorkerCheckpointerEnabled, false, "Enable the storage checkpointer")
 	Flags.Duration(CfgWorkerCheckpointCheckInterval, 1*time.Minute, "Storage checkpointer check interval")
 	Flags.Bool(CfgWorkerCheckpointSyncDisabled, false, "Disable initial storage sync from checkpoints")
+	Flags.StringSlice(CfgWorkerCheckpointSyncTrustedProviders, nil, "Node IDs of the only peers to restore checkpoints from (empty allows any peer)")
+	Flags.Duration(CfgWorkerBlockWatchdogInterval, 0, "Interval after which to poll for blocks when the block subscription stalls (0 disables)")
+	Flags.Duration(CfgWorkerLoopWatchdogInterval, 0, "Interval after which to report a stalled main loop while work is pending (0 disables)")
+	Flags.Duration(CfgWorkerRetryWarningThreshold, 0, "Duration after which to warn about a round whose diffs keep failing (0 disables)")
+	Flags.Duration(CfgWorkerConsistencyCheckInterval, 0, "Interval at which to compare recently finalized roots against peers (0 disables)")
+	Flags.Duration(CfgWorkerScrubInterval, 0, "Interval at which to verify the next finalized round of local storage in the background (0 disables)")
+	Flags.Duration(CfgWorkerScrubRecheckInterval, 24*time.Hour, "Minimum interval between the starts of consecutive passes of the local storage scrubber")
+	Flags.Duration(CfgWorkerBlockBacklogSampleInterval, 0, "Interval at which to sample the backlog of received blocks waiting to be processed (0 disables)")
+	Flags.String(CfgWorkerWriteLogCacheSize, "0", "Maximum size of the cache of recently fetched write logs (0 disables)")
+	Flags.String(CfgWorkerRootFilterSize, "0", "Size of the bloom filter of local storage roots (0 disables)")
+	Flags.Uint64(CfgWorkerApplyBatchSize, 0, "Maximum number of consecutive rounds to apply in a single batch (0 disables)")
+	Flags.Uint(CfgWorkerDiffChannelSize, 0, "Buffer size of the channel for fetched diffs")
+	Flags.Uint(CfgWorkerFinalizeChannelSize, 0, "Buffer size of the channel for finalization results")
+	Flags.Bool(CfgWorkerRequireCommitteePeers, false, "Only accept diffs served by runtime committee members")
+	Flags.String(CfgWorkerFinalizePolicy, committee.FinalizePolicyImmediate, "Storage round finalize policy (immediate, deferred)")
+	Flags.Uint64(CfgWorkerFinalizeInterval, 0, "Round interval at which the deferred finalize policy finalizes rounds")
+	Flags.String(CfgWorkerPruneOrdering, committee.PruneOrderingAfterFinalize, "Ordering of pruning with respect to in-progress round finalization (after_finalize, concurrent)")
+	Flags.String(CfgWorkerFetchOrdering, committee.FetchOrderingOldestFirst, "Order in which diff fetches of rounds being synced are submitted (oldest_first, round_robin)")
+	Flags.Uint(CfgWorkerMaxConcurrentFinalizes, 1, "Maximum number of batches of rounds to finalize concurrently if supported by the storage backend")
+	Flags.Uint(CfgWorkerMaxConcurrentWrites, 0, "Maximum number of concurrent applies and finalizations on local storage (0 is unlimited)")
+	Flags.Uint64(CfgWorkerMaxFinalizeRetries, 0, "Number of times to retry a failed round finalization (0 disables)")
+	Flags.Duration(CfgWorkerFinalizeRetryInterval, time.Second, "Initial interval between round finalization retries")
+	Flags.Duration(CfgWorkerFinalizeGracePeriod, 0, "Time that in-progress round finalizations are given to complete on shutdown (0 disables)")
+	Flags.String(CfgWorkerMaxBufferedDiffBytes, "0", "Soft limit on the total size of fetched diffs waiting to be applied (0 disables)")
+	Flags.Uint64(CfgWorkerPrioritizedTipRounds, 0, "Number of the most recent rounds to fetch before older rounds when catching up (0 disables)")
+	Flags.Bool(CfgWorkerPrioritizeStateFetches, false, "Fetch state diffs ahead of IO diffs when the fetch pool is saturated")
+	Flags.Uint64(CfgWorkerCheckpointRestoreLagThreshold, 0, "Number of rounds a running worker may fall behind before restoring from a checkpoint (0 disables)")
+	defaultTunables := committee.DefaultTunables()
+	Flags.Uint64(CfgWorkerMaxInFlightRounds, defaultTunables.MaxInFlightRounds, "Initial maximum number of rounds to fetch before waiting for them to be applied")
+	Flags.Duration(CfgWorkerGetDiffTimeout, defaultTunables.GetDiffTimeout, "Initial timeout for fetching a single diff (0 disables)")
+	Flags.Duration(CfgWorkerRetryInitialInterval, defaultTunables.RetryInitialInterval, "Initial interval after which to retry rounds that are still being synced")
+	Flags.Duration(CfgWorkerRetryMaxInterval, defaultTunables.RetryMaxInterval, "Maximum interval between retries of rounds that are still being synced")
+	Flags.StringToString(CfgWorkerRuntimeTunables, map[string]string{}, "Per-runtime storage sync tunables overrides (format: <runtime-id>=<name>:<value>;...,...)")
+	Flags.Bool(CfgWorkerDisableRemoteClient, false, "Disable fetching diffs and checkpoints from remote nodes, only replaying locally available state")
+	Flags.Uint(CfgWorkerTransitionLogSize, 0, "Number of the most recent worker state transitions to keep for debugging (0 disables)")
+	Flags.Uint64(CfgWorkerPersistedSummaries, 0, "Maximum number of seen block summaries to persist for use after a restart (0 disables)")
+	Flags.Uint64(CfgWorkerMaxGenesisBlockRetries, 0, "Number of times to retry retrieving the genesis block at startup (0 disables)")
+	Flags.Duration(CfgWorkerGenesisBlockRetryInterval, time.Second, "Initial interval between genesis block retrieval retries")
+	Flags.Uint64(CfgWorkerSyncStatsWindowSize, 0, "Number of recently finalized rounds to keep write log statistics for (0 disables)")
+	Flags.Uint64(CfgWorkerQuarantineThreshold, 0, "Number of distinct peers whose diffs for a round need to fail to apply before the round is quarantined (0 disables)")
+	Flags.Uint64(CfgWorkerSyncCeiling, 0, "Round up to which to sync and finalize rounds (0 disables)")
+	Flags.Uint(CfgWorkerDiffStreamPoolSize, 0, "Maximum number of idle streams per peer to reuse for fetching diffs (0 disables)")
+	Flags.Duration(CfgWorkerDiffStreamIdleTimeout, 30*time.Second, "Time after which idle pooled diff streams are closed")
+	Flags.Float64(CfgWorkerMaxDiffBandwidth, 0, "Maximum aggregate bandwidth for fetching diffs in MB/s (0 disables)")
+	Flags.Bool(CfgWorkerCompactDiffs, false, "Request storage diffs from peers in the compact delta-encoded format")
+	Flags.String(CfgWorkerDiffPeerSelector, storageSync.PeerSelectorScored, "Strategy for selecting the peer that is asked first for each storage diff (scored, round_robin)")
+	Flags.Bool(CfgWorkerSkipEqualRootApply, false, "Skip applying empty write logs for unchanged roots when supported by the storage backend")
+	Flags.Bool(CfgWorkerVerifyBlocks, false, "Verify that incoming blocks match the runtime history and extend the verified chain before syncing them")
 
 	Flags.String(CfgBackend, database.BackendNameBadgerDB, "Storage backend")
 	Flags.String(CfgMaxCacheSize, "64mb", "Maximum in-memory cache size")
@@ -99,5 +374,8 @@ func init() {
 	Flags.Bool(cfgCrashEnabled, false, "UNSAFE: Enable the crashing storage wrapper")
 	_ = Flags.MarkHidden(cfgCrashEnabled)
 
+	Flags.Bool(CfgWorkerDebugValidateRootChaining, false, "Validate storage root chaining assumptions (UNSAFE)")
+	_ = Flags.MarkHidden(CfgWorkerDebugValidateRootChaining)
+
 	_ = viper.BindPFlags(Flags)
 }
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/committee"
+)
+
+func TestParseRuntimeTunables(t *testing.T) {
+	require := require.New(t)
+
+	rt1 := common.NewTestNamespaceFromSeed([]byte("runtime tunables test ns 1"), 0)
+	rt2 := common.NewTestNamespaceFromSeed([]byte("runtime tunables test ns 2"), 0)
+	base := committee.DefaultTunables()
+
+	runtimeTunables, err := parseRuntimeTunables(nil, base)
+	require.NoError(err, "parseRuntimeTunables")
+	require.Empty(runtimeTunables, "no overrides should result in no runtime tunables")
+
+	runtimeTunables, err = parseRuntimeTunables(map[string]string{
+		rt1.String(): "max_in_flight_rounds:4;get_diff_timeout:10s",
+		rt2.String(): "retry_initial_interval:1s;retry_max_interval:1m",
+	}, base)
+	require.NoError(err, "parseRuntimeTunables")
+	require.Len(runtimeTunables, 2, "both runtimes should have tunables")
+
+	expected := base
+	expected.MaxInFlightRounds = 4
+	expected.GetDiffTimeout = 10 * time.Second
+	require.Equal(expected, runtimeTunables[rt1], "overrides should be applied on top of the base tunables")
+
+	expected = base
+	expected.RetryInitialInterval = time.Second
+	expected.RetryMaxInterval = time.Minute
+	require.Equal(expected, runtimeTunables[rt2], "overrides should be applied on top of the base tunables")
+
+	for _, raw := range []map[string]string{
+		{"not-a-runtime-id": "max_in_flight_rounds:4"},
+		{rt1.String(): "max_in_flight_rounds"},
+		{rt1.String(): "max_in_flight_rounds:four"},
+		{rt1.String(): "get_diff_timeout:10"},
+		{rt1.String(): "unknown_tunable:1"},
+	} {
+		_, err = parseRuntimeTunables(raw, base)
+		require.Error(err, "malformed runtime tunables should be rejected: %v", raw)
+	}
+}
@@ -3,8 +3,11 @@ package sync
 import (
 	"context"
 
+	"github.com/libp2p/go-libp2p/core"
+
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
 	"github.com/oasisprotocol/oasis-core/go/worker/common/p2p/rpc"
 )
@@ -13,6 +16,8 @@ import (
 type Client interface {
 	// GetDiff requests a write log of entries that must be applied to get from the first given root
 	// to the second one.
+	//
+	// Implementations must return a non-nil response when the returned error is nil.
 	GetDiff(ctx context.Context, request *GetDiffRequest) (*GetDiffResponse, rpc.PeerFeedback, error)
 
 	// GetCheckpoints returns a list of checkpoint metadata for all known checkpoints.
@@ -24,6 +29,9 @@ type Client interface {
 		request *GetCheckpointChunkRequest,
 		cp *Checkpoint,
 	) (*GetCheckpointChunkResponse, rpc.PeerFeedback, error)
+
+	// GetRoots requests the roots that multiple peers have for the given version.
+	GetRoots(ctx context.Context, request *GetRootsRequest) ([]*PeerRoots, error)
 }
 
 // Checkpoint contains checkpoint metadata together with peer information.
@@ -34,14 +42,32 @@ type Checkpoint struct {
 	Peers []rpc.PeerFeedback
 }
 
+// PeerRoots contains the roots that a peer has for a given version.
+type PeerRoots struct {
+	// Roots are the roots reported by the peer.
+	Roots []storage.Root
+
+	// Peer is the feedback structure of the peer that reported the roots.
+	Peer rpc.PeerFeedback
+}
+
 type client struct {
 	rcDiff        rpc.Client
 	rcCheckpoints rpc.Client
+
+	peerSelector PeerSelector
 }
 
 func (c *client) GetDiff(ctx context.Context, request *GetDiffRequest) (*GetDiffResponse, rpc.PeerFeedback, error) {
+	var opts []rpc.CallOption
+	if c.peerSelector != nil {
+		opts = append(opts, rpc.WithPeerSelectFn(func(candidates []core.PeerID) core.PeerID {
+			return c.peerSelector.Select(request.StartRoot, request.EndRoot, candidates)
+		}))
+	}
+
 	var rsp GetDiffResponse
-	pf, err := c.rcDiff.Call(ctx, MethodGetDiff, request, &rsp, MaxGetDiffResponseTime)
+	pf, err := c.rcDiff.Call(ctx, MethodGetDiff, request, &rsp, MaxGetDiffResponseTime, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -108,13 +134,49 @@ func (c *client) GetCheckpointChunk(
 	return &rsp, pf, nil
 }
 
+func (c *client) GetRoots(ctx context.Context, request *GetRootsRequest) ([]*PeerRoots, error) {
+	var rsp GetRootsResponse
+	rsps, pfs, err := c.rcDiff.CallMulti(ctx, MethodGetRoots, request, rsp,
+		MaxGetRootsResponseTime,
+		MaxGetRootsParallelRequests,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	peerRoots := make([]*PeerRoots, 0, len(rsps))
+	for i, peerRsp := range rsps {
+		roots := peerRsp.(*GetRootsResponse).Roots
+		peerRoots = append(peerRoots, &PeerRoots{
+			Roots: roots,
+			Peer:  pfs[i],
+		})
+
+		// Record success for a peer if it returned at least one root.
+		if len(roots) > 0 {
+			pfs[i].RecordSuccess()
+		}
+	}
+	return peerRoots, nil
+}
+
 // NewClient creates a new storage sync protocol client.
-func NewClient(p2p rpc.P2P, runtimeID common.Namespace) Client {
+//
+// The given peer selector (if any) decides which peer is asked first for each diff, otherwise the
+// best scored peer is used. The given options are used for the client that is used to fetch diffs.
+func NewClient(
+	p2p rpc.P2P,
+	runtimeID common.Namespace,
+	peerSelector PeerSelector,
+	diffOpts ...rpc.ClientOption,
+) Client {
 	return &client{
+		peerSelector: peerSelector,
+
 		// Use two separate clients for the same protocol. This is to make sure that peers are
 		// scored differently between the two use cases (syncing diffs vs. syncing checkpoints). We
 		// could consider separating this into two protocols in the future.
-		rcDiff:        rpc.NewClient(p2p, runtimeID, StorageSyncProtocolID, StorageSyncProtocolVersion),
+		rcDiff:        rpc.NewClient(p2p, runtimeID, StorageSyncProtocolID, StorageSyncProtocolVersion, diffOpts...),
 		rcCheckpoints: rpc.NewClient(p2p, runtimeID, StorageSyncProtocolID, StorageSyncProtocolVersion),
 	}
 }
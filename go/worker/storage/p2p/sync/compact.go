@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"fmt"
+
+	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+// CompactLogEntry is a delta-encoded write log entry.
+//
+// Instead of the full key, each entry only carries the suffix that differs from the key of the
+// preceding entry in the same write log.
+type CompactLogEntry struct {
+	_ struct{} `cbor:",toarray"` // nolint
+
+	// Shared is the length of the prefix shared with the key of the preceding entry.
+	Shared uint32
+	// Suffix is the remainder of the key after the shared prefix.
+	Suffix []byte
+	// Value is the entry value (nil for deletions).
+	Value []byte
+}
+
+// CompactWriteLog is a delta-encoded write log.
+type CompactWriteLog []CompactLogEntry
+
+// EncodeCompactWriteLog delta-encodes the given write log.
+func EncodeCompactWriteLog(writeLog storage.WriteLog) CompactWriteLog {
+	if writeLog == nil {
+		return nil
+	}
+
+	compact := make(CompactWriteLog, 0, len(writeLog))
+	var prevKey []byte
+	for _, entry := range writeLog {
+		shared := commonPrefixLen(prevKey, entry.Key)
+		compact = append(compact, CompactLogEntry{
+			Shared: uint32(shared),
+			Suffix: entry.Key[shared:],
+			Value:  entry.Value,
+		})
+		prevKey = entry.Key
+	}
+	return compact
+}
+
+// DecodeCompactWriteLog decodes a delta-encoded write log back into a standard write log.
+func DecodeCompactWriteLog(compact CompactWriteLog) (storage.WriteLog, error) {
+	if compact == nil {
+		return nil, nil
+	}
+
+	writeLog := make(storage.WriteLog, 0, len(compact))
+	var prevKey []byte
+	for i, entry := range compact {
+		if uint64(entry.Shared) > uint64(len(prevKey)) {
+			return nil, fmt.Errorf("storage/sync: malformed compact write log entry %d: shared prefix too long", i)
+		}
+
+		key := make([]byte, 0, int(entry.Shared)+len(entry.Suffix))
+		key = append(key, prevKey[:entry.Shared]...)
+		key = append(key, entry.Suffix...)
+
+		writeLog = append(writeLog, storage.LogEntry{
+			Key:   key,
+			Value: entry.Value,
+		})
+		prevKey = key
+	}
+	return writeLog, nil
+}
+
+func commonPrefixLen(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
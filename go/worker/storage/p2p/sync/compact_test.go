@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestCompactWriteLog(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		name     string
+		writeLog storage.WriteLog
+	}{
+		{"Nil", nil},
+		{"Empty", storage.WriteLog{}},
+		{"Single", storage.WriteLog{{Key: []byte("key"), Value: []byte("value")}}},
+		{"SharedPrefixes", storage.WriteLog{
+			{Key: []byte("account/alice/balance"), Value: []byte("100")},
+			{Key: []byte("account/alice/nonce"), Value: []byte("1")},
+			{Key: []byte("account/bob/balance"), Value: []byte("200")},
+			{Key: []byte("account/bob"), Value: nil},
+			{Key: []byte("account/bob"), Value: []byte{}},
+			{Key: []byte("other"), Value: []byte("x")},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			compact := EncodeCompactWriteLog(tc.writeLog)
+			decoded, err := DecodeCompactWriteLog(compact)
+			require.NoError(err, "DecodeCompactWriteLog")
+			require.EqualValues(tc.writeLog, decoded, "decoded write log should match")
+
+			// Round-trip through the wire format as well.
+			rsp := GetDiffResponse{CompactWriteLog: compact}
+			var rspDec GetDiffResponse
+			err = cbor.Unmarshal(cbor.Marshal(rsp), &rspDec)
+			require.NoError(err, "cbor.Unmarshal")
+			decoded, err = rspDec.DecodeWriteLog()
+			require.NoError(err, "DecodeWriteLog")
+			require.True(decoded.Equal(tc.writeLog), "decoded write log should match")
+			for i := range decoded {
+				require.Equal(tc.writeLog[i].Type(), decoded[i].Type(), "entry type should be preserved")
+			}
+		})
+	}
+
+	// Shared prefixes should not be repeated.
+	compact := EncodeCompactWriteLog(storage.WriteLog{
+		{Key: []byte("prefix/a"), Value: []byte("1")},
+		{Key: []byte("prefix/b"), Value: []byte("2")},
+	})
+	require.EqualValues(0, compact[0].Shared)
+	require.EqualValues(7, compact[1].Shared)
+	require.EqualValues([]byte("b"), compact[1].Suffix)
+}
+
+func TestCompactWriteLogMalformed(t *testing.T) {
+	require := require.New(t)
+
+	_, err := DecodeCompactWriteLog(CompactWriteLog{
+		{Shared: 0, Suffix: []byte("key")},
+		{Shared: 4, Suffix: []byte("x")},
+	})
+	require.Error(err, "DecodeCompactWriteLog should fail with a too long shared prefix")
+
+	rsp := GetDiffResponse{
+		WriteLog:        storage.WriteLog{{Key: []byte("key"), Value: []byte("value")}},
+		CompactWriteLog: CompactWriteLog{{Suffix: []byte("key"), Value: []byte("value")}},
+	}
+	_, err = rsp.DecodeWriteLog()
+	require.Error(err, "DecodeWriteLog should fail when both formats are present")
+}
+
+func TestDecodeWriteLogPlain(t *testing.T) {
+	require := require.New(t)
+
+	writeLog := storage.WriteLog{{Key: []byte("key"), Value: []byte("value")}}
+	rsp := GetDiffResponse{WriteLog: writeLog}
+	decoded, err := rsp.DecodeWriteLog()
+	require.NoError(err, "DecodeWriteLog")
+	require.EqualValues(writeLog, decoded, "plain write log should be returned as is")
+}
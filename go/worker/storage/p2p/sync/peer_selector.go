@@ -0,0 +1,76 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/libp2p/go-libp2p/core"
+
+	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+const (
+	// PeerSelectorScored is the name of the peer selector which prefers the best scored peer.
+	PeerSelectorScored = "scored"
+	// PeerSelectorRoundRobin is the name of the round-robin peer selector.
+	PeerSelectorRoundRobin = "round_robin"
+)
+
+// PeerSelector is a strategy for selecting the peer from which a diff is requested.
+type PeerSelector interface {
+	// Select returns the peer that should be asked first for the diff between the given roots.
+	//
+	// The candidates are never empty and are ordered by past experience with the peers (best
+	// first). In case the selected peer fails to serve the diff, the remaining candidates are
+	// tried in order.
+	Select(startRoot, endRoot storage.Root, candidates []core.PeerID) core.PeerID
+}
+
+type scoredPeerSelector struct{}
+
+func (scoredPeerSelector) Select(startRoot, endRoot storage.Root, candidates []core.PeerID) core.PeerID {
+	return candidates[0]
+}
+
+// NewScoredPeerSelector creates a peer selector which always selects the best scored peer.
+//
+// This is the same as not using a peer selector at all.
+func NewScoredPeerSelector() PeerSelector {
+	return scoredPeerSelector{}
+}
+
+type roundRobinPeerSelector struct {
+	next uint64
+}
+
+func (s *roundRobinPeerSelector) Select(startRoot, endRoot storage.Root, candidates []core.PeerID) core.PeerID {
+	// Candidates are ordered by score, which changes between calls, so rotate through the peers
+	// in a stable order instead.
+	peers := append([]core.PeerID{}, candidates...)
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i] < peers[j]
+	})
+
+	idx := atomic.AddUint64(&s.next, 1) - 1
+	return peers[idx%uint64(len(peers))]
+}
+
+// NewRoundRobinPeerSelector creates a peer selector which spreads diff requests evenly among the
+// candidate peers.
+func NewRoundRobinPeerSelector() PeerSelector {
+	return &roundRobinPeerSelector{}
+}
+
+// NewPeerSelector creates a new peer selector with the given name.
+func NewPeerSelector(name string) (PeerSelector, error) {
+	switch strings.ToLower(name) {
+	case PeerSelectorScored:
+		return NewScoredPeerSelector(), nil
+	case PeerSelectorRoundRobin:
+		return NewRoundRobinPeerSelector(), nil
+	default:
+		return nil, fmt.Errorf("unsupported peer selector: '%s'", name)
+	}
+}
@@ -0,0 +1,44 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/stretchr/testify/require"
+
+	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+)
+
+func TestPeerSelector(t *testing.T) {
+	require := require.New(t)
+
+	var root storage.Root
+	peers := []core.PeerID{"peer-c", "peer-a", "peer-b"}
+
+	// The scored selector should always select the best scored peer.
+	ps, err := NewPeerSelector(PeerSelectorScored)
+	require.NoError(err, "NewPeerSelector(scored)")
+	for i := 0; i < 3; i++ {
+		require.Equal(peers[0], ps.Select(root, root, peers), "scored peer selector should select the best peer")
+	}
+
+	// The round-robin selector should rotate through all peers, independent of their order.
+	ps, err = NewPeerSelector(PeerSelectorRoundRobin)
+	require.NoError(err, "NewPeerSelector(round_robin)")
+	var selected []core.PeerID
+	for i := 0; i < 6; i++ {
+		candidates := append([]core.PeerID{}, peers...)
+		if i%2 == 1 {
+			candidates[0], candidates[2] = candidates[2], candidates[0]
+		}
+		selected = append(selected, ps.Select(root, root, candidates))
+	}
+	require.Equal([]core.PeerID{"peer-a", "peer-b", "peer-c", "peer-a", "peer-b", "peer-c"}, selected,
+		"round-robin peer selector should rotate through peers",
+	)
+	require.Equal([]core.PeerID{"peer-c", "peer-a", "peer-b"}, peers, "candidates should not be modified")
+
+	// Unknown selectors.
+	_, err = NewPeerSelector("unknown")
+	require.Error(err, "NewPeerSelector(unknown)")
+}
@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
@@ -13,7 +14,7 @@ import (
 const StorageSyncProtocolID = "storagesync"
 
 // StorageSyncProtocolVersion is the supported version of the storage sync protocol.
-var StorageSyncProtocolVersion = version.Version{Major: 1, Minor: 0, Patch: 0}
+var StorageSyncProtocolVersion = version.Version{Major: 1, Minor: 2, Patch: 0}
 
 // Constants related to the GetDiff method.
 const (
@@ -25,11 +26,30 @@ const (
 type GetDiffRequest struct {
 	StartRoot storage.Root `json:"start_root"`
 	EndRoot   storage.Root `json:"end_root"`
+
+	// Compact signals that the requester supports the delta-encoded write log format.
+	Compact bool `json:"compact,omitempty"`
 }
 
 // GetDiffResponse is a response to a GetDiff request.
 type GetDiffResponse struct {
 	WriteLog storage.WriteLog `json:"write_log,omitempty"`
+
+	// CompactWriteLog is the delta-encoded write log, set instead of WriteLog in case the
+	// requester signalled support for the compact format.
+	CompactWriteLog CompactWriteLog `json:"compact_write_log,omitempty"`
+}
+
+// DecodeWriteLog returns the write log carried by the response, decoding the compact format if
+// present and falling back to the plain format otherwise.
+func (r *GetDiffResponse) DecodeWriteLog() (storage.WriteLog, error) {
+	if r.CompactWriteLog == nil {
+		return r.WriteLog, nil
+	}
+	if r.WriteLog != nil {
+		return nil, fmt.Errorf("storage/sync: response contains both plain and compact write logs")
+	}
+	return DecodeCompactWriteLog(r.CompactWriteLog)
 }
 
 // Constants related to the GetCheckpoints method.
@@ -67,3 +87,20 @@ type GetCheckpointChunkRequest struct {
 type GetCheckpointChunkResponse struct {
 	Chunk []byte `json:"chunk,omitempty"`
 }
+
+// Constants related to the GetRoots method.
+const (
+	MethodGetRoots              = "GetRoots"
+	MaxGetRootsResponseTime     = 5 * time.Second
+	MaxGetRootsParallelRequests = 5
+)
+
+// GetRootsRequest is a GetRoots request.
+type GetRootsRequest struct {
+	Version uint64 `json:"version"`
+}
+
+// GetRootsResponse is a response to a GetRoots request.
+type GetRootsResponse struct {
+	Roots []storage.Root `json:"roots,omitempty"`
+}
@@ -38,6 +38,13 @@ func (s *service) HandleRequest(ctx context.Context, method string, body cbor.Ra
 		}
 
 		return s.handleGetCheckpointChunk(ctx, &rq)
+	case MethodGetRoots:
+		var rq GetRootsRequest
+		if err := cbor.Unmarshal(body, &rq); err != nil {
+			return nil, rpc.ErrBadRequest
+		}
+
+		return s.handleGetRoots(ctx, &rq)
 	default:
 		return nil, rpc.ErrMethodNotSupported
 	}
@@ -68,6 +75,10 @@ func (s *service) handleGetDiff(ctx context.Context, request *GetDiffRequest) (*
 		}
 		rsp.WriteLog = append(rsp.WriteLog, chunk)
 	}
+	if request.Compact {
+		rsp.CompactWriteLog = EncodeCompactWriteLog(rsp.WriteLog)
+		rsp.WriteLog = nil
+	}
 	return &rsp, nil
 }
 
@@ -102,6 +113,23 @@ func (s *service) handleGetCheckpointChunk(ctx context.Context, request *GetChec
 	}, nil
 }
 
+func (s *service) handleGetRoots(ctx context.Context, request *GetRootsRequest) (*GetRootsResponse, error) {
+	// Roots can only be queried in case the node database is available.
+	localBackend, ok := s.backend.(storage.LocalBackend)
+	if !ok {
+		return nil, rpc.ErrMethodNotSupported
+	}
+
+	roots, err := localBackend.NodeDB().GetRootsForVersion(ctx, request.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetRootsResponse{
+		Roots: roots,
+	}, nil
+}
+
 // NewServer creates a new storage sync protocol server.
 func NewServer(runtimeID common.Namespace, backend storage.Backend) rpc.Server {
 	return rpc.NewServer(runtimeID, StorageSyncProtocolID, StorageSyncProtocolVersion, &service{backend})
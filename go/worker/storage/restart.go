@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/host"
+	committeeCommon "github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/committee"
+)
+
+var _ committeeCommon.NodeHooks = (*runtimeHooks)(nil)
+
+// runtimeHooks forwards the common committee node hooks of a runtime to the current storage
+// committee node of the runtime, as the node is replaced when the runtime worker is restarted
+// while hooks can't be removed from the common committee node.
+type runtimeHooks struct {
+	w  *Worker
+	id common.Namespace
+}
+
+// withNode calls the given function with the current storage committee node of the runtime. The
+// node is not replaced while the function is running.
+func (h *runtimeHooks) withNode(fn func(node *committee.Node)) {
+	h.w.runtimesLock.RLock()
+	defer h.w.runtimesLock.RUnlock()
+
+	fn(h.w.runtimes[h.id])
+}
+
+func (h *runtimeHooks) HandlePeerTx(ctx context.Context, tx []byte) (err error) {
+	h.withNode(func(node *committee.Node) {
+		err = node.HandlePeerTx(ctx, tx)
+	})
+	return
+}
+
+func (h *runtimeHooks) HandleEpochTransitionLocked(snapshot *committeeCommon.EpochSnapshot) {
+	h.withNode(func(node *committee.Node) {
+		node.HandleEpochTransitionLocked(snapshot)
+	})
+}
+
+func (h *runtimeHooks) HandleNewBlockEarlyLocked(blk *block.Block) {
+	h.withNode(func(node *committee.Node) {
+		node.HandleNewBlockEarlyLocked(blk)
+	})
+}
+
+func (h *runtimeHooks) HandleNewBlockLocked(blk *block.Block) {
+	h.withNode(func(node *committee.Node) {
+		node.HandleNewBlockLocked(blk)
+	})
+}
+
+func (h *runtimeHooks) HandleNewEventLocked(ev *roothash.Event) {
+	h.withNode(func(node *committee.Node) {
+		node.HandleNewEventLocked(ev)
+	})
+}
+
+func (h *runtimeHooks) HandleRuntimeHostEventLocked(ev *host.Event) {
+	h.withNode(func(node *committee.Node) {
+		node.HandleRuntimeHostEventLocked(ev)
+	})
+}
+
+func (h *runtimeHooks) Initialized() (ch <-chan struct{}) {
+	h.withNode(func(node *committee.Node) {
+		ch = node.Initialized()
+	})
+	return
+}
+
+// waitRuntimeQuit waits for the worker of the given runtime to terminate, unless it is being
+// restarted.
+func (w *Worker) waitRuntimeQuit(id common.Namespace) {
+	for {
+		node := w.GetRuntime(id)
+		<-node.Quit()
+
+		// Wait for any restart in progress to complete.
+		w.restartLock.Lock()
+		restarted := w.GetRuntime(id) != node
+		w.restartLock.Unlock()
+		if !restarted {
+			return
+		}
+	}
+}
+
+// RestartRuntimeWorker restarts the worker of the given runtime without affecting the workers of
+// other runtimes.
+//
+// The current storage committee node of the runtime is stopped, waiting for any in-progress work
+// to be drained as configured, and is replaced by a new node which resumes syncing from local
+// storage. In case the new node can't be created, the runtime worker remains stopped and the
+// restart can be retried.
+func (w *Worker) RestartRuntimeWorker(runtimeID common.Namespace) error {
+	w.restartLock.Lock()
+	defer w.restartLock.Unlock()
+
+	old := w.GetRuntime(runtimeID)
+	newNode := w.nodeFactories[runtimeID]
+	switch {
+	case old == nil || newNode == nil:
+		return api.ErrRuntimeNotFound
+	case !w.started:
+		return fmt.Errorf("storage worker not started")
+	case w.stopped:
+		return fmt.Errorf("storage worker stopped")
+	}
+
+	w.logger.Info("restarting runtime worker",
+		"runtime_id", runtimeID,
+	)
+
+	old.Stop()
+	<-old.Quit()
+
+	node, err := newNode()
+	if err != nil {
+		w.logger.Error("failed to create new worker for runtime",
+			"err", err,
+			"runtime_id", runtimeID,
+		)
+		return fmt.Errorf("failed to create storage worker for runtime %s: %w", runtimeID, err)
+	}
+
+	w.runtimesLock.Lock()
+	w.runtimes[runtimeID] = node
+	w.runtimesLock.Unlock()
+
+	// No more hooks can be forwarded to the old node at this point.
+	old.Cleanup()
+
+	if err = node.Start(); err != nil {
+		return fmt.Errorf("failed to start storage worker for runtime %s: %w", runtimeID, err)
+	}
+
+	w.logger.Info("runtime worker restarted",
+		"runtime_id", runtimeID,
+	)
+
+	return nil
+}
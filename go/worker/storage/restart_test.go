@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	runtimeRegistry "github.com/oasisprotocol/oasis-core/go/runtime/registry"
+	workerCommon "github.com/oasisprotocol/oasis-core/go/worker/common"
+	committeeCommon "github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/committee"
+)
+
+type testRuntime struct {
+	runtimeRegistry.Runtime
+
+	id common.Namespace
+}
+
+func (rt *testRuntime) ID() common.Namespace {
+	return rt.id
+}
+
+type testBlockSource struct{}
+
+func (s *testBlockSource) GetGenesisBlock(ctx context.Context, request *roothash.RuntimeRequest) (*block.Block, error) {
+	return block.NewGenesisBlock(request.RuntimeID, 0), nil
+}
+
+func (s *testBlockSource) GetLatestBlock(ctx context.Context, request *roothash.RuntimeRequest) (*block.Block, error) {
+	return block.NewGenesisBlock(request.RuntimeID, 0), nil
+}
+
+func TestRestartRuntimeWorker(t *testing.T) {
+	require := require.New(t)
+
+	rt1 := common.NewTestNamespaceFromSeed([]byte("restart runtime worker test ns 1"), 0)
+	rt2 := common.NewTestNamespaceFromSeed([]byte("restart runtime worker test ns 2"), 0)
+
+	w := &Worker{
+		logger:        logging.GetLogger("worker/storage/test"),
+		runtimes:      make(map[common.Namespace]*committee.Node),
+		nodeFactories: make(map[common.Namespace]func() (*committee.Node, error)),
+	}
+	hooks := make(map[common.Namespace]*runtimeHooks)
+	for _, id := range []common.Namespace{rt1, rt2} {
+		commonNode := &committeeCommon.Node{Runtime: &testRuntime{id: id}}
+		newNode := func() (*committee.Node, error) {
+			return committee.NewNode(commonNode, nil, nil, nil, workerCommon.Config{}, nil, nil,
+				&committee.CheckpointSyncConfig{Disabled: true},
+				&committee.Config{ObserverMode: true, BlockSource: &testBlockSource{}},
+			)
+		}
+		node, err := newNode()
+		require.NoError(err, "NewNode")
+		require.NoError(node.Start(), "Start")
+		w.runtimes[id] = node
+		w.nodeFactories[id] = newNode
+		hooks[id] = &runtimeHooks{w: w, id: id}
+	}
+	w.started = true
+	t.Cleanup(func() {
+		for _, node := range w.getRuntimes() {
+			node.Stop()
+		}
+	})
+	goroutines := runtime.NumGoroutine()
+
+	old, other := w.GetRuntime(rt1), w.GetRuntime(rt2)
+	require.NoError(w.RestartRuntimeWorker(rt1), "RestartRuntimeWorker")
+
+	// The restarted runtime should have a new running node, to which hooks are forwarded.
+	node := w.GetRuntime(rt1)
+	require.NotEqual(old, node, "restarted runtime should have a new node")
+	require.Equal(node.Initialized(), hooks[rt1].Initialized(), "hooks should be forwarded to the new node")
+	select {
+	case <-old.Quit():
+	default:
+		require.Fail("old node should be stopped")
+	}
+	select {
+	case <-node.Quit():
+		require.Fail("new node should be running")
+	default:
+	}
+
+	// The other runtime should not be disturbed.
+	require.Equal(other, w.GetRuntime(rt2), "other runtime should keep its node")
+	select {
+	case <-other.Quit():
+		require.Fail("other node should keep running")
+	default:
+	}
+
+	// Goroutines of the old node should not be leaked.
+	for start := time.Now(); runtime.NumGoroutine() > goroutines; time.Sleep(10 * time.Millisecond) {
+		require.Less(time.Since(start), 5*time.Second, "goroutines of the old node should terminate")
+	}
+
+	require.ErrorIs(w.RestartRuntimeWorker(common.Namespace{}), api.ErrRuntimeNotFound, "unknown runtime")
+
+	// Runtime workers should not be restarted once the worker is stopped.
+	w.stopped = true
+	require.Error(w.RestartRuntimeWorker(rt1), "restart after stop")
+	require.Equal(node, w.GetRuntime(rt1), "node should not be replaced after stop")
+}
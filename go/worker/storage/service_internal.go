@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"sort"
 
 	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
 )
@@ -9,7 +11,7 @@ import (
 var _ api.StorageWorker = (*Worker)(nil)
 
 func (w *Worker) GetLastSyncedRound(ctx context.Context, request *api.GetLastSyncedRoundRequest) (*api.GetLastSyncedRoundResponse, error) {
-	node := w.runtimes[request.RuntimeID]
+	node := w.GetRuntime(request.RuntimeID)
 	if node == nil {
 		return nil, api.ErrRuntimeNotFound
 	}
@@ -23,10 +25,52 @@ func (w *Worker) GetLastSyncedRound(ctx context.Context, request *api.GetLastSyn
 }
 
 func (w *Worker) PauseCheckpointer(ctx context.Context, request *api.PauseCheckpointerRequest) error {
-	node := w.runtimes[request.RuntimeID]
+	node := w.GetRuntime(request.RuntimeID)
 	if node == nil {
 		return api.ErrRuntimeNotFound
 	}
 
 	return node.PauseCheckpointer(request.Pause)
 }
+
+func (w *Worker) UnquarantineRound(ctx context.Context, request *api.UnquarantineRoundRequest) error {
+	node := w.GetRuntime(request.RuntimeID)
+	if node == nil {
+		return api.ErrRuntimeNotFound
+	}
+
+	return node.Unquarantine(request.Round)
+}
+
+func (w *Worker) SetSyncCeiling(ctx context.Context, request *api.SetSyncCeilingRequest) error {
+	node := w.GetRuntime(request.RuntimeID)
+	if node == nil {
+		return api.ErrRuntimeNotFound
+	}
+
+	return node.SetSyncCeiling(request.Round)
+}
+
+func (w *Worker) GetSyncedRuntimes(ctx context.Context) ([]*api.SyncedRuntime, error) {
+	return w.SyncedRuntimes(), nil
+}
+
+// SyncedRuntimes returns the last synced round of every runtime synced by the storage worker,
+// ordered by runtime identifier.
+func (w *Worker) SyncedRuntimes() []*api.SyncedRuntime {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	synced := make([]*api.SyncedRuntime, 0, len(w.runtimes))
+	for id, node := range w.runtimes {
+		round, _, _ := node.GetLastSynced()
+		synced = append(synced, &api.SyncedRuntime{
+			RuntimeID:       id,
+			LastSyncedRound: round,
+		})
+	}
+	sort.Slice(synced, func(i, j int) bool {
+		return bytes.Compare(synced[i].RuntimeID[:], synced[j].RuntimeID[:]) < 0
+	})
+	return synced
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/committee"
+)
+
+func TestSyncedRuntimes(t *testing.T) {
+	require := require.New(t)
+
+	rt1 := common.NewTestNamespaceFromSeed([]byte("synced runtimes test ns 1"), 0)
+	rt2 := common.NewTestNamespaceFromSeed([]byte("synced runtimes test ns 2"), 0)
+
+	w := &Worker{
+		runtimes: map[common.Namespace]*committee.Node{
+			rt1: {},
+			rt2: {},
+		},
+	}
+
+	synced, err := w.GetSyncedRuntimes(context.Background())
+	require.NoError(err, "GetSyncedRuntimes")
+	require.Len(synced, 2, "all registered runtimes should be reported")
+	require.Equal(synced, w.SyncedRuntimes(), "GetSyncedRuntimes should match SyncedRuntimes")
+
+	ids := map[common.Namespace]bool{}
+	for i, rt := range synced {
+		ids[rt.RuntimeID] = true
+		round, _, _ := w.runtimes[rt.RuntimeID].GetLastSynced()
+		require.EqualValues(round, rt.LastSyncedRound, "last synced round should come from the runtime's node")
+		if i > 0 {
+			require.True(synced[i-1].RuntimeID.String() < rt.RuntimeID.String(), "runtimes should be ordered by identifier")
+		}
+	}
+	require.True(ids[rt1] && ids[rt2], "both runtimes should be reported")
+
+	// No runtimes should be reported when there are none registered.
+	w.runtimes = map[common.Namespace]*committee.Node{}
+	require.Empty(w.SyncedRuntimes(), "no runtimes should be reported without registered nodes")
+}
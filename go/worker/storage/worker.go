@@ -1,16 +1,22 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
 
 	"github.com/spf13/viper"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/grpc"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/common/workerpool"
 	genesis "github.com/oasisprotocol/oasis-core/go/genesis/api"
+	cmdFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
 	runtimeRegistry "github.com/oasisprotocol/oasis-core/go/runtime/registry"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
 	workerCommon "github.com/oasisprotocol/oasis-core/go/worker/common"
@@ -18,8 +24,13 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/worker/registration"
 	storageWorkerAPI "github.com/oasisprotocol/oasis-core/go/worker/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/worker/storage/committee"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
 )
 
+// persistedSummariesFilename is the name of the file in the runtime state directory in which the
+// storage worker persists summaries of seen blocks.
+const persistedSummariesFilename = "storage-worker-summaries.cbor"
+
 // Worker is a worker handling storage operations.
 type Worker struct {
 	enabled bool
@@ -31,8 +42,20 @@ type Worker struct {
 	initCh chan struct{}
 	quitCh chan struct{}
 
-	runtimes  map[common.Namespace]*committee.Node
-	fetchPool *workerpool.Pool
+	runtimesLock sync.RWMutex
+	runtimes     map[common.Namespace]*committee.Node
+	// nodeFactories create new storage committee nodes for each runtime, holding the parameters
+	// needed to restart the worker of the runtime.
+	nodeFactories map[common.Namespace]func() (*committee.Node, error)
+
+	// restartLock serializes restarts of runtime workers with starting and stopping the worker.
+	restartLock sync.Mutex
+	started     bool
+	stopped     bool
+
+	fetchPool        *workerpool.Pool
+	fetchQueue       *committee.FetchQueue
+	bandwidthLimiter *committee.BandwidthLimiter
 }
 
 // New constructs a new storage worker.
@@ -51,14 +74,40 @@ func New(
 		initCh:       make(chan struct{}),
 		quitCh:       make(chan struct{}),
 		runtimes:     make(map[common.Namespace]*committee.Node),
+
+		nodeFactories: make(map[common.Namespace]func() (*committee.Node, error)),
 	}
 
 	if !enabled {
 		return s, nil
 	}
 
+	fetcherCount := viper.GetUint(cfgWorkerFetcherCount)
 	s.fetchPool = workerpool.New("storage_fetch")
-	s.fetchPool.Resize(viper.GetUint(cfgWorkerFetcherCount))
+	s.fetchPool.Resize(fetcherCount)
+	s.fetchQueue = committee.NewFetchQueue(s.fetchPool, fetcherCount)
+	for idStr, weightStr := range viper.GetStringMapString(cfgWorkerFetcherWeights) {
+		var id common.Namespace
+		if err := id.UnmarshalText([]byte(idStr)); err != nil {
+			return nil, fmt.Errorf("malformed runtime ID in fetcher weights: %w", err)
+		}
+		weight, err := strconv.ParseUint(weightStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed fetcher weight for runtime %s: %w", id, err)
+		}
+		if err = s.fetchQueue.SetWeight(id, weight); err != nil {
+			return nil, err
+		}
+	}
+
+	if maxBandwidth := viper.GetFloat64(CfgWorkerMaxDiffBandwidth); maxBandwidth > 0 {
+		// The limiter is shared by all runtimes so that it bounds the aggregate bandwidth.
+		bytesPerSecond := uint64(maxBandwidth * 1024 * 1024)
+		if bytesPerSecond == 0 {
+			return nil, fmt.Errorf("maximum diff bandwidth too low: %f MB/s", maxBandwidth)
+		}
+		s.bandwidthLimiter = committee.NewBandwidthLimiter(bytesPerSecond)
+	}
 
 	var checkpointerCfg *checkpoint.CheckpointerConfig
 	if viper.GetBool(CfgWorkerCheckpointerEnabled) {
@@ -107,26 +156,129 @@ func (w *Worker) registerRuntime(dataDir string, commonNode *committeeCommon.Nod
 	if err != nil {
 		return fmt.Errorf("can't create local storage backend: %w", err)
 	}
+	if rootFilterSize := uint64(viper.GetSizeInBytes(CfgWorkerRootFilterSize)); rootFilterSize > 0 {
+		localStorage, err = committee.NewRootFilterBackend(context.Background(), localStorage, id, rootFilterSize)
+		if err != nil {
+			return fmt.Errorf("can't create root filter: %w", err)
+		}
+	}
 
-	node, err := committee.NewNode(
-		commonNode,
-		w.fetchPool,
-		rp,
-		rpRPC,
-		w.commonWorker.GetConfig(),
-		localStorage,
-		checkpointerCfg,
-		&committee.CheckpointSyncConfig{
-			Disabled:          viper.GetBool(CfgWorkerCheckpointSyncDisabled),
-			ChunkFetcherCount: viper.GetUint(cfgWorkerFetcherCount),
-		},
+	finalizePolicy, err := committee.NewFinalizePolicy(
+		viper.GetString(CfgWorkerFinalizePolicy),
+		viper.GetUint64(CfgWorkerFinalizeInterval),
 	)
+	if err != nil {
+		return fmt.Errorf("bad finalize policy: %w", err)
+	}
+
+	pruneOrdering, err := committee.NewPruneOrdering(viper.GetString(CfgWorkerPruneOrdering))
+	if err != nil {
+		return fmt.Errorf("bad prune ordering: %w", err)
+	}
+
+	fetchOrdering, err := committee.NewFetchOrdering(viper.GetString(CfgWorkerFetchOrdering))
+	if err != nil {
+		return fmt.Errorf("bad fetch ordering: %w", err)
+	}
+
+	tunables := tunablesFromFlags()
+	runtimeTunables, err := parseRuntimeTunables(viper.GetStringMapString(CfgWorkerRuntimeTunables), tunables)
+	if err != nil {
+		return fmt.Errorf("bad runtime tunables: %w", err)
+	}
+
+	var trustedCheckpointProviders []signature.PublicKey
+	for _, pubkey := range viper.GetStringSlice(CfgWorkerCheckpointSyncTrustedProviders) {
+		var pk signature.PublicKey
+		if err = pk.UnmarshalText([]byte(pubkey)); err != nil {
+			return fmt.Errorf("malformed trusted checkpoint provider %s: %w", pubkey, err)
+		}
+		trustedCheckpointProviders = append(trustedCheckpointProviders, pk)
+	}
+
+	diffPeerSelector, err := storageSync.NewPeerSelector(viper.GetString(CfgWorkerDiffPeerSelector))
+	if err != nil {
+		return fmt.Errorf("bad diff peer selector: %w", err)
+	}
+
+	checkpointSyncCfg := &committee.CheckpointSyncConfig{
+		Disabled:          viper.GetBool(CfgWorkerCheckpointSyncDisabled),
+		ChunkFetcherCount: viper.GetUint(cfgWorkerFetcherCount),
+		TrustedProviders:  trustedCheckpointProviders,
+	}
+	cfg := &committee.Config{
+		DebugValidateRootChaining: viper.GetBool(CfgWorkerDebugValidateRootChaining) && cmdFlags.DebugDontBlameOasis(),
+		BlockWatchdogInterval:     viper.GetDuration(CfgWorkerBlockWatchdogInterval),
+		LoopWatchdogInterval:      viper.GetDuration(CfgWorkerLoopWatchdogInterval),
+		RetryWarningThreshold:     viper.GetDuration(CfgWorkerRetryWarningThreshold),
+		ConsistencyCheckInterval:  viper.GetDuration(CfgWorkerConsistencyCheckInterval),
+		ScrubInterval:             viper.GetDuration(CfgWorkerScrubInterval),
+		ScrubRecheckInterval:      viper.GetDuration(CfgWorkerScrubRecheckInterval),
+		WriteLogCacheSize:         uint64(viper.GetSizeInBytes(CfgWorkerWriteLogCacheSize)),
+		ApplyBatchSize:            viper.GetUint64(CfgWorkerApplyBatchSize),
+		RequireCommitteePeers:     viper.GetBool(CfgWorkerRequireCommitteePeers),
+		DiffChannelSize:           viper.GetUint(CfgWorkerDiffChannelSize),
+		FinalizeChannelSize:       viper.GetUint(CfgWorkerFinalizeChannelSize),
+		CompactDiffs:              viper.GetBool(CfgWorkerCompactDiffs),
+		DiffPeerSelector:          diffPeerSelector,
+		SkipEqualRootApply:        viper.GetBool(CfgWorkerSkipEqualRootApply),
+		VerifyBlocks:              viper.GetBool(CfgWorkerVerifyBlocks),
+		BandwidthLimiter:          w.bandwidthLimiter,
+		DiffStreamPoolSize:        viper.GetUint(CfgWorkerDiffStreamPoolSize),
+		DiffStreamIdleTimeout:     viper.GetDuration(CfgWorkerDiffStreamIdleTimeout),
+		FinalizePolicy:            finalizePolicy,
+		PruneOrdering:             pruneOrdering,
+		FetchOrdering:             fetchOrdering,
+		MaxConcurrentFinalizes:    viper.GetUint(CfgWorkerMaxConcurrentFinalizes),
+		MaxConcurrentWrites:       viper.GetUint(CfgWorkerMaxConcurrentWrites),
+		MaxFinalizeRetries:        viper.GetUint64(CfgWorkerMaxFinalizeRetries),
+		FinalizeRetryInterval:     viper.GetDuration(CfgWorkerFinalizeRetryInterval),
+		FinalizeGracePeriod:       viper.GetDuration(CfgWorkerFinalizeGracePeriod),
+		MaxGenesisBlockRetries:    viper.GetUint64(CfgWorkerMaxGenesisBlockRetries),
+		PrioritizedTipRounds:      viper.GetUint64(CfgWorkerPrioritizedTipRounds),
+		PrioritizeStateFetches:    viper.GetBool(CfgWorkerPrioritizeStateFetches),
+		TransitionLogSize:         viper.GetUint(CfgWorkerTransitionLogSize),
+		PersistedSummaries:        viper.GetUint64(CfgWorkerPersistedSummaries),
+		PersistedSummariesPath:    filepath.Join(path, persistedSummariesFilename),
+		MaxBufferedDiffBytes:      uint64(viper.GetSizeInBytes(CfgWorkerMaxBufferedDiffBytes)),
+		GenesisBlockRetryInterval: viper.GetDuration(CfgWorkerGenesisBlockRetryInterval),
+		SyncStatsWindowSize:       viper.GetUint64(CfgWorkerSyncStatsWindowSize),
+		QuarantineThreshold:       viper.GetUint64(CfgWorkerQuarantineThreshold),
+		SyncCeiling:               viper.GetUint64(CfgWorkerSyncCeiling),
+		DisableRemoteClient:       viper.GetBool(CfgWorkerDisableRemoteClient),
+		Tunables:                  &tunables,
+		RuntimeTunables:           runtimeTunables,
+
+		CheckpointRestoreLagThreshold: viper.GetUint64(CfgWorkerCheckpointRestoreLagThreshold),
+		BlockBacklogSampleInterval:    viper.GetDuration(CfgWorkerBlockBacklogSampleInterval),
+	}
+	// The storage sync client is shared by all nodes of the runtime, as it can't be released.
+	if !cfg.DisableRemoteClient {
+		cfg.StorageSyncClient = committee.NewStorageSyncClient(commonNode, cfg)
+	}
+
+	newNode := func() (*committee.Node, error) {
+		nodeCfg := *cfg
+		return committee.NewNode(
+			commonNode,
+			w.fetchQueue,
+			rp,
+			rpRPC,
+			w.commonWorker.GetConfig(),
+			localStorage,
+			checkpointerCfg,
+			checkpointSyncCfg,
+			&nodeCfg,
+		)
+	}
+	node, err := newNode()
 	if err != nil {
 		return err
 	}
 	commonNode.Runtime.RegisterStorage(localStorage)
-	commonNode.AddHooks(node)
+	commonNode.AddHooks(&runtimeHooks{w: w, id: id})
 	w.runtimes[id] = node
+	w.nodeFactories[id] = newNode
 
 	w.logger.Info("new runtime registered",
 		"runtime_id", id,
@@ -166,8 +318,8 @@ func (w *Worker) Start() error {
 	go func() {
 		defer close(w.quitCh)
 
-		for _, r := range w.runtimes {
-			<-r.Quit()
+		for _, id := range w.runtimeIDs() {
+			w.waitRuntimeQuit(id)
 		}
 		if w.fetchPool != nil {
 			<-w.fetchPool.Quit()
@@ -176,14 +328,18 @@ func (w *Worker) Start() error {
 
 	// Start all runtimes and wait for initialization.
 	go func() {
-		w.logger.Info("starting storage sync services", "num_runtimes", len(w.runtimes))
+		w.restartLock.Lock()
+		runtimes := w.getRuntimes()
+		w.logger.Info("starting storage sync services", "num_runtimes", len(runtimes))
 
-		for _, r := range w.runtimes {
+		for _, r := range runtimes {
 			_ = r.Start()
 		}
+		w.started = true
+		w.restartLock.Unlock()
 
 		// Wait for runtimes to be initialized and the node to be registered.
-		for _, r := range w.runtimes {
+		for _, r := range runtimes {
 			<-r.Initialized()
 		}
 
@@ -204,7 +360,12 @@ func (w *Worker) Stop() {
 		return
 	}
 
-	for _, r := range w.runtimes {
+	w.restartLock.Lock()
+	w.stopped = true
+	runtimes := w.getRuntimes()
+	w.restartLock.Unlock()
+
+	for _, r := range runtimes {
 		r.Stop()
 	}
 	if w.fetchPool != nil {
@@ -225,5 +386,32 @@ func (w *Worker) Cleanup() {
 //
 // In case the runtime with the specified id was not configured for this node it returns nil.
 func (w *Worker) GetRuntime(id common.Namespace) *committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
 	return w.runtimes[id]
 }
+
+// getRuntimes returns the storage committee nodes of all runtimes.
+func (w *Worker) getRuntimes() []*committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	runtimes := make([]*committee.Node, 0, len(w.runtimes))
+	for _, node := range w.runtimes {
+		runtimes = append(runtimes, node)
+	}
+	return runtimes
+}
+
+// runtimeIDs returns the identifiers of all runtimes.
+func (w *Worker) runtimeIDs() []common.Namespace {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	ids := make([]common.Namespace, 0, len(w.runtimes))
+	for id := range w.runtimes {
+		ids = append(ids, id)
+	}
+	return ids
+}